@@ -0,0 +1,123 @@
+package httpapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/coinbase/x402/go/extensions/types"
+	x402http "github.com/coinbase/x402/go/http"
+)
+
+// discoveryX402Version is the protocol version enforced by the payment middleware.
+const discoveryX402Version = 2
+
+// discoveryEntries builds /discovery/x402 entries from the routes enforced by
+// ConfigurePayments, so discovery cannot drift from what is actually charged.
+func discoveryEntries(routes x402http.RoutesConfig, lastUpdated string) []X402EndpointEntry {
+	patterns := make([]string, 0, len(routes))
+	for pattern := range routes {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	entries := make([]X402EndpointEntry, 0, len(routes))
+	for _, pattern := range patterns {
+		route := routes[pattern]
+		method, _, found := strings.Cut(pattern, " ")
+		if !found {
+			method = "GET"
+		}
+		input := X402InputSchema{
+			Method:      strings.ToUpper(method),
+			QueryParams: discoveryQueryParams(route),
+			Type:        "http",
+		}
+
+		accepts := make([]X402AcceptRequirement, 0, len(route.Accepts))
+		for _, option := range route.Accepts {
+			price, _ := option.Price.(map[string]interface{})
+			payTo, _ := option.PayTo.(string)
+			accepts = append(accepts, X402AcceptRequirement{
+				Asset:             stringValue(price["asset"]),
+				Description:       route.Description,
+				Extra:             stringMap(price["extra"]),
+				MaxAmountRequired: stringValue(price["amount"]),
+				MaxTimeoutSeconds: option.MaxTimeoutSeconds,
+				MimeType:          route.MimeType,
+				Network:           string(option.Network),
+				OutputSchema:      X402OutputSchema{Input: input},
+				PayTo:             payTo,
+				Resource:          route.Resource,
+				Scheme:            option.Scheme,
+			})
+		}
+
+		entries = append(entries, X402EndpointEntry{
+			Accepts:     accepts,
+			LastUpdated: lastUpdated,
+			Resource:    route.Resource,
+			Type:        "http",
+			X402Version: discoveryX402Version,
+		})
+	}
+	return entries
+}
+
+// discoveryQueryParams describes query parameters declared by a route's bazaar
+// extension by their JSON type and the extension's example value.
+func discoveryQueryParams(route x402http.RouteConfig) map[string]X402QueryParamSpec {
+	for _, extension := range route.Extensions {
+		discovery, ok := extension.(types.DiscoveryExtension)
+		if !ok {
+			continue
+		}
+		input, ok := discovery.Info.Input.(types.QueryInput)
+		if !ok || len(input.QueryParams) == 0 {
+			continue
+		}
+		params := make(map[string]X402QueryParamSpec, len(input.QueryParams))
+		for name, example := range input.QueryParams {
+			params[name] = X402QueryParamSpec{Type: jsonTypeName(example), Example: example}
+		}
+		return params
+	}
+	return nil
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return "boolean"
+	case int, int64, float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func stringMap(value interface{}) map[string]string {
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(raw))
+	for key, item := range raw {
+		out[key] = stringValue(item)
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package httpapi
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TrustedProxiesEnv names the comma-separated IPs or CIDRs whose
+// X-Forwarded-Proto and X-Forwarded-Host headers are used to build discovery
+// URLs. Unset means forwarded headers are ignored.
+const TrustedProxiesEnv = "TRUSTED_PROXIES"
+
+// trustedProxies is the allowlist of reverse proxies allowed to set the
+// advertised origin.
+type trustedProxies []netip.Prefix
+
+// trustedProxiesFromEnv parses TrustedProxiesEnv.
+func trustedProxiesFromEnv() (trustedProxies, error) {
+	return parseTrustedProxies(os.Getenv(TrustedProxiesEnv))
+}
+
+func parseTrustedProxies(value string) (trustedProxies, error) {
+	var proxies trustedProxies
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q: %w", TrustedProxiesEnv, entry, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", TrustedProxiesEnv, entry, err)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// trusts reports whether the request came directly from a trusted proxy.
+func (p trustedProxies) trusts(r *http.Request) bool {
+	if len(p) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestBaseURL returns the origin a client reached this server through.
+// A trusted proxy's X-Forwarded-Host (and X-Forwarded-Proto, or the
+// connection's TLS state when absent) replace the scheme and host of baseURL,
+// keeping its path. Otherwise baseURL is returned unchanged.
+func (p trustedProxies) requestBaseURL(r *http.Request, baseURL string) string {
+	if !p.trusts(r) {
+		return baseURL
+	}
+	host := firstForwardedValue(r.Header.Get("X-Forwarded-Host"))
+	if host == "" || !validForwardedHost(host) {
+		return baseURL
+	}
+	scheme := strings.ToLower(firstForwardedValue(r.Header.Get("X-Forwarded-Proto")))
+	switch scheme {
+	case "http", "https":
+	case "":
+		scheme = "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+	default:
+		return baseURL
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	parsed.Scheme = scheme
+	parsed.Host = host
+	return parsed.String()
+}
+
+// firstForwardedValue returns the client-most entry of a comma-separated
+// forwarded header.
+func firstForwardedValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// validForwardedHost accepts a bare host or host:port, rejecting anything that
+// would change the URL beyond its authority.
+func validForwardedHost(host string) bool {
+	if strings.ContainsAny(host, "/?#@\\ ") {
+		return false
+	}
+	parsed, err := url.Parse("//" + host)
+	return err == nil && parsed.Host == host && parsed.Hostname() != ""
+}
+
+// rebaseEntries rewrites resource URLs under from to sit under to.
+func rebaseEntries(entries []X402EndpointEntry, from, to string) []X402EndpointEntry {
+	if from == to {
+		return entries
+	}
+	rebase := func(value string) string {
+		if rest, ok := strings.CutPrefix(value, from); ok {
+			return to + rest
+		}
+		return value
+	}
+	for i := range entries {
+		entries[i].Resource = rebase(entries[i].Resource)
+		for j := range entries[i].Accepts {
+			entries[i].Accepts[j].Resource = rebase(entries[i].Accepts[j].Resource)
+		}
+	}
+	return entries
+}
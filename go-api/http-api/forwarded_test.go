@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func discoveryResourceFor(t *testing.T, r *gin.Engine, req *http.Request) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Entries []X402EndpointEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Entries) == 0 {
+		t.Fatalf("expected discovery entries")
+	}
+	entry := body.Entries[0]
+	if entry.Accepts[0].Resource != entry.Resource {
+		t.Fatalf("expected accepts resource %q to match entry %q", entry.Accepts[0].Resource, entry.Resource)
+	}
+	return entry.Resource
+}
+
+func TestDiscoveryX402ForwardedHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv(TrustedProxiesEnv, "192.0.2.0/24, 2001:db8::1")
+	r, _, err := NewRouter("http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "192.0.2.10:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"},
+			want:       "https://api.example.com/weather",
+		},
+		{
+			name:       "trusted proxy chain",
+			remoteAddr: "[2001:db8::1]:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https, http", "X-Forwarded-Host": "api.example.com:8443, internal"},
+			want:       "https://api.example.com:8443/weather",
+		},
+		{
+			name:       "headers absent",
+			remoteAddr: "192.0.2.10:41000",
+			want:       "http://localhost:8080/weather",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "203.0.113.9:41000",
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.example"},
+			want:       "http://localhost:8080/weather",
+		},
+		{
+			name:       "invalid host",
+			remoteAddr: "192.0.2.10:41000",
+			headers:    map[string]string{"X-Forwarded-Host": "evil.example/path"},
+			want:       "http://localhost:8080/weather",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/discovery/x402", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			if got := discoveryResourceFor(t, r, req); got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewRouterRejectsInvalidTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv(TrustedProxiesEnv, "not-an-ip")
+	if _, _, err := NewRouter(DefaultServerBaseURL); err == nil {
+		t.Fatalf("expected invalid %s to be rejected", TrustedProxiesEnv)
+	}
+}
@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+
+	// readinessTimeout bounds a single facilitator probe
+	readinessTimeout = 2 * time.Second
+	// readinessTTL is how long a probe result is reused
+	readinessTTL = 10 * time.Second
+	// readinessGrace keeps reporting ready after a failed probe if the
+	// facilitator answered successfully this recently
+	readinessGrace = 30 * time.Second
+)
+
+// ComponentStatus reports the health of one dependency.
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthResponse is returned by /healthz and /readyz.
+type HealthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// facilitatorProbe caches the result of pinging the facilitator's /supported.
+type facilitatorProbe struct {
+	facilitator x402local.Facilitator
+	timeout     time.Duration
+	ttl         time.Duration
+	grace       time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lastOK    time.Time
+	lastErr   error
+	// probing is closed when the in-flight probe finishes; nil when idle.
+	probing chan struct{}
+}
+
+func newFacilitatorProbe(facilitator x402local.Facilitator) *facilitatorProbe {
+	return &facilitatorProbe{
+		facilitator: facilitator,
+		timeout:     readinessTimeout,
+		ttl:         readinessTTL,
+		grace:       readinessGrace,
+	}
+}
+
+// status returns the facilitator's component status, probing at most once per
+// TTL. The lock is not held across the probe: while one request probes, the
+// others report the previous result, or wait for the first probe to finish.
+func (p *facilitatorProbe) status(ctx context.Context) ComponentStatus {
+	p.mu.Lock()
+	if p.checkedAt.IsZero() || time.Since(p.checkedAt) >= p.ttl {
+		if p.probing == nil {
+			p.probe(ctx)
+		} else if p.checkedAt.IsZero() {
+			probing := p.probing
+			p.mu.Unlock()
+			select {
+			case <-probing:
+			case <-ctx.Done():
+				return ComponentStatus{Status: statusDegraded, Error: ctx.Err().Error()}
+			}
+			p.mu.Lock()
+		}
+	}
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.lastErr == nil {
+		return ComponentStatus{Status: statusOK}
+	}
+	if !p.lastOK.IsZero() && now.Sub(p.lastOK) < p.grace {
+		// A single slow or failed probe does not flip readiness
+		return ComponentStatus{Status: statusOK}
+	}
+	return ComponentStatus{Status: statusDegraded, Error: p.lastErr.Error()}
+}
+
+// probe pings the facilitator with p.mu released and records the result.
+// It must be called with p.mu held and returns with it held again.
+func (p *facilitatorProbe) probe(ctx context.Context) {
+	done := make(chan struct{})
+	p.probing = done
+	p.mu.Unlock()
+
+	// A client that hangs up should not record a failed probe
+	probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+	_, err := p.facilitator.GetSupported(probeCtx)
+	cancel()
+
+	p.mu.Lock()
+	now := time.Now()
+	p.checkedAt = now
+	p.lastErr = err
+	if err == nil {
+		p.lastOK = now
+	}
+	p.probing = nil
+	close(done)
+}
+
+func registerHealthRoutes(r *gin.Engine, probe *facilitatorProbe) {
+	// GET /healthz - process is up
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, HealthResponse{Status: statusOK})
+	})
+
+	// GET /readyz - dependencies are reachable
+	r.GET("/readyz", func(c *gin.Context) {
+		facilitator := probe.status(c.Request.Context())
+		response := HealthResponse{
+			Status:     facilitator.Status,
+			Components: map[string]ComponentStatus{"facilitator": facilitator},
+		}
+		code := http.StatusOK
+		if facilitator.Status != statusOK {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, response)
+	})
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	x402http "github.com/coinbase/x402/go/http"
+	"github.com/gin-gonic/gin"
+)
+
+func newHealthRouter(facilitatorURL string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerHealthRoutes(r, newFacilitatorProbe(x402http.NewHTTPFacilitatorClient(
+		&x402http.FacilitatorConfig{URL: facilitatorURL},
+	)))
+	return r
+}
+
+func getHealth(t *testing.T, r *gin.Engine, path string) (int, HealthResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var body HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s response: %v", path, err)
+	}
+	return rec.Code, body
+}
+
+func TestReadyzHealthyFacilitator(t *testing.T) {
+	facilitator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/supported" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kinds":[{"x402Version":2,"scheme":"exact","network":"eip155:84532"}]}`))
+	}))
+	defer facilitator.Close()
+
+	r := newHealthRouter(facilitator.URL)
+
+	code, body := getHealth(t, r, "/healthz")
+	if code != http.StatusOK || body.Status != statusOK {
+		t.Fatalf("expected healthz ok, got %d %+v", code, body)
+	}
+
+	code, body = getHealth(t, r, "/readyz")
+	if code != http.StatusOK || body.Status != statusOK {
+		t.Fatalf("expected readyz ok, got %d %+v", code, body)
+	}
+	if body.Components["facilitator"].Status != statusOK {
+		t.Fatalf("expected facilitator ok, got %+v", body.Components)
+	}
+}
+
+func TestReadyzUnreachableFacilitator(t *testing.T) {
+	facilitator := httptest.NewServer(http.NotFoundHandler())
+	url := facilitator.URL
+	facilitator.Close()
+
+	r := newHealthRouter(url)
+
+	code, body := getHealth(t, r, "/healthz")
+	if code != http.StatusOK {
+		t.Fatalf("expected healthz to ignore the facilitator, got %d", code)
+	}
+
+	code, body = getHealth(t, r, "/readyz")
+	if code != http.StatusServiceUnavailable || body.Status != statusDegraded {
+		t.Fatalf("expected readyz degraded, got %d %+v", code, body)
+	}
+	if component := body.Components["facilitator"]; component.Status != statusDegraded || component.Error == "" {
+		t.Fatalf("expected facilitator degraded with error, got %+v", component)
+	}
+}
+
+// blockingFacilitator answers GetSupported only once release is closed.
+type blockingFacilitator struct {
+	*x402local.FakeFacilitator
+	release chan struct{}
+	calls   chan struct{}
+}
+
+func (f *blockingFacilitator) GetSupported(ctx context.Context) (x402local.SupportedResponse, error) {
+	f.calls <- struct{}{}
+	<-f.release
+	return f.FakeFacilitator.GetSupported(ctx)
+}
+
+func TestReadyzDoesNotQueueBehindSlowProbe(t *testing.T) {
+	facilitator := &blockingFacilitator{
+		FakeFacilitator: x402local.NewFakeFacilitator(),
+		release:         make(chan struct{}),
+		calls:           make(chan struct{}, 2),
+	}
+	probe := newFacilitatorProbe(facilitator)
+	probe.timeout = time.Minute
+	// The last probe succeeded but is stale
+	probe.checkedAt = time.Now().Add(-probe.ttl)
+	probe.lastOK = probe.checkedAt
+
+	slow := make(chan ComponentStatus)
+	go func() { slow <- probe.status(context.Background()) }()
+	<-facilitator.calls
+
+	fast := make(chan ComponentStatus)
+	go func() { fast <- probe.status(context.Background()) }()
+	select {
+	case status := <-fast:
+		if status.Status != statusOK {
+			t.Fatalf("expected the cached ok status, got %+v", status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a concurrent status call not to wait for the in-flight probe")
+	}
+	if len(facilitator.calls) != 0 {
+		t.Fatal("expected a single probe in flight")
+	}
+
+	close(facilitator.release)
+	if status := <-slow; status.Status != statusOK {
+		t.Fatalf("expected the probe to report ok, got %+v", status)
+	}
+}
@@ -3,7 +3,6 @@ package httpapi
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 
 	x402local "github.com/andrewreder/agent-poc/go-api/x402"
@@ -25,8 +24,75 @@ func getFacilitatorURL() string {
 	return "http://localhost:8003/v2/x402"
 }
 
-// ConfigurePayments wires x402 payment enforcement for HTTP routes.
-func ConfigurePayments(r *gin.Engine, baseURL string) error {
+// ConfigurePayments wires x402 payment enforcement for HTTP routes and returns
+// the enforced routes so discovery can advertise exactly what is charged.
+// A nil logger falls back to x402local.StdLogger.
+func ConfigurePayments(r *gin.Engine, baseURL string, logger x402local.Logger) (x402http.RoutesConfig, error) {
+	if logger == nil {
+		logger = x402local.StdLogger{}
+	}
+
+	paymentRoutes, err := buildPaymentRoutes(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	facilitator := x402local.FacilitatorFromEnv(getFacilitatorURL(), logger)
+
+	r.Use(ginmw.X402Payment(ginmw.Config{
+		Routes:      paymentRoutes,
+		Facilitator: facilitator,
+		Schemes: []ginmw.SchemeConfig{
+			{
+				Network: x402sdk.Network("eip155:84532"),
+				Server:  evmexact.NewExactEvmScheme(),
+			},
+			{
+				Network: x402sdk.Network("eip155:8453"),
+				Server:  evmexact.NewExactEvmScheme(),
+			},
+			{
+				Network: x402sdk.Network("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"),
+				Server:  solanaexact.NewExactSvmScheme(),
+			},
+			{
+				Network: x402sdk.Network("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"),
+				Server:  solanaexact.NewExactSvmScheme(),
+			},
+		},
+		ErrorHandler: func(c *gin.Context, err error) {
+			paymentSignature := x402local.HeaderValue(c.Request.Header, x402local.HeaderPaymentSignature)
+			xPayment := x402local.HeaderValue(c.Request.Header, x402local.HeaderXPayment)
+			logger.Error(
+				"x402 payment error",
+				"err", err,
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"requestId", x402local.RequestIDFromContext(c.Request.Context()),
+				"paymentSignature", paymentSignature != "",
+				"xPayment", xPayment != "",
+			)
+			if paymentSignature == "" && xPayment != "" {
+				logger.Warn("x402 v2 expects PAYMENT-SIGNATURE; X-PAYMENT is treated as v1")
+			}
+		},
+		SettlementHandler: func(c *gin.Context, settlement *x402sdk.SettleResponse) {
+			logger.Info(
+				"x402 payment settled",
+				"method", c.Request.Method,
+				"path", c.Request.URL.Path,
+				"requestId", x402local.RequestIDFromContext(c.Request.Context()),
+				"network", settlement.Network,
+				"success", settlement.Success,
+			)
+		},
+	}))
+
+	return paymentRoutes, nil
+}
+
+// buildPaymentRoutes declares the paid HTTP routes and their payment options.
+func buildPaymentRoutes(baseURL string) (x402http.RoutesConfig, error) {
 	unpaidJSON := func(message string) x402http.UnpaidResponseBodyFunc {
 		return func(ctx context.Context, reqCtx x402http.HTTPRequestContext) (*x402http.UnpaidResponse, error) {
 			return &x402http.UnpaidResponse{
@@ -71,7 +137,7 @@ func ConfigurePayments(r *gin.Engine, baseURL string) error {
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create bazaar extension: %w", err)
+		return nil, fmt.Errorf("failed to create bazaar extension: %w", err)
 	}
 
 	paymentRoutes := x402http.RoutesConfig{
@@ -193,54 +259,21 @@ func ConfigurePayments(r *gin.Engine, baseURL string) error {
 		},
 	}
 
-	facilitator := x402http.NewHTTPFacilitatorClient(
-		x402local.FacilitatorConfigFromEnv(getFacilitatorURL()),
-	)
+	if err := validateRouteNetworks(paymentRoutes); err != nil {
+		return nil, err
+	}
+	return paymentRoutes, nil
+}
 
-	r.Use(ginmw.X402Payment(ginmw.Config{
-		Routes:      paymentRoutes,
-		Facilitator: facilitator,
-		Schemes: []ginmw.SchemeConfig{
-			{
-				Network: x402sdk.Network("eip155:84532"),
-				Server:  evmexact.NewExactEvmScheme(),
-			},
-			{
-				Network: x402sdk.Network("eip155:8453"),
-				Server:  evmexact.NewExactEvmScheme(),
-			},
-			{
-				Network: x402sdk.Network("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"),
-				Server:  solanaexact.NewExactSvmScheme(),
-			},
-			{
-				Network: x402sdk.Network("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp"),
-				Server:  solanaexact.NewExactSvmScheme(),
-			},
-		},
-		ErrorHandler: func(c *gin.Context, err error) {
-			log.Printf("x402 payment error: %v (method=%s path=%s)", err, c.Request.Method, c.Request.URL.Path)
-			paymentSignature := c.Request.Header.Get("PAYMENT-SIGNATURE")
-			xPayment := c.Request.Header.Get("X-PAYMENT")
-			log.Printf(
-				"x402 payment headers present (PAYMENT-SIGNATURE=%t X-PAYMENT=%t)",
-				paymentSignature != "",
-				xPayment != "",
-			)
-			if paymentSignature == "" && xPayment != "" {
-				log.Printf("x402 v2 expects PAYMENT-SIGNATURE; X-PAYMENT is treated as v1")
+// validateRouteNetworks rejects payment options whose network is not a valid
+// CAIP-2 identifier, so a typo fails at startup rather than at payment time.
+func validateRouteNetworks(routes x402http.RoutesConfig) error {
+	for pattern, route := range routes {
+		for i, option := range route.Accepts {
+			if err := x402local.ValidateNetwork(string(option.Network), false); err != nil {
+				return fmt.Errorf("route %s accepts[%d]: %w", pattern, i, err)
 			}
-		},
-		SettlementHandler: func(c *gin.Context, settlement *x402sdk.SettleResponse) {
-			log.Printf(
-				"x402 payment settled (method=%s path=%s network=%s success=%t)",
-				c.Request.Method,
-				c.Request.URL.Path,
-				settlement.Network,
-				settlement.Success,
-			)
-		},
-	}))
-
+		}
+	}
 	return nil
 }
@@ -1,17 +1,55 @@
 package httpapi
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	mcpserver "github.com/andrewreder/agent-poc/go-api/mcp"
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	x402http "github.com/coinbase/x402/go/http"
 	"github.com/gin-gonic/gin"
 )
 
-const serverBaseURL = "http://localhost:8080"
+// DefaultServerBaseURL is advertised in discovery entries when no base URL is configured.
+const DefaultServerBaseURL = "http://localhost:8080"
+
+// ServerBaseURLFromEnv returns SERVER_BASE_URL, or DefaultServerBaseURL when unset.
+func ServerBaseURLFromEnv() string {
+	if baseURL := strings.TrimSpace(os.Getenv("SERVER_BASE_URL")); baseURL != "" {
+		return baseURL
+	}
+	return DefaultServerBaseURL
+}
+
+// normalizeBaseURL checks that baseURL is absolute and strips any trailing slash.
+func normalizeBaseURL(baseURL string) (string, error) {
+	if baseURL == "" {
+		return DefaultServerBaseURL, nil
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid server base URL %q: %w", baseURL, err)
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return "", fmt.Errorf("server base URL %q must be an absolute URL", baseURL)
+	}
+	return strings.TrimRight(baseURL, "/"), nil
+}
+
+// hostname returns the host of an already-normalized base URL.
+func hostname(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
+}
 
 // Resource represents a discoverable resource
 type Resource struct {
@@ -48,10 +86,17 @@ type X402OutputSchema struct {
 }
 
 type X402InputSchema struct {
-	Method      string            `json:"method"`
-	QueryParams map[string]string `json:"queryParams,omitempty"`
-	Body        map[string]string `json:"body,omitempty"`
-	Type        string            `json:"type"`
+	Method      string                        `json:"method"`
+	QueryParams map[string]X402QueryParamSpec `json:"queryParams,omitempty"`
+	Body        map[string]string             `json:"body,omitempty"`
+	Type        string                        `json:"type"`
+}
+
+// X402QueryParamSpec describes a query parameter by its JSON type and an
+// example value, which MCP clients send as the default when it is omitted.
+type X402QueryParamSpec struct {
+	Type    string      `json:"type"`
+	Example interface{} `json:"example,omitempty"`
 }
 
 type X402AcceptRequirement struct {
@@ -95,34 +140,67 @@ type RestaurantResponse struct {
 	Note        string   `json:"note"`
 }
 
-// NewRouter builds the Gin router with all HTTP routes registered.
-func NewRouter() (*gin.Engine, error) {
+// NewRouter builds the Gin router with all HTTP routes registered. baseURL is
+// the externally reachable origin advertised in discovery and payment
+// requirements; empty means DefaultServerBaseURL. The returned drain funcs
+// settle in-flight payments and should be passed to Serve.
+func NewRouter(baseURL string) (*gin.Engine, []func(context.Context) error, error) {
+	baseURL, err := normalizeBaseURL(baseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	proxies, err := trustedProxiesFromEnv()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	r := gin.Default()
+	// LOG_LEVEL=debug enables the request header dump
+	logger := x402local.StdLogger{Verbose: os.Getenv("LOG_LEVEL") == "debug"}
 
-	attachDebugLogging(r)
-	if err := ConfigurePayments(r, serverBaseURL); err != nil {
-		return nil, err
+	attachRequestID(r)
+	attachDebugLogging(r, logger)
+	paymentRoutes, err := ConfigurePayments(r, baseURL, logger)
+	if err != nil {
+		return nil, nil, err
 	}
-	registerDiscoveryRoutes(r, serverBaseURL)
+	registerHealthRoutes(r, newFacilitatorProbe(x402local.FacilitatorFromEnv(getFacilitatorURL(), logger)))
+	registerDiscoveryRoutes(r, paymentRoutes, baseURL, proxies)
 	registerWeatherRoutes(r)
-	if err := registerMCPRoute(r); err != nil {
-		return nil, err
+	if simulate402Enabled() {
+		logger.Warn("x402 simulated 402 endpoints enabled; never use in production", "path", simulate402Path)
+		registerSimulate402Routes(r)
+	}
+	discoveryServer, err := registerMCPRoute(r, baseURL, logger)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return r, nil
+	return r, []func(context.Context) error{discoveryServer.Drain}, nil
+}
+
+// attachRequestID accepts the caller's X-Request-ID or mints one, echoes it on
+// the response and stores it on the request context for payment logging.
+func attachRequestID(r *gin.Engine) {
+	r.Use(func(c *gin.Context) {
+		ctx := x402local.WithRequestID(c.Request.Context(), c.GetHeader(x402local.RequestIDHeader))
+		c.Request = c.Request.WithContext(ctx)
+		c.Header(x402local.RequestIDHeader, x402local.RequestIDFromContext(ctx))
+		c.Next()
+	})
 }
 
-func attachDebugLogging(r *gin.Engine) {
+func attachDebugLogging(r *gin.Engine, logger x402local.Logger) {
 	// Debug: log payment headers for protected endpoints (toy repo)
 	r.Use(func(c *gin.Context) {
 		if strings.HasPrefix(c.Request.URL.Path, "/weather") || strings.HasPrefix(c.Request.URL.Path, "/restaurants") {
-			log.Printf("Headers: %+v", c.Request.Header)
+			logger.Debug("request headers", "path", c.Request.URL.Path, "headers", mcpserver.RedactHeaders(c.Request.Header, mcpserver.DefaultRedactedHeaders))
 		}
 		c.Next()
 	})
 }
 
-func registerDiscoveryRoutes(r *gin.Engine, baseURL string) {
+func registerDiscoveryRoutes(r *gin.Engine, paymentRoutes x402http.RoutesConfig, baseURL string, proxies trustedProxies) {
 	// GET /discovery/resources - Returns list of available resources
 	r.GET("/discovery/resources", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -130,46 +208,13 @@ func registerDiscoveryRoutes(r *gin.Engine, baseURL string) {
 		})
 	})
 
-	// GET /discovery/x402 - Returns x402 entries for available HTTP endpoints
+	// GET /discovery/x402 - Returns x402 entries for the payment-enforced routes,
+	// addressed through the origin a trusted reverse proxy reports
 	r.GET("/discovery/x402", func(c *gin.Context) {
 		lastUpdated := time.Now().UTC().Format(time.RFC3339Nano)
-		entries := []X402EndpointEntry{
-			{
-				Accepts: []X402AcceptRequirement{
-					{
-						Asset:       "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
-						Description: "Get synthetic weather data for a city",
-						Extra: map[string]string{
-							"name":    "USDC",
-							"version": "2",
-						},
-						MaxAmountRequired: "10000",
-						MaxTimeoutSeconds: 300,
-						MimeType:          "application/json",
-						Network:           "base-sepolia",
-						OutputSchema: X402OutputSchema{
-							Input: X402InputSchema{
-								Method: "GET",
-								QueryParams: map[string]string{
-									"city": "string",
-								},
-								Type: "http",
-							},
-						},
-						PayTo:    "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
-						Resource: fmt.Sprintf("%s/weather", baseURL),
-						Scheme:   "exact",
-					},
-				},
-				LastUpdated: lastUpdated,
-				Resource:    fmt.Sprintf("%s/weather", baseURL),
-				Type:        "http",
-				X402Version: 1,
-			},
-		}
-
+		entries := discoveryEntries(paymentRoutes, lastUpdated)
 		c.JSON(http.StatusOK, gin.H{
-			"entries": entries,
+			"entries": rebaseEntries(entries, baseURL, proxies.requestBaseURL(c.Request, baseURL)),
 		})
 	})
 }
@@ -194,12 +239,95 @@ func registerWeatherRoutes(r *gin.Engine) {
 	})
 }
 
-func registerMCPRoute(r *gin.Engine) error {
+// ToolOverridesFileEnv names a JSON or YAML file of curated titles and
+// descriptions for discovered tools, keyed by resource URL. Unset derives them
+// from the discovery fixture.
+const ToolOverridesFileEnv = "TOOL_OVERRIDES_FILE"
+
+// PaymentOptionPolicyEnv picks the payment option discovered tools recommend:
+// "cheapest", "round-robin", or a comma-separated list of networks in order
+// of preference. Unset recommends none.
+const PaymentOptionPolicyEnv = "PAYMENT_OPTION_POLICY"
+
+// paymentOptionPolicyFromEnv parses PaymentOptionPolicyEnv, returning nil
+// when it is unset.
+func paymentOptionPolicyFromEnv() mcpserver.PaymentOptionPolicy {
+	value := strings.TrimSpace(os.Getenv(PaymentOptionPolicyEnv))
+	switch strings.ToLower(value) {
+	case "":
+		return nil
+	case "cheapest":
+		return mcpserver.CheapestPaymentOption{}
+	case "round-robin":
+		return mcpserver.NewRoundRobinPaymentOption()
+	}
+	var networks mcpserver.PreferredNetworkPaymentOption
+	for _, network := range strings.Split(value, ",") {
+		if network = strings.TrimSpace(network); network != "" {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// CallBudgetAttemptsEnv and CallBudgetTimeoutEnv cap the attempts (e.g. "3")
+// and wall-clock time (e.g. "20s") each proxy_tool_call may spend across its
+// facilitator and upstream retries. Unset leaves that limit off.
+const (
+	CallBudgetAttemptsEnv = "PROXY_CALL_BUDGET_ATTEMPTS"
+	CallBudgetTimeoutEnv  = "PROXY_CALL_BUDGET_TIMEOUT"
+)
+
+// callBudgetFromEnv reads the proxy call budget, failing on values that are
+// not a positive count or duration.
+func callBudgetFromEnv() (x402local.CallBudget, error) {
+	var budget x402local.CallBudget
+	if value := strings.TrimSpace(os.Getenv(CallBudgetAttemptsEnv)); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts <= 0 {
+			return budget, fmt.Errorf("%s must be a positive integer, got %q", CallBudgetAttemptsEnv, value)
+		}
+		budget.MaxAttempts = attempts
+	}
+	if value := strings.TrimSpace(os.Getenv(CallBudgetTimeoutEnv)); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return budget, fmt.Errorf("%s must be a positive duration, got %q", CallBudgetTimeoutEnv, value)
+		}
+		budget.MaxDuration = timeout
+	}
+	return budget, nil
+}
+
+func registerMCPRoute(r *gin.Engine, baseURL string, logger x402local.Logger) (*mcpserver.Server, error) {
+	budget, err := callBudgetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	opts := []mcpserver.ServerOption{mcpserver.WithLogger(logger), mcpserver.WithCallBudget(budget)}
+	if path := strings.TrimSpace(os.Getenv(ToolOverridesFileEnv)); path != "" {
+		overrides, err := mcpserver.LoadToolOverrides(path)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, mcpserver.WithToolOverrides(overrides))
+	}
+	if policy := paymentOptionPolicyFromEnv(); policy != nil {
+		opts = append(opts, mcpserver.WithPaymentOptionPolicy(policy))
+	}
+	if simulate402Enabled() {
+		opts = append(opts, mcpserver.WithResources(simulated402Resources(baseURL)...))
+	}
+
 	// MCP streamable HTTP endpoint
-	discoveryServer, err := mcpserver.NewServer()
+	discoveryServer, err := mcpserver.NewServer(opts...)
 	if err != nil {
-		return fmt.Errorf("failed to initialize MCP discovery server: %w", err)
+		return nil, fmt.Errorf("failed to initialize MCP discovery server: %w", err)
 	}
+	// The bundled fixtures proxy back to this server, so let it reach itself
+	discoveryServer.SetEgressPolicy(mcpserver.EgressPolicy{
+		AllowedHosts: []string{hostname(DefaultServerBaseURL), hostname(baseURL)},
+	})
 	r.Any("/discovery/mcp", gin.WrapH(discoveryServer.Handler()))
-	return nil
+	return discoveryServer, nil
 }
@@ -0,0 +1,189 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mcpserver "github.com/andrewreder/agent-poc/go-api/mcp"
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	x402sdk "github.com/coinbase/x402/go"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDiscoveryX402UsesConfiguredBaseURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, _, err := NewRouter("https://api.example.com/")
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discovery/x402", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var body struct {
+		Entries []X402EndpointEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Entries) == 0 {
+		t.Fatalf("expected discovery entries")
+	}
+	entry := body.Entries[0]
+	if entry.Resource != "https://api.example.com/weather" {
+		t.Fatalf("expected custom base URL in entry, got %q", entry.Resource)
+	}
+	if got := entry.Accepts[0].Resource; got != "https://api.example.com/weather" {
+		t.Fatalf("expected custom base URL in accepts, got %q", got)
+	}
+}
+
+func TestNewRouterRejectsRelativeBaseURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	if _, _, err := NewRouter("api.example.com"); err == nil {
+		t.Fatalf("expected relative base URL to be rejected")
+	}
+}
+
+func TestDiscoveryX402MatchesPaymentRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, _, err := NewRouter(DefaultServerBaseURL)
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+	routes, err := buildPaymentRoutes(DefaultServerBaseURL)
+	if err != nil {
+		t.Fatalf("buildPaymentRoutes error: %v", err)
+	}
+	configured := routes["GET /weather"]
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discovery/x402", nil))
+	var body struct {
+		Entries []X402EndpointEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Entries) != len(routes) {
+		t.Fatalf("expected %d entries, got %d", len(routes), len(body.Entries))
+	}
+
+	entry := body.Entries[0]
+	if entry.X402Version != 2 {
+		t.Fatalf("expected x402Version 2, got %d", entry.X402Version)
+	}
+	if len(entry.Accepts) != len(configured.Accepts) {
+		t.Fatalf("expected %d accepts, got %d", len(configured.Accepts), len(entry.Accepts))
+	}
+	for i, option := range configured.Accepts {
+		price := option.Price.(map[string]interface{})
+		got := entry.Accepts[i]
+		if got.Asset != price["asset"] || got.MaxAmountRequired != price["amount"] || got.Network != string(option.Network) {
+			t.Fatalf("accept %d: got asset=%s amount=%s network=%s, want %v %v %s",
+				i, got.Asset, got.MaxAmountRequired, got.Network, price["asset"], price["amount"], option.Network)
+		}
+	}
+	city := entry.Accepts[0].OutputSchema.Input.QueryParams["city"]
+	if city.Type != "string" || city.Example != "San Francisco" {
+		t.Fatalf("expected city query param from the bazaar extension, got %v", entry.Accepts[0].OutputSchema.Input.QueryParams)
+	}
+}
+
+func TestDiscoveryX402ExamplesBecomeQueryDefaults(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r, _, err := NewRouter("https://api.example.com")
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/discovery/x402", nil))
+	var body struct {
+		Entries []mcpserver.X402DiscoveryResource `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	s, err := mcpserver.NewServer(mcpserver.WithResources(body.Entries...))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	weather := body.Entries[0]
+	preview := func(parameters map[string]any) string {
+		t.Helper()
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &mcpserver.ProxyToolCallParams{
+			ToolName:   mcpserver.HashToolNamer{}.ToolName(weather, "GET"),
+			Parameters: parameters,
+			DryRun:     true,
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("ProxyToolCall: %v %+v", err, result)
+		}
+		return result.StructuredContent.(map[string]any)["url"].(string)
+	}
+
+	if got, want := preview(map[string]any{}), "https://api.example.com/weather?city=San+Francisco"; got != want {
+		t.Fatalf("expected the bazaar example as the default, got %s want %s", got, want)
+	}
+	if got, want := preview(map[string]any{"query": map[string]any{"city": "Paris"}}), "https://api.example.com/weather?city=Paris"; got != want {
+		t.Fatalf("expected the caller's city to win, got %s want %s", got, want)
+	}
+}
+
+func TestValidateRouteNetworks(t *testing.T) {
+	routes, err := buildPaymentRoutes(DefaultServerBaseURL)
+	if err != nil {
+		t.Fatalf("buildPaymentRoutes error: %v", err)
+	}
+	if err := validateRouteNetworks(routes); err != nil {
+		t.Fatalf("expected configured routes to be valid, got %v", err)
+	}
+
+	route := routes["GET /weather"]
+	route.Accepts[0].Network = x402sdk.Network("eip155:8453x")
+	if err := validateRouteNetworks(routes); !errors.Is(err, x402local.ErrInvalidNetwork) {
+		t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+	}
+}
+
+func TestCallBudgetFromEnv(t *testing.T) {
+	t.Setenv(CallBudgetAttemptsEnv, "3")
+	t.Setenv(CallBudgetTimeoutEnv, "20s")
+	budget, err := callBudgetFromEnv()
+	if err != nil {
+		t.Fatalf("callBudgetFromEnv error: %v", err)
+	}
+	if budget != (x402local.CallBudget{MaxAttempts: 3, MaxDuration: 20 * time.Second}) {
+		t.Fatalf("unexpected budget %+v", budget)
+	}
+
+	t.Setenv(CallBudgetAttemptsEnv, "0")
+	if _, _, err := NewRouter(""); err == nil {
+		t.Fatal("expected NewRouter to reject a non-positive attempt budget")
+	}
+}
+
+func TestNewRouterReturnsDrains(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	_, drains, err := NewRouter(DefaultServerBaseURL)
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+	if len(drains) == 0 {
+		t.Fatal("expected NewRouter to return the MCP server's drain")
+	}
+	for _, drain := range drains {
+		if err := drain(context.Background()); err != nil {
+			t.Fatalf("drain with nothing in flight: %v", err)
+		}
+	}
+}
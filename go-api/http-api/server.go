@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultShutdownTimeout bounds how long shutdown waits for in-flight work.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// ShutdownTimeoutFromEnv returns SHUTDOWN_TIMEOUT (e.g. "45s"), or
+// DefaultShutdownTimeout when unset or invalid.
+func ShutdownTimeoutFromEnv() time.Duration {
+	value := strings.TrimSpace(os.Getenv("SHUTDOWN_TIMEOUT"))
+	if value == "" {
+		return DefaultShutdownTimeout
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return DefaultShutdownTimeout
+	}
+	return timeout
+}
+
+// Serve runs srv until ctx is done, then shuts it down gracefully. Shutdown
+// waits up to drainTimeout for in-flight requests and for every drain func
+// (such as x402.Middleware.Drain) so verified payments get settled.
+func Serve(ctx context.Context, srv *http.Server, drainTimeout time.Duration, drains ...func(context.Context) error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown HTTP server: %w", err))
+	}
+	for _, drain := range drains {
+		if err := drain(shutdownCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
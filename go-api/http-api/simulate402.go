@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mcpserver "github.com/andrewreder/agent-poc/go-api/mcp"
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/gin-gonic/gin"
+)
+
+// Simulate402Env enables the /test/402 endpoints when set to 1 or true. They
+// always answer 402 and are meant for exercising an agent's payment-required
+// handling; never enable them in production.
+const Simulate402Env = "X402_SIMULATE_402"
+
+// simulate402Path is the prefix of the simulated payment-required endpoints.
+const simulate402Path = "/test/402"
+
+// simulate402Enabled reports whether Simulate402Env turns the test endpoints on.
+func simulate402Enabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(Simulate402Env))) {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// registerSimulate402Routes serves GET and POST /test/402/v1 and /test/402/v2.
+// Each answers 402 with the payment requirements in both the PAYMENT-REQUIRED
+// header and the JSON body, whatever payment the request carries, so nothing
+// is ever settled.
+func registerSimulate402Routes(r *gin.Engine) {
+	for _, version := range []int{1, 2} {
+		path := simulate402Path + "/v" + strconv.Itoa(version)
+		handler := func(c *gin.Context) {
+			body := simulated402Body(version, requestURL(c.Request))
+			encoded, err := json.Marshal(body)
+			if err != nil {
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			c.Header(x402local.HeaderPaymentRequired, base64.StdEncoding.EncodeToString(encoded))
+			c.Data(http.StatusPaymentRequired, "application/json", encoded)
+		}
+		r.GET(path, handler)
+		r.POST(path, handler)
+	}
+}
+
+// requestURL rebuilds the absolute URL of req for the requirements' resource.
+func requestURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host + req.URL.Path
+}
+
+// simulated402Body returns a well-formed payment-required body for version,
+// priced like the weather endpoint on Base Sepolia.
+func simulated402Body(version int, resourceURL string) map[string]any {
+	const description = "Simulated payment-required response"
+	if version == 1 {
+		return map[string]any{
+			"x402Version": 1,
+			"error":       "X-PAYMENT header is required",
+			"accepts": []map[string]any{{
+				"scheme":            "exact",
+				"network":           "base-sepolia",
+				"maxAmountRequired": "1000",
+				"resource":          resourceURL,
+				"description":       description,
+				"mimeType":          "application/json",
+				"payTo":             "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				"maxTimeoutSeconds": 300,
+				"asset":             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				"extra":             map[string]any{"name": "USDC", "version": "2"},
+			}},
+		}
+	}
+	return map[string]any{
+		"x402Version": 2,
+		"error":       "PAYMENT-SIGNATURE header is required",
+		"resource": map[string]any{
+			"url":         resourceURL,
+			"description": description,
+			"mimeType":    "application/json",
+		},
+		"accepts": []map[string]any{{
+			"scheme":            "exact",
+			"network":           "eip155:84532",
+			"amount":            "1000",
+			"payTo":             "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+			"maxTimeoutSeconds": 300,
+			"asset":             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			"extra":             map[string]any{"name": "USDC", "version": "2"},
+		}},
+	}
+}
+
+// simulated402Resources describes the /test/402 endpoints under baseURL as
+// discovery resources, so agents can find them through search_resources.
+func simulated402Resources(baseURL string) []mcpserver.X402DiscoveryResource {
+	resources := make([]mcpserver.X402DiscoveryResource, 0, 2)
+	for _, version := range []int{1, 2} {
+		url := strings.TrimRight(baseURL, "/") + simulate402Path + "/v" + strconv.Itoa(version)
+		resources = append(resources, mcpserver.X402DiscoveryResource{
+			Accepts: &[]mcpserver.X402PaymentRequirements{{
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				Description:       "Always answers 402 (test harness)",
+				Extra:             map[string]any{"name": "USDC", "version": "2"},
+				MaxAmountRequired: "1000",
+				MaxTimeoutSeconds: 300,
+				MimeType:          "application/json",
+				Network:           "base-sepolia",
+				OutputSchema:      map[string]any{"input": map[string]any{"type": "http", "method": "GET"}},
+				PayTo:             "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				Resource:          url,
+				Scheme:            "exact",
+			}},
+			LastUpdated: time.Now().UTC(),
+			Resource:    url,
+			Type:        "http",
+			X402Version: version,
+		})
+	}
+	return resources
+}
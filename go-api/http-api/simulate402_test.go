@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpserver "github.com/andrewreder/agent-poc/go-api/mcp"
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/gin-gonic/gin"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSimulate402RoundTripsThroughProxyToolCall(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerSimulate402Routes(r)
+	upstream := httptest.NewServer(r)
+	defer upstream.Close()
+
+	resources := simulated402Resources(upstream.URL)
+	s, err := mcpserver.NewServer(mcpserver.WithResources(resources...))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	s.SetEgressPolicy(mcpserver.EgressPolicy{AllowPrivate: true})
+
+	networks := map[int]string{1: "base-sepolia", 2: "eip155:84532"}
+	for _, resource := range resources {
+		version := resource.X402Version
+		// A payment never satisfies the simulated endpoint
+		req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Meta: mcp.Meta{
+			x402local.MetaKeyPayment: map[string]any{
+				"x402Version": 1,
+				"scheme":      "exact",
+				"network":     "base-sepolia",
+				"payload":     map[string]any{"signature": "0xdeadbeef"},
+			},
+		}}}
+		result, _, err := s.ProxyToolCall(context.Background(), req, &mcpserver.ProxyToolCallParams{
+			ToolName: mcpserver.HashToolNamer{}.ToolName(resource, "GET"),
+		})
+		if err != nil {
+			t.Fatalf("v%d: ProxyToolCall error: %v", version, err)
+		}
+		if !result.IsError {
+			t.Fatalf("v%d: expected a payment-required error result", version)
+		}
+		required, ok := result.StructuredContent.(map[string]any)
+		if !ok {
+			t.Fatalf("v%d: expected payment requirements, got %T", version, result.StructuredContent)
+		}
+		if required["x402Version"] != float64(version) {
+			t.Fatalf("v%d: expected x402Version %d, got %v", version, version, required["x402Version"])
+		}
+		accepts, ok := required["accepts"].([]any)
+		if !ok || len(accepts) != 1 || accepts[0].(map[string]any)["network"] != networks[version] {
+			t.Fatalf("v%d: unexpected accepts %v", version, required["accepts"])
+		}
+	}
+}
+
+func TestSimulate402RoutesAreGated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r, _, err := NewRouter("http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test/402/v2", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the endpoint to be off by default, got %d", rec.Code)
+	}
+
+	t.Setenv(Simulate402Env, "1")
+	r, _, err = NewRouter("http://localhost:8080")
+	if err != nil {
+		t.Fatalf("NewRouter error: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/test/402/v2", nil))
+	if rec.Code != http.StatusPaymentRequired {
+		t.Fatalf("expected 402, got %d", rec.Code)
+	}
+	header, err := base64.StdEncoding.DecodeString(rec.Header().Get(x402local.HeaderPaymentRequired))
+	if err != nil {
+		t.Fatalf("decode PAYMENT-REQUIRED: %v", err)
+	}
+	var fromHeader, fromBody map[string]any
+	if err := json.Unmarshal(header, &fromHeader); err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fromBody); err != nil {
+		t.Fatalf("unmarshal body: %v", err)
+	}
+	if fromHeader["x402Version"] != float64(2) || fromBody["x402Version"] != float64(2) {
+		t.Fatalf("expected v2 requirements in header and body, got %v and %v", fromHeader, fromBody)
+	}
+	if resource := fromBody["resource"].(map[string]any); resource["url"] != "http://example.com/test/402/v2" {
+		t.Fatalf("expected the request URL as the resource, got %v", resource["url"])
+	}
+}
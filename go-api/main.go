@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	httpapi "github.com/andrewreder/agent-poc/go-api/http-api"
 )
 
 func main() {
-	r, err := httpapi.NewRouter()
+	r, drains, err := httpapi.NewRouter(httpapi.ServerBaseURLFromEnv())
 	if err != nil {
 		log.Fatalf("failed to initialize HTTP router: %v", err)
 	}
-	r.Run(":8080")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	if err := httpapi.Serve(ctx, srv, httpapi.ShutdownTimeoutFromEnv(), drains...); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
 }
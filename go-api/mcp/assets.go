@@ -0,0 +1,112 @@
+package mcp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AssetInfo describes how to display amounts of a payment asset.
+type AssetInfo struct {
+	Symbol   string
+	Decimals int
+}
+
+// Asset registers the symbol and decimals of an asset address on a network.
+type Asset struct {
+	Network string
+	Address string
+	Info    AssetInfo
+}
+
+// assetRegistry maps assetKey(network, address) to display info.
+type assetRegistry map[string]AssetInfo
+
+var (
+	// builtinAssets lists the USDC deployments. Networks appear under both
+	// their CAIP-2 id and their x402 v1 name.
+	builtinAssets = assetRegistry{
+		assetKey("eip155:84532", "0x036CbD53842c5426634e7929541eC2318f3dCF7e"):                              {Symbol: "USDC", Decimals: 6},
+		assetKey("base-sepolia", "0x036CbD53842c5426634e7929541eC2318f3dCF7e"):                              {Symbol: "USDC", Decimals: 6},
+		assetKey("eip155:8453", "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"):                               {Symbol: "USDC", Decimals: 6},
+		assetKey("base", "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"):                                      {Symbol: "USDC", Decimals: 6},
+		assetKey("solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"): {Symbol: "USDC", Decimals: 6},
+		assetKey("solana", "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"):                                  {Symbol: "USDC", Decimals: 6},
+		assetKey("solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1", "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"): {Symbol: "USDC", Decimals: 6},
+		assetKey("solana-devnet", "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"):                           {Symbol: "USDC", Decimals: 6},
+	}
+	// symbolDecimals gives decimals for well-known symbols named in
+	// extra.name when the asset itself is not registered.
+	symbolDecimals = map[string]int{
+		"USDC": 6,
+		"USDT": 6,
+		"EURC": 6,
+	}
+)
+
+// WithAssets records the symbol and decimals of extra asset addresses, so
+// their discovered pricing can be shown as "0.01 USDC". An asset listed here
+// takes precedence over the built-in USDC entry for the same address.
+func WithAssets(assets ...Asset) ServerOption {
+	return func(s *Server) {
+		if s.assets == nil {
+			s.assets = make(assetRegistry, len(assets))
+		}
+		for _, asset := range assets {
+			s.assets[assetKey(asset.Network, asset.Address)] = asset.Info
+		}
+	}
+}
+
+// assetKey lowercases EVM addresses, which are case-insensitive; other
+// addresses such as Solana mints are kept as-is.
+func assetKey(network, address string) string {
+	if strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X") {
+		address = strings.ToLower(address)
+	}
+	return strings.ToLower(network) + "/" + address
+}
+
+// lookup resolves the display symbol and decimals for a payment requirement:
+// the registered assets first, then the built-in ones, then extra.name with
+// extra.decimals or a well-known symbol's decimals. ok is false when none is
+// known. A nil registry uses only the built-in assets.
+func (r assetRegistry) lookup(requirement X402PaymentRequirements) (AssetInfo, bool) {
+	key := assetKey(requirement.Network, requirement.Asset)
+	if info, ok := r[key]; ok {
+		return info, true
+	}
+	if info, ok := builtinAssets[key]; ok {
+		return info, true
+	}
+
+	name, _ := requirement.Extra["name"].(string)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return AssetInfo{}, false
+	}
+	if decimals, ok := extraDecimals(requirement.Extra["decimals"]); ok {
+		return AssetInfo{Symbol: name, Decimals: decimals}, true
+	}
+	if decimals, ok := symbolDecimals[strings.ToUpper(name)]; ok {
+		return AssetInfo{Symbol: strings.ToUpper(name), Decimals: decimals}, true
+	}
+	return AssetInfo{}, false
+}
+
+func extraDecimals(value any) (int, bool) {
+	switch v := value.(type) {
+	case float64:
+		if v >= 0 && v == float64(int(v)) {
+			return int(v), true
+		}
+	case int:
+		if v >= 0 {
+			return v, true
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return 0, false
+}
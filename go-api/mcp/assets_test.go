@@ -0,0 +1,75 @@
+package mcp
+
+import "testing"
+
+func pricingAccepts(t *testing.T, resource X402DiscoveryResource) map[string]any {
+	t.Helper()
+	meta := buildPricingMeta(resource, "Test resource", "test_tool", nil)
+	required, ok := meta["x402/payment-required"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected x402/payment-required meta, got %v", meta)
+	}
+	accepts, ok := required["accepts"].([]map[string]any)
+	if !ok || len(accepts) != 1 {
+		t.Fatalf("expected one accepts entry, got %v", required["accepts"])
+	}
+	return accepts[0]
+}
+
+func TestBuildPricingMetaResolvesRegisteredAsset(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/weather", "GET", nil)
+	(*resource.Accepts)[0].Asset = "0x036cbd53842c5426634e7929541ec2318f3dcf7e"
+
+	accepts := pricingAccepts(t, resource)
+	if accepts["assetSymbol"] != "USDC" || accepts["assetDecimals"] != 6 {
+		t.Fatalf("expected USDC with 6 decimals, got %v / %v", accepts["assetSymbol"], accepts["assetDecimals"])
+	}
+	if accepts["amount"] != "10000" {
+		t.Fatalf("expected raw amount kept, got %v", accepts["amount"])
+	}
+}
+
+func TestBuildPricingMetaUnknownAsset(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/weather", "GET", nil)
+	(*resource.Accepts)[0].Asset = "0x046CbD53842c5426634e7929541eC2318f3dCF7e"
+
+	accepts := pricingAccepts(t, resource)
+	if _, ok := accepts["assetSymbol"]; ok {
+		t.Fatalf("expected no symbol for unknown asset, got %v", accepts["assetSymbol"])
+	}
+	if _, ok := accepts["assetDecimals"]; ok {
+		t.Fatalf("expected no decimals for unknown asset, got %v", accepts["assetDecimals"])
+	}
+	if accepts["asset"] != "0x046CbD53842c5426634e7929541eC2318f3dCF7e" || accepts["amount"] != "10000" {
+		t.Fatalf("expected raw asset and amount, got %v", accepts)
+	}
+
+	(*resource.Accepts)[0].Extra = map[string]any{"name": "USDC", "version": "2"}
+	accepts = pricingAccepts(t, resource)
+	if accepts["assetSymbol"] != "USDC" || accepts["assetDecimals"] != 6 {
+		t.Fatalf("expected symbol derived from extra.name, got %v / %v", accepts["assetSymbol"], accepts["assetDecimals"])
+	}
+}
+
+func TestWithAssets(t *testing.T) {
+	t.Parallel()
+
+	network, address := "eip155:1337", "0xAbC0000000000000000000000000000000000001"
+	s := &Server{}
+	WithAssets(Asset{Network: network, Address: address, Info: AssetInfo{Symbol: "TEST", Decimals: 18}})(s)
+
+	requirement := X402PaymentRequirements{Network: network, Asset: "0xabc0000000000000000000000000000000000001"}
+	info, ok := s.assets.lookup(requirement)
+	if !ok || info.Symbol != "TEST" || info.Decimals != 18 {
+		t.Fatalf("expected registered asset, got %+v (ok=%t)", info, ok)
+	}
+
+	// Assets belong to the server that registered them
+	if _, ok := (&Server{}).assets.lookup(requirement); ok {
+		t.Fatal("expected another server not to know the asset")
+	}
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// bearerHeader is the header an OAuth bearer token is forwarded in.
+const bearerHeader = "Authorization"
+
+// WithBearerToken forwards token as "Authorization: Bearer <token>" on
+// proxied requests to resources on hosts, for upstreams that require an OAuth
+// token besides the x402 payment. Hosts are hostnames without a port; a token
+// with no hosts is never sent, so one credential cannot leak to every
+// discovered upstream. A bearerToken on the call or WithResourceBearerToken
+// takes precedence, and an Authorization header in params.headers is left
+// as-is. The token is masked in previews and dropped on cross-origin
+// redirects.
+func WithBearerToken(token string, hosts ...string) ServerOption {
+	return func(s *Server) {
+		s.bearerToken = bareToken(token)
+		s.bearerTokenHosts = hostSet(hosts)
+	}
+}
+
+// WithResourceBearerToken forwards token for the resource at resourceURL,
+// overriding WithBearerToken.
+func WithResourceBearerToken(resourceURL, token string) ServerOption {
+	return func(s *Server) {
+		if s.resourceBearerTokens == nil {
+			s.resourceBearerTokens = make(map[string]string)
+		}
+		s.resourceBearerTokens[resourceURL] = bareToken(token)
+	}
+}
+
+// bareToken strips surrounding space and an optional "Bearer " prefix.
+func bareToken(token string) string {
+	token = strings.TrimSpace(token)
+	if len(token) > len("Bearer ") && strings.EqualFold(token[:len("Bearer ")], "Bearer ") {
+		token = strings.TrimSpace(token[len("Bearer "):])
+	}
+	return token
+}
+
+// bearerTokenFor returns the token to forward for resource: the call's own
+// token, then the resource's, then the server's when the resource's host is
+// one it is scoped to.
+func (s *Server) bearerTokenFor(resource X402DiscoveryResource, callToken string) string {
+	if token := bareToken(callToken); token != "" {
+		return token
+	}
+	if token := s.resourceBearerTokens[resource.Resource]; token != "" {
+		return token
+	}
+	if s.bearerToken == "" {
+		return ""
+	}
+	parsed, err := url.Parse(resource.Resource)
+	if err != nil {
+		return ""
+	}
+	if _, ok := s.bearerTokenHosts[strings.ToLower(parsed.Hostname())]; !ok {
+		return ""
+	}
+	return s.bearerToken
+}
+
+// checkBearerToken rejects a call's bearerToken when the call also sets an
+// Authorization header or the payment is configured to travel in
+// Authorization, since one would silently replace the other.
+func (s *Server) checkBearerToken(resource X402DiscoveryResource, callToken string, parameters map[string]any) error {
+	if bareToken(callToken) == "" {
+		return nil
+	}
+	if headers, ok := parameters["headers"].(map[string]any); ok {
+		for name := range headers {
+			if strings.EqualFold(name, bearerHeader) {
+				return fmt.Errorf("conflicts with parameters.headers.%s", name)
+			}
+		}
+	}
+	if strings.EqualFold(s.paymentHeaderNameFor(resource), bearerHeader) {
+		return fmt.Errorf("conflicts with the payment header, which is sent in %s for this resource", bearerHeader)
+	}
+	return nil
+}
+
+// applyBearerToken sets the Authorization header on req unless the caller
+// already sent one or the payment header occupies it.
+func (s *Server) applyBearerToken(req *http.Request, resource X402DiscoveryResource, callToken string) {
+	token := s.bearerTokenFor(resource, callToken)
+	if token == "" || req.Header.Get(bearerHeader) != "" {
+		return
+	}
+	req.Header.Set(bearerHeader, "Bearer "+token)
+}
+
+// bearerHeaderNames lists Authorization when a bearer token may be
+// forwarded, so it stays redacted even if SetRedactedHeaders drops it.
+func (s *Server) bearerHeaderNames(callToken string) []string {
+	if s.bearerToken == "" && len(s.resourceBearerTokens) == 0 && bareToken(callToken) == "" {
+		return nil
+	}
+	return []string{bearerHeader}
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func paidCallRequest() *sdkmcp.CallToolRequest {
+	return &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{Meta: sdkmcp.Meta{
+		"x402/payment": map[string]any{
+			"x402Version": 1,
+			"scheme":      "exact",
+			"network":     "base-sepolia",
+			"payload":     map[string]any{"signature": "0xdeadbeef"},
+		},
+	}}}
+}
+
+func TestProxyToolCallForwardsBearerTokenAndPayment(t *testing.T) {
+	t.Parallel()
+
+	var seen []http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	WithBearerToken("server-token", "127.0.0.1")(s)
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	for _, tt := range []struct {
+		name      string
+		callToken string
+		want      string
+	}{
+		{name: "server token", want: "Bearer server-token"},
+		{name: "call token", callToken: "Bearer call-token", want: "Bearer call-token"},
+	} {
+		result, _, err := s.ProxyToolCall(context.Background(), paidCallRequest(), &ProxyToolCallParams{ToolName: toolName, BearerToken: tt.callToken})
+		if err != nil {
+			t.Fatalf("%s: ProxyToolCall error: %v", tt.name, err)
+		}
+		if result.IsError {
+			t.Fatalf("%s: unexpected error result %+v", tt.name, result.Content)
+		}
+		headers := seen[len(seen)-1]
+		if got := headers.Get("Authorization"); got != tt.want {
+			t.Fatalf("%s: expected Authorization %q, got %q", tt.name, tt.want, got)
+		}
+		if headers.Get("X-PAYMENT") == "" {
+			t.Fatalf("%s: expected the payment header alongside the bearer token", tt.name)
+		}
+	}
+}
+
+func TestBearerTokenScopedToHosts(t *testing.T) {
+	t.Parallel()
+
+	scoped := testResource("https://API.example.com:8443/weather", "GET", nil)
+	other := testResource("https://evil.example.net/weather", "GET", nil)
+	pinned := testResource("https://pinned.example.org/weather", "GET", nil)
+
+	s := &Server{}
+	WithBearerToken("Bearer server-token", "api.example.com")(s)
+	WithResourceBearerToken(pinned.Resource, "pinned-token")(s)
+	for _, tt := range []struct {
+		name     string
+		resource X402DiscoveryResource
+		want     string
+	}{
+		{name: "allowed host", resource: scoped, want: "server-token"},
+		{name: "other host", resource: other, want: ""},
+		{name: "resource token", resource: pinned, want: "pinned-token"},
+	} {
+		if got := s.bearerTokenFor(tt.resource, ""); got != tt.want {
+			t.Fatalf("%s: expected token %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	// Without hosts the server token goes nowhere
+	s = &Server{}
+	WithBearerToken("server-token")(s)
+	if got := s.bearerTokenFor(scoped, ""); got != "" {
+		t.Fatalf("expected an unscoped token not to be sent, got %q", got)
+	}
+}
+
+func TestProxyToolCallBearerTokenConflicts(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("https://api.example.com/weather", "GET", map[string]any{
+		"headers": map[string]any{"Authorization": "Upstream credentials"},
+	})
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:    toolName,
+		Parameters:  map[string]any{"headers": map[string]any{"Authorization": "Basic abc"}},
+		BearerToken: "call-token",
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].(*sdkmcp.TextContent).Text, "bearerToken") {
+		t.Fatalf("expected a bearerToken conflict, got %s", result.Content[0].(*sdkmcp.TextContent).Text)
+	}
+
+	s = &Server{resources: []X402DiscoveryResource{resource}}
+	WithPaymentHeaderName("Authorization")(s)
+	result, _, err = s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: toolName, BearerToken: "call-token", DryRun: true})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected a bearer token to be rejected when the payment travels in Authorization")
+	}
+}
+
+func TestProxyToolCallPreviewRedactsBearerToken(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("https://api.example.com/weather", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	s.SetRedactedHeaders()
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:    toolNameFromResource(resource.Resource, "GET"),
+		BearerToken: "secret-token",
+		DryRun:      true,
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	preview := result.Content[0].(*sdkmcp.TextContent).Text
+	if strings.Contains(preview, "secret-token") || !strings.Contains(preview, redactedHeaderValue) {
+		t.Fatalf("expected the bearer token to be redacted in the preview, got %s", preview)
+	}
+}
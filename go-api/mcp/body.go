@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+const (
+	jsonContentType = "application/json"
+	formContentType = "application/x-www-form-urlencoded"
+)
+
+// bodyTypeContentTypes maps the bazaar input bodyType values to the
+// Content-Type sent upstream.
+var bodyTypeContentTypes = map[string]string{
+	"json":      jsonContentType,
+	"form-data": formContentType,
+	"text":      "text/plain",
+}
+
+// contentTypeFromInput returns the Content-Type implied by a declared input
+// schema's bodyType, or application/json when none is declared.
+func contentTypeFromInput(input map[string]any) string {
+	if input != nil {
+		if bodyType, ok := input["bodyType"].(string); ok {
+			if contentType, ok := bodyTypeContentTypes[strings.ToLower(bodyType)]; ok {
+				return contentType
+			}
+		}
+	}
+	return jsonContentType
+}
+
+// bodyContentType picks the Content-Type for a proxied body: the caller's
+// parameters.bodyContentType, else the resource's declared bodyType.
+func bodyContentType(resource X402DiscoveryResource, params map[string]any) string {
+	if params != nil {
+		if contentType, ok := params["bodyContentType"].(string); ok && strings.TrimSpace(contentType) != "" {
+			return strings.TrimSpace(contentType)
+		}
+	}
+	_, input := extractAcceptsMetadata(resource)
+	if input == nil {
+		input, _ = extractMetadataInput(resource)
+	}
+	return contentTypeFromInput(input)
+}
+
+// encodeProxyBody serializes rawBody for contentType. JSON types are
+// marshaled; form bodies encode a map like query parameters; anything else
+// must be a string and is sent verbatim. Strings are also sent verbatim for
+// form bodies, which lets callers pass a pre-encoded form.
+func encodeProxyBody(rawBody any, contentType string) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bodyContentType %q: %w", contentType, err)
+	}
+
+	switch {
+	case mediaType == jsonContentType || strings.HasSuffix(mediaType, "+json"):
+		return json.Marshal(rawBody)
+	case mediaType == formContentType:
+		switch body := rawBody.(type) {
+		case string:
+			return []byte(body), nil
+		case map[string]any:
+			form := url.Values{}
+			for key, value := range body {
+				setQueryValue(form, key, value)
+			}
+			return []byte(form.Encode()), nil
+		default:
+			return nil, fmt.Errorf("form body must be an object or a string, got %T", rawBody)
+		}
+	default:
+		body, ok := rawBody.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s body must be a string, got %T", mediaType, rawBody)
+		}
+		return []byte(body), nil
+	}
+}
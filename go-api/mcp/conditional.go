@@ -0,0 +1,33 @@
+package mcp
+
+import "net/http"
+
+// conditionalRequestHeaders may always be passed in parameters.headers so an
+// agent can revalidate content it already holds instead of fetching (and
+// paying for) it again.
+var conditionalRequestHeaders = map[string]string{
+	"If-None-Match":     "ETag from a previous response; the upstream answers 304 if the content is unchanged.",
+	"If-Modified-Since": "Last-Modified from a previous response; the upstream answers 304 if the content is unchanged.",
+}
+
+// addCacheValidators copies the response's ETag and Last-Modified, if any,
+// into payload so agents can send them back on the next call.
+func addCacheValidators(payload map[string]any, header http.Header) {
+	if etag := header.Get("ETag"); etag != "" {
+		payload["etag"] = etag
+	}
+	if lastModified := header.Get("Last-Modified"); lastModified != "" {
+		payload["lastModified"] = lastModified
+	}
+}
+
+// notModifiedContent is the structured content of a 304 response: the
+// content the agent already holds is still current.
+func notModifiedContent(header http.Header) map[string]any {
+	content := map[string]any{
+		"status":      http.StatusNotModified,
+		"notModified": true,
+	}
+	addCacheValidators(content, header)
+	return content
+}
@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProxyToolCallConditionalRequests(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"v42"`
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", "Wed, 14 Oct 2026 08:00:00 GMT")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"temperature":21}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", map[string]any{
+		"headers": map[string]any{"X-Units": "metric or imperial"},
+	})
+	toolName := toolNameFromResource(resource.Resource, "GET")
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+
+	t.Run("200 echoes the ETag", func(t *testing.T) {
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName:   toolName,
+			Parameters: map[string]any{"headers": map[string]any{"X-Units": "metric"}},
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("expected success, got %v %+v", err, result)
+		}
+		payload := decodeProxyPayload(t, result)
+		if payload["status"] != float64(http.StatusOK) || payload["etag"] != etag {
+			t.Fatalf("expected status 200 with etag %s, got %+v", etag, payload)
+		}
+		if payload["lastModified"] != "Wed, 14 Oct 2026 08:00:00 GMT" {
+			t.Fatalf("expected lastModified, got %v", payload["lastModified"])
+		}
+		if _, ok := payload["notModified"]; ok {
+			t.Fatalf("expected no notModified flag on a 200")
+		}
+	})
+
+	t.Run("304 is a not-modified result", func(t *testing.T) {
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName: toolName,
+			// If-None-Match is accepted even though the resource only
+			// declares X-Units
+			Parameters: map[string]any{"headers": map[string]any{"If-None-Match": etag}},
+		})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("expected 304 not to be an error, got %+v", result)
+		}
+		structured, ok := result.StructuredContent.(map[string]any)
+		if !ok {
+			t.Fatalf("expected structured content, got %T", result.StructuredContent)
+		}
+		if structured["notModified"] != true || structured["status"] != http.StatusNotModified || structured["etag"] != etag {
+			t.Fatalf("unexpected not-modified content %+v", structured)
+		}
+		payload := decodeProxyPayload(t, result)
+		if payload["notModified"] != true || payload["etag"] != etag || payload["body"] != "" {
+			t.Fatalf("unexpected not-modified payload %+v", payload)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package mcp
+
+// resourceKey identifies a discovered resource for deduplication: two entries
+// with the same URL and declared method would proxy to the same endpoint.
+type resourceKey struct {
+	url    string
+	method string
+}
+
+func keyForResource(resource X402DiscoveryResource) resourceKey {
+	return resourceKey{url: resource.Resource, method: declaredMethod(resource)}
+}
+
+// dedupeResources collapses entries with the same resource URL and method,
+// keeping the one with the latest LastUpdated. On a tie the entry loaded last
+// wins, so a refresh replaces what it refreshes. The survivor takes the
+// position of the first entry, and the number of collapsed entries is
+// returned. Entries that differ only by method are kept.
+func dedupeResources(resources []X402DiscoveryResource) ([]X402DiscoveryResource, int) {
+	deduped := make([]X402DiscoveryResource, 0, len(resources))
+	index := make(map[resourceKey]int, len(resources))
+	for _, resource := range resources {
+		key := keyForResource(resource)
+		i, seen := index[key]
+		if !seen {
+			index[key] = len(deduped)
+			deduped = append(deduped, resource)
+			continue
+		}
+		if !resource.LastUpdated.Before(deduped[i].LastUpdated) {
+			deduped[i] = resource
+		}
+	}
+	return deduped, len(resources) - len(deduped)
+}
+
+// dedupeResources drops duplicate discovered resources, logging how many were
+// collapsed.
+func (s *Server) dedupeResources(resources []X402DiscoveryResource) []X402DiscoveryResource {
+	deduped, collapsed := dedupeResources(resources)
+	if collapsed > 0 {
+		s.logSink().Info("collapsed duplicate discovery resources", "duplicates", collapsed, "resources", len(deduped))
+	}
+	return deduped
+}
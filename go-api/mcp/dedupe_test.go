@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"testing"
+	"time"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+)
+
+type infoRecorder struct {
+	x402local.StdLogger
+	infos []string
+}
+
+func (r *infoRecorder) Info(msg string, keyvals ...any) { r.infos = append(r.infos, msg) }
+
+func updatedResource(url, method, description string, updated time.Time) X402DiscoveryResource {
+	resource := testResource(url, method, nil)
+	(*resource.Accepts)[0].Description = description
+	resource.LastUpdated = updated
+	return resource
+}
+
+func TestDedupeResources(t *testing.T) {
+	t.Parallel()
+
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	resources := []X402DiscoveryResource{
+		updatedResource("https://api.example.com/weather", "GET", "fixture", older),
+		updatedResource("https://api.example.com/weather", "POST", "post", older),
+		updatedResource("https://api.example.com/alerts", "GET", "remote", newer),
+		updatedResource("https://api.example.com/weather", "GET", "refresh", newer),
+		updatedResource("https://api.example.com/alerts", "GET", "stale", older),
+		updatedResource("https://api.example.com/weather", "POST", "post again", older),
+	}
+
+	logger := &infoRecorder{}
+	s := &Server{logger: logger}
+	deduped := s.dedupeResources(resources)
+	var got []string
+	for _, resource := range deduped {
+		got = append(got, declaredMethod(resource)+" "+resource.Resource+" "+(*resource.Accepts)[0].Description)
+	}
+	want := []string{
+		"GET https://api.example.com/weather refresh",
+		"POST https://api.example.com/weather post again",
+		"GET https://api.example.com/alerts remote",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entry %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if len(logger.infos) != 1 {
+		t.Fatalf("expected one log line for the collapsed duplicates, got %v", logger.infos)
+	}
+	if _, collapsed := dedupeResources(deduped); collapsed != 0 {
+		t.Fatalf("expected deduped resources to have no duplicates, got %d", collapsed)
+	}
+
+	// Names stay unique and resolve to the surviving entry
+	names := map[string]bool{}
+	for _, resource := range deduped {
+		name := resourceToolName(nil, resource)
+		if names[name] {
+			t.Fatalf("duplicate tool name %q after dedupe", name)
+		}
+		names[name] = true
+		found, err := findResourceForToolName(deduped, name, nil)
+		if err != nil || found.Resource != resource.Resource || declaredMethod(*found) != declaredMethod(resource) {
+			t.Fatalf("expected %q to resolve to its own resource, got %+v, %v", name, found, err)
+		}
+	}
+}
+
+func TestFindResourceForToolNamePrefersLatestDuplicate(t *testing.T) {
+	t.Parallel()
+
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	items := []X402DiscoveryResource{
+		updatedResource("https://api.example.com/weather", "GET", "fixture", older.Add(time.Hour)),
+		updatedResource("https://api.example.com/weather", "GET", "stale", older),
+	}
+	found, err := findResourceForToolName(items, toolNameFromResource("https://api.example.com/weather", "GET"), nil)
+	if err != nil {
+		t.Fatalf("findResourceForToolName error: %v", err)
+	}
+	if description := (*found.Accepts)[0].Description; description != "fixture" {
+		t.Fatalf("expected the most recently updated duplicate, got %q", description)
+	}
+}
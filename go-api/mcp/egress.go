@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrEgressBlocked is returned when a proxy target resolves to an address the
+// server's egress policy does not permit.
+var ErrEgressBlocked = errors.New("egress blocked")
+
+// cgnatPrefix is the carrier-grade NAT shared address space (RFC 6598),
+// which netip does not count as private.
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// EgressPolicy decides which upstream addresses proxy_tool_call may reach.
+// The zero value blocks loopback, private, carrier-grade NAT, link-local and
+// unspecified addresses, which keeps a poisoned resource from reaching cloud
+// metadata endpoints or internal services. The policy is enforced on the
+// address each connection actually dials, so a hostname that re-resolves to
+// an internal address after the up-front check is still refused.
+type EgressPolicy struct {
+	// AllowedHosts lists hostnames, IPs or CIDR prefixes that may be reached
+	// even when they resolve to a blocked address.
+	AllowedHosts []string
+	// AllowPrivate disables the private address check entirely.
+	AllowPrivate bool
+	// Resolver looks up hostnames. Nil uses net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// SetEgressPolicy replaces the policy checked before each proxied request.
+// NewServer installs the zero EgressPolicy.
+func (s *Server) SetEgressPolicy(policy EgressPolicy) {
+	s.egress = &policy
+}
+
+// check resolves the target's host and rejects it if any address is blocked.
+// It reports a blocked target before the request is built; dialContext
+// enforces the policy on the address actually connected to.
+func (p *EgressPolicy) check(ctx context.Context, target *url.URL) error {
+	if p == nil || p.AllowPrivate {
+		return nil
+	}
+	host := strings.ToLower(target.Hostname())
+	if p.hostAllowed(host) {
+		return nil
+	}
+
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		resolver := p.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		addrs, err = resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return fmt.Errorf("%w: resolve %s: %v", ErrEgressBlocked, host, err)
+		}
+	}
+
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if isInternalAddr(addr) && !p.addrAllowed(addr) {
+			return fmt.Errorf("%w: %s resolves to non-public address %s", ErrEgressBlocked, host, addr)
+		}
+	}
+	return nil
+}
+
+// dialContext dials address with dialer, refusing any connection whose
+// resolved IP the policy blocks. The check runs in the dialer's Control hook,
+// after resolution, so DNS rebinding between check and dial cannot bypass it.
+func (p *EgressPolicy) dialContext(ctx context.Context, dialer net.Dialer, network, address string) (net.Conn, error) {
+	if p != nil && !p.AllowPrivate {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if !p.hostAllowed(strings.ToLower(host)) {
+			dialer.Control = p.control(host)
+		}
+		if p.Resolver != nil {
+			dialer.Resolver = p.Resolver
+		}
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// control rejects a dial to a blocked IP. address is the resolved ip:port.
+func (p *EgressPolicy) control(host string) func(network, address string, _ syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		ip, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrEgressBlocked, host, err)
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrEgressBlocked, host, err)
+		}
+		addr = addr.Unmap()
+		if isInternalAddr(addr) && !p.addrAllowed(addr) {
+			return fmt.Errorf("%w: %s dialed non-public address %s", ErrEgressBlocked, host, addr)
+		}
+		return nil
+	}
+}
+
+// newEgressTransport returns the proxy transport, which dials through the
+// egress policy returned by policy at dial time. It connects to upstreams
+// directly, without an environment proxy, so the policy sees the real
+// upstream address.
+func newEgressTransport(policy func() *EgressPolicy) *http.Transport {
+	dialer := net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return policy().dialContext(ctx, dialer, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+func (p *EgressPolicy) hostAllowed(host string) bool {
+	for _, allowed := range p.AllowedHosts {
+		if strings.EqualFold(strings.TrimSpace(allowed), host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *EgressPolicy) addrAllowed(addr netip.Addr) bool {
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.TrimSpace(allowed)
+		if prefix, err := netip.ParsePrefix(allowed); err == nil && prefix.Contains(addr) {
+			return true
+		}
+		if ip, err := netip.ParseAddr(allowed); err == nil && ip.Unmap() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func isInternalAddr(addr netip.Addr) bool {
+	return addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		cgnatPrefix.Contains(addr) ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified()
+}
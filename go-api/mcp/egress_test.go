@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestEgressPolicyCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		policy  EgressPolicy
+		target  string
+		blocked bool
+	}{
+		{name: "public ip", target: "https://93.184.216.34/weather"},
+		{name: "loopback", target: "http://127.0.0.1:8080/weather", blocked: true},
+		{name: "private", target: "http://10.1.2.3/weather", blocked: true},
+		{name: "metadata", target: "http://169.254.169.254/latest/meta-data", blocked: true},
+		{name: "carrier-grade nat", target: "http://100.100.100.200/latest/meta-data", blocked: true},
+		{name: "ipv6 loopback", target: "http://[::1]/weather", blocked: true},
+		{name: "mapped private", target: "http://[::ffff:192.168.0.1]/weather", blocked: true},
+		{name: "allowed cidr", policy: EgressPolicy{AllowedHosts: []string{"10.0.0.0/8"}}, target: "http://10.1.2.3/weather"},
+		{name: "allowed host", policy: EgressPolicy{AllowedHosts: []string{"LOCALHOST"}}, target: "http://localhost:8080/weather"},
+		{name: "allow private", policy: EgressPolicy{AllowPrivate: true}, target: "http://10.1.2.3/weather"},
+	}
+	for _, tt := range tests {
+		target, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", tt.name, err)
+		}
+		err = tt.policy.check(context.Background(), target)
+		if tt.blocked != errors.Is(err, ErrEgressBlocked) {
+			t.Errorf("%s: blocked=%v, got err %v", tt.name, tt.blocked, err)
+		}
+	}
+}
+
+func TestProxyToolCallBlocksPrivateEgress(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	s.SetEgressPolicy(EgressPolicy{})
+
+	result, _, err := s.ProxyToolCall(context.Background(), &sdkmcp.CallToolRequest{}, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(resource.Resource, "GET"),
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected an error result")
+	}
+	text := result.Content[0].(*sdkmcp.TextContent).Text
+	if !strings.Contains(text, ErrEgressBlocked.Error()) {
+		t.Fatalf("expected egress error, got %q", text)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("expected no upstream calls, got %d", hits.Load())
+	}
+}
+
+func TestEgressPolicyEnforcedAtDial(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer upstream.Close()
+	_, port, _ := strings.Cut(strings.TrimPrefix(upstream.URL, "http://"), ":")
+
+	// A request that skipped or raced the up-front check is refused on dial
+	s := &Server{}
+	s.SetEgressPolicy(EgressPolicy{})
+	if _, err := s.proxyHTTPClient().Get(upstream.URL); !errors.Is(err, ErrEgressBlocked) {
+		t.Fatalf("expected the dial to be blocked, got %v", err)
+	}
+	if _, err := s.proxyHTTPClient().Get("http://localhost:" + port); !errors.Is(err, ErrEgressBlocked) {
+		t.Fatalf("expected a hostname resolving to loopback to be blocked on dial, got %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("expected no upstream calls, got %d", hits.Load())
+	}
+
+	s.SetEgressPolicy(EgressPolicy{AllowedHosts: []string{"localhost"}})
+	resp, err := s.proxyHTTPClient().Get("http://localhost:" + port)
+	if err != nil {
+		t.Fatalf("expected an allowed host to dial, got %v", err)
+	}
+	resp.Body.Close()
+	if hits.Load() != 1 {
+		t.Fatalf("expected one upstream call, got %d", hits.Load())
+	}
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// fieldErrorListKeys are the top-level keys under which common validation
+// error formats list per-field problems: {"errors": [...]}, FastAPI's
+// {"detail": [...]}, RFC 7807's "invalid-params" and similar.
+var fieldErrorListKeys = []string{"errors", "detail", "invalid-params", "invalid_params", "invalidParams", "fieldErrors", "field_errors"}
+
+// fieldErrorNameKeys name the offending field inside one list entry.
+var fieldErrorNameKeys = []string{"field", "param", "parameter", "name", "property", "path", "loc"}
+
+// fieldErrorMapKeys hold a map of field name to message, e.g.
+// {"fields": {"city": "is required"}}.
+var fieldErrorMapKeys = []string{"fields", "errors", "fieldErrors", "field_errors"}
+
+// fieldErrorNamesKeys hold a plain list of field names.
+var fieldErrorNamesKeys = []string{"missing", "missingParams", "missing_params", "required"}
+
+// fieldErrorNames extracts the names of missing or invalid fields from an
+// upstream validation error body, in a stable order. It returns nil when the
+// body has no recognizable field-error structure.
+func fieldErrorNames(body any) []string {
+	object, ok := body.(map[string]any)
+	if !ok {
+		return nil
+	}
+	if nested, ok := object["error"].(map[string]any); ok {
+		if names := fieldErrorNames(nested); len(names) > 0 {
+			return names
+		}
+	}
+
+	var names []string
+	add := func(name string) {
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	for _, key := range fieldErrorListKeys {
+		entries, ok := object[key].([]any)
+		if !ok {
+			continue
+		}
+		for _, entry := range entries {
+			if fields, ok := entry.(map[string]any); ok {
+				add(fieldNameFromEntry(fields))
+			}
+		}
+	}
+	for _, key := range fieldErrorMapKeys {
+		fields, ok := object[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		keys := make([]string, 0, len(fields))
+		for name := range fields {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		for _, name := range keys {
+			add(name)
+		}
+	}
+	for _, key := range fieldErrorNamesKeys {
+		list, ok := object[key].([]any)
+		if !ok {
+			continue
+		}
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				add(name)
+			}
+		}
+	}
+	return names
+}
+
+// fieldNameFromEntry returns the field an error entry refers to. Paths given
+// as lists (FastAPI's loc, JSON Schema paths) are joined with dots, dropping a
+// leading location such as "query" or "body".
+func fieldNameFromEntry(entry map[string]any) string {
+	for _, key := range fieldErrorNameKeys {
+		switch value := entry[key].(type) {
+		case string:
+			if value != "" {
+				return strings.TrimPrefix(value, "/")
+			}
+		case []any:
+			parts := make([]string, 0, len(value))
+			for _, part := range value {
+				parts = append(parts, fmt.Sprint(part))
+			}
+			if len(parts) > 1 && slices.Contains([]string{"query", "body", "path", "header"}, parts[0]) {
+				parts = parts[1:]
+			}
+			if len(parts) > 0 {
+				return strings.Join(parts, ".")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFieldErrorNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "errors list with field", body: `{"errors":[{"field":"city","message":"required"},{"field":"units","message":"invalid"}]}`, want: []string{"city", "units"}},
+		{name: "FastAPI detail", body: `{"detail":[{"loc":["query","city"],"msg":"field required"}]}`, want: []string{"city"}},
+		{name: "RFC 7807 invalid-params", body: `{"type":"about:blank","invalid-params":[{"name":"age","reason":"must be positive"}]}`, want: []string{"age"}},
+		{name: "field map", body: `{"message":"validation failed","fields":{"units":"invalid","city":"required"}}`, want: []string{"city", "units"}},
+		{name: "missing list", body: `{"missing":["city"]}`, want: []string{"city"}},
+		{name: "nested error", body: `{"error":{"errors":[{"path":"/city"}]}}`, want: []string{"city"}},
+		{name: "plain message", body: `{"error":"bad request"}`, want: nil},
+		{name: "string detail", body: `{"detail":"city is required"}`, want: nil},
+		{name: "array body", body: `["city"]`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var body any
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := fieldErrorNames(body); !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHTTPResponseToMCPResultLiftsFieldErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{name: "validation error", body: `{"errors":[{"field":"city","message":"required"}]}`, want: []string{"city"}},
+		{name: "opaque 400", body: `upstream says no`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+			result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+			if err != nil {
+				t.Fatalf("httpResponseToMCPResult error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("expected a 400 to be an error result")
+			}
+			structured := result.StructuredContent.(map[string]any)
+			got, lifted := structured["missingOrInvalidParams"].([]string)
+			if tt.want == nil {
+				if lifted {
+					t.Fatalf("expected no missingOrInvalidParams for an opaque body, got %v", got)
+				}
+				if structured["error"] != tt.body {
+					t.Fatalf("expected the body to pass through unchanged, got %v", structured["error"])
+				}
+				return
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected missingOrInvalidParams %v, got %v", tt.want, got)
+			}
+			if _, ok := structured["error"].(map[string]any); !ok {
+				t.Fatalf("expected the decoded body to be kept, got %T", structured["error"])
+			}
+		})
+	}
+}
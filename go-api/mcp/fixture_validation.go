@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+)
+
+// ErrInvalidFixture is returned by NewServer when strict fixture validation
+// finds malformed discovery entries.
+var ErrInvalidFixture = errors.New("invalid discovery fixture")
+
+// maxFixtureTimeoutSeconds bounds maxTimeoutSeconds in fixture entries.
+const maxFixtureTimeoutSeconds = 24 * 60 * 60
+
+// FixtureValidation selects how NewServer treats malformed discovery fixture
+// entries.
+type FixtureValidation int
+
+const (
+	// FixtureValidationStrict fails NewServer with an error listing every
+	// malformed entry. This is the default.
+	FixtureValidationStrict FixtureValidation = iota
+	// FixtureValidationLenient skips malformed entries, logging a warning for
+	// each.
+	FixtureValidationLenient
+)
+
+// WithFixtureValidation selects how malformed discovery fixture entries are
+// handled.
+func WithFixtureValidation(mode FixtureValidation) ServerOption {
+	return func(s *Server) {
+		s.fixtureValidation = mode
+	}
+}
+
+// validateFixtures checks every fixture entry. In strict mode any problem
+// fails with ErrInvalidFixture naming each bad entry by index; in lenient mode
+// bad entries are dropped with a warning.
+func (s *Server) validateFixtures(resources []X402DiscoveryResource) ([]X402DiscoveryResource, error) {
+	valid := make([]X402DiscoveryResource, 0, len(resources))
+	var invalid []string
+	for i, resource := range resources {
+		problems := validateDiscoveryResource(resource)
+		if len(problems) == 0 {
+			valid = append(valid, resource)
+			continue
+		}
+		entry := fmt.Sprintf("items[%d] %q: %s", i, resource.Resource, strings.Join(problems, ", "))
+		if s.fixtureValidation == FixtureValidationLenient {
+			s.logSink().Warn("skipping invalid discovery fixture entry", "index", i, "resource", resource.Resource, "problems", strings.Join(problems, ", "))
+			continue
+		}
+		invalid = append(invalid, entry)
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%w: %d invalid entries: %s", ErrInvalidFixture, len(invalid), strings.Join(invalid, "; "))
+	}
+	return valid, nil
+}
+
+// validateDiscoveryResource returns one message per malformed field of
+// resource: required fields, URL format, x402 version, payment scheme,
+// network, amount and timeout.
+func validateDiscoveryResource(resource X402DiscoveryResource) []string {
+	var problems []string
+	if resource.Resource == "" {
+		problems = append(problems, "resource is required")
+	} else if parsed, err := url.Parse(resource.Resource); err != nil || !parsed.IsAbs() || parsed.Host == "" {
+		problems = append(problems, "resource must be an absolute URL")
+	}
+	if resource.Type != "http" {
+		problems = append(problems, fmt.Sprintf("type %q must be \"http\"", resource.Type))
+	}
+	if !slices.Contains(SupportedX402Versions, resource.X402Version) {
+		problems = append(problems, fmt.Sprintf("x402Version %d is not one of %v", resource.X402Version, SupportedX402Versions))
+	}
+	if resource.Accepts == nil {
+		return problems
+	}
+	for i, accept := range *resource.Accepts {
+		prefix := fmt.Sprintf("accepts[%d].", i)
+		switch accept.Scheme {
+		case x402local.SchemeExact, x402local.SchemeUpto:
+		case "":
+			problems = append(problems, prefix+"scheme is required")
+		default:
+			problems = append(problems, fmt.Sprintf("%sscheme %q is not supported", prefix, accept.Scheme))
+		}
+		if network, err := x402local.NormalizeNetwork(accept.Network); err != nil {
+			problems = append(problems, fmt.Sprintf("%snetwork: %v", prefix, err))
+		} else if err := x402local.ValidateNetwork(string(network), true); err != nil {
+			problems = append(problems, fmt.Sprintf("%snetwork: %v", prefix, err))
+		}
+		if accept.Asset == "" {
+			problems = append(problems, prefix+"asset is required")
+		}
+		if accept.PayTo == "" {
+			problems = append(problems, prefix+"payTo is required")
+		}
+		if accept.MaxAmountRequired != "" && strings.Trim(accept.MaxAmountRequired, "0123456789") != "" {
+			problems = append(problems, fmt.Sprintf("%smaxAmountRequired %q must be a decimal integer", prefix, accept.MaxAmountRequired))
+		}
+		if accept.MaxTimeoutSeconds < 0 || accept.MaxTimeoutSeconds > maxFixtureTimeoutSeconds {
+			problems = append(problems, fmt.Sprintf("%smaxTimeoutSeconds %d must be between 0 and %d", prefix, accept.MaxTimeoutSeconds, maxFixtureTimeoutSeconds))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,89 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const invalidFixture = `{"items": [
+  {"resource": "http://localhost:8080/weather", "type": "http", "x402Version": 1,
+   "accepts": [{"scheme": "exact", "network": "base-sepolia", "asset": "0xusdc", "payTo": "0xpay", "maxAmountRequired": "10000", "maxTimeoutSeconds": 300}]},
+  {"type": "http", "x402Version": 1},
+  {"resource": "http://localhost:8080/stocks", "type": "http", "x402Version": 1,
+   "accepts": [{"scheme": "exact", "network": "not-a-network", "asset": "0xusdc", "payTo": "0xpay", "maxTimeoutSeconds": -5}]},
+  {"resource": "/relative", "type": "grpc", "x402Version": 7,
+   "accepts": [{"scheme": "stream", "network": "eip155:84532", "maxAmountRequired": "0.01"}]},
+  {"resource": "https://api.example.com/free", "type": "http", "x402Version": 2}
+]}`
+
+func decodeFixture(t *testing.T, payload string) []X402DiscoveryResource {
+	t.Helper()
+	var decoded fixtureResponse
+	if err := json.Unmarshal([]byte(payload), &decoded); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	return decoded.Items
+}
+
+func TestValidateFixturesStrict(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{}
+	_, err := s.validateFixtures(decodeFixture(t, invalidFixture))
+	if !errors.Is(err, ErrInvalidFixture) {
+		t.Fatalf("expected ErrInvalidFixture, got %v", err)
+	}
+	message := err.Error()
+	for _, want := range []string{
+		"3 invalid entries",
+		`items[1] "": resource is required`,
+		`items[2] "http://localhost:8080/stocks": accepts[0].network: unknown network "not-a-network"`,
+		"accepts[0].maxTimeoutSeconds -5 must be between 0 and 86400",
+		`items[3] "/relative": resource must be an absolute URL`,
+		`type "grpc" must be "http"`,
+		"x402Version 7 is not one of [1 2]",
+		`accepts[0].scheme "stream" is not supported`,
+		"accepts[0].asset is required",
+		"accepts[0].payTo is required",
+		`accepts[0].maxAmountRequired "0.01" must be a decimal integer`,
+	} {
+		if !strings.Contains(message, want) {
+			t.Fatalf("expected error to contain %q, got %q", want, message)
+		}
+	}
+	if strings.Contains(message, "items[0]") || strings.Contains(message, "items[4]") {
+		t.Fatalf("expected valid entries to be left out of the error, got %q", message)
+	}
+}
+
+func TestValidateFixturesLenient(t *testing.T) {
+	t.Parallel()
+
+	logger := &warnCounter{}
+	s := &Server{logger: logger}
+	WithFixtureValidation(FixtureValidationLenient)(s)
+	valid, err := s.validateFixtures(decodeFixture(t, invalidFixture))
+	if err != nil {
+		t.Fatalf("lenient validation error: %v", err)
+	}
+	if len(valid) != 2 || valid[0].Resource != "http://localhost:8080/weather" || valid[1].Resource != "https://api.example.com/free" {
+		t.Fatalf("expected the two valid entries, got %+v", valid)
+	}
+	if logger.warns != 3 {
+		t.Fatalf("expected a warning per skipped entry, got %d", logger.warns)
+	}
+}
+
+func TestBundledFixturesAreValid(t *testing.T) {
+	t.Parallel()
+
+	resources, err := loadDiscoveryResources()
+	if err != nil {
+		t.Fatalf("loadDiscoveryResources: %v", err)
+	}
+	if _, err := (&Server{}).validateFixtures(resources); err != nil {
+		t.Fatalf("bundled fixtures are invalid: %v", err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 )
 
@@ -20,6 +19,15 @@ var (
 	fixtureErr       error
 )
 
+// WithResources adds resources to the entries loaded from the discovery
+// fixture, e.g. from another discovery source. They are validated, filtered
+// and deduplicated like fixture entries, after them.
+func WithResources(resources ...X402DiscoveryResource) ServerOption {
+	return func(s *Server) {
+		s.extraResources = append(s.extraResources, resources...)
+	}
+}
+
 func loadDiscoveryResources() ([]X402DiscoveryResource, error) {
 	fixtureOnce.Do(func() {
 		path, err := fixturePath()
@@ -51,53 +59,54 @@ func fixturePath() (string, error) {
 	return filepath.Clean(filepath.Join(baseDir, "..", "fixtures", "x402-endpoints.json")), nil
 }
 
-func filterWeatherResources(items []X402DiscoveryResource) []X402DiscoveryResource {
-	filtered := make([]X402DiscoveryResource, 0, len(items))
-	for _, item := range items {
-		if strings.Contains(strings.ToLower(item.Resource), "/weather") {
-			filtered = append(filtered, item)
-		}
-	}
-	return filtered
-}
-
+// paginateResources pages items by limit and offset, returning at most
+// maxResults items regardless of limit. A limit above maxResults is clamped
+// and flagged in the pagination.
 func paginateResources(
 	items []X402DiscoveryResource,
 	limit *int,
 	offset *int,
+	maxResults int,
 ) ([]X402DiscoveryResource, SearchResourcesPagination) {
 	total := len(items)
-	start := 0
-	if offset != nil && *offset > 0 {
-		start = *offset
-		if start > total {
-			start = total
-		}
-	}
-	end := total
-	if limit != nil && *limit >= 0 {
-		end = start + *limit
-		if end > total {
-			end = total
-		}
-	}
-	paged := items[start:end]
 
+	// Negative values are clamped to zero and echoed back clamped
 	var limitPtr *int
+	clamped := false
 	if limit != nil {
-		value := *limit
+		value := max(*limit, 0)
+		if value > maxResults {
+			value, clamped = maxResults, true
+		}
 		limitPtr = &value
 	}
 	var offsetPtr *int
 	if offset != nil {
-		value := *offset
+		value := max(*offset, 0)
 		offsetPtr = &value
 	}
+
+	start := 0
+	if offsetPtr != nil {
+		start = min(*offsetPtr, total)
+	}
+	pageSize := maxResults
+	if limitPtr != nil {
+		pageSize = *limitPtr
+	}
+	end := min(start+pageSize, total)
+	paged := items[start:end]
+
 	totalPtr := total
+	returned := len(paged)
+	hasMore := end < total
 
 	return paged, SearchResourcesPagination{
-		Limit:  limitPtr,
-		Offset: offsetPtr,
-		Total:  &totalPtr,
+		Limit:        limitPtr,
+		Offset:       offsetPtr,
+		Total:        &totalPtr,
+		Returned:     &returned,
+		HasMore:      &hasMore,
+		LimitClamped: clamped,
 	}
 }
@@ -0,0 +1,51 @@
+package mcp
+
+import "testing"
+
+func paginationFixture(n int) []X402DiscoveryResource {
+	items := make([]X402DiscoveryResource, n)
+	for i := range items {
+		items[i] = testResource("http://localhost:8080/weather", "GET", nil)
+	}
+	return items
+}
+
+func TestPaginateResourcesBounds(t *testing.T) {
+	t.Parallel()
+
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		name       string
+		limit      *int
+		offset     *int
+		wantLen    int
+		wantOffset int
+		wantMore   bool
+	}{
+		{name: "negative offset", limit: intPtr(2), offset: intPtr(-3), wantLen: 2, wantOffset: 0, wantMore: true},
+		{name: "offset past end", limit: intPtr(2), offset: intPtr(10), wantLen: 0, wantOffset: 10, wantMore: false},
+		{name: "partial final page", limit: intPtr(2), offset: intPtr(4), wantLen: 1, wantOffset: 4, wantMore: false},
+		{name: "full page with more", limit: intPtr(2), offset: intPtr(2), wantLen: 2, wantOffset: 2, wantMore: true},
+		{name: "negative limit", limit: intPtr(-1), offset: intPtr(0), wantLen: 0, wantOffset: 0, wantMore: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			paged, pagination := paginateResources(paginationFixture(5), tc.limit, tc.offset, DefaultMaxSearchResults)
+			if len(paged) != tc.wantLen {
+				t.Fatalf("expected %d items, got %d", tc.wantLen, len(paged))
+			}
+			if pagination.Offset == nil || *pagination.Offset != tc.wantOffset {
+				t.Fatalf("expected offset %d, got %v", tc.wantOffset, pagination.Offset)
+			}
+			if pagination.Limit == nil || *pagination.Limit < 0 {
+				t.Fatalf("expected non-negative limit, got %v", pagination.Limit)
+			}
+			if pagination.Total == nil || *pagination.Total != 5 {
+				t.Fatalf("expected total 5, got %v", pagination.Total)
+			}
+			if pagination.HasMore == nil || *pagination.HasMore != tc.wantMore {
+				t.Fatalf("expected hasMore %t, got %v", tc.wantMore, pagination.HasMore)
+			}
+		})
+	}
+}
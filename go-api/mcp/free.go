@@ -0,0 +1,31 @@
+package mcp
+
+// WithoutFreeTools hides resources without payment requirements from
+// search_resources, list_tool_names and direct tools. They are included by
+// default and marked with _meta["x402/free"] = true. Hidden tools can still
+// be called by name through proxy_tool_call.
+func WithoutFreeTools() ServerOption {
+	return func(s *Server) {
+		s.excludeFree = true
+	}
+}
+
+// isFreeResource reports whether a resource advertises no payment
+// requirements.
+func isFreeResource(resource X402DiscoveryResource) bool {
+	return resource.Accepts == nil || len(*resource.Accepts) == 0
+}
+
+// filterFree drops free resources when the server excludes them.
+func (s *Server) filterFree(resources []X402DiscoveryResource) []X402DiscoveryResource {
+	if !s.excludeFree {
+		return resources
+	}
+	kept := make([]X402DiscoveryResource, 0, len(resources))
+	for _, resource := range resources {
+		if !isFreeResource(resource) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSearchResourcesFreeTools(t *testing.T) {
+	t.Parallel()
+
+	free := X402DiscoveryResource{
+		Resource:    "http://localhost:8080/weather-free",
+		Type:        "http",
+		X402Version: 1,
+		Metadata:    &map[string]any{"description": "Free weather"},
+	}
+	paid := testResource("http://localhost:8080/weather", "GET", nil)
+	freeName := resourceToTool(free, nil, ToolOverride{}, nil).Name
+
+	s := &Server{resources: []X402DiscoveryResource{free, paid}}
+	_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	if len(output.Tools) != 2 {
+		t.Fatalf("expected free and paid tools by default, got %d", len(output.Tools))
+	}
+	for _, tool := range output.Tools {
+		_, marked := tool.Meta["x402/free"]
+		if marked != (tool.Name == freeName) {
+			t.Fatalf("expected only %s to be marked free, got %s meta %v", freeName, tool.Name, tool.Meta)
+		}
+	}
+
+	WithoutFreeTools()(s)
+	_, output, err = s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	if len(output.Tools) != 1 || output.Tools[0].Name == freeName {
+		t.Fatalf("expected the free tool to be hidden, got %d tools", len(output.Tools))
+	}
+}
+
+func TestDirectToolsFreeTools(t *testing.T) {
+	t.Parallel()
+
+	free := X402DiscoveryResource{
+		Resource:    "http://localhost:8080/weather-free",
+		Type:        "http",
+		X402Version: 1,
+	}
+	paid := testResource("http://localhost:8080/weather-direct", "GET", nil)
+	freeName := resourceToTool(free, nil, ToolOverride{}, nil).Name
+	paidName := resourceToTool(paid, nil, ToolOverride{}, nil).Name
+
+	for _, tt := range []struct {
+		name     string
+		options  []ServerOption
+		wantFree bool
+	}{
+		{name: "default", wantFree: true},
+		{name: "without free tools", options: []ServerOption{WithoutFreeTools()}, wantFree: false},
+	} {
+		options := append([]ServerOption{WithResources(free, paid), WithDirectTools(1000)}, tt.options...)
+		s, err := NewServer(options...)
+		if err != nil {
+			t.Fatalf("%s: NewServer error: %v", tt.name, err)
+		}
+		listed, err := connectClient(t, s).ListTools(context.Background(), &sdkmcp.ListToolsParams{})
+		if err != nil {
+			t.Fatalf("%s: ListTools error: %v", tt.name, err)
+		}
+		names := make(map[string]bool, len(listed.Tools))
+		for _, tool := range listed.Tools {
+			names[tool.Name] = true
+		}
+		if !names[paidName] {
+			t.Fatalf("%s: expected the paid direct tool %s", tt.name, paidName)
+		}
+		if names[freeName] != tt.wantFree {
+			t.Fatalf("%s: expected free direct tool listed %t, got %t", tt.name, tt.wantFree, names[freeName])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ErrHostOverrideDenied is returned when a proxied call asks for a Host header
+// the server has not allowed.
+var ErrHostOverrideDenied = errors.New("host override not allowed")
+
+// hostHeaderPattern matches a hostname or IPv4 address with an optional port.
+// Anything else, notably whitespace, CR/LF or a path, is rejected so a Host
+// override can never smuggle a second request line.
+var hostHeaderPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]{1,5})?$`)
+
+// WithHostOverrides lets proxy_tool_call send one of hosts as the upstream
+// Host header, for virtual-hosted resources whose expected Host differs from
+// the URL host. Entries match a host with or without its port. Without this
+// option any override other than the URL's own host is rejected.
+func WithHostOverrides(hosts ...string) ServerOption {
+	return func(s *Server) {
+		if s.hostOverrides == nil {
+			s.hostOverrides = make(map[string]struct{}, len(hosts))
+		}
+		for _, host := range hosts {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				s.hostOverrides[host] = struct{}{}
+			}
+		}
+	}
+}
+
+// requestedHost returns the Host override in params.host, falling back to a
+// Host entry in params.headers.
+func requestedHost(params map[string]any) string {
+	if host, ok := params["host"].(string); ok && host != "" {
+		return host
+	}
+	if rawHeaders, ok := params["headers"].(map[string]any); ok {
+		for key, value := range rawHeaders {
+			if http.CanonicalHeaderKey(key) == "Host" {
+				return fmt.Sprint(value)
+			}
+		}
+	}
+	return ""
+}
+
+// checkHostOverride validates a requested Host override for resource. An
+// empty host or the resource URL's own host is always accepted.
+func (s *Server) checkHostOverride(resource X402DiscoveryResource, host string) error {
+	if host == "" {
+		return nil
+	}
+	if !hostHeaderPattern.MatchString(host) {
+		return fmt.Errorf("%q is not a valid host", host)
+	}
+	host = strings.ToLower(host)
+	if endpoint, err := url.Parse(resource.Resource); err == nil && strings.EqualFold(endpoint.Host, host) {
+		return nil
+	}
+	if _, ok := s.hostOverrides[host]; ok {
+		return nil
+	}
+	if hostname, _, found := strings.Cut(host, ":"); found {
+		if _, ok := s.hostOverrides[hostname]; ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrHostOverrideDenied, host)
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+)
+
+func TestProxyToolCallToHTTPRequestSetsHost(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("https://203.0.113.10/weather", "GET", nil)
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   string
+	}{
+		{name: "no override", params: nil, want: ""},
+		{name: "host parameter", params: map[string]any{"host": "api.example.com"}, want: "api.example.com"},
+		{name: "Host header", params: map[string]any{"headers": map[string]any{"host": "api.example.com:8443"}}, want: "api.example.com:8443"},
+		{
+			name: "host parameter wins over header",
+			params: map[string]any{
+				"host":    "api.example.com",
+				"headers": map[string]any{"Host": "other.example.com"},
+			},
+			want: "api.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := proxyToolCallToHTTPRequest(context.Background(), resource, tt.params, x402local.StdLogger{})
+			if err != nil {
+				t.Fatalf("proxyToolCallToHTTPRequest error: %v", err)
+			}
+			if req.Host != tt.want {
+				t.Fatalf("expected req.Host %q, got %q", tt.want, req.Host)
+			}
+			if got := req.Header.Get("Host"); got != "" {
+				t.Fatalf("expected no Host in req.Header, got %q", got)
+			}
+		})
+	}
+}
+
+func TestProxyToolCallHostOverride(t *testing.T) {
+	t.Parallel()
+
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Host)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	toolName := toolNameFromResource(resource.Resource, "GET")
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	WithHostOverrides("api.example.com")(s)
+
+	call := func(params map[string]any) *ProxyToolCallParams {
+		return &ProxyToolCallParams{ToolName: toolName, Parameters: params}
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, call(map[string]any{"host": "api.example.com"}))
+	if err != nil || result.IsError {
+		t.Fatalf("expected allowed override to succeed, got %v %+v", err, result)
+	}
+	if got, _ := seen.Load().(string); got != "api.example.com" {
+		t.Fatalf("expected upstream Host api.example.com, got %q", got)
+	}
+
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+	if result, _, err := s.ProxyToolCall(context.Background(), nil, call(map[string]any{"host": upstreamHost})); err != nil || result.IsError {
+		t.Fatalf("expected the URL's own host to be accepted, got %v %+v", err, result)
+	}
+
+	for _, host := range []string{"internal.example.com", "api.example.com\r\nX-Injected: 1", "api.example.com/path"} {
+		seen.Store("")
+		result, _, err := s.ProxyToolCall(context.Background(), nil, call(map[string]any{"headers": map[string]any{"Host": host}}))
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		if !result.IsError {
+			t.Fatalf("expected Host %q to be rejected", host)
+		}
+		structured, _ := result.StructuredContent.(map[string]any)
+		if structured["error"] != "invalid_parameters" {
+			t.Fatalf("expected invalid_parameters for %q, got %+v", host, structured)
+		}
+		if got, _ := seen.Load().(string); got != "" {
+			t.Fatalf("expected no upstream request for Host %q", host)
+		}
+	}
+}
+
+func TestCheckHostOverrideMatchesWithoutPort(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{}
+	WithHostOverrides("API.example.com")(s)
+	resource := testResource("https://203.0.113.10/weather", "GET", nil)
+	if err := s.checkHostOverride(resource, "api.example.com:8443"); err != nil {
+		t.Fatalf("expected hostname entry to allow any port, got %v", err)
+	}
+	if err := s.checkHostOverride(resource, "other.example.com"); !errors.Is(err, ErrHostOverrideDenied) {
+		t.Fatalf("expected ErrHostOverrideDenied, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultAccept is sent upstream when parameters.accept is not supplied.
+const defaultAccept = "application/json"
+
+// requestedAccept returns the caller's parameters.accept value, if any.
+func requestedAccept(params map[string]any) string {
+	if params == nil {
+		return ""
+	}
+	accept, _ := params["accept"].(string)
+	return strings.TrimSpace(accept)
+}
+
+// validateAccept checks that at least one media range in accept matches the
+// resource's declared mimeType. Resources without a mimeType accept anything.
+func validateAccept(resource X402DiscoveryResource, accept string) error {
+	if accept == "" || resource.Accepts == nil {
+		return nil
+	}
+	declared := findMimeType(*resource.Accepts)
+	if declared == "" {
+		return nil
+	}
+	declaredType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		return nil
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if mediaRangeMatches(mediaRange, declaredType) {
+			return nil
+		}
+	}
+	return fmt.Errorf("accept %q does not match the resource mimeType %q", accept, declared)
+}
+
+// mediaRangeMatches reports whether an Accept media range such as "text/*"
+// covers mediaType.
+func mediaRangeMatches(mediaRange, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	rangeMajor, rangeMinor, ok := strings.Cut(mediaRange, "/")
+	if !ok || rangeMinor != "*" {
+		return false
+	}
+	major, _, _ := strings.Cut(mediaType, "/")
+	return rangeMajor == major
+}
+
+// isTextualMediaType reports whether a response with this Content-Type can be
+// relayed as a string. Responses without a Content-Type are treated as text.
+func isTextualMediaType(contentType string) bool {
+	if strings.TrimSpace(contentType) == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded", "application/yaml", "application/x-ndjson",
+		"image/svg+xml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// binaryResponseContent returns MCP content carrying a binary body: image
+// content for images, otherwise an embedded blob resource keyed by the
+// request URL.
+func binaryResponseContent(resp *http.Response, body []byte) mcp.Content {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	if strings.HasPrefix(mediaType, "image/") {
+		return &mcp.ImageContent{Data: body, MIMEType: mediaType}
+	}
+	if resp.Request == nil || resp.Request.URL == nil {
+		return nil
+	}
+	return &mcp.EmbeddedResource{
+		Resource: &mcp.ResourceContents{
+			URI:      resp.Request.URL.String(),
+			MIMEType: mediaType,
+			Blob:     body,
+		},
+	}
+}
+
+// encodeBinaryBody base64-encodes a binary body for the JSON payload.
+func encodeBinaryBody(body []byte) string {
+	return base64.StdEncoding.EncodeToString(body)
+}
@@ -0,0 +1,88 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrMethodNotAllowed is returned when a resource would be called with an
+// HTTP method outside the server's allowlist.
+var ErrMethodNotAllowed = errors.New("http method not allowed")
+
+// DefaultAllowedMethods are the HTTP methods proxy_tool_call may send when
+// WithAllowedMethods is not used.
+var DefaultAllowedMethods = []string{http.MethodGet, http.MethodPost}
+
+// WithAllowedMethods restricts the HTTP methods proxy_tool_call may send
+// upstream, replacing DefaultAllowedMethods. Resources declaring any other
+// method are hidden from search_resources and direct tools and rejected by
+// proxy_tool_call.
+func WithAllowedMethods(methods ...string) ServerOption {
+	return func(s *Server) {
+		s.allowedMethods = make(map[string]struct{}, len(methods))
+		for _, method := range methods {
+			if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+				s.allowedMethods[method] = struct{}{}
+			}
+		}
+	}
+}
+
+// methodAllowed reports whether method may be sent upstream.
+func (s *Server) methodAllowed(method string) bool {
+	method = strings.ToUpper(method)
+	if s.allowedMethods == nil {
+		return slices.Contains(DefaultAllowedMethods, method)
+	}
+	_, ok := s.allowedMethods[method]
+	return ok
+}
+
+// allowedMethodList returns the allowlist in sorted order.
+func (s *Server) allowedMethodList() []string {
+	if s.allowedMethods == nil {
+		return slices.Sorted(slices.Values(DefaultAllowedMethods))
+	}
+	return slices.Sorted(maps.Keys(s.allowedMethods))
+}
+
+// resourceMethodAllowed checks the resource's declared method. Resources that
+// declare none are only checked at call time, once the method is known.
+func (s *Server) resourceMethodAllowed(resource X402DiscoveryResource) bool {
+	method := declaredMethod(resource)
+	return method == "" || s.methodAllowed(method)
+}
+
+// filterDisallowedMethods drops resources declaring a method outside the
+// allowlist.
+func (s *Server) filterDisallowedMethods(resources []X402DiscoveryResource) []X402DiscoveryResource {
+	kept := make([]X402DiscoveryResource, 0, len(resources))
+	for _, resource := range resources {
+		if s.resourceMethodAllowed(resource) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
+
+func methodNotAllowedResult(toolName, method string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: %s: %v: %s", toolName, ErrMethodNotAllowed, method),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":    "method_not_allowed",
+			"toolName": toolName,
+			"method":   method,
+		},
+		IsError: true,
+	}
+}
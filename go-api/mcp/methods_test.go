@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAllowedMethodsGuardsDeleteResources(t *testing.T) {
+	t.Parallel()
+
+	var deletes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes.Add(1)
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	deleteResource := testResource(upstream.URL+"/weather/alerts", "DELETE", nil)
+	getResource := testResource(upstream.URL+"/weather", "GET", nil)
+	deleteName := toolNameFromResource(deleteResource.Resource, "DELETE")
+
+	search := func(s *Server) []string {
+		t.Helper()
+		_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+		if err != nil {
+			t.Fatalf("SearchResources error: %v", err)
+		}
+		names := make([]string, 0, len(output.Tools))
+		for _, tool := range output.Tools {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+
+	t.Run("default blocks DELETE", func(t *testing.T) {
+		s := &Server{resources: []X402DiscoveryResource{deleteResource, getResource}}
+		for _, name := range search(s) {
+			if name == deleteName {
+				t.Fatalf("expected %s to be hidden from search", deleteName)
+			}
+		}
+
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: deleteName})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		structured, _ := result.StructuredContent.(map[string]any)
+		if !result.IsError || structured["error"] != "method_not_allowed" || structured["method"] != http.MethodDelete {
+			t.Fatalf("expected method_not_allowed for DELETE, got %+v", result)
+		}
+		if deletes.Load() != 0 {
+			t.Fatalf("expected no DELETE to reach the upstream")
+		}
+	})
+
+	t.Run("explicitly allowed DELETE", func(t *testing.T) {
+		s := &Server{resources: []X402DiscoveryResource{deleteResource, getResource}}
+		WithAllowedMethods("get", "post", "delete")(s)
+		found := false
+		for _, name := range search(s) {
+			found = found || name == deleteName
+		}
+		if !found {
+			t.Fatalf("expected %s to be listed once DELETE is allowed", deleteName)
+		}
+
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: deleteName})
+		if err != nil || result.IsError {
+			t.Fatalf("expected DELETE call to succeed, got %v %+v", err, result)
+		}
+		if deletes.Load() != 1 {
+			t.Fatalf("expected one DELETE upstream, got %d", deletes.Load())
+		}
+	})
+}
+
+func TestAllowedMethodsChecksEffectiveMethod(t *testing.T) {
+	t.Parallel()
+
+	// A GET-only allowlist must still catch a GET resource sent as POST
+	// because a body was supplied.
+	resource := testResource("http://203.0.113.10/weather", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	WithAllowedMethods(http.MethodGet)(s)
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:   toolNameFromResource(resource.Resource, "GET"),
+		Parameters: map[string]any{"body": map[string]any{"city": "Paris"}},
+		DryRun:     true,
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if structured["error"] != "method_not_allowed" {
+		t.Fatalf("expected method_not_allowed for the POST fallback, got %+v", result)
+	}
+}
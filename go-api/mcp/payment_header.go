@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"slices"
+	"strings"
+)
+
+// WithPaymentHeaderName sends payments in header name for every resource,
+// e.g. "X-PAYMENT" for upstreams that only read the v1 header whatever the
+// payment version. By default the name follows the payment's x402 version.
+func WithPaymentHeaderName(name string) ServerOption {
+	return func(s *Server) {
+		s.paymentHeaderName = strings.TrimSpace(name)
+	}
+}
+
+// WithResourcePaymentHeaderName sends payments in header name for the
+// resource at resourceURL, overriding WithPaymentHeaderName.
+func WithResourcePaymentHeaderName(resourceURL, name string) ServerOption {
+	return func(s *Server) {
+		if s.resourcePaymentHeaderNames == nil {
+			s.resourcePaymentHeaderNames = make(map[string]string)
+		}
+		s.resourcePaymentHeaderNames[resourceURL] = strings.TrimSpace(name)
+	}
+}
+
+// paymentHeaderNameFor returns the configured payment header for resource, or
+// "" to pick it from the payment's version.
+func (s *Server) paymentHeaderNameFor(resource X402DiscoveryResource) string {
+	if name := s.resourcePaymentHeaderNames[resource.Resource]; name != "" {
+		return name
+	}
+	return s.paymentHeaderName
+}
+
+// paymentHeaderNames lists the payment headers configured with
+// WithPaymentHeaderName or WithResourcePaymentHeaderName, so they are
+// redacted like the standard ones.
+func (s *Server) paymentHeaderNames() []string {
+	var names []string
+	if s.paymentHeaderName != "" {
+		names = append(names, s.paymentHeaderName)
+	}
+	for _, name := range s.resourcePaymentHeaderNames {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
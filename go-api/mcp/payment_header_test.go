@@ -0,0 +1,104 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestInjectPaymentSignatureHeaderNameOverride(t *testing.T) {
+	t.Parallel()
+
+	v2 := map[string]any{
+		"x402Version": 2,
+		"resource":    map[string]any{"url": "mcp://tool/financial_analysis"},
+		"accepted":    map[string]any{"scheme": "exact", "network": "eip155:84532"},
+		"payload":     map[string]any{"signature": "0xdeadbeef"},
+	}
+	v1 := map[string]any{
+		"x402Version": 1,
+		"scheme":      "exact",
+		"network":     "base-sepolia",
+		"payload":     map[string]any{"signature": "0xdeadbeef"},
+	}
+	tests := []struct {
+		name        string
+		payment     map[string]any
+		headerName  string
+		wantHeader  string
+		otherHeader string
+		wantVersion float64
+	}{
+		{name: "v2 forced to X-PAYMENT", payment: v2, headerName: "X-PAYMENT", wantHeader: "X-PAYMENT", otherHeader: "PAYMENT-SIGNATURE", wantVersion: 2},
+		{name: "v1 forced to PAYMENT-SIGNATURE", payment: v1, headerName: "PAYMENT-SIGNATURE", wantHeader: "PAYMENT-SIGNATURE", otherHeader: "X-PAYMENT", wantVersion: 1},
+		{name: "v2 default", payment: v2, wantHeader: "PAYMENT-SIGNATURE", otherHeader: "X-PAYMENT", wantVersion: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			params, err := injectPaymentSignature(nil, tt.payment, PaymentHeaderOverride, tt.headerName)
+			if err != nil {
+				t.Fatalf("injectPaymentSignature error: %v", err)
+			}
+			if headers := params["headers"].(map[string]any); headers[tt.otherHeader] != nil {
+				t.Fatalf("expected only %s, got %v", tt.wantHeader, headers)
+			}
+			headerPayload := decodeInjectedHeader(t, params, tt.wantHeader)
+			if headerPayload["x402Version"] != tt.wantVersion {
+				t.Fatalf("expected x402Version %v, got %v", tt.wantVersion, headerPayload["x402Version"])
+			}
+		})
+	}
+}
+
+func TestProxyToolCallPaymentHeaderName(t *testing.T) {
+	t.Parallel()
+
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Header.Clone())
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	weather := testResource(upstream.URL+"/weather", "GET", nil)
+	alerts := testResource(upstream.URL+"/weather/alerts", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{weather, alerts}}
+	WithPaymentHeaderName("X-PAYMENT")(s)
+	WithResourcePaymentHeaderName(alerts.Resource, "PAYMENT-SIGNATURE")(s)
+
+	req := &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{
+		Meta: sdkmcp.Meta{
+			"x402/payment": map[string]any{
+				"x402Version": 2,
+				"resource":    map[string]any{"url": weather.Resource},
+				"accepted":    map[string]any{"scheme": "exact", "network": "eip155:84532"},
+				"payload":     map[string]any{"signature": "0xdeadbeef"},
+			},
+		},
+	}}
+	tests := []struct {
+		resource    X402DiscoveryResource
+		wantHeader  string
+		otherHeader string
+	}{
+		{resource: weather, wantHeader: "X-PAYMENT", otherHeader: "PAYMENT-SIGNATURE"},
+		{resource: alerts, wantHeader: "PAYMENT-SIGNATURE", otherHeader: "X-PAYMENT"},
+	}
+	for _, tt := range tests {
+		result, _, err := s.ProxyToolCall(context.Background(), req, &ProxyToolCallParams{
+			ToolName: toolNameFromResource(tt.resource.Resource, "GET"),
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("ProxyToolCall failed: %v %+v", err, result)
+		}
+		headers, _ := seen.Load().(http.Header)
+		if headers.Get(tt.wantHeader) == "" || headers.Get(tt.otherHeader) != "" {
+			t.Fatalf("%s: expected payment in %s only, got %v", tt.resource.Resource, tt.wantHeader, headers)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package mcp
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
 
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -26,7 +29,7 @@ func TestInjectPaymentSignature(t *testing.T) {
 		"payload": map[string]any{
 			"signature": "0xdeadbeef",
 		},
-	})
+	}, PaymentHeaderOverride, "")
 	if err != nil {
 		t.Fatalf("injectPaymentSignature error: %v", err)
 	}
@@ -74,7 +77,7 @@ func TestInjectPaymentSignatureV1UsesXPayment(t *testing.T) {
 		"payload": map[string]any{
 			"signature": "0xdeadbeef",
 		},
-	})
+	}, PaymentHeaderOverride, "")
 	if err != nil {
 		t.Fatalf("injectPaymentSignature error: %v", err)
 	}
@@ -127,7 +130,7 @@ func TestHTTPResponseToMCPResultAddsPaymentMeta(t *testing.T) {
 		Body: io.NopCloser(strings.NewReader(`{"ok":true}`)),
 	}
 
-	result, err := httpResponseToMCPResult(resp)
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
 	if err != nil {
 		t.Fatalf("httpResponseToMCPResult error: %v", err)
 	}
@@ -139,6 +142,85 @@ func TestHTTPResponseToMCPResultAddsPaymentMeta(t *testing.T) {
 	}
 }
 
+func TestHTTPResponseToMCPResultKeepsEveryPaymentResponse(t *testing.T) {
+	t.Parallel()
+
+	encode := func(tx string) string {
+		payload, err := json.Marshal(map[string]any{"success": true, "transaction": tx})
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(payload)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   []string
+	}{
+		{
+			name:   "single",
+			header: http.Header{"Payment-Response": {encode("0x1")}},
+			want:   []string{"0x1"},
+		},
+		{
+			name:   "repeated header",
+			header: http.Header{"Payment-Response": {encode("0x1"), encode("0x2")}},
+			want:   []string{"0x1", "0x2"},
+		},
+		{
+			name:   "folded header",
+			header: http.Header{"Payment-Response": {encode("0x1") + ", " + encode("0x2")}},
+			want:   []string{"0x1", "0x2"},
+		},
+		{
+			name: "v2 header wins over v1",
+			header: http.Header{
+				"Payment-Response":   {encode("0x1")},
+				"X-Payment-Response": {encode("0xv1")},
+			},
+			want: []string{"0x1"},
+		},
+		{
+			name:   "v1 fallback",
+			header: http.Header{"X-Payment-Response": {encode("0xv1a"), encode("0xv1b")}},
+			want:   []string{"0xv1a", "0xv1b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     tt.header,
+				Body:       io.NopCloser(strings.NewReader(`{"ok":true}`)),
+			}
+			result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+			if err != nil {
+				t.Fatalf("httpResponseToMCPResult error: %v", err)
+			}
+
+			var got []string
+			switch meta := result.Meta["x402/payment-response"].(type) {
+			case map[string]any:
+				got = append(got, fmt.Sprint(meta["transaction"]))
+			case []map[string]any:
+				if len(meta) < 2 {
+					t.Fatalf("expected a list only for several settlements, got %d", len(meta))
+				}
+				for _, paymentResponse := range meta {
+					got = append(got, fmt.Sprint(paymentResponse["transaction"]))
+				}
+			default:
+				t.Fatalf("unexpected x402/payment-response meta %T", meta)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Fatalf("expected settlements %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestHTTPResponseToMCPResultPaymentRequired(t *testing.T) {
 	t.Parallel()
 
@@ -178,7 +260,7 @@ func TestHTTPResponseToMCPResultPaymentRequired(t *testing.T) {
 		Body: io.NopCloser(strings.NewReader(`{"error":"payment required"}`)),
 	}
 
-	result, err := httpResponseToMCPResult(resp)
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
 	if err != nil {
 		t.Fatalf("httpResponseToMCPResult error: %v", err)
 	}
@@ -246,7 +328,7 @@ func TestHTTPResponseToMCPResultPaymentRequiredV1Body(t *testing.T) {
 		Body:       io.NopCloser(strings.NewReader(string(payload))),
 	}
 
-	result, err := httpResponseToMCPResult(resp)
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
 	if err != nil {
 		t.Fatalf("httpResponseToMCPResult error: %v", err)
 	}
@@ -285,7 +367,7 @@ func TestHTTPResponseToMCPResultAddsPaymentMetaV1Header(t *testing.T) {
 		Body: io.NopCloser(strings.NewReader(`{"ok":true}`)),
 	}
 
-	result, err := httpResponseToMCPResult(resp)
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
 	if err != nil {
 		t.Fatalf("httpResponseToMCPResult error: %v", err)
 	}
@@ -296,3 +378,338 @@ func TestHTTPResponseToMCPResultAddsPaymentMetaV1Header(t *testing.T) {
 		t.Fatalf("expected x402/payment-response in meta")
 	}
 }
+
+func TestDecodePaymentHeaderEncodings(t *testing.T) {
+	t.Parallel()
+
+	// "???>>>" encodes to characters that differ between std and url alphabets
+	payload := []byte(`{"success":true,"note":"???>>>"}`)
+	if base64.StdEncoding.EncodeToString(payload) == base64.URLEncoding.EncodeToString(payload) {
+		t.Fatalf("test payload must exercise the url-safe alphabet")
+	}
+
+	for name, encoding := range map[string]*base64.Encoding{
+		"std":     base64.StdEncoding,
+		"raw-std": base64.RawStdEncoding,
+		"url":     base64.URLEncoding,
+		"raw-url": base64.RawURLEncoding,
+	} {
+		t.Run(name, func(t *testing.T) {
+			decoded := decodePaymentHeader(encoding.EncodeToString(payload))
+			if decoded == nil || decoded["note"] != "???>>>" || decoded["success"] != true {
+				t.Fatalf("expected payload to decode, got %v", decoded)
+			}
+		})
+	}
+}
+
+func TestDecodePaymentHeaderGarbage(t *testing.T) {
+	t.Parallel()
+
+	for _, raw := range []string{"not base64 at all!", base64.StdEncoding.EncodeToString([]byte("not json"))} {
+		if decoded := decodePaymentHeader(raw); decoded != nil {
+			t.Fatalf("expected nil for %q, got %v", raw, decoded)
+		}
+	}
+}
+
+func TestHTTPResponseToMCPResultPaymentRequiredV2Body(t *testing.T) {
+	t.Parallel()
+
+	paymentRequired := func(message string) []byte {
+		payload, err := json.Marshal(map[string]any{
+			"x402Version": 2,
+			"error":       message,
+			"resource": map[string]any{
+				"url":      "https://api.example.com/premium-data",
+				"mimeType": "application/json",
+			},
+			"accepts": []any{
+				map[string]any{
+					"scheme":            "exact",
+					"network":           "eip155:84532",
+					"amount":            "10000",
+					"asset":             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+					"payTo":             "0x209693Bc6afc0C5328bA36FaF03C514EF312287C",
+					"maxTimeoutSeconds": 60,
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return payload
+	}
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		wantErr string
+	}{
+		{
+			name:    "body only",
+			header:  http.Header{"Content-Type": []string{"application/json"}},
+			wantErr: "from body",
+		},
+		{
+			name: "header takes precedence",
+			header: http.Header{
+				"Content-Type":     []string{"application/json"},
+				"Payment-Required": []string{base64.StdEncoding.EncodeToString(paymentRequired("from header"))},
+			},
+			wantErr: "from header",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusPaymentRequired,
+				Header:     tc.header,
+				Body:       io.NopCloser(strings.NewReader(string(paymentRequired("from body")))),
+			}
+
+			result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+			if err != nil {
+				t.Fatalf("httpResponseToMCPResult error: %v", err)
+			}
+			structured, ok := result.StructuredContent.(map[string]any)
+			if !ok {
+				t.Fatalf("expected structuredContent to be map, got %T", result.StructuredContent)
+			}
+			if structured["x402Version"] != float64(2) {
+				t.Fatalf("expected x402Version 2, got %v", structured["x402Version"])
+			}
+			if structured["error"] != tc.wantErr {
+				t.Fatalf("expected error %q, got %v", tc.wantErr, structured["error"])
+			}
+		})
+	}
+}
+
+func decodeInjectedHeader(t *testing.T, params map[string]any, name string) map[string]any {
+	t.Helper()
+	headers, ok := params["headers"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected headers object, got %T", params["headers"])
+	}
+	rawHeader, ok := headers[name].(string)
+	if !ok || rawHeader == "" {
+		t.Fatalf("expected %s to be set", name)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(rawHeader)
+	if err != nil {
+		t.Fatalf("decode %s header: %v", name, err)
+	}
+	var headerPayload map[string]any
+	if err := json.Unmarshal(decoded, &headerPayload); err != nil {
+		t.Fatalf("unmarshal %s payload: %v", name, err)
+	}
+	return headerPayload
+}
+
+func TestBuildPaymentMetaV2(t *testing.T) {
+	t.Parallel()
+
+	meta, err := BuildPaymentMeta(2,
+		map[string]any{"url": "mcp://tool/financial_analysis"},
+		map[string]any{"scheme": "exact", "network": "eip155:84532"},
+		map[string]any{"signature": "0xdeadbeef"},
+	)
+	if err != nil {
+		t.Fatalf("BuildPaymentMeta error: %v", err)
+	}
+
+	params, err := injectPaymentSignature(nil, meta["x402/payment"], PaymentHeaderOverride, "")
+	if err != nil {
+		t.Fatalf("injectPaymentSignature error: %v", err)
+	}
+	headerPayload := decodeInjectedHeader(t, params, "PAYMENT-SIGNATURE")
+	if headerPayload["x402Version"] != float64(2) {
+		t.Fatalf("expected x402Version to be 2, got %v", headerPayload["x402Version"])
+	}
+	resource, ok := headerPayload["resource"].(map[string]any)
+	if !ok || resource["url"] != "mcp://tool/financial_analysis" {
+		t.Fatalf("expected resource url to be set")
+	}
+	accepted, ok := headerPayload["accepted"].(map[string]any)
+	if !ok || accepted["scheme"] != "exact" || accepted["network"] != "eip155:84532" {
+		t.Fatalf("expected accepted scheme/network to be set")
+	}
+	payload, ok := headerPayload["payload"].(map[string]any)
+	if !ok || payload["signature"] != "0xdeadbeef" {
+		t.Fatalf("expected payload signature to be set")
+	}
+}
+
+func TestBuildPaymentMetaV1UsesXPayment(t *testing.T) {
+	t.Parallel()
+
+	meta, err := BuildPaymentMeta(1, nil,
+		map[string]any{"scheme": "exact", "network": "base-sepolia"},
+		map[string]any{"signature": "0xdeadbeef"},
+	)
+	if err != nil {
+		t.Fatalf("BuildPaymentMeta error: %v", err)
+	}
+
+	params, err := injectPaymentSignature(nil, meta["x402/payment"], PaymentHeaderOverride, "")
+	if err != nil {
+		t.Fatalf("injectPaymentSignature error: %v", err)
+	}
+	headerPayload := decodeInjectedHeader(t, params, "X-PAYMENT")
+	if headerPayload["x402Version"] != float64(1) {
+		t.Fatalf("expected x402Version to be 1, got %v", headerPayload["x402Version"])
+	}
+	if headerPayload["scheme"] != "exact" || headerPayload["network"] != "base-sepolia" {
+		t.Fatalf("expected scheme/network to be set")
+	}
+	if _, ok := headerPayload["accepted"]; ok {
+		t.Fatalf("expected v1 payment to be flattened, got accepted")
+	}
+}
+
+func TestBuildPaymentMetaRejectsIncompletePayments(t *testing.T) {
+	t.Parallel()
+
+	accepted := map[string]any{"scheme": "exact", "network": "eip155:84532"}
+	resource := map[string]any{"url": "https://api.example.com/weather"}
+	payload := map[string]any{"signature": "0xdeadbeef"}
+
+	tests := []struct {
+		name     string
+		version  int
+		resource map[string]any
+		accepted map[string]any
+		payload  map[string]any
+	}{
+		{name: "v2 missing resource", version: 2, accepted: accepted, payload: payload},
+		{name: "v2 missing accepted", version: 2, resource: resource, payload: payload},
+		{name: "v1 missing network", version: 1, accepted: map[string]any{"scheme": "exact"}, payload: payload},
+		{name: "missing payload", version: 2, resource: resource, accepted: accepted},
+		{name: "unknown version", version: 3, resource: resource, accepted: accepted, payload: payload},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := BuildPaymentMeta(tc.version, tc.resource, tc.accepted, tc.payload); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestInjectPaymentSignatureHeaderConflict(t *testing.T) {
+	t.Parallel()
+
+	newPayment := func() map[string]any {
+		return map[string]any{
+			"x402Version": 2,
+			"resource":    map[string]any{"url": "https://api.example.com/weather"},
+			"accepted":    map[string]any{"scheme": "exact", "network": "eip155:84532"},
+			"payload":     map[string]any{"signature": "0xdeadbeef"},
+		}
+	}
+	newParams := func() map[string]any {
+		return map[string]any{
+			"headers": map[string]any{"payment-signature": "stale"},
+		}
+	}
+
+	t.Run("override", func(t *testing.T) {
+		params, err := injectPaymentSignature(newParams(), newPayment(), PaymentHeaderOverride, "")
+		if err != nil {
+			t.Fatalf("injectPaymentSignature error: %v", err)
+		}
+		headers := params["headers"].(map[string]any)
+		if _, ok := headers["payment-signature"]; ok {
+			t.Fatalf("expected stale caller header to be removed, got %v", headers)
+		}
+		headerPayload := decodeInjectedHeader(t, params, "PAYMENT-SIGNATURE")
+		payload := headerPayload["payload"].(map[string]any)
+		if payload["signature"] != "0xdeadbeef" {
+			t.Fatalf("expected meta payment to win, got %v", headerPayload)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := injectPaymentSignature(newParams(), newPayment(), PaymentHeaderReject, "")
+		if err == nil || !strings.Contains(err.Error(), "PAYMENT-SIGNATURE") {
+			t.Fatalf("expected conflict error naming the header, got %v", err)
+		}
+	})
+}
+
+func TestInjectPaymentSignatureRejectsNonObjectPayment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		payment  any
+		wantKind string
+	}{
+		{name: "string", payment: "0xdeadbeef", wantKind: "string"},
+		{name: "number", payment: 10000, wantKind: "number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := injectPaymentSignature(nil, tt.payment, PaymentHeaderOverride, "")
+			if !errors.Is(err, x402local.ErrInvalidPaymentMeta) {
+				t.Fatalf("expected ErrInvalidPaymentMeta, got %v", err)
+			}
+			if want := "x402/payment must be a JSON object, got " + tt.wantKind; err.Error() != want {
+				t.Fatalf("expected %q, got %q", want, err.Error())
+			}
+		})
+	}
+
+	t.Run("object", func(t *testing.T) {
+		t.Parallel()
+		params, err := injectPaymentSignature(nil, json.RawMessage(`{"x402Version":2,"resource":{"url":"mcp://tool/financial_analysis"},"accepted":{"scheme":"exact","network":"eip155:84532"},"payload":{"signature":"0xdeadbeef"}}`), PaymentHeaderOverride, "")
+		if err != nil {
+			t.Fatalf("injectPaymentSignature error: %v", err)
+		}
+		decodeInjectedHeader(t, params, "PAYMENT-SIGNATURE")
+	})
+}
+
+func TestPaymentHeadersReadInAnyCasing(t *testing.T) {
+	t.Parallel()
+
+	encode := func(value map[string]any) string {
+		payload, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.StdEncoding.EncodeToString(payload)
+	}
+	settlement := encode(map[string]any{"success": true, "transaction": "0x1"})
+	required := encode(map[string]any{"x402Version": 2, "accepts": []any{}})
+
+	for _, casing := range []func(string) string{strings.ToUpper, strings.ToLower, http.CanonicalHeaderKey} {
+		responseKey := casing(x402local.HeaderPaymentResponse)
+		t.Run(responseKey, func(t *testing.T) {
+			t.Parallel()
+			header := http.Header{
+				responseKey:                              {settlement},
+				casing(x402local.HeaderPaymentRequired):  {required},
+				casing(x402local.HeaderPaymentSignature): {"signed"},
+				casing(x402local.HeaderXPaymentResponse): {settlement},
+			}
+			resp := &http.Response{StatusCode: http.StatusOK, Header: header}
+
+			if decoded := decodePaymentResponses(resp); len(decoded) != 1 || decoded[0]["transaction"] != "0x1" {
+				t.Fatalf("expected the settlement from %s, got %v", responseKey, decoded)
+			}
+			if decodePaymentRequired(resp, nil) == nil {
+				t.Fatalf("expected payment requirements from %s", casing(x402local.HeaderPaymentRequired))
+			}
+			redacted := RedactHeaders(header, DefaultRedactedHeaders)
+			for key, values := range redacted {
+				if key != casing(x402local.HeaderPaymentRequired) && values[0] != redactedHeaderValue {
+					t.Fatalf("expected %s to be redacted, got %q", key, values[0])
+				}
+			}
+		})
+	}
+}
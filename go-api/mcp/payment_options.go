@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// PaymentOptionPolicy picks the payment option the server recommends for a
+// resource, so agents without their own preference get a sensible default.
+// Every option stays listed either way.
+type PaymentOptionPolicy interface {
+	// Recommend returns the index in options to recommend for resource, or -1
+	// to recommend none. options holds only the options the price caps allow.
+	Recommend(resource X402DiscoveryResource, options []X402PaymentRequirements) int
+}
+
+// PaymentOptionPolicyFunc adapts a function to PaymentOptionPolicy.
+type PaymentOptionPolicyFunc func(resource X402DiscoveryResource, options []X402PaymentRequirements) int
+
+// Recommend implements PaymentOptionPolicy.
+func (f PaymentOptionPolicyFunc) Recommend(resource X402DiscoveryResource, options []X402PaymentRequirements) int {
+	return f(resource, options)
+}
+
+// CheapestPaymentOption recommends the option with the lowest amount. Amounts
+// are compared in whole units when the asset's decimals are known and in
+// smallest units otherwise; the two scales are never compared, so once any
+// option's decimals are known the options with unknown decimals are skipped.
+// Ties go to the first listed option. Assets added with WithAssets count as
+// known.
+type CheapestPaymentOption struct {
+	assets assetRegistry
+}
+
+// Recommend implements PaymentOptionPolicy.
+func (p CheapestPaymentOption) Recommend(_ X402DiscoveryResource, options []X402PaymentRequirements) int {
+	prices := make([]*big.Rat, len(options))
+	known := make([]bool, len(options))
+	anyKnown := false
+	for idx, option := range options {
+		prices[idx], known[idx] = optionPrice(option, p.assets)
+		anyKnown = anyKnown || (prices[idx] != nil && known[idx])
+	}
+
+	best := -1
+	for idx, price := range prices {
+		if price == nil || known[idx] != anyKnown {
+			continue
+		}
+		if best < 0 || price.Cmp(prices[best]) < 0 {
+			best = idx
+		}
+	}
+	return best
+}
+
+// optionPrice returns option's amount, or nil when it is not a valid amount.
+// known reports whether the amount was scaled to whole units using the
+// asset's decimals.
+func optionPrice(option X402PaymentRequirements, assets assetRegistry) (price *big.Rat, known bool) {
+	amount, ok := new(big.Int).SetString(strings.TrimSpace(option.MaxAmountRequired), 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, false
+	}
+	price = new(big.Rat).SetInt(amount)
+	if asset, ok := assets.lookup(option); ok && asset.Decimals > 0 {
+		scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(asset.Decimals)), nil)
+		price.Quo(price, new(big.Rat).SetInt(scale))
+		return price, true
+	}
+	return price, false
+}
+
+// withAssets implements assetAwarePolicy.
+func (p CheapestPaymentOption) withAssets(assets assetRegistry) PaymentOptionPolicy {
+	p.assets = assets
+	return p
+}
+
+// assetAwarePolicy is implemented by policies that need the server's assets
+// to compare options.
+type assetAwarePolicy interface {
+	withAssets(assets assetRegistry) PaymentOptionPolicy
+}
+
+// PreferredNetworkPaymentOption recommends the first option on the earliest
+// listed network, e.g. networks ordered from lowest to highest settlement
+// latency. Networks match the ids used in discovery, in any casing. It
+// recommends nothing when no option is on a listed network.
+type PreferredNetworkPaymentOption []string
+
+// Recommend implements PaymentOptionPolicy.
+func (networks PreferredNetworkPaymentOption) Recommend(_ X402DiscoveryResource, options []X402PaymentRequirements) int {
+	for _, network := range networks {
+		for idx, option := range options {
+			if strings.EqualFold(option.Network, network) {
+				return idx
+			}
+		}
+	}
+	return -1
+}
+
+// RoundRobinPaymentOption rotates the recommendation through each resource's
+// options, one step per listing, to spread payments across them. Create it
+// with NewRoundRobinPaymentOption.
+type RoundRobinPaymentOption struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// NewRoundRobinPaymentOption returns a RoundRobinPaymentOption starting at
+// each resource's first option.
+func NewRoundRobinPaymentOption() *RoundRobinPaymentOption {
+	return &RoundRobinPaymentOption{next: make(map[string]int)}
+}
+
+// Recommend implements PaymentOptionPolicy.
+func (p *RoundRobinPaymentOption) Recommend(resource X402DiscoveryResource, options []X402PaymentRequirements) int {
+	if len(options) == 0 {
+		return -1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := resource.Resource
+	idx := p.next[key] % len(options)
+	p.next[key] = idx + 1
+	return idx
+}
+
+// WithPaymentOptionPolicy recommends one of each tool's payment options with
+// policy. The recommendation is marked in _meta["x402/payment-required"] as
+// recommendedIndex and as recommended on the option itself. A nil policy
+// recommends nothing.
+func WithPaymentOptionPolicy(policy PaymentOptionPolicy) ServerOption {
+	return func(s *Server) {
+		s.paymentOptionPolicy = policy
+	}
+}
+
+// markRecommendedOption marks the option chosen by the server's policy in a
+// tool's pricing meta. Options over the price caps are never recommended.
+func (s *Server) markRecommendedOption(resource X402DiscoveryResource, meta map[string]any) {
+	if s.paymentOptionPolicy == nil || resource.Accepts == nil {
+		return
+	}
+	pricing, ok := meta["x402/payment-required"].(map[string]any)
+	if !ok {
+		return
+	}
+	accepts, ok := pricing["accepts"].([]map[string]any)
+	if !ok || len(accepts) != len(*resource.Accepts) {
+		return
+	}
+
+	var candidates []X402PaymentRequirements
+	var positions []int
+	for idx, option := range *resource.Accepts {
+		if s.requirementWithinCap(option) {
+			candidates = append(candidates, option)
+			positions = append(positions, idx)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	policy := s.paymentOptionPolicy
+	if aware, ok := policy.(assetAwarePolicy); ok {
+		policy = aware.withAssets(s.assets)
+	}
+	choice := policy.Recommend(resource, candidates)
+	if choice < 0 || choice >= len(candidates) {
+		return
+	}
+	recommended := positions[choice]
+	pricing["recommendedIndex"] = recommended
+	accepts[recommended]["recommended"] = true
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// multiOptionResource accepts the same USDC amount on Base and Base Sepolia,
+// and a cheaper one on Solana devnet.
+func multiOptionResource() X402DiscoveryResource {
+	resource := testResource("http://localhost:8080/weather", "GET", nil)
+	base := (*resource.Accepts)[0]
+	mainnet := base
+	mainnet.Network = "base"
+	mainnet.Asset = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	solana := base
+	solana.Network = "solana-devnet"
+	solana.Asset = "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"
+	solana.MaxAmountRequired = "5000"
+	accepts := []X402PaymentRequirements{base, mainnet, solana}
+	resource.Accepts = &accepts
+	return resource
+}
+
+// recommendedOption returns the recommendedIndex in tool's pricing meta and
+// checks that exactly that option is flagged, with every option kept.
+func recommendedOption(t *testing.T, s *Server, resource X402DiscoveryResource) int {
+	t.Helper()
+	tool := s.resourceTool(resource)
+	pricing := tool.Meta["x402/payment-required"].(map[string]any)
+	accepts := pricing["accepts"].([]map[string]any)
+	if len(accepts) != len(*resource.Accepts) {
+		t.Fatalf("expected all %d options listed, got %d", len(*resource.Accepts), len(accepts))
+	}
+	idx, ok := pricing["recommendedIndex"].(int)
+	if !ok {
+		t.Fatalf("expected a recommendedIndex, got %v", pricing["recommendedIndex"])
+	}
+	for i, option := range accepts {
+		if _, flagged := option["recommended"]; flagged != (i == idx) {
+			t.Fatalf("option %d: expected recommended %t, got %v", i, i == idx, option["recommended"])
+		}
+	}
+	return idx
+}
+
+func TestCheapestPaymentOption(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{paymentOptionPolicy: CheapestPaymentOption{}}
+	resource := multiOptionResource()
+	if idx := recommendedOption(t, s, resource); idx != 2 {
+		t.Fatalf("expected the cheaper Solana option, got %d", idx)
+	}
+
+	// Equal amounts go to the first listed option
+	(*resource.Accepts)[2].MaxAmountRequired = "10000"
+	if idx := recommendedOption(t, s, resource); idx != 0 {
+		t.Fatalf("expected the first of equally priced options, got %d", idx)
+	}
+
+	// Options over the price caps are never recommended
+	s.maxPriceByNetwork = parsePriceCaps(map[string]string{"base-sepolia": "1"}, strings.ToLower)
+	(*resource.Accepts)[0].MaxAmountRequired = "1000"
+	if idx := recommendedOption(t, s, resource); idx != 1 {
+		t.Fatalf("expected the capped option skipped, got %d", idx)
+	}
+}
+
+func TestCheapestPaymentOptionUsesServerAssets(t *testing.T) {
+	t.Parallel()
+
+	// 5000 of an 18-decimal token is far below 0.01 USDC, but only once its
+	// decimals are known
+	resource := multiOptionResource()
+	accepts := (*resource.Accepts)[:2]
+	accepts[0].Network, accepts[0].Asset, accepts[0].MaxAmountRequired = "eip155:1337", "0xabc0000000000000000000000000000000000001", "5000"
+	resource.Accepts = &accepts
+
+	if idx := recommendedOption(t, &Server{paymentOptionPolicy: CheapestPaymentOption{}}, resource); idx != 1 {
+		t.Fatalf("expected the unknown asset skipped beside a known one, got %d", idx)
+	}
+	s := &Server{paymentOptionPolicy: CheapestPaymentOption{}}
+	WithAssets(Asset{Network: "eip155:1337", Address: accepts[0].Asset, Info: AssetInfo{Symbol: "TEST", Decimals: 18}})(s)
+	if idx := recommendedOption(t, s, resource); idx != 0 {
+		t.Fatalf("expected the registered asset scaled by its decimals, got %d", idx)
+	}
+}
+
+func TestCheapestPaymentOptionMixedDecimals(t *testing.T) {
+	t.Parallel()
+
+	// 5 smallest units of an unknown token cannot be compared with 10 USDC, so
+	// only the options with known decimals are candidates
+	resource := multiOptionResource()
+	accepts := *resource.Accepts
+	accepts[0].MaxAmountRequired = "10000000"
+	accepts[1].MaxAmountRequired = "20000000"
+	accepts[2].Network, accepts[2].Asset, accepts[2].MaxAmountRequired = "eip155:1337", "0xabc0000000000000000000000000000000000001", "5"
+
+	s := &Server{paymentOptionPolicy: CheapestPaymentOption{}}
+	if idx := recommendedOption(t, s, resource); idx != 0 {
+		t.Fatalf("expected the cheapest option with known decimals, got %d", idx)
+	}
+
+	// Without any known decimals the raw amounts are compared
+	for i := range accepts {
+		accepts[i].Network = "eip155:1337"
+	}
+	if idx := recommendedOption(t, s, resource); idx != 2 {
+		t.Fatalf("expected the smallest raw amount, got %d", idx)
+	}
+}
+
+func TestRoundRobinPaymentOption(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{paymentOptionPolicy: NewRoundRobinPaymentOption()}
+	resource := multiOptionResource()
+	other := multiOptionResource()
+	other.Resource = "http://localhost:8080/forecast"
+
+	var got []int
+	for range 4 {
+		got = append(got, recommendedOption(t, s, resource))
+	}
+	if want := []int{0, 1, 2, 0}; !slices.Equal(got, want) {
+		t.Fatalf("expected rotation %v, got %v", want, got)
+	}
+	if idx := recommendedOption(t, s, other); idx != 0 {
+		t.Fatalf("expected each resource to rotate separately, got %d", idx)
+	}
+}
+
+func TestPreferredNetworkPaymentOption(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{paymentOptionPolicy: PreferredNetworkPaymentOption{"Base", "base-sepolia"}}
+	if idx := recommendedOption(t, s, multiOptionResource()); idx != 1 {
+		t.Fatalf("expected the Base option, got %d", idx)
+	}
+
+	s.paymentOptionPolicy = PreferredNetworkPaymentOption{"solana"}
+	tool := s.resourceTool(multiOptionResource())
+	if _, ok := tool.Meta["x402/payment-required"].(map[string]any)["recommendedIndex"]; ok {
+		t.Fatal("expected no recommendation without a preferred network")
+	}
+}
+
+func TestNoPaymentOptionPolicy(t *testing.T) {
+	t.Parallel()
+
+	tool := (&Server{}).resourceTool(multiOptionResource())
+	if _, ok := tool.Meta["x402/payment-required"].(map[string]any)["recommendedIndex"]; ok {
+		t.Fatal("expected no recommendation without a policy")
+	}
+}
@@ -0,0 +1,200 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrPriceExceedsCap is returned when every payment option of a tool costs
+// more than the configured price caps allow, or when the attached payment
+// pays for an option that does.
+var ErrPriceExceedsCap = errors.New("price exceeds configured cap")
+
+// WithMaxPriceByAsset caps the amount, in the asset's smallest units, the
+// server will let an agent pay per call for each asset address. Tools whose
+// every payment option is over the cap are hidden from search_resources and
+// rejected by proxy_tool_call. A cap that is not a non-negative integer
+// blocks its asset entirely.
+func WithMaxPriceByAsset(caps map[string]string) ServerOption {
+	return func(s *Server) {
+		s.maxPriceByAsset = parsePriceCaps(caps, assetCapKey)
+	}
+}
+
+// WithMaxPriceByNetwork caps the amount, in smallest units, of any asset paid
+// on each network, keyed by the network id used in discovery (for example
+// base-sepolia or eip155:84532). It combines with WithMaxPriceByAsset; both
+// caps must be met.
+func WithMaxPriceByNetwork(caps map[string]string) ServerOption {
+	return func(s *Server) {
+		s.maxPriceByNetwork = parsePriceCaps(caps, strings.ToLower)
+	}
+}
+
+func parsePriceCaps(caps map[string]string, key func(string) string) map[string]*big.Int {
+	parsed := make(map[string]*big.Int, len(caps))
+	for name, value := range caps {
+		limit, ok := new(big.Int).SetString(strings.TrimSpace(value), 10)
+		if !ok || limit.Sign() < 0 {
+			limit = new(big.Int)
+		}
+		parsed[key(name)] = limit
+	}
+	return parsed
+}
+
+// assetCapKey lowercases EVM addresses so caps match regardless of checksum
+// casing.
+func assetCapKey(address string) string {
+	if strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X") {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// withinPriceCap reports whether at least one of the resource's payment
+// options is allowed by the configured caps. Resources without payment
+// options, and servers without caps, always pass.
+func (s *Server) withinPriceCap(resource X402DiscoveryResource) bool {
+	if len(s.maxPriceByAsset) == 0 && len(s.maxPriceByNetwork) == 0 {
+		return true
+	}
+	if resource.Accepts == nil || len(*resource.Accepts) == 0 {
+		return true
+	}
+	for _, requirement := range *resource.Accepts {
+		if s.requirementWithinCap(requirement) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) requirementWithinCap(requirement X402PaymentRequirements) bool {
+	var limits []*big.Int
+	if limit, ok := s.maxPriceByAsset[assetCapKey(requirement.Asset)]; ok {
+		limits = append(limits, limit)
+	}
+	if limit, ok := s.maxPriceByNetwork[strings.ToLower(requirement.Network)]; ok {
+		limits = append(limits, limit)
+	}
+	if len(limits) == 0 {
+		return true
+	}
+	amount, ok := new(big.Int).SetString(requirement.MaxAmountRequired, 10)
+	if !ok {
+		// An unparseable price cannot be shown to be under the cap
+		return false
+	}
+	for _, limit := range limits {
+		if amount.Cmp(limit) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// paymentWithinCap reports whether the payment attached to a call pays for an
+// option the caps allow. The option is matched on the payment's scheme,
+// network and asset (v2 accepted, or the v1 top-level fields), and every
+// amount the payment declares is held to the same caps. A payment that
+// matches no option fails. A malformed payment passes here and is rejected
+// when the header is built.
+func (s *Server) paymentWithinCap(resource X402DiscoveryResource, payment any) bool {
+	if len(s.maxPriceByAsset) == 0 && len(s.maxPriceByNetwork) == 0 {
+		return true
+	}
+	paymentMap, err := x402local.PaymentMetaObject(payment)
+	if err != nil {
+		return true
+	}
+	paid := paymentMap
+	if accepted, ok := paymentMap["accepted"].(map[string]any); ok {
+		paid = accepted
+	}
+	scheme, _ := paid["scheme"].(string)
+	network, _ := paid["network"].(string)
+	asset, _ := paid["asset"].(string)
+	amounts := declaredPaymentAmounts(paymentMap, paid)
+
+	matched := false
+	if resource.Accepts != nil {
+		for _, requirement := range *resource.Accepts {
+			if (scheme != "" && !strings.EqualFold(requirement.Scheme, scheme)) ||
+				(network != "" && !sameNetwork(requirement.Network, network)) ||
+				(asset != "" && assetCapKey(requirement.Asset) != assetCapKey(asset)) {
+				continue
+			}
+			matched = true
+			if !s.requirementWithinCap(requirement) {
+				return false
+			}
+			for _, amount := range amounts {
+				requirement.MaxAmountRequired = amount
+				if !s.requirementWithinCap(requirement) {
+					return false
+				}
+			}
+		}
+	}
+	return matched
+}
+
+// declaredPaymentAmounts returns the amounts a payment names: the accepted
+// option's amount and the signed authorization's value.
+func declaredPaymentAmounts(payment, paid map[string]any) []string {
+	var amounts []string
+	for _, key := range []string{"amount", "maxAmountRequired"} {
+		if amount, ok := paid[key].(string); ok && amount != "" {
+			amounts = append(amounts, amount)
+		}
+	}
+	if payload, ok := payment["payload"].(map[string]any); ok {
+		if authorization, ok := payload["authorization"].(map[string]any); ok {
+			if value, ok := authorization["value"].(string); ok && value != "" {
+				amounts = append(amounts, value)
+			}
+		}
+	}
+	return amounts
+}
+
+// filterPriceCapped drops resources whose every payment option is over cap.
+func (s *Server) filterPriceCapped(resources []X402DiscoveryResource) []X402DiscoveryResource {
+	if len(s.maxPriceByAsset) == 0 && len(s.maxPriceByNetwork) == 0 {
+		return resources
+	}
+	kept := make([]X402DiscoveryResource, 0, len(resources))
+	for _, resource := range resources {
+		if s.withinPriceCap(resource) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
+
+func priceExceedsCapResult(toolName string, resource X402DiscoveryResource) *mcp.CallToolResult {
+	var prices []string
+	if resource.Accepts != nil {
+		for _, requirement := range *resource.Accepts {
+			prices = append(prices, fmt.Sprintf("%s %s on %s", requirement.MaxAmountRequired, requirement.Asset, requirement.Network))
+		}
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: %s: %v (offered: %s)", toolName, ErrPriceExceedsCap, strings.Join(prices, "; ")),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":    "price_exceeds_cap",
+			"toolName": toolName,
+		},
+		IsError: true,
+	}
+}
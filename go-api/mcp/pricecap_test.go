@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPriceCapHidesAndRejectsExpensiveTools(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	cheap := testResource(upstream.URL+"/weather", "GET", nil)
+	expensive := testResource(upstream.URL+"/weather-premium", "GET", nil)
+	(*expensive.Accepts)[0].MaxAmountRequired = "5000000"
+
+	s := &Server{resources: []X402DiscoveryResource{cheap, expensive}}
+	WithMaxPriceByAsset(map[string]string{
+		"0x036cbd53842c5426634e7929541ec2318f3dcf7e": "10000",
+	})(s)
+
+	_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	if len(output.Tools) != 1 || output.Tools[0].Name != toolNameFromResource(cheap.Resource, "GET") {
+		t.Fatalf("expected only the tool under the cap, got %d tools", len(output.Tools))
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(expensive.Resource, "GET"),
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if !result.IsError || structured["error"] != "price_exceeds_cap" {
+		t.Fatalf("expected price_exceeds_cap, got %+v", result)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("expected over-cap call not to reach upstream")
+	}
+
+	result, _, err = s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(cheap.Resource, "GET"),
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if result.IsError || hits.Load() != 1 {
+		t.Fatalf("expected under-cap call to be proxied, got %+v", result)
+	}
+}
+
+func TestPriceCapByNetwork(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/weather", "GET", nil)
+	s := &Server{}
+	WithMaxPriceByNetwork(map[string]string{"Base-Sepolia": "9999"})(s)
+	if s.withinPriceCap(resource) {
+		t.Fatal("expected 10000 to exceed a 9999 network cap")
+	}
+
+	WithMaxPriceByNetwork(map[string]string{"base-sepolia": "10000"})(s)
+	if !s.withinPriceCap(resource) {
+		t.Fatal("expected 10000 to be within a 10000 network cap")
+	}
+
+	WithMaxPriceByNetwork(map[string]string{"eip155:8453": "1"})(s)
+	if !s.withinPriceCap(resource) {
+		t.Fatal("expected a cap on another network not to apply")
+	}
+}
+
+func TestPriceCapChecksPaidOption(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	// The Base Sepolia option is under the cap, the Base one is not
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	expensive := (*resource.Accepts)[0]
+	expensive.Network = "base"
+	expensive.Asset = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	expensive.MaxAmountRequired = "5000000"
+	*resource.Accepts = append(*resource.Accepts, expensive)
+
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	WithMaxPriceByNetwork(map[string]string{"base-sepolia": "10000", "base": "10000"})(s)
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	call := func(payment map[string]any) *sdkmcp.CallToolResult {
+		t.Helper()
+		req := &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{Meta: sdkmcp.Meta{"x402/payment": payment}}}
+		result, _, err := s.ProxyToolCall(context.Background(), req, &ProxyToolCallParams{ToolName: toolName})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		return result
+	}
+	authorization := func(value string) map[string]any {
+		return map[string]any{"signature": "0xsig", "authorization": map[string]any{"from": "0xabc", "value": value}}
+	}
+	rejected := func(result *sdkmcp.CallToolResult) bool {
+		structured, _ := result.StructuredContent.(map[string]any)
+		return result.IsError && structured["error"] == "price_exceeds_cap"
+	}
+
+	cases := []struct {
+		name    string
+		payment map[string]any
+		reject  bool
+	}{
+		{
+			name:    "v1 payment for the capped network",
+			payment: map[string]any{"x402Version": 1, "scheme": "exact", "network": "base", "payload": authorization("5000000")},
+			reject:  true,
+		},
+		{
+			name: "v2 payment accepting the capped option",
+			payment: map[string]any{
+				"x402Version": 2,
+				"resource":    map[string]any{"url": resource.Resource},
+				"accepted":    map[string]any{"scheme": "exact", "network": "eip155:8453", "asset": expensive.Asset, "amount": "5000000"},
+				"payload":     authorization("5000000"),
+			},
+			reject: true,
+		},
+		{
+			name:    "authorization above the cap on an allowed option",
+			payment: map[string]any{"x402Version": 1, "scheme": "exact", "network": "base-sepolia", "payload": authorization("20000")},
+			reject:  true,
+		},
+		{
+			name:    "payment for no listed option",
+			payment: map[string]any{"x402Version": 1, "scheme": "exact", "network": "solana-devnet", "payload": authorization("1")},
+			reject:  true,
+		},
+		{
+			name:    "payment for the allowed option",
+			payment: map[string]any{"x402Version": 1, "scheme": "exact", "network": "base-sepolia", "payload": authorization("10000")},
+		},
+	}
+	for _, tc := range cases {
+		before := hits.Load()
+		result := call(tc.payment)
+		if rejected(result) != tc.reject {
+			t.Fatalf("%s: expected rejected=%t, got %+v", tc.name, tc.reject, result)
+		}
+		if sent := hits.Load() != before; sent == tc.reject {
+			t.Fatalf("%s: expected upstream reached=%t", tc.name, !tc.reject)
+		}
+	}
+}
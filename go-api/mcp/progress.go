@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// DefaultProgressInterval is how often proxy_tool_call reports progress
+	// while it waits on an upstream response.
+	DefaultProgressInterval = 2 * time.Second
+	// minProgressInterval bounds the notification rate whatever is configured.
+	minProgressInterval = 100 * time.Millisecond
+)
+
+// WithProgressInterval sets how often proxy_tool_call sends a "waiting on
+// upstream" progress notification to callers that passed a progress token.
+// Intervals below 100ms are raised to it; a non-positive interval disables
+// the notifications.
+func WithProgressInterval(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		if interval <= 0 {
+			s.progressInterval = -1
+			return
+		}
+		s.progressInterval = max(interval, minProgressInterval)
+	}
+}
+
+// upstreamWaitInterval returns the notification interval, or zero when
+// notifications are disabled.
+func (s *Server) upstreamWaitInterval() time.Duration {
+	switch {
+	case s.progressInterval < 0:
+		return 0
+	case s.progressInterval == 0:
+		return DefaultProgressInterval
+	}
+	return s.progressInterval
+}
+
+// notifyUpstreamWait reports the elapsed wait to the caller every interval
+// until the returned stop function is called. It is a no-op when the request
+// has no progress token. stop returns once no further notification can be sent.
+func (s *Server) notifyUpstreamWait(ctx context.Context, req *mcp.CallToolRequest, toolName string) (stop func()) {
+	interval := s.upstreamWaitInterval()
+	if interval == 0 || req == nil || req.Session == nil || req.Params == nil {
+		return func() {}
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return func() {}
+	}
+
+	started := time.Now()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				elapsed := time.Since(started)
+				// Notification failures must not abort the call
+				_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: token,
+					Progress:      elapsed.Seconds(),
+					Message:       fmt.Sprintf("waiting on upstream for %s: %s elapsed", toolName, elapsed.Round(minProgressInterval)),
+				})
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-finished
+	}
+}
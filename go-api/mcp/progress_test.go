@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestProxyToolCallReportsUpstreamWait(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(450 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	s := &Server{
+		mcpServer: sdkmcp.NewServer(&sdkmcp.Implementation{Name: "test", Version: "1.0.0"}, nil),
+		resources: []X402DiscoveryResource{resource},
+	}
+	WithProgressInterval(10 * time.Millisecond)(s)
+	s.registerTools()
+
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	var (
+		mu       sync.Mutex
+		messages []string
+	)
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, &sdkmcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *sdkmcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, req.Params.Message)
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	toolName := toolNameFromResource(resource.Resource, "GET")
+	call := func(meta sdkmcp.Meta) []string {
+		t.Helper()
+		mu.Lock()
+		messages = nil
+		mu.Unlock()
+		result, err := clientSession.CallTool(ctx, &sdkmcp.CallToolParams{
+			Meta:      meta,
+			Name:      "proxy_tool_call",
+			Arguments: map[string]any{"toolName": toolName},
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("CallTool failed: %v %+v", err, result)
+		}
+		// Let notifications sent before the result reach the handler
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), messages...)
+	}
+
+	withToken := call(sdkmcp.Meta{"progressToken": "wait-1"})
+	// 10ms is raised to the 100ms floor, so a 450ms wait yields about four
+	if len(withToken) < 2 || len(withToken) > 5 {
+		t.Fatalf("expected 2-5 rate-limited progress notifications, got %d: %v", len(withToken), withToken)
+	}
+	for _, message := range withToken {
+		if !strings.Contains(message, "waiting on upstream for "+toolName) {
+			t.Fatalf("unexpected progress message %q", message)
+		}
+	}
+
+	if withoutToken := call(nil); len(withoutToken) != 0 {
+		t.Fatalf("expected no progress notifications without a token, got %v", withoutToken)
+	}
+}
+
+func TestWithProgressInterval(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{}
+	if got := s.upstreamWaitInterval(); got != DefaultProgressInterval {
+		t.Fatalf("expected default interval, got %v", got)
+	}
+	WithProgressInterval(time.Millisecond)(s)
+	if got := s.upstreamWaitInterval(); got != minProgressInterval {
+		t.Fatalf("expected the interval raised to %v, got %v", minProgressInterval, got)
+	}
+	WithProgressInterval(0)(s)
+	if got := s.upstreamWaitInterval(); got != 0 {
+		t.Fatalf("expected notifications disabled, got %v", got)
+	}
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// PaymentWorkflowPrompt is the name of the prompt that walks an agent through
+// search_resources, building x402/payment meta and calling proxy_tool_call.
+const PaymentWorkflowPrompt = "x402_payment_workflow"
+
+func (s *Server) registerPrompts() {
+	s.mcpServer.AddPrompt(&mcp.Prompt{
+		Name:        PaymentWorkflowPrompt,
+		Title:       "Pay for and call an x402 tool",
+		Description: "Step-by-step guidance for discovering an x402 tool, attaching a payment in _meta and executing it with proxy_tool_call.",
+		Arguments: []*mcp.PromptArgument{
+			{
+				Name:        "toolName",
+				Description: "Tool name returned by search_resources.",
+				Required:    true,
+			},
+			{
+				Name:        "network",
+				Description: "Network to pay on, such as eip155:84532 or base-sepolia. Defaults to the first accepted network.",
+			},
+		},
+	}, s.paymentWorkflowPrompt)
+}
+
+func (s *Server) paymentWorkflowPrompt(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	toolName := strings.TrimSpace(req.Params.Arguments["toolName"])
+	if toolName == "" {
+		return nil, fmt.Errorf("prompt %s requires toolName", PaymentWorkflowPrompt)
+	}
+	network := strings.TrimSpace(req.Params.Arguments["network"])
+
+	version := 2
+	var requirement *X402PaymentRequirements
+	if resource, err := findResourceForToolName(s.resources, toolName, s.toolNamer()); err == nil {
+		version = resource.X402Version
+		requirement = requirementForNetwork(*resource, network)
+	}
+	if requirement != nil && network == "" {
+		network = requirement.Network
+	}
+	if network == "" {
+		network = "<network>"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "To call the x402 tool %q on %s:\n\n", toolName, network)
+	fmt.Fprintf(&b, "1. Call search_resources with searchQuery set to %q. Read the tool's _meta[\"x402/payment-required\"]. It lists the accepted scheme, network, asset, amount and payTo.\n", toolName)
+	if requirement != nil {
+		accepted, _ := json.MarshalIndent(requirement, "   ", "  ")
+		fmt.Fprintf(&b, "   The requirement for %s is:\n   %s\n", network, accepted)
+	} else {
+		fmt.Fprintf(&b, "   No discovered requirement matches %s; pick one of the accepted networks instead.\n", network)
+	}
+	b.WriteString("2. Sign a payment payload for that requirement with your wallet. Do not reuse a payload across calls.\n")
+	b.WriteString("3. Call proxy_tool_call with the toolName, the tool's parameters and the payment in _meta[\"x402/payment\"]. ")
+	if version == 1 {
+		b.WriteString("This is an x402 v1 resource, so the payment carries scheme and network at the top level:\n")
+		b.WriteString(`   {"x402/payment": {"x402Version": 1, "scheme": "exact", "network": "` + network + `", "payload": {...}}}` + "\n")
+	} else {
+		b.WriteString("This is an x402 v2 resource, so the payment carries the resource and the accepted requirement:\n")
+		b.WriteString(`   {"x402/payment": {"x402Version": 2, "resource": {"url": "..."}, "accepted": {...}, "payload": {...}}}` + "\n")
+	}
+	b.WriteString("   Do not also set PAYMENT-SIGNATURE or X-PAYMENT in parameters.headers; the server derives the header from _meta.\n")
+	b.WriteString("4. An error result whose structuredContent lists payment requirements means the upstream answered 402 and the payment was rejected. Sign a new payload against those requirements and retry. A successful result carries the settlement in _meta[\"x402/payment-response\"].\n")
+
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("Pay for and call %s", toolName),
+		Messages: []*mcp.PromptMessage{
+			{
+				Role:    "user",
+				Content: &mcp.TextContent{Text: b.String()},
+			},
+		},
+	}, nil
+}
+
+// requirementForNetwork returns the resource's payment requirement for
+// network, or its first requirement when network is empty.
+func requirementForNetwork(resource X402DiscoveryResource, network string) *X402PaymentRequirements {
+	if resource.Accepts == nil {
+		return nil
+	}
+	for idx, requirement := range *resource.Accepts {
+		if network == "" || strings.EqualFold(requirement.Network, network) {
+			return &(*resource.Accepts)[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPaymentWorkflowPromptListedAndRendered(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/weather", "GET", nil)
+	s := &Server{
+		mcpServer: sdkmcp.NewServer(&sdkmcp.Implementation{Name: "test", Version: "1.0.0"}, nil),
+		resources: []X402DiscoveryResource{resource},
+	}
+	s.registerPrompts()
+
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	listed, err := clientSession.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(listed.Prompts) != 1 || listed.Prompts[0].Name != PaymentWorkflowPrompt {
+		t.Fatalf("expected %s to be listed, got %+v", PaymentWorkflowPrompt, listed.Prompts)
+	}
+
+	toolName := toolNameFromResource(resource.Resource, "GET")
+	rendered, err := clientSession.GetPrompt(ctx, &sdkmcp.GetPromptParams{
+		Name:      PaymentWorkflowPrompt,
+		Arguments: map[string]string{"toolName": toolName, "network": "base-sepolia"},
+	})
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if len(rendered.Messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(rendered.Messages))
+	}
+	text := rendered.Messages[0].Content.(*sdkmcp.TextContent).Text
+	for _, want := range []string{toolName, "proxy_tool_call", `"x402Version": 1`, `"network": "base-sepolia"`, "0x8D170Db9aB247E7013d024566093E13dc7b0f181"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected prompt to mention %q, got:\n%s", want, text)
+		}
+	}
+}
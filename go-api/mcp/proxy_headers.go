@@ -0,0 +1,66 @@
+package mcp
+
+import (
+	"net/http"
+	"slices"
+)
+
+// WithDefaultProxyHeaders adds headers to every proxied request, e.g. a
+// constant API key or tenant header an upstream requires besides payment.
+// Headers in params.headers take precedence. The values are treated as
+// secrets: they are masked in dry-run previews and dropped on cross-origin
+// redirects.
+func WithDefaultProxyHeaders(headers map[string]string) ServerOption {
+	return func(s *Server) {
+		s.defaultProxyHeaders = canonicalHeaders(headers)
+	}
+}
+
+// WithResourceProxyHeaders adds headers to proxied requests for the resource
+// at resourceURL, overriding WithDefaultProxyHeaders for the same names.
+// Headers in params.headers still take precedence.
+func WithResourceProxyHeaders(resourceURL string, headers map[string]string) ServerOption {
+	return func(s *Server) {
+		if s.resourceProxyHeaders == nil {
+			s.resourceProxyHeaders = make(map[string]http.Header)
+		}
+		s.resourceProxyHeaders[resourceURL] = canonicalHeaders(headers)
+	}
+}
+
+func canonicalHeaders(headers map[string]string) http.Header {
+	canonical := make(http.Header, len(headers))
+	for name, value := range headers {
+		canonical.Set(name, value)
+	}
+	return canonical
+}
+
+// applyProxyHeaders sets the configured static headers on req for resource,
+// leaving any header the caller already supplied untouched.
+func (s *Server) applyProxyHeaders(req *http.Request, resource X402DiscoveryResource) {
+	for _, headers := range []http.Header{s.resourceProxyHeaders[resource.Resource], s.defaultProxyHeaders} {
+		for name, values := range headers {
+			if _, set := req.Header[name]; !set {
+				req.Header[name] = slices.Clone(values)
+			}
+		}
+	}
+}
+
+// staticHeaderNames lists every header configured with
+// WithDefaultProxyHeaders or WithResourceProxyHeaders.
+func (s *Server) staticHeaderNames() []string {
+	var names []string
+	for name := range s.defaultProxyHeaders {
+		names = append(names, name)
+	}
+	for _, headers := range s.resourceProxyHeaders {
+		for name := range headers {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
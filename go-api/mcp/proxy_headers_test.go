@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestProxyToolCallStaticHeaders(t *testing.T) {
+	t.Parallel()
+
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Header.Clone())
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	weather := testResource(upstream.URL+"/weather", "GET", nil)
+	alerts := testResource(upstream.URL+"/weather/alerts", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{weather, alerts}}
+	WithDefaultProxyHeaders(map[string]string{"x-api-key": "secret-key", "X-Tenant": "acme"})(s)
+	WithResourceProxyHeaders(alerts.Resource, map[string]string{"X-Tenant": "alerts-tenant"})(s)
+
+	call := func(resource X402DiscoveryResource, params map[string]any) http.Header {
+		t.Helper()
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName:   toolNameFromResource(resource.Resource, "GET"),
+			Parameters: params,
+		})
+		if err != nil || result.IsError {
+			t.Fatalf("ProxyToolCall failed: %v %+v", err, result)
+		}
+		headers, _ := seen.Load().(http.Header)
+		return headers
+	}
+
+	tests := []struct {
+		name       string
+		resource   X402DiscoveryResource
+		params     map[string]any
+		wantKey    string
+		wantTenant string
+	}{
+		{name: "defaults applied", resource: weather, wantKey: "secret-key", wantTenant: "acme"},
+		{name: "resource override", resource: alerts, wantKey: "secret-key", wantTenant: "alerts-tenant"},
+		{
+			name:       "caller wins",
+			resource:   alerts,
+			params:     map[string]any{"headers": map[string]any{"X-Tenant": "caller", "X-Api-Key": "caller-key"}},
+			wantKey:    "caller-key",
+			wantTenant: "caller",
+		},
+	}
+	for _, tt := range tests {
+		headers := call(tt.resource, tt.params)
+		if got := headers.Get("X-Api-Key"); got != tt.wantKey {
+			t.Fatalf("%s: expected X-Api-Key %q, got %q", tt.name, tt.wantKey, got)
+		}
+		if got := headers.Get("X-Tenant"); got != tt.wantTenant {
+			t.Fatalf("%s: expected X-Tenant %q, got %q", tt.name, tt.wantTenant, got)
+		}
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(weather.Resource, "GET"),
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	preview, _ := result.StructuredContent.(map[string]any)
+	headers, _ := preview["headers"].(http.Header)
+	if headers.Get("X-Api-Key") != redactedHeaderValue || headers.Get("X-Tenant") != redactedHeaderValue {
+		t.Fatalf("expected static headers to be masked in the preview, got %v", headers)
+	}
+}
@@ -0,0 +1,57 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// queryParamDefault returns the value to send for a declared query param the
+// caller omits. Only object entries declare one, under "default" or, failing
+// that, "example"; a bare value is ambiguous between a type name, a
+// description and an example, so it never becomes a default.
+func queryParamDefault(declared any) (any, bool) {
+	entry, ok := declared.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	for _, key := range []string{"default", "example"} {
+		if value, ok := entry[key]; ok && value != nil {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// queryParamDescription describes a declared query param for the tool schema.
+func queryParamDescription(declared any) string {
+	if entry, ok := declared.(map[string]any); ok {
+		description, _ := entry["description"].(string)
+		return description
+	}
+	if declared == nil {
+		return ""
+	}
+	return fmt.Sprint(declared)
+}
+
+// declaredQueryParams returns the queryParams declared by the resource's
+// accepts input schema.
+func declaredQueryParams(resource X402DiscoveryResource) map[string]any {
+	_, input := extractAcceptsMetadata(resource)
+	queryParams, _ := input["queryParams"].(map[string]any)
+	return queryParams
+}
+
+// applyQueryDefaults sets declared defaults for query params that neither the
+// caller nor the resource URL supplies. An explicit caller value, including
+// null, always wins.
+func applyQueryDefaults(query url.Values, resource X402DiscoveryResource, supplied map[string]any) {
+	for key, declared := range declaredQueryParams(resource) {
+		if _, ok := supplied[key]; ok || query.Has(key) {
+			continue
+		}
+		if value, ok := queryParamDefault(declared); ok {
+			setQueryValue(query, key, value)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+)
+
+func queryDefaultsResource() X402DiscoveryResource {
+	return testResource("https://api.example.com/weather?units=metric", "GET", map[string]any{
+		"queryParams": map[string]any{
+			"city":  map[string]any{"type": "string", "description": "City name", "example": "San Francisco"},
+			"days":  map[string]any{"default": float64(3)},
+			"units": map[string]any{"default": "imperial"},
+			"lang":  "string",
+		},
+	})
+}
+
+func TestProxyToolCallToHTTPRequestAppliesQueryDefaults(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   map[string]string
+		absent []string
+	}{
+		{
+			name:   "defaults fill omitted params",
+			want:   map[string]string{"city": "San Francisco", "days": "3", "units": "metric"},
+			absent: []string{"lang"},
+		},
+		{
+			name:   "caller values win",
+			params: map[string]any{"query": map[string]any{"city": "Paris", "days": float64(1), "units": "kelvin"}},
+			want:   map[string]string{"city": "Paris", "days": "1", "units": "kelvin"},
+		},
+		{
+			name:   "explicit null omits the param",
+			params: map[string]any{"query": map[string]any{"city": nil}},
+			want:   map[string]string{"days": "3"},
+			absent: []string{"city"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req, err := proxyToolCallToHTTPRequest(context.Background(), queryDefaultsResource(), tt.params, x402local.StdLogger{})
+			if err != nil {
+				t.Fatalf("proxyToolCallToHTTPRequest error: %v", err)
+			}
+			query := req.URL.Query()
+			for key, want := range tt.want {
+				if got := query.Get(key); got != want {
+					t.Fatalf("expected %s=%q, got %q (url %s)", key, want, got, req.URL)
+				}
+			}
+			for _, key := range tt.absent {
+				if query.Has(key) {
+					t.Fatalf("expected no %s param, got %q", key, query.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestResourceToToolMarksQueryDefaultsOptional(t *testing.T) {
+	t.Parallel()
+
+	tool := resourceToTool(queryDefaultsResource(), nil, ToolOverride{}, nil)
+	schema := tool.InputSchema.(map[string]any)
+	parameters := schema["properties"].(map[string]any)["parameters"].(map[string]any)
+	query := parameters["properties"].(map[string]any)["query"].(map[string]any)
+	props := query["properties"].(map[string]any)
+
+	city := props["city"].(map[string]any)
+	if city["default"] != "San Francisco" {
+		t.Fatalf("expected city default San Francisco, got %v", city["default"])
+	}
+	if city["description"] != "City name (optional, defaults to San Francisco)" {
+		t.Fatalf("unexpected city description %q", city["description"])
+	}
+	if _, ok := props["lang"].(map[string]any)["default"]; ok {
+		t.Fatal("expected a bare declared value not to become a default")
+	}
+	if _, ok := query["required"]; ok {
+		t.Fatalf("expected query params to stay optional, got required %v", query["required"])
+	}
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rangeRequestHeaders may always be passed in parameters.headers so an agent
+// can fetch part of a large resource instead of a capped whole body.
+var rangeRequestHeaders = map[string]string{
+	"Range":    "Byte range to fetch, e.g. bytes=0-65535. The upstream answers 206 with the range it returned in contentRange.",
+	"If-Range": "ETag or Last-Modified the content must still match for Range to apply; otherwise the full content is returned.",
+}
+
+// contentRange is a parsed "bytes start-end/total" Content-Range. Total is -1
+// when the upstream reports it as unknown ("*").
+type contentRange struct {
+	Start int64
+	End   int64
+	Total int64
+}
+
+// parseContentRange parses a satisfied byte range Content-Range value.
+func parseContentRange(value string) (contentRange, bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !ok {
+		return contentRange{}, false
+	}
+	span, total, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return contentRange{}, false
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return contentRange{}, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return contentRange{}, false
+	}
+	end, err := strconv.ParseInt(last, 10, 64)
+	if err != nil || end < start {
+		return contentRange{}, false
+	}
+	parsed := contentRange{Start: start, End: end, Total: -1}
+	if total != "*" {
+		if parsed.Total, err = strconv.ParseInt(total, 10, 64); err != nil || parsed.Total <= end {
+			return contentRange{}, false
+		}
+	}
+	return parsed, true
+}
+
+// addPartialContent describes a 206 response in payload: the raw
+// Content-Range and the byte range actually delivered. When the body was cut
+// short, e.g. by maxProxyResponseBytes, the range is narrowed to what was
+// delivered and nextRange asks for the rest.
+func addPartialContent(payload map[string]any, header http.Header, delivered int) {
+	payload["partialContent"] = true
+	value := header.Get("Content-Range")
+	if value == "" {
+		return
+	}
+	payload["contentRange"] = value
+	parsed, ok := parseContentRange(value)
+	if !ok {
+		return
+	}
+
+	end := parsed.End
+	if declared := parsed.End - parsed.Start + 1; int64(delivered) < declared {
+		end = parsed.Start + int64(delivered) - 1
+		payload["truncated"] = true
+		payload["nextRange"] = fmt.Sprintf("bytes=%d-%d", end+1, parsed.End)
+	}
+	byteRange := map[string]any{
+		"start": parsed.Start,
+		"end":   end,
+	}
+	if parsed.Total >= 0 {
+		byteRange["total"] = parsed.Total
+	}
+	payload["range"] = byteRange
+}
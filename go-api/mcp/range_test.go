@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxyToolCallRangeRequest(t *testing.T) {
+	t.Parallel()
+
+	const content = "abcdefghij"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		http.ServeContent(w, r, "dataset.txt", time.Time{}, strings.NewReader(content))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/dataset", "GET", map[string]any{
+		"headers": map[string]any{"X-Format": "csv or json"},
+	})
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	if problems := validateProxyParameters(resource, map[string]any{"headers": map[string]any{"Range": "bytes=2-5"}}); len(problems) > 0 {
+		t.Fatalf("expected Range to be accepted alongside declared headers, got %v", problems)
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:   toolNameFromResource(resource.Resource, "GET"),
+		Parameters: map[string]any{"headers": map[string]any{"Range": "bytes=2-5"}},
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected 206 not to be an error, got %+v", result)
+	}
+	payload := decodeProxyPayload(t, result)
+	if payload["status"] != float64(http.StatusPartialContent) || payload["partialContent"] != true {
+		t.Fatalf("expected a partial content payload, got %+v", payload)
+	}
+	if payload["contentRange"] != "bytes 2-5/10" || payload["body"] != "cdef" {
+		t.Fatalf("expected bytes 2-5 of the content, got range %v body %v", payload["contentRange"], payload["body"])
+	}
+	byteRange, ok := payload["range"].(map[string]any)
+	if !ok || byteRange["start"] != float64(2) || byteRange["end"] != float64(5) || byteRange["total"] != float64(10) {
+		t.Fatalf("unexpected range %+v", payload["range"])
+	}
+	if _, ok := payload["truncated"]; ok {
+		t.Fatalf("expected a complete range not to be truncated")
+	}
+}
+
+func TestHTTPResponseToMCPResultCapsPartialContent(t *testing.T) {
+	t.Parallel()
+
+	// The upstream returns a range larger than the proxy will read
+	size := maxProxyResponseBytes + 100
+	resp := &http.Response{
+		StatusCode: http.StatusPartialContent,
+		Header: http.Header{
+			"Content-Type":  {"text/plain"},
+			"Content-Range": {"bytes 1000-" + strconv.Itoa(1000+size-1) + "/*"},
+		},
+		Body: io.NopCloser(bytes.NewReader(bytes.Repeat([]byte("x"), size))),
+	}
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	if result.IsError {
+		t.Fatal("expected a capped 206 not to be an error")
+	}
+	payload := decodeProxyPayload(t, result)
+	wantEnd := 1000 + maxProxyResponseBytes - 1
+	byteRange := payload["range"].(map[string]any)
+	if payload["truncated"] != true || byteRange["end"] != float64(wantEnd) {
+		t.Fatalf("expected the range to end at the size cap (%d), got %+v truncated=%v", wantEnd, byteRange, payload["truncated"])
+	}
+	if _, ok := byteRange["total"]; ok {
+		t.Fatalf("expected no total for an unknown length, got %v", byteRange["total"])
+	}
+	if want := "bytes=" + strconv.Itoa(wantEnd+1) + "-" + strconv.Itoa(1000+size-1); payload["nextRange"] != want {
+		t.Fatalf("expected nextRange %s, got %v", want, payload["nextRange"])
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  contentRange
+		ok    bool
+	}{
+		{value: "bytes 0-99/1000", want: contentRange{Start: 0, End: 99, Total: 1000}, ok: true},
+		{value: "bytes 500-999/*", want: contentRange{Start: 500, End: 999, Total: -1}, ok: true},
+		{value: "bytes */1000"},
+		{value: "bytes 10-5/1000"},
+		{value: "bytes 0-1000/1000"},
+		{value: "items 0-9/10"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseContentRange(tt.value)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Fatalf("parseContentRange(%q) = %+v, %t; want %+v, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
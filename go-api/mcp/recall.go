@@ -0,0 +1,185 @@
+package mcp
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// DefaultRecallCacheSize is how many search results recall_tools keeps.
+	DefaultRecallCacheSize = 256
+	// DefaultRecallTTL is how long a search result stays recallable.
+	DefaultRecallTTL = 15 * time.Minute
+)
+
+// ErrRecallExpired is returned when a recall token is unknown, expired or
+// belongs to another session.
+var ErrRecallExpired = errors.New("recall token expired or unknown")
+
+// RecallToolsParams defines the input parameters for the recall_tools tool.
+type RecallToolsParams struct {
+	RecallToken string `json:"recallToken" jsonschema:"recallToken returned by search_resources,required"`
+}
+
+// WithRecallCache sets how many search_resources results recall_tools keeps
+// and for how long. A non-positive size disables recall.
+func WithRecallCache(size int, ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		if size <= 0 {
+			s.recall = nil
+			return
+		}
+		s.recall = newRecallCache(size, ttl)
+	}
+}
+
+// recallCache maps recall tokens to search results, evicting the oldest entry
+// once full and dropping entries after ttl.
+type recallCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	order   *list.List // of *recallEntry, oldest first
+	entries map[string]*list.Element
+}
+
+type recallEntry struct {
+	token     string
+	sessionID string
+	expires   time.Time
+	output    SearchResourcesOutput
+}
+
+func newRecallCache(size int, ttl time.Duration) *recallCache {
+	if ttl <= 0 {
+		ttl = DefaultRecallTTL
+	}
+	return &recallCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// store keeps output for sessionID and returns its recall token.
+func (c *recallCache) store(sessionID string, output SearchResourcesOutput) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate recall token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.order.Len() >= c.size {
+		oldest := c.order.Front()
+		delete(c.entries, oldest.Value.(*recallEntry).token)
+		c.order.Remove(oldest)
+	}
+	c.entries[token] = c.order.PushBack(&recallEntry{
+		token:     token,
+		sessionID: sessionID,
+		expires:   c.now().Add(c.ttl),
+		output:    output,
+	})
+	return token, nil
+}
+
+// recall returns the output stored under token for sessionID.
+func (c *recallCache) recall(sessionID, token string) (SearchResourcesOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[token]
+	if !ok {
+		return SearchResourcesOutput{}, ErrRecallExpired
+	}
+	entry := element.Value.(*recallEntry)
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, token)
+		c.order.Remove(element)
+		return SearchResourcesOutput{}, ErrRecallExpired
+	}
+	if entry.sessionID != sessionID {
+		return SearchResourcesOutput{}, ErrRecallExpired
+	}
+	return entry.output, nil
+}
+
+// sessionIDFor returns the MCP session of req, or "" outside a session.
+func sessionIDFor(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	return req.Session.ID()
+}
+
+// rememberSearch stores output for recall_tools and sets its recall token.
+// Recall is best effort: a failure leaves the token empty.
+func (s *Server) rememberSearch(req *mcp.CallToolRequest, output *SearchResourcesOutput) {
+	if s.recall == nil {
+		return
+	}
+	token, err := s.recall.store(sessionIDFor(req), *output)
+	if err != nil {
+		s.logSink().Warn("search_resources: recall unavailable", "err", err)
+		return
+	}
+	output.RecallToken = token
+}
+
+// RecallTools returns the search_resources result issued with
+// params.RecallToken in the caller's session.
+func (s *Server) RecallTools(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	params *RecallToolsParams,
+) (*mcp.CallToolResult, any, error) {
+	if s.recall == nil {
+		return recallExpiredResult(params.RecallToken, errors.New("recall is disabled")), nil, nil
+	}
+	output, err := s.recall.recall(sessionIDFor(req), params.RecallToken)
+	if err != nil {
+		return recallExpiredResult(params.RecallToken, err), nil, nil
+	}
+	output.RecallToken = params.RecallToken
+
+	contentJSON, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal recalled tools: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(contentJSON),
+			},
+		},
+		StructuredContent: output,
+	}, nil, nil
+}
+
+func recallExpiredResult(token string, err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: %v. Call search_resources again.", err),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":       "recall_expired",
+			"recallToken": token,
+		},
+		IsError: true,
+	}
+}
@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRecallToolsReturnsStoredSearch(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{resources: []X402DiscoveryResource{
+		testResource("http://localhost:8080/weather", "GET", nil),
+		testResource("http://localhost:8080/weather/hourly", "GET", nil),
+	}}
+	WithRecallCache(4, time.Minute)(s)
+
+	limit := 1
+	_, searched, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{Limit: &limit})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	if searched.RecallToken == "" {
+		t.Fatalf("expected a recall token")
+	}
+
+	result, _, err := s.RecallTools(context.Background(), nil, &RecallToolsParams{RecallToken: searched.RecallToken})
+	if err != nil {
+		t.Fatalf("RecallTools error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected recall to succeed, got %+v", result.StructuredContent)
+	}
+	recalled, ok := result.StructuredContent.(SearchResourcesOutput)
+	if !ok {
+		t.Fatalf("expected SearchResourcesOutput, got %T", result.StructuredContent)
+	}
+	if len(recalled.Tools) != 1 || recalled.Tools[0].Name != searched.Tools[0].Name {
+		t.Fatalf("expected the same page of tools, got %+v", recalled.Tools)
+	}
+	if *recalled.Pagination.Total != 2 || recalled.RecallToken != searched.RecallToken {
+		t.Fatalf("expected pagination and token to be recalled, got %+v", recalled)
+	}
+}
+
+func TestRecallToolsExpiredToken(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{resources: []X402DiscoveryResource{testResource("http://localhost:8080/weather", "GET", nil)}}
+	WithRecallCache(4, time.Minute)(s)
+	now := time.Now()
+	s.recall.now = func() time.Time { return now }
+
+	_, searched, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	now = now.Add(time.Minute)
+
+	for _, token := range []string{searched.RecallToken, "unknown"} {
+		result, _, err := s.RecallTools(context.Background(), nil, &RecallToolsParams{RecallToken: token})
+		if err != nil {
+			t.Fatalf("RecallTools error: %v", err)
+		}
+		structured, _ := result.StructuredContent.(map[string]any)
+		if !result.IsError || structured["error"] != "recall_expired" {
+			t.Fatalf("expected recall_expired for %q, got %+v", token, result)
+		}
+	}
+}
+
+func TestRecallCacheBoundsEntriesAndSessions(t *testing.T) {
+	t.Parallel()
+
+	cache := newRecallCache(2, time.Minute)
+	first, _ := cache.store("session-a", SearchResourcesOutput{X402Version: 1})
+	second, _ := cache.store("session-a", SearchResourcesOutput{X402Version: 2})
+	third, _ := cache.store("session-b", SearchResourcesOutput{X402Version: 3})
+
+	if _, err := cache.recall("session-a", first); !errors.Is(err, ErrRecallExpired) {
+		t.Fatalf("expected the oldest entry to be evicted, got %v", err)
+	}
+	if output, err := cache.recall("session-a", second); err != nil || output.X402Version != 2 {
+		t.Fatalf("expected second entry, got %+v %v", output, err)
+	}
+	if _, err := cache.recall("session-a", third); !errors.Is(err, ErrRecallExpired) {
+		t.Fatalf("expected another session's token to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// DefaultMaxRedirects is how many redirects a proxied request may follow.
+const DefaultMaxRedirects = 5
+
+// ErrTooManyRedirects is returned when an upstream redirects more than
+// DefaultMaxRedirects times.
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// WithoutRedirects stops proxy_tool_call from following upstream redirects;
+// the 3xx response is returned to the agent as-is.
+func WithoutRedirects() ServerOption {
+	return func(s *Server) {
+		s.noRedirects = true
+	}
+}
+
+// proxyHTTPClient returns defaultHTTPClient with the server's redirect policy
+// and a transport that enforces its egress policy on every dial.
+func (s *Server) proxyHTTPClient() *http.Client {
+	client := *defaultHTTPClient
+	client.CheckRedirect = s.checkRedirect
+	s.transportOnce.Do(func() {
+		s.transport = newEgressTransport(func() *EgressPolicy { return s.egress })
+	})
+	client.Transport = s.transport
+	return &client
+}
+
+// checkRedirect caps the redirect count, re-applies the egress policy to each
+// hop and drops payment and auth headers when the redirect leaves the
+// original origin, so a paid call never hands its signature to another host.
+func (s *Server) checkRedirect(req *http.Request, via []*http.Request) error {
+	if s.noRedirects {
+		return http.ErrUseLastResponse
+	}
+	// via includes the original request, so it holds one more than the
+	// redirects followed so far
+	if len(via) > DefaultMaxRedirects {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, DefaultMaxRedirects)
+	}
+	if err := s.egress.check(req.Context(), req.URL); err != nil {
+		return err
+	}
+	if !sameOrigin(req, via[0]) {
+		for _, name := range DefaultRedactedHeaders {
+			req.Header.Del(name)
+		}
+		for _, name := range s.headersToRedact() {
+			req.Header.Del(name)
+		}
+		req.Header.Del("Cookie")
+	}
+	return nil
+}
+
+func sameOrigin(a, b *http.Request) bool {
+	return a.URL.Scheme == b.URL.Scheme && a.URL.Host == b.URL.Host
+}
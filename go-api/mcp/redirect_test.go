@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func redirectingCall(t *testing.T, s *Server, target string) (*sdkmcp.CallToolResult, error) {
+	t.Helper()
+	resource := testResource(target, "GET", nil)
+	s.resources = []X402DiscoveryResource{resource}
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(resource.Resource, "GET"),
+		Parameters: map[string]any{
+			"headers": map[string]any{"X-PAYMENT": "signed", "Authorization": "Bearer secret"},
+		},
+	})
+	return result, err
+}
+
+func TestProxyToolCallSameOriginRedirectKeepsHeaders(t *testing.T) {
+	t.Parallel()
+
+	var payment atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		payment.Store(r.Header.Get("X-PAYMENT"))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	result, err := redirectingCall(t, &Server{}, upstream.URL+"/start")
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if got := payment.Load(); got != "signed" {
+		t.Fatalf("expected payment header kept on same-origin redirect, got %v", got)
+	}
+	if payload := decodeProxyPayload(t, result); payload["url"] != upstream.URL+"/final" {
+		t.Fatalf("expected final url in result, got %v", payload["url"])
+	}
+}
+
+func TestProxyToolCallCrossOriginRedirectStripsHeaders(t *testing.T) {
+	t.Parallel()
+
+	var payment, auth atomic.Value
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payment.Store(r.Header.Get("X-PAYMENT"))
+		auth.Store(r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer other.Close()
+	upstream := httptest.NewServer(http.RedirectHandler(other.URL+"/elsewhere", http.StatusTemporaryRedirect))
+	defer upstream.Close()
+
+	result, err := redirectingCall(t, &Server{}, upstream.URL+"/start")
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if got := payment.Load(); got != "" {
+		t.Fatalf("expected payment header stripped, got %q", got)
+	}
+	if got := auth.Load(); got != "" {
+		t.Fatalf("expected authorization stripped, got %q", got)
+	}
+	if payload := decodeProxyPayload(t, result); payload["url"] != other.URL+"/elsewhere" {
+		t.Fatalf("expected final url in result, got %v", payload["url"])
+	}
+}
+
+func TestProxyToolCallRedirectCap(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer upstream.Close()
+
+	result, err := redirectingCall(t, &Server{}, upstream.URL+"/start")
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if !result.IsError || structured["error"] != "too_many_redirects" {
+		t.Fatalf("expected a too_many_redirects result, got %+v", result)
+	}
+	if got := hits.Load(); got != DefaultMaxRedirects+1 {
+		t.Fatalf("expected %d upstream hits, got %d", DefaultMaxRedirects+1, got)
+	}
+}
+
+func TestProxyToolCallWithoutRedirects(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.RedirectHandler("/final", http.StatusFound))
+	defer upstream.Close()
+
+	s := &Server{}
+	WithoutRedirects()(s)
+	result, err := redirectingCall(t, s, upstream.URL+"/start")
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if payload := decodeProxyPayload(t, result); payload["status"] != float64(http.StatusFound) {
+		t.Fatalf("expected the 302 to be returned, got %v", payload["status"])
+	}
+}
+
+func decodeProxyPayload(t *testing.T, result *sdkmcp.CallToolResult) map[string]any {
+	t.Helper()
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(*sdkmcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("decode proxy payload: %v", err)
+	}
+	return payload
+}
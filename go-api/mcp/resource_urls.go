@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WithAllowedHosts restricts discovered resources to the given hostnames.
+// Matching is case-insensitive and ignores the port. With no allowed hosts,
+// any host not denied is accepted.
+func WithAllowedHosts(hosts ...string) ServerOption {
+	return func(s *Server) {
+		s.allowedHosts = hostSet(hosts)
+	}
+}
+
+// WithDeniedHosts drops discovered resources whose hostname is listed.
+// Denied hosts win over allowed ones.
+func WithDeniedHosts(hosts ...string) ServerOption {
+	return func(s *Server) {
+		s.deniedHosts = hostSet(hosts)
+	}
+}
+
+func hostSet(hosts []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			set[host] = struct{}{}
+		}
+	}
+	return set
+}
+
+// validateResourceURL checks that raw is an absolute http(s) URL whose host
+// passes the server's allow and deny lists.
+func (s *Server) validateResourceURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid resource URL %q: %w", raw, err)
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("resource URL %q must use http or https", raw)
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return fmt.Errorf("resource URL %q has no host", raw)
+	}
+	if _, denied := s.deniedHosts[host]; denied {
+		return fmt.Errorf("resource host %q is denied", host)
+	}
+	if len(s.allowedHosts) > 0 {
+		if _, allowed := s.allowedHosts[host]; !allowed {
+			return fmt.Errorf("resource host %q is not in the allow list", host)
+		}
+	}
+	return nil
+}
+
+// filterResources drops resources with unusable or disallowed URLs, logging
+// each one so a bad fixture entry is visible at startup.
+func (s *Server) filterResources(resources []X402DiscoveryResource) []X402DiscoveryResource {
+	valid := make([]X402DiscoveryResource, 0, len(resources))
+	for _, resource := range resources {
+		if err := s.validateResourceURL(resource.Resource); err != nil {
+			s.logSink().Warn("skipping discovery resource", "resource", resource.Resource, "err", err)
+			continue
+		}
+		valid = append(valid, resource)
+	}
+	return valid
+}
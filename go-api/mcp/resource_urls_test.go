@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+)
+
+type warnCounter struct {
+	x402local.StdLogger
+	warns int
+}
+
+func (w *warnCounter) Warn(msg string, keyvals ...any) { w.warns++ }
+
+func TestValidateResourceURL(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{deniedHosts: hostSet([]string{"169.254.169.254"})}
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{name: "valid", url: "https://api.example.com/weather"},
+		{name: "malformed", url: "http://%zz", wantErr: "invalid resource URL"},
+		{name: "relative", url: "/weather", wantErr: "must use http or https"},
+		{name: "file scheme", url: "file:///etc/passwd", wantErr: "must use http or https"},
+		{name: "missing host", url: "http:///weather", wantErr: "has no host"},
+		{name: "denied host", url: "http://169.254.169.254/latest/meta-data", wantErr: "is denied"},
+	}
+	for _, tt := range tests {
+		err := s.validateResourceURL(tt.url)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestFilterResourcesAppliesAllowList(t *testing.T) {
+	t.Parallel()
+
+	logger := &warnCounter{}
+	s := &Server{logger: logger, allowedHosts: hostSet([]string{"API.example.com"})}
+	resources := s.filterResources([]X402DiscoveryResource{
+		{Resource: "https://api.example.com:8443/weather", Type: "http"},
+		{Resource: "https://other.example.com/weather", Type: "http"},
+		{Resource: "not a url", Type: "http"},
+	})
+
+	if len(resources) != 1 || resources[0].Resource != "https://api.example.com:8443/weather" {
+		t.Fatalf("expected only the allowed resource, got %+v", resources)
+	}
+	if logger.warns != 2 {
+		t.Fatalf("expected 2 skip warnings, got %d", logger.warns)
+	}
+}
+
+func TestNewServerDropsDeniedHosts(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer(WithDeniedHosts("localhost"), WithLogger(&warnCounter{}))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if len(s.resources) != 0 {
+		t.Fatalf("expected localhost fixtures to be dropped, got %d", len(s.resources))
+	}
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// discoveryResourceContent is returned by resources/read for a discovered
+// x402 resource.
+type discoveryResourceContent struct {
+	Resource    string                     `json:"resource"`
+	ToolName    string                     `json:"toolName"`
+	X402Version int                        `json:"x402Version"`
+	Accepts     *[]X402PaymentRequirements `json:"accepts,omitempty"`
+}
+
+// registerResources exposes each discovered HTTP resource through
+// resources/list so resource-aware clients can enumerate them without calling
+// search_resources. Reading one returns its payment requirements as JSON.
+func (s *Server) registerResources() {
+	for _, resource := range s.resources {
+		tool := s.resourceTool(resource)
+		if tool == nil {
+			continue
+		}
+		content := discoveryResourceContent{
+			Resource:    resource.Resource,
+			ToolName:    tool.Name,
+			X402Version: resource.X402Version,
+			Accepts:     resource.Accepts,
+		}
+		s.mcpServer.AddResource(&mcp.Resource{
+			URI:         resource.Resource,
+			Name:        tool.Name,
+			Description: tool.Description,
+			MIMEType:    "application/json",
+		}, discoveryResourceHandler(content))
+	}
+}
+
+func discoveryResourceHandler(content discoveryResourceContent) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		payload, err := json.Marshal(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal resource %s: %w", content.Resource, err)
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{
+				{
+					URI:      content.Resource,
+					MIMEType: "application/json",
+					Text:     string(payload),
+				},
+			},
+		}, nil
+	}
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestNewServerListsDiscoveryResources(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	var want []string
+	for _, resource := range s.resources {
+		if !slices.Contains(want, resource.Resource) {
+			want = append(want, resource.Resource)
+		}
+	}
+	if len(want) == 0 {
+		t.Fatal("expected fixture resources")
+	}
+
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	listed, err := clientSession.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListResources: %v", err)
+	}
+	var got []string
+	for _, resource := range listed.Resources {
+		got = append(got, resource.URI)
+	}
+	slices.Sort(want)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected resources %v, got %v", want, got)
+	}
+
+	read, err := clientSession.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: want[0]})
+	if err != nil {
+		t.Fatalf("ReadResource: %v", err)
+	}
+	var content discoveryResourceContent
+	if err := json.Unmarshal([]byte(read.Contents[0].Text), &content); err != nil {
+		t.Fatalf("decode resource content: %v", err)
+	}
+	if content.Resource != want[0] || content.ToolName == "" || content.Accepts == nil || len(*content.Accepts) == 0 {
+		t.Fatalf("expected payment requirements for %s, got %+v", want[0], content)
+	}
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultResponseHeaders are the upstream response headers echoed in proxy
+// results unless WithResponseHeaders replaces them. A trailing "*" matches any
+// header with that prefix. Cookies, auth challenges and server internals are
+// left out; payment headers are decoded into result meta instead.
+var DefaultResponseHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Content-Language",
+	"Content-Range",
+	"Accept-Ranges",
+	"ETag",
+	"Last-Modified",
+	"Cache-Control",
+	"Expires",
+	"Age",
+	"Retry-After",
+	"X-RateLimit-*",
+	"RateLimit-*",
+	"X-Request-Id",
+}
+
+// WithResponseHeaders replaces DefaultResponseHeaders, the upstream response
+// headers echoed in proxy results. Names match in any casing, and a trailing
+// "*" matches a prefix, so "*" alone echoes every header. Echoed headers are
+// still redacted. Passing no names echoes none.
+func WithResponseHeaders(names ...string) ServerOption {
+	return func(s *Server) {
+		s.responseHeaders = append([]string{}, names...)
+	}
+}
+
+// responseHeaderFilter selects the upstream response headers a result echoes
+// and masks the sensitive ones among them.
+type responseHeaderFilter struct {
+	// allowed lists the headers to echo. Nil echoes every header.
+	allowed  []string
+	redacted []string
+}
+
+// responseHeaderFilter returns the filter for proxied responses.
+func (s *Server) responseHeaderFilter() responseHeaderFilter {
+	allowed := s.responseHeaders
+	if allowed == nil {
+		allowed = DefaultResponseHeaders
+	}
+	return responseHeaderFilter{allowed: allowed, redacted: s.headersToRedact()}
+}
+
+// apply returns the allowed headers of header, redacted.
+func (f responseHeaderFilter) apply(header http.Header) http.Header {
+	if f.allowed == nil {
+		return RedactHeaders(header, f.redacted)
+	}
+	kept := http.Header{}
+	for key, values := range header {
+		if headerAllowed(f.allowed, key) {
+			kept[key] = values
+		}
+	}
+	return RedactHeaders(kept, f.redacted)
+}
+
+// headerAllowed reports whether name matches an entry of allowed, ignoring
+// case. An entry ending in "*" matches any name with that prefix.
+func headerAllowed(allowed []string, name string) bool {
+	for _, pattern := range allowed {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestProxyToolCallEchoesAllowlistedHeaders(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "41")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="internal"`)
+		w.Header().Set("Server", "internal-gateway/2.3")
+		w.Header().Set("X-Custom", "value")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	call := func(s *Server) []string {
+		t.Helper()
+		s.resources = []X402DiscoveryResource{resource}
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName: toolNameFromResource(resource.Resource, "GET"),
+		})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		headers, ok := decodeProxyPayload(t, result)["headers"].(map[string]any)
+		if !ok {
+			t.Fatalf("expected a headers object in the payload")
+		}
+		var names []string
+		for name := range headers {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	got := call(&Server{})
+	want := []string{"Content-Length", "Content-Type", "Etag", "X-Ratelimit-Remaining"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected only the default allowlist %v, got %v", want, got)
+	}
+
+	s := &Server{}
+	WithResponseHeaders("x-custom", "Set-Cookie")(s)
+	s.SetRedactedHeaders("Set-Cookie")
+	if got := call(s); !slices.Equal(got, []string{"Set-Cookie", "X-Custom"}) {
+		t.Fatalf("expected the configured allowlist, got %v", got)
+	}
+
+	s = &Server{}
+	WithResponseHeaders("*")(s)
+	if got := call(s); !slices.Contains(got, "Server") || !slices.Contains(got, "Www-Authenticate") {
+		t.Fatalf("expected * to echo every header, got %v", got)
+	}
+}
+
+func TestResponseHeaderFilterRedactsAllowedHeaders(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{
+		"X-Payment-Response": {"secret"},
+		"Content-Type":       {"application/json"},
+	}
+	filtered := responseHeaderFilter{allowed: []string{"x-payment-*", "content-type"}, redacted: DefaultRedactedHeaders}.apply(header)
+	if filtered.Get("X-Payment-Response") != redactedHeaderValue || filtered.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected allowed headers to still be redacted, got %v", filtered)
+	}
+	if header.Get("X-Payment-Response") != "secret" {
+		t.Fatal("expected the upstream header to be left untouched")
+	}
+	if len(responseHeaderFilter{allowed: []string{}}.apply(header)) != 0 {
+		t.Fatal("expected an empty allowlist to echo nothing")
+	}
+}
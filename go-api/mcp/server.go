@@ -3,41 +3,269 @@
 package mcp
 
 import (
+	"math/big"
 	"net/http"
+	"slices"
+	"sync"
+	"time"
 
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // Server wraps the MCP server implementation for x402 discovery.
 type Server struct {
-	mcpServer *mcp.Server
-	resources []X402DiscoveryResource
+	mcpServer  *mcp.Server
+	resources  []X402DiscoveryResource
+	callBudget x402local.CallBudget
+	// redactedHeaders overrides DefaultRedactedHeaders when non-nil.
+	redactedHeaders []string
+	// directToolLimit caps how many discovered resources are registered as
+	// first-class tools. Zero disables direct registration.
+	directToolLimit int
+	metrics         x402local.Metrics
+	logger          x402local.Logger
+	// headerPolicy decides what happens when params.headers already carries
+	// the payment header that x402/payment meta would set.
+	headerPolicy PaymentHeaderPolicy
+	// paymentHeaderName and resourcePaymentHeaderNames override the
+	// version-based payment header; resourcePaymentHeaderNames is keyed by
+	// resource URL.
+	paymentHeaderName          string
+	resourcePaymentHeaderNames map[string]string
+	// allowedHosts and deniedHosts filter discovered resources by hostname.
+	allowedHosts map[string]struct{}
+	deniedHosts  map[string]struct{}
+	// egress is checked before each proxied request and on each dial. Nil
+	// skips the check.
+	egress *EgressPolicy
+	// transport dials upstreams through egress; built on first use.
+	transportOnce sync.Once
+	transport     *http.Transport
+	// maxSearchResults caps the tools returned by search_resources. Zero
+	// means DefaultMaxSearchResults.
+	maxSearchResults int
+	// noRedirects returns upstream 3xx responses instead of following them.
+	noRedirects bool
+	// maxPriceByAsset and maxPriceByNetwork cap what an agent may pay per
+	// call, in smallest units.
+	maxPriceByAsset   map[string]*big.Int
+	maxPriceByNetwork map[string]*big.Int
+	// excludeFree hides resources without payment requirements from
+	// search_resources and direct tools.
+	excludeFree bool
+	// userAgent overrides DefaultUserAgent when non-empty.
+	userAgent string
+	// hostOverrides lists the Host headers a proxied call may send in place
+	// of the resource URL's host.
+	hostOverrides map[string]struct{}
+	// allowedMethods overrides DefaultAllowedMethods when non-nil.
+	allowedMethods map[string]struct{}
+	// defaultProxyHeaders and resourceProxyHeaders are static headers added
+	// to proxied requests; resourceProxyHeaders is keyed by resource URL.
+	defaultProxyHeaders  http.Header
+	resourceProxyHeaders map[string]http.Header
+	// recall keeps recent search_resources results for recall_tools. Nil
+	// disables recall.
+	recall *recallCache
+	// progressInterval overrides DefaultProgressInterval when positive;
+	// negative disables upstream wait notifications.
+	progressInterval time.Duration
+	// fixtureValidation decides whether malformed fixture entries fail
+	// NewServer or are skipped.
+	fixtureValidation FixtureValidation
+	// namer names discovered tools. Nil means HashToolNamer.
+	namer ToolNamer
+	// fees charges the server's own fee for its meta-tools. Nil keeps them
+	// free.
+	fees *x402local.Middleware
+	// responseHeaders overrides DefaultResponseHeaders when non-nil.
+	responseHeaders []string
+	// bearerToken and resourceBearerTokens are forwarded as OAuth bearer
+	// tokens; bearerToken only to bearerTokenHosts, and resourceBearerTokens
+	// is keyed by resource URL.
+	bearerToken          string
+	bearerTokenHosts     map[string]struct{}
+	resourceBearerTokens map[string]string
+	// toolOverrides curates discovered tools' titles and descriptions, keyed
+	// by resource URL.
+	toolOverrides map[string]ToolOverride
+	// extraResources are added to the fixture entries by WithResources.
+	extraResources []X402DiscoveryResource
+	// paymentOptionPolicy recommends one of each tool's payment options. Nil
+	// recommends none.
+	paymentOptionPolicy PaymentOptionPolicy
+	// assets adds display info for assets beyond the built-in USDC ones.
+	assets assetRegistry
+}
+
+const (
+	serverName    = "x402-discovery"
+	serverVersion = "1.0.0"
+)
+
+// DefaultUserAgent identifies this proxy to upstream x402 resources.
+const DefaultUserAgent = serverName + "-proxy/" + serverVersion
+
+// DefaultMaxSearchResults is the most tools a single search_resources call
+// returns, whatever limit the caller asks for.
+const DefaultMaxSearchResults = 50
+
+// PaymentHeaderPolicy controls how a caller-supplied payment header interacts
+// with the payment in x402/payment meta.
+type PaymentHeaderPolicy int
+
+const (
+	// PaymentHeaderOverride replaces the caller's header with the value
+	// derived from meta. This is the default.
+	PaymentHeaderOverride PaymentHeaderPolicy = iota
+	// PaymentHeaderReject fails the call when both are present.
+	PaymentHeaderReject
+)
+
+// ServerOption configures a Server at construction time.
+type ServerOption func(*Server)
+
+// WithDirectTools registers up to limit discovered resources as first-class
+// MCP tools in tools/list, alongside search_resources and proxy_tool_call.
+func WithDirectTools(limit int) ServerOption {
+	return func(s *Server) {
+		s.directToolLimit = limit
+	}
+}
+
+// WithLogger sets the logger used for proxy diagnostics. The default is
+// x402local.StdLogger.
+func WithLogger(logger x402local.Logger) ServerOption {
+	return func(s *Server) {
+		if logger != nil {
+			s.logger = logger
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent sent on proxied requests. The default is
+// DefaultUserAgent; a User-Agent in params.headers still takes precedence.
+func WithUserAgent(userAgent string) ServerOption {
+	return func(s *Server) {
+		s.userAgent = userAgent
+	}
 }
 
 // NewServer creates a new MCP server instance with x402 discovery capabilities.
-func NewServer() (*Server, error) {
+func NewServer(opts ...ServerOption) (*Server, error) {
 	resources, err := loadDiscoveryResources()
 	if err != nil {
 		return nil, err
 	}
 	mcpServer := mcp.NewServer(
 		&mcp.Implementation{
-			Name:    "x402-discovery",
-			Version: "1.0.0",
+			Name:    serverName,
+			Version: serverVersion,
 		},
 		&mcp.ServerOptions{},
 	)
 
 	s := &Server{
 		mcpServer: mcpServer,
-		resources: resources,
+		metrics:   x402local.NopMetrics{},
+		logger:    x402local.StdLogger{},
+		egress:    &EgressPolicy{},
+		recall:    newRecallCache(DefaultRecallCacheSize, DefaultRecallTTL),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	resources = slices.Concat(resources, s.extraResources)
+	if resources, err = s.validateFixtures(resources); err != nil {
+		return nil, err
+	}
+	s.resources = s.dedupeResources(s.filterResources(resources))
 
 	s.registerTools()
+	s.registerResources()
+	s.registerPrompts()
 
 	return s, nil
 }
 
+// WithCallBudget caps the attempts and time spent on each proxy_tool_call,
+// shared by its facilitator and upstream retries. When the call is wrapped by
+// x402 middleware with its own budget, the middleware budget takes precedence.
+func WithCallBudget(budget x402local.CallBudget) ServerOption {
+	return func(s *Server) {
+		s.callBudget = budget
+	}
+}
+
+// SetRedactedHeaders replaces the headers masked in proxied responses and
+// request previews. Passing no names disables redaction.
+func (s *Server) SetRedactedHeaders(names ...string) {
+	s.redactedHeaders = append([]string{}, names...)
+}
+
+// headersToRedact returns the headers masked in previews and responses. Static
+// proxy headers are always included, even when redaction is otherwise off.
+func (s *Server) headersToRedact() []string {
+	names := s.redactedHeaders
+	if names == nil {
+		names = DefaultRedactedHeaders
+	}
+	if extra := slices.Concat(s.staticHeaderNames(), s.paymentHeaderNames(), s.bearerHeaderNames("")); len(extra) > 0 {
+		names = append(slices.Clone(names), extra...)
+	}
+	return names
+}
+
+// userAgentHeader returns the User-Agent for proxied requests.
+func (s *Server) userAgentHeader() string {
+	if s.userAgent == "" {
+		return DefaultUserAgent
+	}
+	return s.userAgent
+}
+
+// SetMetrics sets the sink for proxy call counts and upstream latency.
+func (s *Server) SetMetrics(metrics x402local.Metrics) {
+	if metrics == nil {
+		metrics = x402local.NopMetrics{}
+	}
+	s.metrics = metrics
+}
+
+func (s *Server) metricsSink() x402local.Metrics {
+	if s.metrics == nil {
+		return x402local.NopMetrics{}
+	}
+	return s.metrics
+}
+
+func (s *Server) logSink() x402local.Logger {
+	if s.logger == nil {
+		return x402local.StdLogger{}
+	}
+	return s.logger
+}
+
+// SetMaxSearchResults caps the tools returned by one search_resources call.
+// A non-positive value restores DefaultMaxSearchResults.
+func (s *Server) SetMaxSearchResults(n int) {
+	s.maxSearchResults = max(n, 0)
+}
+
+func (s *Server) searchResultCap() int {
+	if s.maxSearchResults <= 0 {
+		return DefaultMaxSearchResults
+	}
+	return s.maxSearchResults
+}
+
+// SetPaymentHeaderPolicy selects how proxy_tool_call resolves a payment header
+// passed in params.headers alongside x402/payment meta.
+func (s *Server) SetPaymentHeaderPolicy(policy PaymentHeaderPolicy) {
+	s.headerPolicy = policy
+}
+
 // Handler returns an http.Handler for the MCP streamable HTTP transport.
 // This handler should be mounted at /discovery/mcp.
 func (s *Server) Handler() http.Handler {
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"runtime/debug"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ServerInfoOutput defines the structured output for the server_info tool.
+type ServerInfoOutput struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// X402Versions lists the x402 protocol versions of the discovered resources.
+	X402Versions []int `json:"x402Versions"`
+	// Networks lists every network a discovered resource accepts payment on.
+	Networks []string       `json:"networks"`
+	Features ServerFeatures `json:"features"`
+	Build    *ServerBuild   `json:"build,omitempty"`
+}
+
+// ServerFeatures reports the server's configurable behavior.
+type ServerFeatures struct {
+	Resources        int    `json:"resources"`
+	DirectTools      int    `json:"directTools"`
+	MaxSearchResults int    `json:"maxSearchResults"`
+	FollowRedirects  bool   `json:"followRedirects"`
+	EgressPolicy     bool   `json:"egressPolicy"`
+	PriceCaps        bool   `json:"priceCaps"`
+	IncludeFree      bool   `json:"includeFree"`
+	UserAgent        string `json:"userAgent"`
+	// AllowedMethods lists the HTTP methods proxied requests may use.
+	AllowedMethods []string `json:"allowedMethods"`
+	// ServiceFees lists the meta-tools that charge the server's own fee.
+	ServiceFees []string `json:"serviceFees,omitempty"`
+}
+
+// ServerBuild is the Go build information embedded in the binary.
+type ServerBuild struct {
+	GoVersion string `json:"goVersion"`
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// ServerInfo reports the server version, the x402 versions and networks of
+// the discovered resources, and the enabled features.
+func (s *Server) ServerInfo(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	params any,
+) (*mcp.CallToolResult, ServerInfoOutput, error) {
+	info := ServerInfoOutput{
+		Name:         serverName,
+		Version:      serverVersion,
+		X402Versions: []int{},
+		Networks:     []string{},
+		Features: ServerFeatures{
+			Resources:        len(s.resources),
+			DirectTools:      s.directToolLimit,
+			MaxSearchResults: s.searchResultCap(),
+			FollowRedirects:  !s.noRedirects,
+			EgressPolicy:     s.egress != nil,
+			PriceCaps:        len(s.maxPriceByAsset) > 0 || len(s.maxPriceByNetwork) > 0,
+			IncludeFree:      !s.excludeFree,
+			UserAgent:        s.userAgentHeader(),
+			AllowedMethods:   s.allowedMethodList(),
+			ServiceFees:      s.serviceFeeTools(),
+		},
+		Build: readServerBuild(),
+	}
+	for _, resource := range s.resources {
+		if !slices.Contains(info.X402Versions, resource.X402Version) {
+			info.X402Versions = append(info.X402Versions, resource.X402Version)
+		}
+		if resource.Accepts == nil {
+			continue
+		}
+		for _, requirement := range *resource.Accepts {
+			if requirement.Network != "" && !slices.Contains(info.Networks, requirement.Network) {
+				info.Networks = append(info.Networks, requirement.Network)
+			}
+		}
+	}
+	slices.Sort(info.X402Versions)
+	slices.Sort(info.Networks)
+	return nil, info, nil
+}
+
+func readServerBuild() *ServerBuild {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	build := &ServerBuild{
+		GoVersion: buildInfo.GoVersion,
+		Module:    buildInfo.Main.Path,
+		Version:   buildInfo.Main.Version,
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Revision = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
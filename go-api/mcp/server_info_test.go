@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestServerInfoReportsConfiguration(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer(WithDirectTools(1), WithoutRedirects())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	var wantVersions []int
+	var wantNetworks []string
+	for _, resource := range s.resources {
+		if !slices.Contains(wantVersions, resource.X402Version) {
+			wantVersions = append(wantVersions, resource.X402Version)
+		}
+		for _, requirement := range *resource.Accepts {
+			if !slices.Contains(wantNetworks, requirement.Network) {
+				wantNetworks = append(wantNetworks, requirement.Network)
+			}
+		}
+	}
+	slices.Sort(wantVersions)
+	slices.Sort(wantNetworks)
+
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.CallTool(ctx, &sdkmcp.CallToolParams{Name: "server_info"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("server_info failed: %v", result.Content)
+	}
+	raw, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatalf("marshal structured content: %v", err)
+	}
+	var info ServerInfoOutput
+	if err := json.Unmarshal(raw, &info); err != nil {
+		t.Fatalf("decode server info: %v", err)
+	}
+
+	if info.Name != serverName || info.Version != serverVersion {
+		t.Fatalf("expected %s %s, got %s %s", serverName, serverVersion, info.Name, info.Version)
+	}
+	if !slices.Equal(info.X402Versions, wantVersions) {
+		t.Fatalf("expected x402 versions %v, got %v", wantVersions, info.X402Versions)
+	}
+	if !slices.Equal(info.Networks, wantNetworks) {
+		t.Fatalf("expected networks %v, got %v", wantNetworks, info.Networks)
+	}
+	if info.Features.DirectTools != 1 || info.Features.FollowRedirects || info.Features.Resources != len(s.resources) {
+		t.Fatalf("expected features to reflect options, got %+v", info.Features)
+	}
+}
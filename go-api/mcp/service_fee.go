@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Service fee _meta keys for proxy_tool_call, whose standard x402/payment
+// keys carry the upstream payment and settlement.
+const (
+	MetaKeyServicePayment         = "x402/service-payment"
+	MetaKeyServicePaymentRequired = "x402/service-payment-required"
+	MetaKeyServicePaymentResponse = "x402/service-payment-response"
+	// MetaKeyServiceFee describes a meta-tool's fee in its tools/list entry.
+	MetaKeyServiceFee = "x402/service-fee"
+)
+
+// ServiceFeeMetaKeys are the _meta keys proxy_tool_call uses for its own fee.
+var ServiceFeeMetaKeys = x402local.PaymentMetaKeys{
+	Payment:         MetaKeyServicePayment,
+	PaymentRequired: MetaKeyServicePaymentRequired,
+	PaymentResponse: MetaKeyServicePaymentResponse,
+}
+
+// MetaToolNames lists the server's own tools, which WithServiceFees can
+// charge for. Direct tools are upstream resources and never carry a fee.
+var MetaToolNames = []string{
+	"search_resources",
+	"proxy_tool_call",
+	"get_tool",
+	"list_tool_names",
+	"recall_tools",
+	"server_info",
+}
+
+// WithServiceFees charges the server's own fee for the meta-tools priced on
+// fees, e.g. fees.SetToolPrice("search_resources", "1000"), on top of any
+// upstream payment. Each meta-tool is wrapped with x402 WrapToolHandler, so
+// unpriced meta-tools stay free unless fees is strict.
+//
+// WithServiceFees modifies fees: it calls fees.SetPaymentMetaKeys so that
+// proxy_tool_call reads its fee from ServiceFeeMetaKeys, leaving x402/payment
+// to the upstream. Any keys set earlier for proxy_tool_call are replaced.
+func WithServiceFees(fees *x402local.Middleware) ServerOption {
+	return func(s *Server) {
+		s.fees = fees
+		if fees != nil {
+			fees.SetPaymentMetaKeys("proxy_tool_call", ServiceFeeMetaKeys)
+		}
+	}
+}
+
+// Drain waits until the meta-tool calls that are paying a service fee have
+// settled, including settlements deferred by the fee middleware's retry
+// queue, or until ctx is done. Without service fees it returns at once.
+func (s *Server) Drain(ctx context.Context) error {
+	if s.fees == nil {
+		return nil
+	}
+	return s.fees.Drain(ctx)
+}
+
+// addMetaTool registers one of the server's own tools, charging the service
+// fee configured for it, if any.
+func addMetaTool[In, Out any](s *Server, tool *mcp.Tool, handler mcp.ToolHandlerFor[In, Out]) {
+	if s.fees == nil {
+		mcp.AddTool(s.mcpServer, tool, handler)
+		return
+	}
+	if pricing := s.fees.GetPaymentRequirements(tool.Name); pricing != nil {
+		if tool.Meta == nil {
+			tool.Meta = map[string]any{}
+		}
+		tool.Meta[MetaKeyServiceFee] = map[string]any{
+			"paymentRequired": pricing,
+			"metaKeys":        s.fees.PaymentMetaKeysFor(tool.Name),
+		}
+	}
+
+	// The SDK marshals and validates a typed Out even on error results, which
+	// would replace a payment error with an invalid zero output. Register the
+	// wrapped tool with an untyped output and keep Out's schema explicitly.
+	if tool.OutputSchema == nil && reflect.TypeFor[Out]() != reflect.TypeFor[any]() {
+		schema, err := jsonschema.ForType(reflect.TypeFor[Out](), &jsonschema.ForOptions{})
+		if err != nil {
+			panic(fmt.Sprintf("%s: output schema: %v", tool.Name, err))
+		}
+		tool.OutputSchema = schema
+	}
+	wrapped := x402local.WrapToolHandler(s.fees, tool.Name, handler)
+	mcp.AddTool(s.mcpServer, tool, func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, any, error) {
+		result, out, err := wrapped(ctx, req, input)
+		if err != nil || (result != nil && result.IsError) {
+			return result, nil, err
+		}
+		return result, out, nil
+	})
+}
+
+// serviceFeeTools lists the meta-tools that currently charge a fee.
+func (s *Server) serviceFeeTools() []string {
+	if s.fees == nil {
+		return nil
+	}
+	var priced []string
+	for _, name := range MetaToolNames {
+		if s.fees.GetPaymentRequirements(name) != nil {
+			priced = append(priced, name)
+		}
+	}
+	return priced
+}
@@ -0,0 +1,212 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newServiceFeeMiddleware prices search_resources and proxy_tool_call at a
+// small fee, settled by fake
+func newServiceFeeMiddleware(t *testing.T, fake *x402local.FakeFacilitator) *x402local.Middleware {
+	t.Helper()
+	fees := x402local.NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		x402local.Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		"http://facilitator.invalid",
+		x402local.WithFacilitator(fake),
+	)
+	for _, name := range []string{"search_resources", "proxy_tool_call"} {
+		if err := fees.SetToolPrice(name, "1000"); err != nil {
+			t.Fatalf("SetToolPrice(%s): %v", name, err)
+		}
+	}
+	return fees
+}
+
+// feePayment is a v2 payment for the 1000 unit service fee
+func feePayment() map[string]any {
+	return map[string]any{
+		"x402Version": 2,
+		"accepted": map[string]any{
+			"scheme":  "exact",
+			"network": "eip155:84532",
+			"amount":  "1000",
+			"asset":   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+			"payTo":   "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		},
+		"payload": map[string]any{"signature": "0xfee"},
+	}
+}
+
+// connectClient opens an in-memory client session to s.
+func connectClient(t *testing.T, s *Server) *sdkmcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	t.Cleanup(func() { clientSession.Close() })
+	return clientSession
+}
+
+func TestSearchResourcesRequiresServiceFee(t *testing.T) {
+	t.Parallel()
+
+	fake := x402local.NewFakeFacilitator()
+	s, err := NewServer(WithServiceFees(newServiceFeeMiddleware(t, fake)))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	session := connectClient(t, s)
+	ctx := context.Background()
+
+	listed, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	for _, tool := range listed.Tools {
+		_, charged := tool.Meta[MetaKeyServiceFee]
+		if want := tool.Name == "search_resources" || tool.Name == "proxy_tool_call"; charged != want {
+			t.Fatalf("%s: expected service fee meta %t, got %t", tool.Name, want, charged)
+		}
+	}
+
+	unpaid, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "search_resources", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !unpaid.IsError || unpaid.Meta[x402local.MetaKeyPaymentRequired] == nil {
+		t.Fatalf("expected search_resources to require payment, got %+v", unpaid)
+	}
+	if len(fake.Settled()) != 0 {
+		t.Fatal("expected nothing settled for an unpaid call")
+	}
+
+	paid, err := session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "search_resources",
+		Arguments: map[string]any{},
+		Meta:      sdkmcp.Meta{x402local.MetaKeyPayment: feePayment()},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if paid.IsError || paid.Meta[x402local.MetaKeyPaymentResponse] == nil {
+		t.Fatalf("expected a paid search to succeed with a settlement, got %+v", paid)
+	}
+	if settled := fake.Settled(); len(settled) != 1 {
+		t.Fatalf("expected the fee to settle once, got %d", len(settled))
+	}
+
+	// Unpriced meta-tools stay free
+	info, err := session.CallTool(ctx, &sdkmcp.CallToolParams{Name: "server_info", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if info.IsError {
+		t.Fatalf("expected server_info to stay free, got %+v", info)
+	}
+	var output ServerInfoOutput
+	raw, _ := json.Marshal(info.StructuredContent)
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("decode server_info: %v", err)
+	}
+	if len(output.Features.ServiceFees) != 2 {
+		t.Fatalf("expected two fee-charging meta-tools, got %v", output.Features.ServiceFees)
+	}
+}
+
+func TestProxyToolCallServiceFeeKeepsUpstreamPayment(t *testing.T) {
+	t.Parallel()
+
+	var upstreamPayments []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPayments = append(upstreamPayments, r.Header.Get("X-PAYMENT"))
+		settle, _ := json.Marshal(map[string]any{"success": true, "transaction": "0xupstream", "network": "base-sepolia"})
+		w.Header().Set("X-PAYMENT-RESPONSE", base64.StdEncoding.EncodeToString(settle))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	fake := x402local.NewFakeFacilitator()
+	s, err := NewServer(WithServiceFees(newServiceFeeMiddleware(t, fake)))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	s.SetEgressPolicy(EgressPolicy{AllowPrivate: true})
+	resource := testResource(upstream.URL+"/paid", "GET", nil)
+	s.resources = append(s.resources, resource)
+	session := connectClient(t, s)
+	ctx := context.Background()
+
+	upstreamPayment := map[string]any{
+		"x402Version": 1,
+		"scheme":      "exact",
+		"network":     "base-sepolia",
+		"payload":     map[string]any{"signature": "0xupstream"},
+	}
+	args := map[string]any{"toolName": toolNameFromResource(resource.Resource, "GET")}
+
+	// The upstream payment alone does not pay the fee
+	unpaid, err := session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "proxy_tool_call",
+		Arguments: args,
+		Meta:      sdkmcp.Meta{x402local.MetaKeyPayment: upstreamPayment},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if !unpaid.IsError || unpaid.Meta[MetaKeyServicePaymentRequired] == nil || unpaid.Meta[x402local.MetaKeyPaymentKey] != MetaKeyServicePayment {
+		t.Fatalf("expected the fee to be required under %s, got %+v", MetaKeyServicePayment, unpaid.Meta)
+	}
+	if len(upstreamPayments) != 0 {
+		t.Fatal("expected the upstream not to be called before the fee is paid")
+	}
+
+	paid, err := session.CallTool(ctx, &sdkmcp.CallToolParams{
+		Name:      "proxy_tool_call",
+		Arguments: args,
+		Meta: sdkmcp.Meta{
+			x402local.MetaKeyPayment: upstreamPayment,
+			MetaKeyServicePayment:    feePayment(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if paid.IsError {
+		t.Fatalf("expected the paid call to succeed, got %+v", paid.Content)
+	}
+	if len(upstreamPayments) != 1 || upstreamPayments[0] == "" {
+		t.Fatalf("expected the upstream payment to be forwarded, got %q", upstreamPayments)
+	}
+	upstreamSettle, _ := json.Marshal(paid.Meta[x402local.MetaKeyPaymentResponse])
+	var settle x402local.SettleResponse
+	if err := json.Unmarshal(upstreamSettle, &settle); err != nil || settle.Transaction != "0xupstream" {
+		t.Fatalf("expected the upstream settlement under %s, got %s", x402local.MetaKeyPaymentResponse, upstreamSettle)
+	}
+	if paid.Meta[MetaKeyServicePaymentResponse] == nil {
+		t.Fatalf("expected the fee settlement under %s, got %+v", MetaKeyServicePaymentResponse, paid.Meta)
+	}
+	if settled := fake.Settled(); len(settled) != 1 {
+		t.Fatalf("expected only the fee to settle with the server's facilitator, got %d", len(settled))
+	}
+}
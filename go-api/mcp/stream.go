@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxStreamDuration bounds how long an upstream event stream is relayed. It is
+// shorter than the proxy client timeout so collected events are returned
+// rather than lost to a client error.
+const maxStreamDuration = 20 * time.Second
+
+// streamEvent is one server-sent event relayed from the upstream.
+type streamEvent struct {
+	Event string `json:"event,omitempty"`
+	ID    string `json:"id,omitempty"`
+	Data  string `json:"data"`
+}
+
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// relayEventStream reads an upstream text/event-stream response event by
+// event. When the caller sent a progress token, each event is forwarded as a
+// progress notification as soon as it arrives. The final result lists every
+// event; it is marked truncated when the byte or time bound cut the stream.
+func relayEventStream(ctx context.Context, req *mcp.CallToolRequest, resp *http.Response, headers responseHeaderFilter, maxDuration time.Duration) (*mcp.CallToolResult, error) {
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(maxDuration, func() {
+		timedOut.Store(true)
+		resp.Body.Close()
+	})
+	defer timer.Stop()
+
+	notify := progressNotifier(ctx, req)
+	limited := &io.LimitedReader{R: resp.Body, N: maxProxyResponseBytes}
+	reader := bufio.NewReader(limited)
+
+	var (
+		events    []streamEvent
+		current   streamEvent
+		data      []string
+		truncated bool
+	)
+	dispatch := func() {
+		if len(data) > 0 {
+			current.Data = strings.Join(data, "\n")
+			events = append(events, current)
+			notify(len(events), current)
+		}
+		current, data = streamEvent{}, nil
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" && err == nil {
+			dispatch()
+			continue
+		}
+		if line != "" && !strings.HasPrefix(line, ":") {
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "data":
+				data = append(data, value)
+			case "event":
+				current.Event = value
+			case "id":
+				current.ID = value
+			}
+		}
+		if err != nil {
+			if limited.N <= 0 || timedOut.Load() {
+				truncated = true
+			} else if !errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("failed to read event stream: %w", err)
+			}
+			break
+		}
+	}
+	if !truncated {
+		dispatch()
+	}
+
+	payload := map[string]any{
+		"status":    resp.StatusCode,
+		"headers":   headers.apply(resp.Header),
+		"events":    events,
+		"truncated": truncated,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		payload["url"] = resp.Request.URL.String()
+	}
+	contentJSON, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal proxy response: %w", err)
+	}
+
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(contentJSON),
+			},
+		},
+	}
+	if paymentResponse := decodePaymentResponse(resp); paymentResponse != nil {
+		result.Meta = map[string]any{
+			"x402/payment-response": paymentResponse,
+		}
+	}
+	return result, nil
+}
+
+// progressNotifier returns a callback that sends each event to the caller as
+// a progress notification, or a no-op when the request has no progress token.
+func progressNotifier(ctx context.Context, req *mcp.CallToolRequest) func(int, streamEvent) {
+	if req == nil || req.Session == nil || req.Params == nil {
+		return func(int, streamEvent) {}
+	}
+	token := req.Params.GetProgressToken()
+	if token == nil {
+		return func(int, streamEvent) {}
+	}
+	return func(n int, event streamEvent) {
+		// Notification failures must not abort the relay
+		_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      float64(n),
+			Message:       event.Data,
+		})
+	}
+}
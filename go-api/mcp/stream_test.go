@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func sseServer(t *testing.T, events []string, hang bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		flusher := w.(http.Flusher)
+		for i, data := range events {
+			fmt.Fprintf(w, ": keep-alive\nid: %d\nevent: token\ndata: %s\n\n", i+1, data)
+			flusher.Flush()
+		}
+		if hang {
+			<-r.Context().Done()
+		}
+	}))
+}
+
+func decodeStreamPayload(t *testing.T, result *sdkmcp.CallToolResult) (events []streamEvent, truncated bool) {
+	t.Helper()
+	var payload struct {
+		Events    []streamEvent `json:"events"`
+		Truncated bool          `json:"truncated"`
+	}
+	text := result.Content[0].(*sdkmcp.TextContent).Text
+	if err := json.Unmarshal([]byte(text), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	return payload.Events, payload.Truncated
+}
+
+func TestProxyToolCallRelaysEventStreamAsProgress(t *testing.T) {
+	t.Parallel()
+
+	upstream := sseServer(t, []string{"Hel", "lo", "!"}, false)
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/stream", "GET", nil)
+	s := &Server{
+		mcpServer: sdkmcp.NewServer(&sdkmcp.Implementation{Name: "test", Version: "1.0.0"}, nil),
+		resources: []X402DiscoveryResource{resource},
+	}
+	s.registerTools()
+
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	progress := make(chan string, 10)
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, &sdkmcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *sdkmcp.ProgressNotificationClientRequest) {
+			progress <- req.Params.Message
+		},
+	})
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.CallTool(ctx, &sdkmcp.CallToolParams{
+		Meta:      sdkmcp.Meta{"progressToken": "stream-1"},
+		Name:      "proxy_tool_call",
+		Arguments: map[string]any{"toolName": toolNameFromResource(resource.Resource, "GET")},
+	})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("unexpected error result: %+v", result.Content)
+	}
+
+	events, truncated := decodeStreamPayload(t, result)
+	if truncated || len(events) != 3 {
+		t.Fatalf("expected 3 complete events, got %+v (truncated=%v)", events, truncated)
+	}
+	if events[0] != (streamEvent{Event: "token", ID: "1", Data: "Hel"}) {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+
+	for _, want := range []string{"Hel", "lo", "!"} {
+		select {
+		case got := <-progress:
+			if got != want {
+				t.Fatalf("expected progress %q, got %q", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for progress %q", want)
+		}
+	}
+}
+
+func TestRelayEventStreamStopsAtDuration(t *testing.T) {
+	t.Parallel()
+
+	upstream := sseServer(t, []string{"first"}, true)
+	defer upstream.Close()
+
+	resp, err := http.Get(upstream.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result, err := relayEventStream(context.Background(), nil, resp, responseHeaderFilter{}, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("relayEventStream: %v", err)
+	}
+	events, truncated := decodeStreamPayload(t, result)
+	if !truncated || len(events) != 1 || events[0].Data != "first" {
+		t.Fatalf("expected one event and truncation, got %+v (truncated=%v)", events, truncated)
+	}
+}
@@ -0,0 +1,61 @@
+package mcp
+
+// ToolNamer names the MCP tool that proxies a discovered resource. Names must
+// be unique across the discovered resources and valid MCP tool names.
+type ToolNamer interface {
+	// ToolName returns the tool name for resource. method is the HTTP method
+	// the resource declares, or "" when it declares none.
+	ToolName(resource X402DiscoveryResource, method string) string
+}
+
+// ToolNameResolver is an optional ToolNamer extension that maps a tool name
+// back to its resource, e.g. through a lookup table. Without it a name is
+// resolved by naming every resource until one matches.
+type ToolNameResolver interface {
+	// ResolveToolName returns the resource among resources named name, or
+	// false if none is.
+	ResolveToolName(resources []X402DiscoveryResource, name string) (*X402DiscoveryResource, bool)
+}
+
+// ToolNamerFunc adapts a function to ToolNamer.
+type ToolNamerFunc func(resource X402DiscoveryResource, method string) string
+
+// ToolName implements ToolNamer.
+func (f ToolNamerFunc) ToolName(resource X402DiscoveryResource, method string) string {
+	return f(resource, method)
+}
+
+// HashToolNamer is the default ToolNamer. It produces
+// x402_<method>_<url slug>_<hash>, where the hash covers the method and the
+// full resource URL so distinct resources never share a name.
+type HashToolNamer struct{}
+
+// ToolName implements ToolNamer.
+func (HashToolNamer) ToolName(resource X402DiscoveryResource, method string) string {
+	return toolNameFromResource(resource.Resource, method)
+}
+
+// WithToolNamer names discovered tools with namer instead of HashToolNamer,
+// e.g. to give clients short names that survive small resource URL changes.
+// A nil namer keeps the default.
+func WithToolNamer(namer ToolNamer) ServerOption {
+	return func(s *Server) {
+		s.namer = namer
+	}
+}
+
+// toolNamer returns the configured namer, defaulting to HashToolNamer.
+func (s *Server) toolNamer() ToolNamer {
+	if s == nil || s.namer == nil {
+		return HashToolNamer{}
+	}
+	return s.namer
+}
+
+// resourceToolName names resource with namer, or HashToolNamer when nil.
+func resourceToolName(namer ToolNamer, resource X402DiscoveryResource) string {
+	if namer == nil {
+		namer = HashToolNamer{}
+	}
+	return namer.ToolName(resource, declaredMethod(resource))
+}
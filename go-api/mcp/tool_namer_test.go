@@ -0,0 +1,110 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pathToolNamer names a tool after the last segment of its resource path, so
+// the name survives host and query changes
+var pathToolNamer = ToolNamerFunc(func(resource X402DiscoveryResource, method string) string {
+	parsed, err := url.Parse(resource.Resource)
+	if err != nil {
+		return "resource"
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	return strings.ToLower(method) + "_" + path.Base(parsed.Path)
+})
+
+// tableToolNamer resolves names through a fixed table instead of renaming
+// every resource
+type tableToolNamer struct {
+	names map[string]string // resource URL to tool name
+}
+
+func (n tableToolNamer) ToolName(resource X402DiscoveryResource, method string) string {
+	return n.names[resource.Resource]
+}
+
+func (n tableToolNamer) ResolveToolName(resources []X402DiscoveryResource, name string) (*X402DiscoveryResource, bool) {
+	for i := range resources {
+		if n.names[resources[i].Resource] == name {
+			return &resources[i], true
+		}
+	}
+	return nil, false
+}
+
+func TestToolNamerStableNamesResolveBack(t *testing.T) {
+	t.Parallel()
+
+	weather := testResource("http://localhost:8080/weather", "GET", nil)
+	moved := testResource("https://api.example.com/v2/weather?units=metric", "GET", nil)
+	if a, b := resourceToolName(pathToolNamer, weather), resourceToolName(pathToolNamer, moved); a != "get_weather" || a != b {
+		t.Fatalf("expected a stable name across URL changes, got %q and %q", a, b)
+	}
+	if resourceToolName(nil, weather) != toolNameFromResource(weather.Resource, "GET") {
+		t.Fatal("expected a nil namer to use HashToolNamer")
+	}
+
+	alerts := testResource("http://localhost:8080/alerts", "POST", nil)
+	resources := []X402DiscoveryResource{weather, alerts}
+	table := tableToolNamer{names: map[string]string{weather.Resource: "weather", alerts.Resource: "alerts"}}
+	for _, namer := range []ToolNamer{pathToolNamer, table} {
+		for _, want := range resources {
+			name := resourceToTool(want, namer, ToolOverride{}, nil).Name
+			got, err := findResourceForToolName(resources, name, namer)
+			if err != nil {
+				t.Fatalf("%T: resolve %q: %v", namer, name, err)
+			}
+			if got.Resource != want.Resource {
+				t.Fatalf("%T: expected %q to resolve to %s, got %s", namer, name, want.Resource, got.Resource)
+			}
+		}
+		if _, err := findResourceForToolName(resources, toolNameFromResource(weather.Resource, "GET"), namer); !errors.Is(err, ErrToolNotFound) {
+			t.Fatalf("%T: expected the default name not to resolve, got %v", namer, err)
+		}
+	}
+}
+
+func TestServerUsesToolNamer(t *testing.T) {
+	t.Parallel()
+
+	free := testResource("http://localhost:8080/forecast", "GET", nil)
+	free.Accepts = nil
+	s, err := NewServer(WithToolNamer(pathToolNamer), WithDirectTools(10))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	s.resources = append(s.resources, free)
+
+	if names := listToolNames(t, s); !slices.Contains(names, "get_weather") {
+		t.Fatalf("expected the direct tool to use the custom name, got %v", names)
+	}
+
+	_, listed, err := s.ListToolNames(context.Background(), nil, &ListToolNamesParams{})
+	if err != nil {
+		t.Fatalf("ListToolNames error: %v", err)
+	}
+	if !slices.Contains(listed.Names, "get_forecast") {
+		t.Fatalf("expected list_tool_names to use the custom name, got %v", listed.Names)
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), &sdkmcp.CallToolRequest{}, &ProxyToolCallParams{ToolName: "get_forecast", DryRun: true})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected the custom name to resolve for proxy_tool_call, got %+v", result.Content)
+	}
+}
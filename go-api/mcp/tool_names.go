@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListToolNamesParams defines the input parameters for the list_tool_names tool.
+type ListToolNamesParams struct {
+	SearchQuery string `json:"searchQuery,omitempty" jsonschema:"Search string for filtering resources"`
+	Network     string `json:"network,omitempty"     jsonschema:"Only tools payable on this network (CAIP-2 id or legacy name)"`
+	Asset       string `json:"asset,omitempty"       jsonschema:"Only tools payable in this asset (address or symbol such as USDC)"`
+}
+
+// ToolNameEntry identifies one discovered tool without its schema or meta.
+type ToolNameEntry struct {
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	Resource string `json:"resource"`
+}
+
+// ListToolNamesOutput defines the structured output for the list_tool_names tool.
+type ListToolNamesOutput struct {
+	Names []string        `json:"names"`
+	Tools []ToolNameEntry `json:"tools"`
+}
+
+// ListToolNames returns the names, methods and resource URLs of the tools
+// search_resources would return for the same filters, without schemas or
+// pricing meta and without pagination.
+func (s *Server) ListToolNames(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	params *ListToolNamesParams,
+) (*mcp.CallToolResult, ListToolNamesOutput, error) {
+	resources := filterDiscoveryResources(s.searchableResources(), params.SearchQuery)
+	resources = filterByPayment(resources, params.Network, params.Asset, s.assets)
+
+	output := ListToolNamesOutput{
+		Names: make([]string, 0, len(resources)),
+		Tools: make([]ToolNameEntry, 0, len(resources)),
+	}
+	for _, resource := range resources {
+		if strings.ToLower(resource.Type) != "http" {
+			continue
+		}
+		method := declaredMethod(resource)
+		name := s.toolNamer().ToolName(resource, method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		output.Names = append(output.Names, name)
+		output.Tools = append(output.Tools, ToolNameEntry{
+			Name:     name,
+			Method:   strings.ToUpper(method),
+			Resource: resource.Resource,
+		})
+	}
+	return nil, output, nil
+}
+
+// searchableResources returns the resources search_resources may list, after
+// the price cap, method allowlist and free-tool filters.
+func (s *Server) searchableResources() []X402DiscoveryResource {
+	return s.filterFree(s.filterDisallowedMethods(s.filterPriceCapped(s.resources)))
+}
+
+// filterByPayment keeps resources with at least one payment option on network
+// in asset. Empty filters match anything; free resources match only when both
+// are empty.
+func filterByPayment(resources []X402DiscoveryResource, network, asset string, assets assetRegistry) []X402DiscoveryResource {
+	network, asset = strings.TrimSpace(network), strings.TrimSpace(asset)
+	if network == "" && asset == "" {
+		return resources
+	}
+	kept := make([]X402DiscoveryResource, 0, len(resources))
+	for _, resource := range resources {
+		if resource.Accepts == nil {
+			continue
+		}
+		for _, requirement := range *resource.Accepts {
+			if (network == "" || sameNetwork(requirement.Network, network)) &&
+				(asset == "" || sameAsset(requirement, asset, assets)) {
+				kept = append(kept, resource)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// sameNetwork compares networks by their CAIP-2 form, so base-sepolia
+// matches eip155:84532.
+func sameNetwork(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	normalizedA, errA := x402local.NormalizeNetwork(a)
+	normalizedB, errB := x402local.NormalizeNetwork(b)
+	return errA == nil && errB == nil && normalizedA == normalizedB
+}
+
+// sameAsset matches the requirement's asset address, or its symbol when the
+// asset is known.
+func sameAsset(requirement X402PaymentRequirements, asset string, assets assetRegistry) bool {
+	if strings.EqualFold(requirement.Asset, asset) {
+		return true
+	}
+	info, ok := assets.lookup(requirement)
+	return ok && strings.EqualFold(info.Symbol, asset)
+}
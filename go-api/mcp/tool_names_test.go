@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestListToolNamesMatchesSearchResources(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	limit := DefaultMaxSearchResults
+	_, search, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{Limit: &limit})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	_, listed, err := s.ListToolNames(context.Background(), nil, &ListToolNamesParams{})
+	if err != nil {
+		t.Fatalf("ListToolNames error: %v", err)
+	}
+
+	want := make([]string, 0, len(search.Tools))
+	for _, tool := range search.Tools {
+		want = append(want, tool.Name)
+	}
+	if len(want) == 0 || !slices.Equal(listed.Names, want) {
+		t.Fatalf("expected names %v, got %v", want, listed.Names)
+	}
+	for i, entry := range listed.Tools {
+		if entry.Name != listed.Names[i] || entry.Method == "" || entry.Resource == "" {
+			t.Fatalf("unexpected entry %+v", entry)
+		}
+	}
+}
+
+func TestListToolNamesFilters(t *testing.T) {
+	t.Parallel()
+
+	base := testResource("http://localhost:8080/weather", "GET", nil)
+	solana := testResource("http://localhost:8080/weather/forecast", "POST", nil)
+	(*solana.Accepts)[0].Network = "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1"
+	(*solana.Accepts)[0].Asset = "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"
+	s := &Server{resources: []X402DiscoveryResource{base, solana}}
+
+	tests := []struct {
+		name   string
+		params ListToolNamesParams
+		want   []string
+	}{
+		{name: "no filters", want: []string{"/weather", "/weather/forecast"}},
+		{name: "CAIP-2 network matches legacy name", params: ListToolNamesParams{Network: "eip155:84532"}, want: []string{"/weather"}},
+		{name: "asset address", params: ListToolNamesParams{Asset: "4zMMC9srt5Ri5X14GAgXhaHii3GnPAEERYPJgZJDncDU"}, want: []string{"/weather/forecast"}},
+		{name: "asset symbol", params: ListToolNamesParams{Network: "base-sepolia", Asset: "usdc"}, want: []string{"/weather"}},
+		{name: "no match", params: ListToolNamesParams{Network: "eip155:8453"}, want: []string{}},
+		{name: "search query", params: ListToolNamesParams{SearchQuery: "forecast"}, want: []string{"/weather/forecast"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, output, err := s.ListToolNames(context.Background(), nil, &tt.params)
+			if err != nil {
+				t.Fatalf("ListToolNames error: %v", err)
+			}
+			got := []string{}
+			for _, entry := range output.Tools {
+				got = append(got, entry.Resource[len("http://localhost:8080"):])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+
+			// search_resources applies the same filters
+			_, search, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{
+				SearchQuery: tt.params.SearchQuery,
+				Network:     tt.params.Network,
+				Asset:       tt.params.Asset,
+			})
+			if err != nil {
+				t.Fatalf("SearchResources error: %v", err)
+			}
+			if len(search.Tools) != len(output.Names) {
+				t.Fatalf("expected search_resources to return %d tools, got %d", len(output.Names), len(search.Tools))
+			}
+		})
+	}
+}
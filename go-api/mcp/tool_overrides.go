@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolOverride curates how the tool for one discovered resource is presented.
+// Empty fields keep the values derived from the resource.
+type ToolOverride struct {
+	// Title sets Tool.Title.
+	Title string `json:"title,omitempty"`
+	// Description replaces the description derived from accepts or metadata.
+	Description string `json:"description,omitempty"`
+}
+
+// WithToolOverrides sets curated titles and descriptions for discovered tools,
+// keyed by resource URL. Later calls add to or replace earlier entries.
+func WithToolOverrides(overrides map[string]ToolOverride) ServerOption {
+	return func(s *Server) {
+		if s.toolOverrides == nil {
+			s.toolOverrides = make(map[string]ToolOverride, len(overrides))
+		}
+		for resourceURL, override := range overrides {
+			s.toolOverrides[resourceURL] = ToolOverride{
+				Title:       strings.TrimSpace(override.Title),
+				Description: strings.TrimSpace(override.Description),
+			}
+		}
+	}
+}
+
+// LoadToolOverrides reads a side config mapping resource URLs to a title and
+// description, for WithToolOverrides. Files ending in .yaml or .yml are
+// parsed as YAML, anything else as JSON.
+func LoadToolOverrides(path string) (map[string]ToolOverride, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tool overrides: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse tool overrides %s: %w", path, err)
+		}
+	}
+	var overrides map[string]ToolOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("parse tool overrides %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+// resourceTool returns the tool for resource with the server's namer, any
+// override configured for it and its recommended payment option.
+func (s *Server) resourceTool(resource X402DiscoveryResource) *mcp.Tool {
+	tool := resourceToTool(resource, s.toolNamer(), s.toolOverrides[resource.Resource], s.assets)
+	if tool != nil {
+		s.markRecommendedOption(resource, tool.Meta)
+	}
+	return tool
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolOverrideSetsTitleAndDescription(t *testing.T) {
+	t.Parallel()
+
+	const weatherURL = "http://localhost:8080/weather"
+	s, err := NewServer(WithToolOverrides(map[string]ToolOverride{
+		weatherURL: {Title: "City Weather", Description: " Current temperature and conditions for a US city. "},
+	}))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+
+	_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	var weather, other *sdkmcp.Tool
+	for _, tool := range output.Tools {
+		if tool.Name == toolNameFromResource(weatherURL, "GET") {
+			weather = tool
+		} else if other == nil {
+			other = tool
+		}
+	}
+	if weather == nil || other == nil {
+		t.Fatalf("expected the weather tool and at least one other, got %d tools", len(output.Tools))
+	}
+	if weather.Title != "City Weather" {
+		t.Fatalf("expected the override title, got %q", weather.Title)
+	}
+	if !strings.HasPrefix(weather.Description, "Current temperature and conditions for a US city. Use proxy_tool_call") {
+		t.Fatalf("expected the override description, got %q", weather.Description)
+	}
+	resourceMeta := weather.Meta["x402/payment-required"].(map[string]any)["resource"].(map[string]any)
+	if resourceMeta["description"] != weather.Description {
+		t.Fatalf("expected the pricing meta to carry the override description, got %v", resourceMeta["description"])
+	}
+	if other.Title != "" || strings.Contains(other.Description, "US city") {
+		t.Fatalf("expected other tools to keep derived values, got %q / %q", other.Title, other.Description)
+	}
+
+	result, _, err := s.GetTool(context.Background(), nil, &GetToolParams{ToolName: weather.Name})
+	if err != nil {
+		t.Fatalf("GetTool error: %v", err)
+	}
+	if got := result.StructuredContent.(*sdkmcp.Tool); got.Title != "City Weather" {
+		t.Fatalf("expected get_tool to use the override, got %q", got.Title)
+	}
+}
+
+func TestLoadToolOverrides(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "overrides.yaml")
+	content := "\"https://api.example.com/weather\":\n  title: Weather\n  description: Forecasts by city\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write overrides: %v", err)
+	}
+	overrides, err := LoadToolOverrides(path)
+	if err != nil {
+		t.Fatalf("LoadToolOverrides error: %v", err)
+	}
+	if got := overrides["https://api.example.com/weather"]; got.Title != "Weather" || got.Description != "Forecasts by city" {
+		t.Fatalf("unexpected overrides %+v", overrides)
+	}
+
+	bad := filepath.Join(dir, "overrides.json")
+	if err := os.WriteFile(bad, []byte(`["not", "a", "map"]`), 0o600); err != nil {
+		t.Fatalf("write overrides: %v", err)
+	}
+	if _, err := LoadToolOverrides(bad); err == nil || !strings.Contains(err.Error(), "parse tool overrides") {
+		t.Fatalf("expected a parse error, got %v", err)
+	}
+}
@@ -3,15 +3,20 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 // registerTools registers all MCP tools for x402 discovery.
 func (s *Server) registerTools() {
-	mcp.AddTool(s.mcpServer, &mcp.Tool{
+	addMetaTool(s, &mcp.Tool{
 		Name:        "search_resources",
 		Title:       "Search x402 Tools",
 		Description: "Discover additional x402 tools you can use. Use searchQuery to filter by text. After discovery, execute a returned tool via proxy_tool_call with a payment attached in meta x402/payment.",
@@ -25,7 +30,7 @@ func (s *Server) registerTools() {
 	}, s.SearchResources)
 
 	// Register proxy_tool_call tool
-	mcp.AddTool(s.mcpServer, &mcp.Tool{
+	addMetaTool(s, &mcp.Tool{
 		Name:        "proxy_tool_call",
 		Title:       "Execute x402 Tool",
 		Description: "Executes a discovered x402 tool. Provide toolName and parameters. Use search_resources to discover available tools.",
@@ -36,6 +41,86 @@ func (s *Server) registerTools() {
 			},
 		},
 	}, s.ProxyToolCall)
+
+	addMetaTool(s, &mcp.Tool{
+		Name:        "get_tool",
+		Title:       "Inspect x402 Tool",
+		Description: "Returns one discovered x402 tool by name, including its input schema and pricing meta, without paging through search_resources.",
+		Meta: map[string]any{
+			"x402/usage": map[string]any{
+				"step": "discover",
+				"next": "proxy_tool_call",
+			},
+		},
+	}, s.GetTool)
+
+	addMetaTool(s, &mcp.Tool{
+		Name:        "list_tool_names",
+		Title:       "List x402 Tool Names",
+		Description: "Lists the names, HTTP methods and resource URLs of discovered x402 tools, without schemas or pricing. Accepts the same searchQuery, network and asset filters as search_resources.",
+		Meta: map[string]any{
+			"x402/usage": map[string]any{
+				"step": "discover",
+				"next": "get_tool",
+			},
+		},
+	}, s.ListToolNames)
+
+	addMetaTool(s, &mcp.Tool{
+		Name:        "recall_tools",
+		Title:       "Recall x402 Tools",
+		Description: "Returns a previous search_resources result again by its recallToken, without searching. Tokens are valid for the same session until they expire.",
+		Meta: map[string]any{
+			"x402/usage": map[string]any{
+				"step": "discover",
+				"next": "proxy_tool_call",
+			},
+		},
+	}, s.RecallTools)
+
+	addMetaTool(s, &mcp.Tool{
+		Name:        "server_info",
+		Title:       "x402 Server Info",
+		Description: "Reports this server's version, the x402 versions and payment networks of its discovered tools, and its enabled features.",
+	}, s.ServerInfo)
+
+	s.registerDirectTools()
+}
+
+// registerDirectTools exposes discovered resources as first-class MCP tools,
+// up to the configured limit. Each tool proxies through ProxyToolCall.
+func (s *Server) registerDirectTools() {
+	registered := 0
+	for _, resource := range s.resources {
+		if registered >= s.directToolLimit {
+			return
+		}
+		tool := s.resourceTool(resource)
+		if tool == nil || !s.withinPriceCap(resource) || !s.resourceMethodAllowed(resource) || (s.excludeFree && isFreeResource(resource)) {
+			continue
+		}
+		toolName := tool.Name
+		tool.Description = strings.Replace(
+			tool.Description,
+			"Use proxy_tool_call with payment to execute.",
+			"Attach payment in meta x402/payment to execute.",
+			1,
+		)
+		tool.Meta["x402/call-with"] = map[string]any{
+			"tool": toolName,
+		}
+		mcp.AddTool(s.mcpServer, tool, func(
+			ctx context.Context,
+			req *mcp.CallToolRequest,
+			input *DirectToolParams,
+		) (*mcp.CallToolResult, any, error) {
+			return s.ProxyToolCall(ctx, req, &ProxyToolCallParams{
+				ToolName:   toolName,
+				Parameters: input.Parameters,
+			})
+		})
+		registered++
+	}
 }
 
 // SearchResourcesParams defines parameters for the search_resources tool.
@@ -46,6 +131,10 @@ type SearchResourcesParams struct {
 	Limit *int `json:"limit,omitempty"       jsonschema:"Optional pagination limit"`
 	// Offset optional pagination offset.
 	Offset *int `json:"offset,omitempty"      jsonschema:"Optional pagination offset"`
+	// Network keeps only tools payable on this network.
+	Network string `json:"network,omitempty"     jsonschema:"Only tools payable on this network (CAIP-2 id or legacy name)"`
+	// Asset keeps only tools payable in this asset.
+	Asset string `json:"asset,omitempty"       jsonschema:"Only tools payable in this asset (address or symbol such as USDC)"`
 }
 
 // SearchResourcesPagination defines pagination for the search_resources tool output.
@@ -53,21 +142,56 @@ type SearchResourcesPagination struct {
 	Limit  *int `json:"limit,omitempty"`
 	Offset *int `json:"offset,omitempty"`
 	Total  *int `json:"total,omitempty"`
+	// Returned is the number of tools in this page.
+	Returned *int `json:"returned,omitempty"`
+	// HasMore reports whether items remain after this page.
+	HasMore *bool `json:"hasMore,omitempty"`
+	// LimitClamped is set when the requested limit exceeded the server cap.
+	LimitClamped bool `json:"limitClamped,omitempty"`
 }
 
 // SearchResourcesOutput defines the structured output for the search_resources tool.
 type SearchResourcesOutput struct {
-	Pagination  SearchResourcesPagination `json:"pagination"`
-	X402Version int                       `json:"x402Version"`
-	Tools       []*mcp.Tool               `json:"tools,omitempty"`
+	Pagination SearchResourcesPagination `json:"pagination"`
+	// X402Version is the highest x402 version the server can proxy payments
+	// for. It does not depend on the results; each tool's
+	// x402/payment-required meta carries its resource's own version.
+	X402Version int `json:"x402Version"`
+	// X402Versions lists every x402 version the server can proxy payments for.
+	X402Versions []int       `json:"x402Versions"`
+	Tools        []*mcp.Tool `json:"tools,omitempty"`
+	// RecallToken returns this result again through recall_tools, within the
+	// same session, until it expires.
+	RecallToken string `json:"recallToken,omitempty"`
+}
+
+// SupportedX402Versions are the x402 versions proxy_tool_call accepts payment
+// meta for, in ascending order.
+var SupportedX402Versions = []int{1, x402local.X402Version}
+
+// GetToolParams defines parameters for the get_tool tool.
+type GetToolParams struct {
+	// ToolName is the name of the discovered tool to describe.
+	ToolName string `json:"toolName" jsonschema:"Tool name returned by search_resources,required"`
 }
 
 // ProxyToolCallParams defines parameters for the proxy_tool_call tool.
 type ProxyToolCallParams struct {
 	// ToolName is the name of the tool to proxy.
-	ToolName string `json:"toolName"             jsonschema:"Tool name to proxy,required"`
+	ToolName string `json:"toolName"              jsonschema:"Tool name to proxy,required"`
 	// Parameters is the input for the proxied tool call.
-	Parameters map[string]any `json:"parameters,omitempty" jsonschema:"Tool parameters for the proxied call"`
+	Parameters map[string]any `json:"parameters,omitempty"  jsonschema:"Tool parameters for the proxied call"`
+	// DryRun returns a preview of the HTTP request instead of sending it.
+	DryRun bool `json:"dryRun,omitempty"      jsonschema:"Preview the HTTP request without sending it or paying"`
+	// BearerToken is forwarded as an OAuth Authorization: Bearer header,
+	// separately from the x402 payment.
+	BearerToken string `json:"bearerToken,omitempty" jsonschema:"OAuth bearer token the upstream requires besides payment, sent as Authorization: Bearer"`
+}
+
+// DirectToolParams defines parameters for a discovered tool registered directly.
+type DirectToolParams struct {
+	// Parameters is the input for the proxied HTTP call.
+	Parameters map[string]any `json:"parameters,omitempty"`
 }
 
 // SearchResources returns a static list of resources matching the search query.
@@ -78,25 +202,50 @@ func (s *Server) SearchResources(
 	params *SearchResourcesParams,
 ) (*mcp.CallToolResult, SearchResourcesOutput, error) {
 	query := params.SearchQuery
-	resources := filterWeatherResources(s.resources)
-	filtered := filterDiscoveryResources(resources, query)
-	paged, pagination := paginateResources(filtered, params.Limit, params.Offset)
+	filtered := filterDiscoveryResources(s.searchableResources(), query)
+	filtered = filterByPayment(filtered, params.Network, params.Asset, s.assets)
+	paged, pagination := paginateResources(filtered, params.Limit, params.Offset, s.searchResultCap())
 	tools := make([]*mcp.Tool, 0, len(paged))
 	for _, resource := range paged {
-		if tool := resourceToTool(resource); tool != nil {
+		if tool := s.resourceTool(resource); tool != nil {
 			tools = append(tools, tool)
 		}
 	}
-	x402Version := 1
-	if len(filtered) > 0 {
-		x402Version = filtered[0].X402Version
+	output := SearchResourcesOutput{
+		Pagination:   pagination,
+		X402Version:  slices.Max(SupportedX402Versions),
+		X402Versions: slices.Clone(SupportedX402Versions),
+		Tools:        tools,
 	}
+	s.rememberSearch(req, &output)
+	return nil, output, nil
+}
 
-	return nil, SearchResourcesOutput{
-		Pagination:  pagination,
-		X402Version: x402Version,
-		Tools:       tools,
-	}, nil
+// GetTool returns the discovered tool named by params.ToolName, as
+// search_resources would list it. Tools search_resources hides are not found.
+func (s *Server) GetTool(
+	ctx context.Context,
+	req *mcp.CallToolRequest,
+	params *GetToolParams,
+) (*mcp.CallToolResult, any, error) {
+	resource, err := findResourceForToolName(s.searchableResources(), params.ToolName, s.toolNamer())
+	if err != nil {
+		return toolNotFoundResult(params.ToolName, err), nil, nil
+	}
+	tool := s.resourceTool(*resource)
+
+	contentJSON, err := json.MarshalIndent(tool, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal tool %s: %w", params.ToolName, err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(contentJSON),
+			},
+		},
+		StructuredContent: tool,
+	}, nil, nil
 }
 
 // ProxyToolCall proxies a call to an HTTP x402 resource and returns an MCP response.
@@ -116,12 +265,41 @@ func (s *Server) ProxyToolCall(
 		}, nil, nil
 	}
 
+	metrics := s.metricsSink()
+	metrics.ToolCalled(params.ToolName)
+
+	ctx = x402local.WithRequestID(ctx, requestIDFor(ctx, req))
+	requestID := x402local.RequestIDFromContext(ctx)
+
+	ctx, cancel := x402local.WithCallBudget(ctx, s.callBudget)
+	defer cancel()
+
+	resource, err := findResourceForToolName(s.resources, params.ToolName, s.toolNamer())
+	if err != nil {
+		return toolNotFoundResult(params.ToolName, err), nil, nil
+	}
+	if !s.withinPriceCap(*resource) {
+		return priceExceedsCapResult(params.ToolName, *resource), nil, nil
+	}
+
+	if problems := validateProxyParameters(*resource, params.Parameters); len(problems) > 0 {
+		return invalidParametersResult(params.ToolName, problems), nil, nil
+	}
+	if err := s.checkHostOverride(*resource, requestedHost(params.Parameters)); err != nil {
+		return invalidParametersResult(params.ToolName, []string{fmt.Sprintf("parameters.host: %v", err)}), nil, nil
+	}
+	if err := s.checkBearerToken(*resource, params.BearerToken, params.Parameters); err != nil {
+		return invalidParametersResult(params.ToolName, []string{fmt.Sprintf("bearerToken: %v", err)}), nil, nil
+	}
+
 	parameters := params.Parameters
 	if req != nil && req.Params != nil {
 		if meta := req.Params.GetMeta(); meta != nil {
 			if payment, ok := meta["x402/payment"]; ok && payment != nil {
-				var err error
-				parameters, err = injectPaymentSignature(parameters, payment)
+				if !s.paymentWithinCap(*resource, payment) {
+					return priceExceedsCapResult(params.ToolName, *resource), nil, nil
+				}
+				parameters, err = injectPaymentSignature(parameters, payment, s.headerPolicy, s.paymentHeaderNameFor(*resource))
 				if err != nil {
 					return &mcp.CallToolResult{
 						Content: []mcp.Content{
@@ -136,40 +314,215 @@ func (s *Server) ProxyToolCall(
 		}
 	}
 
-	resource, err := findResourceForToolName(s.resources, params.ToolName)
+	httpReq, err := proxyToolCallToHTTPRequest(ctx, *resource, parameters, s.logSink())
 	if err != nil {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{
-					Text: err.Error(),
-				},
-			},
-			IsError: true,
-		}, nil, nil
+		return nil, nil, fmt.Errorf("failed to build proxy request: %w", err)
+	}
+	if !s.methodAllowed(httpReq.Method) {
+		return methodNotAllowedResult(params.ToolName, httpReq.Method), nil, nil
+	}
+	s.applyBearerToken(httpReq, *resource, params.BearerToken)
+	s.applyProxyHeaders(httpReq, *resource)
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", s.userAgentHeader())
 	}
 
-	httpReq, err := proxyToolCallToHTTPRequest(ctx, *resource, parameters)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to build proxy request: %w", err)
+	if params.DryRun {
+		result, _, err := previewHTTPRequest(httpReq, slices.Concat(s.headersToRedact(), s.bearerHeaderNames(params.BearerToken)))
+		if err != nil {
+			return nil, nil, err
+		}
+		return attachRequestID(result, requestID), nil, nil
 	}
 
-	httpResp, err := defaultHTTPClient.Do(httpReq)
+	if err := s.egress.check(ctx, httpReq.URL); err != nil {
+		s.logSink().Warn("proxy: egress blocked", "tool", params.ToolName, "requestId", requestID, "err", err)
+		return egressBlockedResult(err), nil, nil
+	}
+
+	if err := x402local.SpendAttempt(ctx); err != nil {
+		return callBudgetExhaustedResult(err), nil, nil
+	}
+	started := time.Now()
+	stopProgress := s.notifyUpstreamWait(ctx, req, params.ToolName)
+	httpResp, err := s.proxyHTTPClient().Do(httpReq)
+	stopProgress()
 	if err != nil {
-		return nil, nil, fmt.Errorf("proxy request failed: %w", err)
+		elapsed := time.Since(started)
+		metrics.ProxyLatency(params.ToolName, 0, elapsed)
+		if err := x402local.BudgetError(ctx, err); errors.Is(err, x402local.ErrCallBudgetExhausted) {
+			result := attachProxyTiming(callBudgetExhaustedResult(err), 0, elapsed, httpReq.URL.String())
+			return attachRequestID(result, requestID), nil, nil
+		}
+		result := upstreamFailedResult(params.ToolName, err)
+		if errors.Is(err, ErrEgressBlocked) {
+			// The host re-resolved to a blocked address between check and dial
+			s.logSink().Warn("proxy: egress blocked", "tool", params.ToolName, "requestId", requestID, "err", err)
+			result = egressBlockedResult(err)
+		} else {
+			s.logSink().Warn("proxy: upstream request failed", "tool", params.ToolName, "requestId", requestID, "err", err)
+		}
+		result = attachProxyTiming(result, 0, elapsed, httpReq.URL.String())
+		return attachRequestID(result, requestID), nil, nil
 	}
 	defer httpResp.Body.Close()
+	metrics.ProxyLatency(params.ToolName, httpResp.StatusCode, time.Since(started))
 
-	result, err := httpResponseToMCPResult(httpResp)
+	var result *mcp.CallToolResult
+	if isEventStream(httpResp) && httpResp.StatusCode < http.StatusBadRequest {
+		result, err = relayEventStream(ctx, req, httpResp, s.responseHeaderFilter(), maxStreamDuration)
+	} else {
+		result, err = httpResponseToMCPResult(httpResp, s.responseHeaderFilter())
+	}
 	if err != nil {
 		return nil, nil, err
 	}
-	return result, nil, nil
+	result = attachProxyTiming(result, httpResp.StatusCode, time.Since(started), httpResp.Request.URL.String())
+	return attachRequestID(result, requestID), nil, nil
+}
+
+// egressBlockedResult reports a target the egress policy refused.
+func egressBlockedResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: %v", err),
+			},
+		},
+		IsError: true,
+	}
 }
 
-func injectPaymentSignature(params map[string]any, payment any) (map[string]any, error) {
-	paymentMap, ok := payment.(map[string]any)
+// upstreamFailedResult reports a proxied request that got no response, such
+// as a refused connection or too many redirects.
+func upstreamFailedResult(toolName string, err error) *mcp.CallToolResult {
+	code := "upstream_unreachable"
+	if errors.Is(err, ErrTooManyRedirects) {
+		code = "too_many_redirects"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: proxy request for %s failed: %v", toolName, err),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":    code,
+			"toolName": toolName,
+			"message":  err.Error(),
+		},
+		IsError: true,
+	}
+}
+
+// attachRequestID echoes the call's correlation ID in the result meta.
+func attachRequestID(result *mcp.CallToolResult, requestID string) *mcp.CallToolResult {
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	result.Meta[x402local.MetaKeyRequestID] = requestID
+	return result
+}
+
+// attachProxyTiming records the upstream status, round-trip time and final URL
+// after redirects in the result meta. A status of 0 means no response arrived.
+func attachProxyTiming(result *mcp.CallToolResult, status int, elapsed time.Duration, finalURL string) *mcp.CallToolResult {
+	if result.Meta == nil {
+		result.Meta = mcp.Meta{}
+	}
+	result.Meta["x402/proxy-timing"] = map[string]any{
+		"upstreamStatus": status,
+		"durationMs":     elapsed.Milliseconds(),
+		"finalURL":       finalURL,
+	}
+	return result
+}
+
+// requestIDFor picks the correlation ID for a call: x402/request-id meta, then
+// the caller's X-Request-ID header, then any ID already on ctx. An empty result
+// makes WithRequestID generate one.
+func requestIDFor(ctx context.Context, req *mcp.CallToolRequest) string {
+	if req != nil && req.Params != nil {
+		if id, ok := req.Params.GetMeta()[x402local.MetaKeyRequestID].(string); ok && id != "" {
+			return id
+		}
+	}
+	if req != nil && req.Extra != nil && req.Extra.Header != nil {
+		if id := req.Extra.Header.Get(x402local.RequestIDHeader); id != "" {
+			return id
+		}
+	}
+	return x402local.RequestIDFromContext(ctx)
+}
+
+// validateProxyParameters checks agent-supplied parameters against the input
+// schema advertised for the resource's tool.
+func validateProxyParameters(resource X402DiscoveryResource, parameters map[string]any) []string {
+	// The schema does not depend on the tool's name
+	tool := resourceToTool(resource, nil, ToolOverride{}, nil)
+	if tool == nil {
+		return nil
+	}
+	schema, ok := tool.InputSchema.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("x402/payment metadata must be an object")
+		return nil
+	}
+	input := map[string]any{}
+	if parameters != nil {
+		input["parameters"] = parameters
+	}
+	problems := validateAgainstSchema(input, schema, "")
+	if err := validateAccept(resource, requestedAccept(parameters)); err != nil {
+		problems = append(problems, fmt.Sprintf("parameters.accept: %v", err))
+	}
+	return problems
+}
+
+func invalidParametersResult(toolName string, problems []string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: invalid parameters for %s: %s", toolName, strings.Join(problems, "; ")),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":  "invalid_parameters",
+			"fields": problems,
+		},
+		IsError: true,
+	}
+}
+
+func toolNotFoundResult(toolName string, err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: err.Error(),
+			},
+		},
+		StructuredContent: map[string]any{
+			"error":    "tool_not_found",
+			"toolName": toolName,
+		},
+		IsError: true,
+	}
+}
+
+func callBudgetExhaustedResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Error: tool call aborted: %v", err),
+			},
+		},
+		IsError: true,
+	}
+}
+
+func injectPaymentSignature(params map[string]any, payment any, policy PaymentHeaderPolicy, headerName string) (map[string]any, error) {
+	paymentMap, err := x402local.PaymentMetaObject(payment)
+	if err != nil {
+		return nil, err
 	}
 
 	payload, ok := paymentMap["payload"]
@@ -177,18 +530,13 @@ func injectPaymentSignature(params map[string]any, payment any) (map[string]any,
 		return nil, fmt.Errorf("x402/payment metadata missing payload")
 	}
 
-	header, err := encodePaymentHeader(paymentMap)
+	header, err := encodePaymentHeader(paymentMap, headerName)
 	if err != nil {
 		return nil, fmt.Errorf("unable to encode x402 payment payload: %w", err)
 	}
 
-	if header.Version >= 2 {
-		if _, ok := paymentMap["resource"].(map[string]any); !ok {
-			return nil, fmt.Errorf("x402/payment metadata missing resource for v2 payment")
-		}
-		if _, ok := paymentMap["accepted"].(map[string]any); !ok {
-			return nil, fmt.Errorf("x402/payment metadata missing accepted for v2 payment")
-		}
+	if err := validatePaymentShape(header.Version, paymentMap); err != nil {
+		return nil, err
 	}
 
 	if params == nil {
@@ -200,9 +548,16 @@ func injectPaymentSignature(params map[string]any, payment any) (map[string]any,
 		if !ok {
 			return nil, fmt.Errorf("headers must be an object to set %s", header.Name)
 		}
-		if _, exists := headers[header.Name]; !exists {
-			headers[header.Name] = header.Value
+		for name := range headers {
+			if !strings.EqualFold(name, header.Name) {
+				continue
+			}
+			if policy == PaymentHeaderReject {
+				return nil, fmt.Errorf("headers already contain %s; remove it or the x402/payment metadata", header.Name)
+			}
+			delete(headers, name)
 		}
+		headers[header.Name] = header.Value
 		params["headers"] = headers
 		return params, nil
 	}
@@ -241,13 +596,21 @@ func searchResourcesOutputSchema() map[string]any {
 			"pagination": map[string]any{
 				"type": "object",
 				"properties": map[string]any{
-					"limit":  map[string]any{"type": "integer"},
-					"offset": map[string]any{"type": "integer"},
-					"total":  map[string]any{"type": "integer"},
+					"limit":        map[string]any{"type": "integer"},
+					"offset":       map[string]any{"type": "integer"},
+					"total":        map[string]any{"type": "integer"},
+					"returned":     map[string]any{"type": "integer"},
+					"hasMore":      map[string]any{"type": "boolean"},
+					"limitClamped": map[string]any{"type": "boolean"},
 				},
 				"additionalProperties": false,
 			},
 			"x402Version": map[string]any{"type": "integer"},
+			"x402Versions": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "integer"},
+			},
+			"recallToken": map[string]any{"type": "string"},
 			"tools": map[string]any{
 				"type": "array",
 				"items": map[string]any{
@@ -0,0 +1,681 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func testResource(url, method string, input map[string]any) X402DiscoveryResource {
+	schemaInput := map[string]any{
+		"method": method,
+		"type":   "http",
+	}
+	for key, value := range input {
+		schemaInput[key] = value
+	}
+	return X402DiscoveryResource{
+		Accepts: &[]X402PaymentRequirements{
+			{
+				Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				Description:       "Test resource",
+				MaxAmountRequired: "10000",
+				MaxTimeoutSeconds: 300,
+				MimeType:          "application/json",
+				Network:           "base-sepolia",
+				OutputSchema:      map[string]any{"input": schemaInput},
+				PayTo:             "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				Resource:          url,
+				Scheme:            "exact",
+			},
+		},
+		Resource:    url,
+		Type:        "http",
+		X402Version: 1,
+	}
+}
+
+func TestProxyToolCallDryRunSkipsNetwork(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/restaurants", "POST", map[string]any{
+		"body": map[string]any{"city": "string"},
+	})
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+
+	req := &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{
+		Meta: sdkmcp.Meta{
+			"x402/payment": map[string]any{
+				"x402Version": 1,
+				"scheme":      "exact",
+				"network":     "base-sepolia",
+				"payload":     map[string]any{"signature": "0xdeadbeef"},
+			},
+		},
+	}}
+	result, _, err := s.ProxyToolCall(context.Background(), req, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(resource.Resource, "POST"),
+		Parameters: map[string]any{
+			"query": map[string]any{"lang": "en"},
+			"body":  map[string]any{"city": "Paris"},
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if hits.Load() != 0 {
+		t.Fatalf("expected no upstream calls, got %d", hits.Load())
+	}
+
+	preview, ok := result.StructuredContent.(map[string]any)
+	if !ok {
+		t.Fatalf("expected structuredContent to be map, got %T", result.StructuredContent)
+	}
+	if preview["method"] != http.MethodPost {
+		t.Fatalf("expected POST, got %v", preview["method"])
+	}
+	if url, _ := preview["url"].(string); !strings.Contains(url, "lang=en") {
+		t.Fatalf("expected query to be injected, got %v", preview["url"])
+	}
+	body, ok := preview["body"].(map[string]any)
+	if !ok || body["city"] != "Paris" {
+		t.Fatalf("expected body to be previewed, got %v", preview["body"])
+	}
+	headers, ok := preview["headers"].(http.Header)
+	if !ok {
+		t.Fatalf("expected headers, got %T", preview["headers"])
+	}
+	if headers.Get("X-PAYMENT") != redactedHeaderValue {
+		t.Fatalf("expected X-PAYMENT to be redacted, got %q", headers.Get("X-PAYMENT"))
+	}
+}
+
+func TestProxyToolCallPropagatesRequestID(t *testing.T) {
+	t.Parallel()
+
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Header.Get(x402local.RequestIDHeader))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+
+	req := &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{
+		Meta: sdkmcp.Meta{x402local.MetaKeyRequestID: "caller-123"},
+	}}
+	result, _, err := s.ProxyToolCall(context.Background(), req, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(resource.Resource, "GET"),
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if got, _ := seen.Load().(string); got != "caller-123" {
+		t.Fatalf("expected upstream %s caller-123, got %q", x402local.RequestIDHeader, got)
+	}
+	if got := result.Meta[x402local.MetaKeyRequestID]; got != "caller-123" {
+		t.Fatalf("expected result meta request ID caller-123, got %v", got)
+	}
+}
+
+func listToolNames(t *testing.T, s *Server) []string {
+	t.Helper()
+	ctx := context.Background()
+	clientTransport, serverTransport := sdkmcp.NewInMemoryTransports()
+	serverSession, err := s.mcpServer.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer clientSession.Close()
+
+	listed, err := clientSession.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	names := make([]string, 0, len(listed.Tools))
+	for _, tool := range listed.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestNewServerWithDirectToolsListsDiscoveredTools(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer(WithDirectTools(10))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	names := listToolNames(t, s)
+
+	for _, resource := range s.resources {
+		want := resourceToTool(resource, nil, ToolOverride{}, nil).Name
+		if !slices.Contains(names, want) {
+			t.Fatalf("expected %s in tools/list, got %v", want, names)
+		}
+	}
+	if !slices.Contains(names, "search_resources") || !slices.Contains(names, "proxy_tool_call") {
+		t.Fatalf("expected meta-tools to remain listed, got %v", names)
+	}
+}
+
+func TestNewServerDirectToolsRespectsLimit(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer(WithDirectTools(1))
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	if names := listToolNames(t, s); len(names) != 7 {
+		t.Fatalf("expected 6 meta-tools and 1 direct tool, got %v", names)
+	}
+
+	s, err = NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	if names := listToolNames(t, s); len(names) != 6 {
+		t.Fatalf("expected only meta-tools by default, got %v", names)
+	}
+}
+
+// TestNewServerListsOnlyDiscoveryTools pins the default tools/list: the
+// discovery server registers no demo tools such as get_weather, so deployments
+// that only want discovery and proxying need no extra configuration.
+func TestNewServerListsOnlyDiscoveryTools(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	names := listToolNames(t, s)
+	slices.Sort(names)
+	want := []string{"get_tool", "list_tool_names", "proxy_tool_call", "recall_tools", "search_resources", "server_info"}
+	if !slices.Equal(names, want) {
+		t.Fatalf("expected only discovery tools %v, got %v", want, names)
+	}
+	for _, demo := range []string{"get_weather", "get_stock_quote", "get_news"} {
+		if slices.Contains(names, demo) {
+			t.Fatalf("expected demo tool %s to be absent", demo)
+		}
+	}
+}
+
+func resultText(t *testing.T, result *sdkmcp.CallToolResult) string {
+	t.Helper()
+	if result == nil || len(result.Content) == 0 {
+		t.Fatalf("expected result content")
+	}
+	text, ok := result.Content[0].(*sdkmcp.TextContent)
+	if !ok {
+		t.Fatalf("expected TextContent, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
+func TestProxyToolCallValidatesParameters(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"city":"` + r.URL.Query().Get("city") + `"}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", map[string]any{
+		"queryParams": map[string]any{"city": "string"},
+	})
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	t.Run("unknown query key", func(t *testing.T) {
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName:   toolName,
+			Parameters: map[string]any{"query": map[string]any{"cty": "Paris"}},
+		})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(t, result), "parameters.query.cty: unknown field") {
+			t.Fatalf("expected unknown field error, got %q", resultText(t, result))
+		}
+	})
+
+	t.Run("missing required param", func(t *testing.T) {
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: toolName})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(t, result), "parameters: missing required field") {
+			t.Fatalf("expected missing required error, got %q", resultText(t, result))
+		}
+	})
+
+	if hits.Load() != 0 {
+		t.Fatalf("expected invalid calls not to reach upstream, got %d", hits.Load())
+	}
+
+	t.Run("valid call", func(t *testing.T) {
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName:   toolName,
+			Parameters: map[string]any{"query": map[string]any{"city": "Paris"}},
+		})
+		if err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("expected valid call to succeed, got %q", resultText(t, result))
+		}
+		if hits.Load() != 1 {
+			t.Fatalf("expected one upstream call, got %d", hits.Load())
+		}
+	})
+}
+
+func TestSearchResourcesCapsResults(t *testing.T) {
+	t.Parallel()
+
+	resources := make([]X402DiscoveryResource, 8)
+	for i := range resources {
+		resources[i] = testResource(fmt.Sprintf("https://api.example.com/weather/%d", i), "GET", nil)
+	}
+	s := &Server{resources: resources}
+	s.SetMaxSearchResults(3)
+
+	limit := 10
+	tests := []struct {
+		name        string
+		limit       *int
+		wantClamped bool
+	}{
+		{name: "limit above cap", limit: &limit, wantClamped: true},
+		{name: "no limit", limit: nil},
+	}
+	for _, tc := range tests {
+		_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{Limit: tc.limit})
+		if err != nil {
+			t.Fatalf("%s: SearchResources: %v", tc.name, err)
+		}
+		pagination := output.Pagination
+		if len(output.Tools) != 3 {
+			t.Fatalf("%s: expected 3 tools, got %d", tc.name, len(output.Tools))
+		}
+		if pagination.Total == nil || *pagination.Total != 8 {
+			t.Fatalf("%s: expected total 8, got %v", tc.name, pagination.Total)
+		}
+		if pagination.Returned == nil || *pagination.Returned != 3 {
+			t.Fatalf("%s: expected returned 3, got %v", tc.name, pagination.Returned)
+		}
+		if pagination.HasMore == nil || !*pagination.HasMore {
+			t.Fatalf("%s: expected hasMore", tc.name)
+		}
+		if pagination.LimitClamped != tc.wantClamped {
+			t.Fatalf("%s: expected limitClamped %t, got %t", tc.name, tc.wantClamped, pagination.LimitClamped)
+		}
+		if tc.wantClamped && (pagination.Limit == nil || *pagination.Limit != 3) {
+			t.Fatalf("%s: expected limit clamped to 3, got %v", tc.name, pagination.Limit)
+		}
+	}
+}
+
+func TestProxyToolCallReportsUnknownTool(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{resources: []X402DiscoveryResource{testResource("https://api.example.com/weather", "GET", nil)}}
+	if _, err := findResourceForToolName(s.resources, "missing_tool", s.toolNamer()); !errors.Is(err, ErrToolNotFound) {
+		t.Fatalf("expected ErrToolNotFound, got %v", err)
+	}
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: "missing_tool"})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected an error result")
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["error"] != "tool_not_found" || structured["toolName"] != "missing_tool" {
+		t.Fatalf("expected tool_not_found code, got %v", result.StructuredContent)
+	}
+	if text := result.Content[0].(*sdkmcp.TextContent).Text; !strings.Contains(text, "missing_tool") {
+		t.Fatalf("expected human-readable text naming the tool, got %q", text)
+	}
+}
+
+func TestGetTool(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/weather", "GET", map[string]any{
+		"queryParams": map[string]any{"city": "City name"},
+	})
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	result, _, err := s.GetTool(context.Background(), nil, &GetToolParams{ToolName: toolName})
+	if err != nil {
+		t.Fatalf("GetTool error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected tool, got error %s", resultText(t, result))
+	}
+	tool, ok := result.StructuredContent.(*sdkmcp.Tool)
+	if !ok {
+		t.Fatalf("expected *mcp.Tool structured content, got %T", result.StructuredContent)
+	}
+	if tool.Name != toolName || tool.InputSchema == nil {
+		t.Fatalf("expected %s with an input schema, got %+v", toolName, tool)
+	}
+	if _, ok := tool.Meta["x402/payment-required"]; !ok {
+		t.Fatalf("expected pricing meta, got %v", tool.Meta)
+	}
+
+	result, _, err = s.GetTool(context.Background(), nil, &GetToolParams{ToolName: "x402_get_missing"})
+	if err != nil {
+		t.Fatalf("GetTool error: %v", err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if !result.IsError || structured["error"] != "tool_not_found" {
+		t.Fatalf("expected tool_not_found, got %+v", result)
+	}
+}
+
+func TestGetToolHidesFilteredTools(t *testing.T) {
+	t.Parallel()
+
+	free := X402DiscoveryResource{
+		Resource:    "http://localhost:8080/health",
+		Type:        "http",
+		X402Version: 1,
+	}
+	tests := []struct {
+		name     string
+		resource X402DiscoveryResource
+		option   ServerOption
+	}{
+		{
+			name:     "over the price cap",
+			resource: testResource("http://localhost:8080/weather", "GET", nil),
+			option:   WithMaxPriceByNetwork(map[string]string{"base-sepolia": "1"}),
+		},
+		{
+			name:     "method not allowed",
+			resource: testResource("http://localhost:8080/weather", "POST", nil),
+			option:   WithAllowedMethods("GET"),
+		},
+		{
+			name:     "free tools excluded",
+			resource: free,
+			option:   WithoutFreeTools(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &Server{resources: []X402DiscoveryResource{tt.resource}}
+			toolName := resourceToTool(tt.resource, nil, ToolOverride{}, nil).Name
+			if result, _, _ := s.GetTool(context.Background(), nil, &GetToolParams{ToolName: toolName}); result.IsError {
+				t.Fatalf("expected the tool without the filter, got %s", resultText(t, result))
+			}
+
+			tt.option(s)
+			result, _, err := s.GetTool(context.Background(), nil, &GetToolParams{ToolName: toolName})
+			if err != nil {
+				t.Fatalf("GetTool error: %v", err)
+			}
+			structured, _ := result.StructuredContent.(map[string]any)
+			if !result.IsError || structured["error"] != "tool_not_found" {
+				t.Fatalf("expected tool_not_found, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestProxyToolCallUserAgent(t *testing.T) {
+	t.Parallel()
+
+	var seen atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Header.Get("User-Agent"))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/weather", "GET", nil)
+	toolName := toolNameFromResource(resource.Resource, "GET")
+	call := func(s *Server, params map[string]any) string {
+		t.Helper()
+		s.resources = []X402DiscoveryResource{resource}
+		if _, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{ToolName: toolName, Parameters: params}); err != nil {
+			t.Fatalf("ProxyToolCall error: %v", err)
+		}
+		got, _ := seen.Load().(string)
+		return got
+	}
+
+	if got := call(&Server{}, nil); got != DefaultUserAgent {
+		t.Fatalf("expected default User-Agent %q, got %q", DefaultUserAgent, got)
+	}
+
+	s := &Server{}
+	WithUserAgent("acme-agent/2.0")(s)
+	if got := call(s, nil); got != "acme-agent/2.0" {
+		t.Fatalf("expected configured User-Agent, got %q", got)
+	}
+	if got := call(s, map[string]any{"headers": map[string]any{"User-Agent": "caller/1.0"}}); got != "caller/1.0" {
+		t.Fatalf("expected caller User-Agent to win, got %q", got)
+	}
+}
+
+func TestProxyToolCallTimingMeta(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/broken" {
+			http.Error(w, `{"error":"unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantError  bool
+	}{
+		{path: "/weather", wantStatus: http.StatusOK},
+		{path: "/broken", wantStatus: http.StatusServiceUnavailable, wantError: true},
+	}
+	for _, tt := range tests {
+		resource := testResource(upstream.URL+tt.path, "GET", nil)
+		s := &Server{resources: []X402DiscoveryResource{resource}}
+		result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+			ToolName: toolNameFromResource(resource.Resource, "GET"),
+		})
+		if err != nil {
+			t.Fatalf("%s: ProxyToolCall error: %v", tt.path, err)
+		}
+		if result.IsError != tt.wantError {
+			t.Fatalf("%s: expected IsError=%v, got %v", tt.path, tt.wantError, result.IsError)
+		}
+		timing, ok := result.Meta["x402/proxy-timing"].(map[string]any)
+		if !ok {
+			t.Fatalf("%s: expected x402/proxy-timing meta, got %+v", tt.path, result.Meta)
+		}
+		if timing["upstreamStatus"] != tt.wantStatus {
+			t.Fatalf("%s: expected upstreamStatus %d, got %v", tt.path, tt.wantStatus, timing["upstreamStatus"])
+		}
+		if ms, _ := timing["durationMs"].(int64); ms < 20 || ms > 10_000 {
+			t.Fatalf("%s: implausible durationMs %v", tt.path, timing["durationMs"])
+		}
+		if timing["finalURL"] != upstream.URL+tt.path {
+			t.Fatalf("%s: expected finalURL %s, got %v", tt.path, upstream.URL+tt.path, timing["finalURL"])
+		}
+	}
+}
+
+func TestProxyToolCallTransportErrorResult(t *testing.T) {
+	t.Parallel()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	target := upstream.URL + "/weather"
+	upstream.Close()
+
+	resource := testResource(target, "GET", nil)
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	req := &sdkmcp.CallToolRequest{Params: &sdkmcp.CallToolParamsRaw{Meta: sdkmcp.Meta{"x402/request-id": "req-123"}}}
+	result, _, err := s.ProxyToolCall(context.Background(), req, &ProxyToolCallParams{
+		ToolName: toolNameFromResource(resource.Resource, "GET"),
+	})
+	if err != nil {
+		t.Fatalf("expected a result rather than an error, got %v", err)
+	}
+	structured, _ := result.StructuredContent.(map[string]any)
+	if !result.IsError || structured["error"] != "upstream_unreachable" {
+		t.Fatalf("expected an upstream_unreachable result, got %+v", result)
+	}
+	timing, ok := result.Meta["x402/proxy-timing"].(map[string]any)
+	if !ok || timing["upstreamStatus"] != 0 || timing["finalURL"] != target {
+		t.Fatalf("expected proxy timing with no status, got %+v", result.Meta)
+	}
+	if result.Meta["x402/request-id"] != "req-123" {
+		t.Fatalf("expected the request ID echoed, got %v", result.Meta["x402/request-id"])
+	}
+}
+
+func TestSearchResourcesReportsSupportedX402Versions(t *testing.T) {
+	t.Parallel()
+
+	v1 := testResource("http://localhost:8080/weather", "GET", nil)
+	v2 := testResource("http://localhost:8080/weather/hourly", "GET", nil)
+	v2.X402Version = 2
+	s := &Server{resources: []X402DiscoveryResource{v1, v2}}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTools map[string]int
+	}{
+		{name: "mixed versions", wantTools: map[string]int{v1.Resource: 1, v2.Resource: 2}},
+		{name: "v2 only first", query: "hourly", wantTools: map[string]int{v2.Resource: 2}},
+		{name: "empty result", query: "no-such-resource", wantTools: map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{SearchQuery: tt.query})
+			if err != nil {
+				t.Fatalf("SearchResources error: %v", err)
+			}
+			if output.X402Version != 2 || !slices.Equal(output.X402Versions, []int{1, 2}) {
+				t.Fatalf("expected server versions 2 and [1 2], got %d and %v", output.X402Version, output.X402Versions)
+			}
+			if len(output.Tools) != len(tt.wantTools) {
+				t.Fatalf("expected %d tools, got %d", len(tt.wantTools), len(output.Tools))
+			}
+			for _, tool := range output.Tools {
+				required, _ := tool.Meta["x402/payment-required"].(map[string]any)
+				resource, _ := required["resource"].(map[string]any)
+				if want := tt.wantTools[fmt.Sprint(resource["url"])]; required["x402Version"] != want {
+					t.Fatalf("expected %s meta x402Version %d, got %v", tool.Name, want, required["x402Version"])
+				}
+			}
+		})
+	}
+}
+
+// TestSearchResourcesQueryMatchesAnyResource documents the filtering
+// semantics: searchQuery is the only text filter, matched case-insensitively
+// against the resource URL, so resources outside /weather are searchable.
+func TestSearchResourcesQueryMatchesAnyResource(t *testing.T) {
+	t.Parallel()
+
+	weather := testResource("https://api.example.com/weather", "GET", nil)
+	stocks := testResource("https://api.example.com/stocks/quote", "GET", nil)
+	restaurants := testResource("https://api.example.com/restaurants", "POST", nil)
+	s := &Server{resources: []X402DiscoveryResource{weather, stocks, restaurants}}
+
+	tests := []struct {
+		query string
+		want  []X402DiscoveryResource
+	}{
+		{query: "stocks", want: []X402DiscoveryResource{stocks}},
+		{query: "STOCKS", want: []X402DiscoveryResource{stocks}},
+		{query: "weather", want: []X402DiscoveryResource{weather}},
+		{query: "api.example.com", want: []X402DiscoveryResource{weather, stocks, restaurants}},
+		{query: "", want: []X402DiscoveryResource{weather, stocks, restaurants}},
+		{query: "crypto", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+			_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{SearchQuery: tt.query})
+			if err != nil {
+				t.Fatalf("SearchResources error: %v", err)
+			}
+			var got []string
+			for _, tool := range output.Tools {
+				got = append(got, tool.Name)
+			}
+			var want []string
+			for _, resource := range tt.want {
+				want = append(want, toolNameFromResource(resource.Resource, declaredMethod(resource)))
+			}
+			if !slices.Equal(got, want) {
+				t.Fatalf("query %q: expected %v, got %v", tt.query, want, got)
+			}
+		})
+	}
+}
+
+func TestSearchResourcesFindsNonWeatherFixtures(t *testing.T) {
+	t.Parallel()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	_, output, err := s.SearchResources(context.Background(), nil, &SearchResourcesParams{SearchQuery: "restaurants"})
+	if err != nil {
+		t.Fatalf("SearchResources error: %v", err)
+	}
+	if len(output.Tools) == 0 {
+		t.Fatalf("expected the restaurants fixtures to be searchable")
+	}
+	for _, tool := range output.Tools {
+		if !strings.Contains(tool.Name, "restaurants") {
+			t.Fatalf("expected only restaurants tools, got %s", tool.Name)
+		}
+	}
+}
@@ -2,35 +2,51 @@ package mcp
 
 import (
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 const maxProxyResponseBytes = 1 << 20 // 1MB
 
+// ErrToolNotFound is returned when proxy_tool_call names a tool that no
+// discovered resource provides.
+var ErrToolNotFound = errors.New("tool not found")
+
 var defaultHTTPClient = &http.Client{
 	Timeout: 30 * time.Second,
 }
 
-func resourceToTool(resource X402DiscoveryResource) *mcp.Tool {
+// resourceToTool describes resource as an MCP tool named by namer, or by
+// HashToolNamer when namer is nil. override's title and description take
+// precedence over the derived ones. It returns nil for non-HTTP resources.
+func resourceToTool(resource X402DiscoveryResource, namer ToolNamer, override ToolOverride, assets assetRegistry) *mcp.Tool {
 	if strings.ToLower(resource.Type) != "http" {
 		return nil
 	}
 
 	description := fmt.Sprintf("Proxy call to %s", resource.Resource)
 	acceptsDesc, input := extractAcceptsMetadata(resource)
-	if acceptsDesc != "" {
+	if override.Description != "" {
+		description = override.Description
+	} else if acceptsDesc != "" {
 		description = acceptsDesc
 	} else if resource.Metadata != nil {
 		if rawDesc, ok := (*resource.Metadata)["description"]; ok {
@@ -40,27 +56,25 @@ func resourceToTool(resource X402DiscoveryResource) *mcp.Tool {
 		}
 	}
 
-	method := methodFromInput(input)
-	if method == "" {
-		if metaInput, ok := extractMetadataInput(resource); ok {
-			method = methodFromInput(metaInput)
-		}
-	}
-
 	description = fmt.Sprintf("%s Use proxy_tool_call with payment to execute.", strings.TrimSpace(description))
 
-	toolName := toolNameFromResource(resource.Resource, method)
+	toolName := resourceToolName(namer, resource)
 	tool := &mcp.Tool{
 		Name:        toolName,
+		Title:       override.Title,
 		Description: description,
 		InputSchema: defaultProxyToolSchema(resource, input),
 	}
-	if meta := buildPricingMeta(resource, description, toolName); meta != nil {
+	if meta := buildPricingMeta(resource, description, toolName, assets); meta != nil {
 		tool.Meta = meta
 	}
 	if tool.Meta == nil {
 		tool.Meta = map[string]any{}
 	}
+	if isFreeResource(resource) {
+		// No payment requirements: call without x402/payment meta
+		tool.Meta["x402/free"] = true
+	}
 	tool.Meta["x402/call-with"] = map[string]any{
 		"tool": "proxy_tool_call",
 	}
@@ -120,10 +134,17 @@ func defaultProxyToolSchema(resource X402DiscoveryResource, input map[string]any
 			queryProps := map[string]any{}
 			for key, value := range rawQueryParams {
 				prop := map[string]any{
-					"type": "string",
+					"type":  []any{"string", "number", "boolean", "array"},
+					"items": map[string]any{"type": []any{"string", "number", "boolean"}},
 				}
-				if value != nil {
-					prop["description"] = fmt.Sprint(value)
+				description := queryParamDescription(value)
+				if defaultValue, ok := queryParamDefault(value); ok {
+					// Optional: the proxy sends the default when it is omitted
+					prop["default"] = defaultValue
+					description = strings.TrimSpace(fmt.Sprintf("%s (optional, defaults to %s)", description, formatQueryScalar(defaultValue)))
+				}
+				if description != "" {
+					prop["description"] = description
 				}
 				queryProps[key] = prop
 			}
@@ -146,6 +167,16 @@ func defaultProxyToolSchema(resource X402DiscoveryResource, input map[string]any
 				}
 				headerProps[key] = prop
 			}
+			for _, standard := range []map[string]string{conditionalRequestHeaders, rangeRequestHeaders} {
+				for key, description := range standard {
+					if _, declared := headerProps[key]; !declared {
+						headerProps[key] = map[string]any{
+							"type":        "string",
+							"description": description,
+						}
+					}
+				}
+			}
 			parametersProps["headers"] = map[string]any{
 				"type":                 "object",
 				"additionalProperties": false,
@@ -154,9 +185,20 @@ func defaultProxyToolSchema(resource X402DiscoveryResource, input map[string]any
 			}
 		}
 
-		if _, ok := input["body"]; ok {
-			parametersProps["body"] = map[string]any{
-				"description": "JSON body to include on the request.",
+		if rawBody, ok := input["body"]; ok {
+			contentType := contentTypeFromInput(input)
+			if contentType == jsonContentType || contentType == formContentType {
+				parametersProps["body"] = bodySchemaFromInput(rawBody)
+			} else {
+				parametersProps["body"] = map[string]any{
+					"type":        "string",
+					"description": "Raw body to include on the request.",
+				}
+			}
+			parametersProps["bodyContentType"] = map[string]any{
+				"type":        "string",
+				"description": "Content-Type of the body. application/json marshals it, application/x-www-form-urlencoded form-encodes an object, and other types send a string verbatim.",
+				"default":     contentType,
 			}
 		}
 	}
@@ -164,10 +206,82 @@ func defaultProxyToolSchema(resource X402DiscoveryResource, input map[string]any
 	if len(parametersProps) > 0 {
 		schema["required"] = []string{"parameters"}
 	}
+	parametersProps["accept"] = map[string]any{
+		"type":        "string",
+		"description": "Accept header to send upstream. Defaults to application/json; must match the resource mimeType when one is declared.",
+	}
+	parametersProps["host"] = map[string]any{
+		"type":        "string",
+		"description": "Host header to send upstream for virtual-hosted resources. Defaults to the resource URL's host; other values must be allowed by the server.",
+	}
 
 	return schema
 }
 
+// bodySchemaFromInput reflects a declared request body into a JSON schema.
+// It accepts either a JSON schema with properties/required or a shorthand map
+// of field name to type name, and falls back to an untyped body otherwise.
+func bodySchemaFromInput(rawBody any) map[string]any {
+	schema := map[string]any{
+		"description": "JSON body to include on the request.",
+	}
+	declared, ok := rawBody.(map[string]any)
+	if !ok || len(declared) == 0 {
+		return schema
+	}
+
+	if rawProps, ok := declared["properties"].(map[string]any); ok {
+		schema["type"] = "object"
+		schema["properties"] = rawProps
+		if required := stringList(declared["required"]); len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	bodyProps := map[string]any{}
+	for key, value := range declared {
+		prop := map[string]any{
+			"type": "string",
+		}
+		if typeName, ok := value.(string); ok && isJSONSchemaType(typeName) {
+			prop["type"] = typeName
+		} else if value != nil {
+			prop["description"] = fmt.Sprint(value)
+		}
+		bodyProps[key] = prop
+	}
+	schema["type"] = "object"
+	schema["properties"] = bodyProps
+	return schema
+}
+
+func isJSONSchemaType(value string) bool {
+	switch value {
+	case "string", "number", "integer", "boolean", "object", "array":
+		return true
+	default:
+		return false
+	}
+}
+
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
 func extractMetadataInput(resource X402DiscoveryResource) (map[string]any, bool) {
 	if resource.Metadata == nil {
 		return nil, false
@@ -207,6 +321,21 @@ func extractAcceptsMetadata(resource X402DiscoveryResource) (string, map[string]
 	return "", nil
 }
 
+// declaredMethod returns the HTTP method declared by the resource's accepts
+// input schema, falling back to its metadata input. It is empty when neither
+// declares one.
+func declaredMethod(resource X402DiscoveryResource) string {
+	if _, input := extractAcceptsMetadata(resource); input != nil {
+		if method := methodFromInput(input); method != "" {
+			return method
+		}
+	}
+	if metaInput, ok := extractMetadataInput(resource); ok {
+		return methodFromInput(metaInput)
+	}
+	return ""
+}
+
 func methodFromInput(input map[string]any) string {
 	if input == nil {
 		return ""
@@ -221,6 +350,7 @@ func buildPricingMeta(
 	resource X402DiscoveryResource,
 	description string,
 	toolName string,
+	assets assetRegistry,
 ) map[string]any {
 	if resource.Accepts == nil || len(*resource.Accepts) == 0 {
 		return nil
@@ -245,14 +375,20 @@ func buildPricingMeta(
 			"maxTimeoutSeconds": decoded["maxTimeoutSeconds"],
 			"extra":             decoded["extra"],
 		}
+		if asset, ok := assets.lookup(requirement); ok {
+			accepts["assetSymbol"] = asset.Symbol
+			accepts["assetDecimals"] = asset.Decimals
+		}
 		acceptsList = append(acceptsList, accepts)
 	}
 	if len(acceptsList) == 0 {
 		return nil
 	}
 
+	// url is the upstream endpoint the payment is for; id keeps the MCP handle
 	resourceMeta := map[string]any{
-		"url":         fmt.Sprintf("mcp://tool/%s", toolName),
+		"url":         resource.Resource,
+		"id":          fmt.Sprintf("mcp://tool/%s", toolName),
 		"description": description,
 	}
 	if mimeType := findMimeType(*resource.Accepts); mimeType != "" {
@@ -285,49 +421,92 @@ func findMimeType(accepts []X402PaymentRequirements) string {
 	return ""
 }
 
+// findResourceForToolName returns the HTTP resource in items that namer names
+// toolName, using namer's own lookup when it implements ToolNameResolver.
 func findResourceForToolName(
 	items []X402DiscoveryResource,
 	toolName string,
+	namer ToolNamer,
 ) (*X402DiscoveryResource, error) {
+	if resolver, ok := namer.(ToolNameResolver); ok {
+		if resource, ok := resolver.ResolveToolName(items, toolName); ok && strings.ToLower(resource.Type) == "http" {
+			return resource, nil
+		}
+		return nil, fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+	}
+	// Duplicates are collapsed on load, but items may come from elsewhere;
+	// the most recently updated match wins so resolution never depends on order
+	var found *X402DiscoveryResource
 	for idx := range items {
 		resource := items[idx]
-		if resourceToTool(resource) == nil {
+		if strings.ToLower(resource.Type) != "http" {
 			continue
 		}
-		method := ""
-		if _, input := extractAcceptsMetadata(resource); input != nil {
-			method = methodFromInput(input)
+		if resourceToolName(namer, resource) != toolName {
+			continue
+		}
+		if found == nil || resource.LastUpdated.After(found.LastUpdated) {
+			found = &resource
 		}
-		if method == "" {
-			if metaInput, ok := extractMetadataInput(resource); ok {
-				method = methodFromInput(metaInput)
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %q", ErrToolNotFound, toolName)
+	}
+	return found, nil
+}
+
+// setQueryValue encodes a JSON parameter value as query parameters on key,
+// replacing any value already in the resource URL:
+//   - strings are sent as-is and booleans as "true"/"false"
+//   - numbers use the shortest decimal form, never exponent notation
+//   - arrays repeat the key once per element (?k=1&k=2)
+//   - objects and nested arrays are sent as compact JSON
+//   - null omits the parameter
+func setQueryValue(query url.Values, key string, value any) {
+	query.Del(key)
+	if items, ok := value.([]any); ok {
+		for _, item := range items {
+			if item != nil {
+				query.Add(key, formatQueryScalar(item))
 			}
 		}
-		if toolNameFromResource(resource.Resource, method) == toolName {
-			return &resource, nil
+		return
+	}
+	if value != nil {
+		query.Set(key, formatQueryScalar(value))
+	}
+}
+
+func formatQueryScalar(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case json.Number:
+		return v.String()
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
 		}
+		return string(encoded)
 	}
-	return nil, fmt.Errorf("tool %q not found", toolName)
 }
 
 func proxyToolCallToHTTPRequest(
 	ctx context.Context,
 	resource X402DiscoveryResource,
 	params map[string]any,
+	logger x402local.Logger,
 ) (*http.Request, error) {
-	method := http.MethodGet
-	if _, input := extractAcceptsMetadata(resource); input != nil {
-		if derived := methodFromInput(input); derived != "" {
-			method = derived
-		}
-	}
-	if method == http.MethodGet {
-		if metaInput, ok := extractMetadataInput(resource); ok {
-			if derived := methodFromInput(metaInput); derived != "" {
-				method = derived
-			}
-		}
-	}
+	method := declaredMethod(resource)
 
 	endpoint, err := url.Parse(resource.Resource)
 	if err != nil {
@@ -335,52 +514,109 @@ func proxyToolCallToHTTPRequest(
 	}
 
 	query := endpoint.Query()
-	if params != nil {
-		if rawQuery, ok := params["query"].(map[string]any); ok {
-			for key, value := range rawQuery {
-				query.Set(key, fmt.Sprint(value))
-			}
-		}
+	rawQuery, _ := params["query"].(map[string]any)
+	for key, value := range rawQuery {
+		setQueryValue(query, key, value)
 	}
+	applyQueryDefaults(query, resource, rawQuery)
 	endpoint.RawQuery = query.Encode()
 
 	var body io.Reader
+	var contentType string
 	if params != nil {
 		if rawBody, ok := params["body"]; ok && rawBody != nil {
-			payload, err := json.Marshal(rawBody)
+			contentType = bodyContentType(resource, params)
+			payload, err := encodeProxyBody(rawBody, contentType)
 			if err != nil {
 				return nil, fmt.Errorf("invalid body payload: %w", err)
 			}
 			body = bytes.NewReader(payload)
-			if method == http.MethodGet {
+			switch method {
+			case "":
+				method = http.MethodPost
+			case http.MethodGet:
+				logger.Warn("proxy: GET declared but a body was supplied; sending POST", "resource", resource.Resource, "requestId", x402local.RequestIDFromContext(ctx))
 				method = http.MethodPost
 			}
 		}
 	}
+	if method == "" {
+		method = http.MethodGet
+	}
 
 	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Accept", "application/json")
+	accept := requestedAccept(params)
+	if accept == "" {
+		accept = defaultAccept
+	}
+	req.Header.Set("Accept", accept)
+	if requestID := x402local.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(x402local.RequestIDHeader, requestID)
+	}
 	if body != nil {
-		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Type", contentType)
 	}
 
 	if params != nil {
 		if rawHeaders, ok := params["headers"].(map[string]any); ok {
 			for key, value := range rawHeaders {
+				// net/http ignores a Host header; it must go on req.Host
+				if http.CanonicalHeaderKey(key) == "Host" {
+					continue
+				}
 				req.Header.Set(key, fmt.Sprint(value))
 			}
 		}
 	}
+	req.Host = requestedHost(params)
 
 	return req, nil
 }
 
-func httpResponseToMCPResult(resp *http.Response) (*mcp.CallToolResult, error) {
-	bodyBytes, err := io.ReadAll(io.LimitReader(resp.Body, maxProxyResponseBytes))
+// previewHTTPRequest describes req without sending it. Headers named in
+// redacted are masked so previews are safe to echo back to the agent.
+func previewHTTPRequest(req *http.Request, redacted []string) (*mcp.CallToolResult, any, error) {
+	preview := map[string]any{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"headers": RedactHeaders(req.Header, redacted),
+	}
+	if req.Host != "" {
+		preview["host"] = req.Host
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read preview body: %w", err)
+		}
+		defer body.Close()
+		bodyBytes, err := io.ReadAll(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read preview body: %w", err)
+		}
+		preview["body"] = decodeErrorBody(bodyBytes)
+	}
+
+	contentJSON, err := json.MarshalIndent(preview, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request preview: %w", err)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(contentJSON),
+			},
+		},
+		StructuredContent: preview,
+	}, nil, nil
+}
+
+func httpResponseToMCPResult(resp *http.Response, headers responseHeaderFilter) (*mcp.CallToolResult, error) {
+	bodyBytes, err := readProxyBody(resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read proxy response: %w", err)
 	}
@@ -403,9 +639,26 @@ func httpResponseToMCPResult(resp *http.Response) (*mcp.CallToolResult, error) {
 
 	payload := map[string]any{
 		"status":  resp.StatusCode,
-		"headers": resp.Header,
+		"headers": headers.apply(resp.Header),
 		"body":    string(bodyBytes),
 	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		payload["url"] = resp.Request.URL.String()
+	}
+	addCacheValidators(payload, resp.Header)
+	if resp.StatusCode == http.StatusPartialContent {
+		addPartialContent(payload, resp.Header, len(bodyBytes))
+	}
+	notModified := resp.StatusCode == http.StatusNotModified
+	if notModified {
+		payload["notModified"] = true
+	}
+	binary := !notModified && !isTextualMediaType(resp.Header.Get("Content-Type"))
+	if binary {
+		// Binary bodies would be corrupted by a string conversion
+		payload["body"] = encodeBinaryBody(bodyBytes)
+		payload["bodyEncoding"] = "base64"
+	}
 
 	contentJSON, err := json.MarshalIndent(payload, "", "  ")
 	if err != nil {
@@ -420,6 +673,32 @@ func httpResponseToMCPResult(resp *http.Response) (*mcp.CallToolResult, error) {
 		},
 		IsError: resp.StatusCode >= http.StatusBadRequest,
 	}
+	if binary {
+		if content := binaryResponseContent(resp, bodyBytes); content != nil {
+			result.Content = append(result.Content, content)
+		}
+	}
+	if result.IsError {
+		errorBody := decodeErrorBody(bodyBytes)
+		structured := map[string]any{
+			"status": resp.StatusCode,
+			"error":  errorBody,
+		}
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+			if names := fieldErrorNames(errorBody); len(names) > 0 {
+				structured["missingOrInvalidParams"] = names
+			}
+		}
+		result.StructuredContent = structured
+	}
+	if notModified {
+		result.StructuredContent = notModifiedContent(resp.Header)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			result.StructuredContent.(map[string]any)["retryAfterSeconds"] = seconds
+		}
+	}
 
 	if paymentResponse := decodePaymentResponse(resp); paymentResponse != nil {
 		result.Meta = map[string]any{
@@ -430,20 +709,107 @@ func httpResponseToMCPResult(resp *http.Response) (*mcp.CallToolResult, error) {
 	return result, nil
 }
 
+// readProxyBody reads at most maxProxyResponseBytes of the response body,
+// transparently decoding gzip and deflate content. The limit applies to the
+// decoded size. A body that does not match its declared encoding is returned
+// as-is.
+func readProxyBody(resp *http.Response) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxProxyResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	var decoder io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return raw, nil
+		}
+		defer gz.Close()
+		decoder = gz
+	case "deflate":
+		// Servers send either zlib-wrapped or raw deflate data under this name
+		if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+			defer zr.Close()
+			decoder = zr
+		} else {
+			decoder = flate.NewReader(bytes.NewReader(raw))
+		}
+	default:
+		return raw, nil
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(decoder, maxProxyResponseBytes))
+	if err != nil && (len(decoded) == 0 || !errors.Is(err, io.ErrUnexpectedEOF)) {
+		// Keep partially decoded data only when the compressed body was truncated
+		return raw, nil
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	return decoded, nil
+}
+
+// parseRetryAfter converts a Retry-After value in delta-seconds or HTTP-date
+// form to whole seconds from now. Dates in the past yield zero.
+func parseRetryAfter(value string, now time.Time) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return seconds, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	wait := at.Sub(now)
+	if wait <= 0 {
+		return 0, true
+	}
+	return int(math.Ceil(wait.Seconds())), true
+}
+
+// decodeErrorBody returns a body as decoded JSON when possible, falling back to
+// the raw string so agents always receive something to inspect.
+func decodeErrorBody(body []byte) any {
+	if len(body) == 0 {
+		return nil
+	}
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		return decoded
+	}
+	return string(body)
+}
+
 func decodePaymentHeader(raw string) map[string]any {
 	if raw == "" {
 		return nil
 	}
-	payload, err := base64.StdEncoding.DecodeString(raw)
-	if err != nil {
-		payload, err = base64.RawStdEncoding.DecodeString(raw)
+	for _, encoding := range paymentHeaderEncodings {
+		payload, err := encoding.DecodeString(raw)
 		if err != nil {
+			continue
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(payload, &decoded); err != nil {
 			return nil
 		}
+		return decoded
 	}
-	var decoded map[string]any
-	if err := json.Unmarshal(payload, &decoded); err != nil {
-		return nil
-	}
-	return decoded
+	return nil
+}
+
+// paymentHeaderEncodings lists the base64 variants x402 clients send, in the
+// order decodePaymentHeader tries them.
+var paymentHeaderEncodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
 }
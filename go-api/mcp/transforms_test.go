@@ -0,0 +1,638 @@
+package mcp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestHTTPResponseToMCPResultJSONErrorBody(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusNotFound,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":"city not found"}`)),
+	}
+
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatalf("expected 404 to return IsError=true")
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok {
+		t.Fatalf("expected structuredContent to be map, got %T", result.StructuredContent)
+	}
+	if structured["status"] != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %v", structured["status"])
+	}
+	upstreamErr, ok := structured["error"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected decoded JSON error, got %T", structured["error"])
+	}
+	if upstreamErr["error"] != "city not found" {
+		t.Fatalf("expected upstream error message, got %v", upstreamErr["error"])
+	}
+}
+
+func TestHTTPResponseToMCPResultPlainTextErrorBody(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader("internal failure")),
+	}
+
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatalf("expected 500 to return IsError=true")
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok {
+		t.Fatalf("expected structuredContent to be map, got %T", result.StructuredContent)
+	}
+	if structured["status"] != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %v", structured["status"])
+	}
+	if structured["error"] != "internal failure" {
+		t.Fatalf("expected raw body error, got %v", structured["error"])
+	}
+}
+
+func TestHTTPResponseToMCPResultSuccessHasNoStructuredError(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"ok":true}`)),
+	}
+
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	if result.StructuredContent != nil {
+		t.Fatalf("expected no structuredContent on success, got %v", result.StructuredContent)
+	}
+}
+
+func TestHTTPResponseToMCPResultRedactsSensitiveHeaders(t *testing.T) {
+	t.Parallel()
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Payment-Response": []string{"c2VjcmV0"},
+			"Authorization":    []string{"Bearer secret"},
+			"Content-Type":     []string{"application/json"},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"ok":true}`)),
+	}
+
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	textContent, ok := result.Content[0].(*sdkmcp.TextContent)
+	if !ok {
+		t.Fatalf("expected TextContent, got %T", result.Content[0])
+	}
+	var payload struct {
+		Headers http.Header `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(textContent.Text), &payload); err != nil {
+		t.Fatalf("expected content text to be JSON: %v", err)
+	}
+	if got := payload.Headers.Get("Payment-Response"); got != redactedHeaderValue {
+		t.Fatalf("expected Payment-Response to be redacted, got %q", got)
+	}
+	if got := payload.Headers.Get("Authorization"); got != redactedHeaderValue {
+		t.Fatalf("expected Authorization to be redacted, got %q", got)
+	}
+	if got := payload.Headers.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("expected Content-Type to be preserved, got %q", got)
+	}
+	if resp.Header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("expected original response headers to be untouched")
+	}
+}
+
+func TestServerRedactedHeadersConfigurable(t *testing.T) {
+	t.Parallel()
+
+	s := &Server{}
+	if len(s.headersToRedact()) != len(DefaultRedactedHeaders) {
+		t.Fatalf("expected default redaction list")
+	}
+	s.SetRedactedHeaders("X-Api-Key")
+	redacted := RedactHeaders(http.Header{
+		"X-Api-Key":     []string{"secret"},
+		"Authorization": []string{"Bearer token"},
+	}, s.headersToRedact())
+	if redacted.Get("X-Api-Key") != redactedHeaderValue {
+		t.Fatalf("expected X-Api-Key to be redacted")
+	}
+	if redacted.Get("Authorization") != "Bearer token" {
+		t.Fatalf("expected Authorization to be left alone when not listed")
+	}
+}
+
+func bodySchemaForResource(t *testing.T, resource X402DiscoveryResource) map[string]any {
+	t.Helper()
+	tool := resourceToTool(resource, nil, ToolOverride{}, nil)
+	if tool == nil {
+		t.Fatalf("expected tool for resource")
+	}
+	schema := tool.InputSchema.(map[string]any)
+	parameters := schema["properties"].(map[string]any)["parameters"].(map[string]any)
+	body, ok := parameters["properties"].(map[string]any)["body"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected body schema, got %v", parameters["properties"])
+	}
+	return body
+}
+
+func TestResourceToToolBodySchemaFromShorthand(t *testing.T) {
+	t.Parallel()
+
+	body := bodySchemaForResource(t, testResource("http://localhost:8080/restaurants", "POST", map[string]any{
+		"body": map[string]any{"city": "string", "guests": "integer"},
+	}))
+	if body["type"] != "object" {
+		t.Fatalf("expected object body, got %v", body["type"])
+	}
+	props := body["properties"].(map[string]any)
+	if props["city"].(map[string]any)["type"] != "string" {
+		t.Fatalf("expected city to be a string, got %v", props["city"])
+	}
+	if props["guests"].(map[string]any)["type"] != "integer" {
+		t.Fatalf("expected guests to be an integer, got %v", props["guests"])
+	}
+}
+
+func TestResourceToToolBodySchemaFromJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	body := bodySchemaForResource(t, testResource("http://localhost:8080/restaurants", "POST", map[string]any{
+		"body": map[string]any{
+			"properties": map[string]any{
+				"city": map[string]any{"type": "string", "description": "City to search"},
+				"food": map[string]any{"type": "string"},
+			},
+			"required": []any{"city"},
+		},
+	}))
+	props := body["properties"].(map[string]any)
+	if props["city"].(map[string]any)["description"] != "City to search" {
+		t.Fatalf("expected declared property to be preserved, got %v", props["city"])
+	}
+	required, ok := body["required"].([]string)
+	if !ok || len(required) != 1 || required[0] != "city" {
+		t.Fatalf("expected required [city], got %v", body["required"])
+	}
+}
+
+func TestResourceToToolBodySchemaFallback(t *testing.T) {
+	t.Parallel()
+
+	body := bodySchemaForResource(t, testResource("http://localhost:8080/restaurants", "POST", map[string]any{
+		"body": "json",
+	}))
+	if _, ok := body["type"]; ok {
+		t.Fatalf("expected permissive body schema, got %v", body)
+	}
+	if body["description"] == nil {
+		t.Fatalf("expected body description to be kept")
+	}
+}
+
+func TestProxyToolCallToHTTPRequestMethods(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		method   string
+		params   map[string]any
+		want     string
+		wantBody bool
+	}{
+		{name: "declared DELETE", method: "DELETE", want: http.MethodDelete},
+		{name: "bodyless POST", method: "POST", want: http.MethodPost},
+		{name: "GET with body", method: "GET", params: map[string]any{"body": map[string]any{"a": 1}}, want: http.MethodPost, wantBody: true},
+		{name: "undeclared without body", method: "", want: http.MethodGet},
+		{name: "undeclared with body", method: "", params: map[string]any{"body": map[string]any{"a": 1}}, want: http.MethodPost, wantBody: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			resource := testResource("http://localhost:8080/items", tc.method, nil)
+			req, err := proxyToolCallToHTTPRequest(context.Background(), resource, tc.params, x402local.StdLogger{})
+			if err != nil {
+				t.Fatalf("proxyToolCallToHTTPRequest error: %v", err)
+			}
+			if req.Method != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, req.Method)
+			}
+			if (req.Body != nil) != tc.wantBody {
+				t.Fatalf("expected body=%t, got body=%t", tc.wantBody, req.Body != nil)
+			}
+		})
+	}
+}
+
+func TestProxyToolCallToHTTPRequestEncodesQueryValues(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("https://api.example.com/weather?units=metric&ids=0", "GET", map[string]any{
+		"queryParams": map[string]any{"ids": "ids", "lat": "latitude", "days": "days", "hourly": "hourly"},
+	})
+	params := map[string]any{
+		"query": map[string]any{
+			"ids":    []any{float64(1), "two", true},
+			"lat":    48.8566,
+			"days":   float64(1e21),
+			"hourly": false,
+		},
+	}
+	if problems := validateProxyParameters(resource, params); len(problems) > 0 {
+		t.Fatalf("expected typed query values to validate, got %v", problems)
+	}
+
+	req, err := proxyToolCallToHTTPRequest(context.Background(), resource, params, x402local.StdLogger{})
+	if err != nil {
+		t.Fatalf("proxyToolCallToHTTPRequest error: %v", err)
+	}
+	query := req.URL.Query()
+	if got := query["ids"]; !slices.Equal(got, []string{"1", "two", "true"}) {
+		t.Fatalf("expected repeated ids, got %v", got)
+	}
+	if got := query.Get("lat"); got != "48.8566" {
+		t.Fatalf("expected lat 48.8566, got %q", got)
+	}
+	if got := query.Get("days"); got != "1000000000000000000000" {
+		t.Fatalf("expected days without exponent, got %q", got)
+	}
+	if got := query.Get("hourly"); got != "false" {
+		t.Fatalf("expected hourly false, got %q", got)
+	}
+	if got := query.Get("units"); got != "metric" {
+		t.Fatalf("expected resource query to be kept, got %q", got)
+	}
+}
+
+func TestValidateProxyParametersRejectsObjectQueryValue(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("https://api.example.com/weather", "GET", map[string]any{
+		"queryParams": map[string]any{"ids": "ids"},
+	})
+	problems := validateProxyParameters(resource, map[string]any{
+		"query": map[string]any{"ids": []any{map[string]any{"nested": true}}},
+	})
+	if len(problems) != 1 || !strings.Contains(problems[0], "parameters.query.ids[0]") {
+		t.Fatalf("expected one problem for the nested object, got %v", problems)
+	}
+}
+
+func TestResourceToToolPaymentRequiredUsesUpstreamURL(t *testing.T) {
+	t.Parallel()
+
+	tool := resourceToTool(testResource("http://localhost:8080/weather", "GET", nil), nil, ToolOverride{}, nil)
+	if tool == nil {
+		t.Fatalf("expected tool for resource")
+	}
+	required, ok := tool.Meta["x402/payment-required"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected x402/payment-required meta, got %v", tool.Meta)
+	}
+	resource := required["resource"].(map[string]any)
+	if resource["url"] != "http://localhost:8080/weather" {
+		t.Fatalf("expected upstream resource url, got %v", resource["url"])
+	}
+	if resource["id"] != "mcp://tool/"+tool.Name {
+		t.Fatalf("expected mcp tool id, got %v", resource["id"])
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.January, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		value  string
+		want   int
+		wantOK bool
+	}{
+		{name: "seconds", value: "120", want: 120, wantOK: true},
+		{name: "http date", value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90, wantOK: true},
+		{name: "past date", value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, wantOK: true},
+		{name: "empty", value: ""},
+		{name: "garbage", value: "soon"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tc.value, now)
+			if ok != tc.wantOK || got != tc.want {
+				t.Fatalf("parseRetryAfter(%q) = %d, %t; want %d, %t", tc.value, got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestHTTPResponseToMCPResultRateLimited(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       any
+	}{
+		{name: "seconds", retryAfter: "30", want: 30},
+		{name: "http date", retryAfter: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+		{name: "no header"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.retryAfter != "" {
+				header.Set("Retry-After", tc.retryAfter)
+			}
+			resp := &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader("slow down")),
+			}
+
+			result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+			if err != nil {
+				t.Fatalf("httpResponseToMCPResult error: %v", err)
+			}
+			if !result.IsError {
+				t.Fatalf("expected 429 to be an error")
+			}
+			structured := result.StructuredContent.(map[string]any)
+			seconds, ok := structured["retryAfterSeconds"]
+			switch {
+			case tc.retryAfter == "":
+				if ok {
+					t.Fatalf("expected no retryAfterSeconds, got %v", seconds)
+				}
+			case tc.want != nil:
+				if seconds != tc.want {
+					t.Fatalf("expected retryAfterSeconds %v, got %v", tc.want, seconds)
+				}
+			default:
+				if s, _ := seconds.(int); s < 3500 || s > 3600 {
+					t.Fatalf("expected retryAfterSeconds near 3600, got %v", seconds)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPResponseToMCPResultDecompressesBody(t *testing.T) {
+	t.Parallel()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(`{"city":"Paris","temperature":18}`))
+	gz.Close()
+
+	var deflated bytes.Buffer
+	zw := zlib.NewWriter(&deflated)
+	zw.Write([]byte(`{"city":"Paris","temperature":18}`))
+	zw.Close()
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantBody string
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped.Bytes(), wantBody: `{"city":"Paris","temperature":18}`},
+		{name: "deflate", encoding: "deflate", body: deflated.Bytes(), wantBody: `{"city":"Paris","temperature":18}`},
+		{name: "mislabeled", encoding: "gzip", body: []byte(`{"city":"Paris"}`), wantBody: `{"city":"Paris"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: http.StatusOK,
+				Header: http.Header{
+					"Content-Type":     []string{"application/json"},
+					"Content-Encoding": []string{tc.encoding},
+				},
+				Body: io.NopCloser(bytes.NewReader(tc.body)),
+			}
+
+			result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+			if err != nil {
+				t.Fatalf("httpResponseToMCPResult error: %v", err)
+			}
+			var payload map[string]any
+			text := result.Content[0].(*sdkmcp.TextContent).Text
+			if err := json.Unmarshal([]byte(text), &payload); err != nil {
+				t.Fatalf("decode content: %v", err)
+			}
+			if payload["body"] != tc.wantBody {
+				t.Fatalf("expected body %q, got %q", tc.wantBody, payload["body"])
+			}
+		})
+	}
+}
+
+func TestReadProxyBodyLimitsDecompressedSize(t *testing.T) {
+	t.Parallel()
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write(bytes.Repeat([]byte("a"), 4*maxProxyResponseBytes))
+	gz.Close()
+
+	resp := &http.Response{
+		Header: http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:   io.NopCloser(bytes.NewReader(gzipped.Bytes())),
+	}
+	body, err := readProxyBody(resp)
+	if err != nil {
+		t.Fatalf("readProxyBody error: %v", err)
+	}
+	if len(body) != maxProxyResponseBytes {
+		t.Fatalf("expected decoded body capped at %d bytes, got %d", maxProxyResponseBytes, len(body))
+	}
+}
+
+func TestProxyToolCallNegotiatesCSV(t *testing.T) {
+	t.Parallel()
+
+	var seenAccept atomic.Value
+	seenAccept.Store("")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenAccept.Store(r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_, _ = w.Write([]byte("city,temp\nParis,18\n"))
+	}))
+	defer upstream.Close()
+
+	resource := testResource(upstream.URL+"/report", "GET", nil)
+	(*resource.Accepts)[0].MimeType = "text/csv"
+	s := &Server{resources: []X402DiscoveryResource{resource}}
+	toolName := toolNameFromResource(resource.Resource, "GET")
+
+	result, _, err := s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:   toolName,
+		Parameters: map[string]any{"accept": "application/json"},
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if !result.IsError || seenAccept.Load() != "" {
+		t.Fatalf("expected accept mismatch to be rejected before sending, got %+v", result)
+	}
+
+	result, _, err = s.ProxyToolCall(context.Background(), nil, &ProxyToolCallParams{
+		ToolName:   toolName,
+		Parameters: map[string]any{"accept": "text/csv"},
+	})
+	if err != nil {
+		t.Fatalf("ProxyToolCall error: %v", err)
+	}
+	if got := seenAccept.Load(); got != "text/csv" {
+		t.Fatalf("expected upstream Accept text/csv, got %q", got)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(*sdkmcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload["body"] != "city,temp\nParis,18\n" || payload["bodyEncoding"] != nil {
+		t.Fatalf("expected CSV relayed as text, got %v", payload)
+	}
+}
+
+func TestHTTPResponseToMCPResultBinaryBody(t *testing.T) {
+	t.Parallel()
+
+	png := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff}
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"image/png"}},
+		Body:       io.NopCloser(bytes.NewReader(png)),
+	}
+
+	result, err := httpResponseToMCPResult(resp, responseHeaderFilter{redacted: DefaultRedactedHeaders})
+	if err != nil {
+		t.Fatalf("httpResponseToMCPResult error: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(result.Content[0].(*sdkmcp.TextContent).Text), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload["bodyEncoding"] != "base64" || payload["body"] != base64.StdEncoding.EncodeToString(png) {
+		t.Fatalf("expected base64 body, got %v", payload)
+	}
+	if len(result.Content) != 2 {
+		t.Fatalf("expected text and image content, got %d items", len(result.Content))
+	}
+	image, ok := result.Content[1].(*sdkmcp.ImageContent)
+	if !ok {
+		t.Fatalf("expected image content, got %T", result.Content[1])
+	}
+	if image.MIMEType != "image/png" || !bytes.Equal(image.Data, png) {
+		t.Fatalf("expected original PNG bytes, got %s %v", image.MIMEType, image.Data)
+	}
+}
+
+func TestProxyToolCallToHTTPRequestNonJSONBodies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		input           map[string]any
+		params          map[string]any
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "form-encoded map",
+			input:           map[string]any{"body": map[string]any{"city": "string"}},
+			params:          map[string]any{"bodyContentType": "application/x-www-form-urlencoded", "body": map[string]any{"city": "San Francisco", "tags": []any{"a", "b"}}},
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "city=San+Francisco&tags=a&tags=b",
+		},
+		{
+			name:            "declared form bodyType",
+			input:           map[string]any{"bodyType": "form-data", "body": map[string]any{"city": "string"}},
+			params:          map[string]any{"body": map[string]any{"city": "Paris"}},
+			wantContentType: "application/x-www-form-urlencoded",
+			wantBody:        "city=Paris",
+		},
+		{
+			name:            "raw text",
+			input:           map[string]any{"body": map[string]any{}},
+			params:          map[string]any{"bodyContentType": "text/plain; charset=utf-8", "body": "hello {not json}"},
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "hello {not json}",
+		},
+		{
+			name:            "json default",
+			input:           map[string]any{"body": map[string]any{"city": "string"}},
+			params:          map[string]any{"body": map[string]any{"city": "Paris"}},
+			wantContentType: "application/json",
+			wantBody:        `{"city":"Paris"}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resource := testResource("http://localhost:8080/restaurants", "POST", tc.input)
+			req, err := proxyToolCallToHTTPRequest(context.Background(), resource, tc.params, x402local.StdLogger{})
+			if err != nil {
+				t.Fatalf("proxyToolCallToHTTPRequest error: %v", err)
+			}
+			if got := req.Header.Get("Content-Type"); got != tc.wantContentType {
+				t.Fatalf("expected Content-Type %q, got %q", tc.wantContentType, got)
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+			if string(body) != tc.wantBody {
+				t.Fatalf("expected body %q, got %q", tc.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestProxyToolCallToHTTPRequestRejectsNonStringTextBody(t *testing.T) {
+	t.Parallel()
+
+	resource := testResource("http://localhost:8080/restaurants", "POST", nil)
+	_, err := proxyToolCallToHTTPRequest(context.Background(), resource, map[string]any{
+		"bodyContentType": "text/plain",
+		"body":            map[string]any{"city": "Paris"},
+	}, x402local.StdLogger{})
+	if err == nil {
+		t.Fatal("expected an object body to be rejected for text/plain")
+	}
+}
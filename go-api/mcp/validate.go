@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// validateAgainstSchema checks value against the subset of JSON schema that
+// resourceToTool emits: type (single or list), properties, required,
+// additionalProperties and items.
+// It returns one message per offending field, prefixed with its path.
+func validateAgainstSchema(value any, schema map[string]any, path string) []string {
+	if schema == nil {
+		return nil
+	}
+	var problems []string
+
+	if typeName, ok := schema["type"].(string); ok && !matchesSchemaType(value, typeName) {
+		return append(problems, fmt.Sprintf("%s: expected %s, got %s", path, typeName, describeJSONType(value)))
+	}
+	if typeNames := stringList(schema["type"]); len(typeNames) > 0 && !matchesAnySchemaType(value, typeNames) {
+		return append(problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(typeNames, " or "), describeJSONType(value)))
+	}
+	if items, ok := value.([]any); ok {
+		if itemSchema, ok := schema["items"].(map[string]any); ok {
+			for i, item := range items {
+				problems = append(problems, validateAgainstSchema(item, itemSchema, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+
+	object, ok := value.(map[string]any)
+	if !ok {
+		return problems
+	}
+	props, _ := schema["properties"].(map[string]any)
+
+	for _, name := range stringList(schema["required"]) {
+		if _, present := object[name]; !present {
+			problems = append(problems, fmt.Sprintf("%s: missing required field", joinPath(path, name)))
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		propSchema, declared := props[key].(map[string]any)
+		if !declared {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				problems = append(problems, fmt.Sprintf("%s: unknown field", joinPath(path, key)))
+			}
+			continue
+		}
+		problems = append(problems, validateAgainstSchema(object[key], propSchema, joinPath(path, key))...)
+	}
+	return problems
+}
+
+func matchesAnySchemaType(value any, typeNames []string) bool {
+	for _, typeName := range typeNames {
+		if matchesSchemaType(value, typeName) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSchemaType(value any, typeName string) bool {
+	switch typeName {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := numericValue(value)
+		return ok
+	case "integer":
+		number, ok := numericValue(value)
+		return ok && number == math.Trunc(number)
+	default:
+		return true
+	}
+}
+
+func numericValue(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		parsed, err := v.Float64()
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func describeJSONType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := numericValue(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
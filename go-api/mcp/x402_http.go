@@ -3,18 +3,82 @@ package mcp
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
+	x402local "github.com/andrewreder/agent-poc/go-api/x402"
 	x402types "github.com/coinbase/x402/go/types"
 )
 
+const redactedHeaderValue = "***redacted***"
+
+// DefaultRedactedHeaders lists headers that carry credentials or payment data
+// and are masked when echoed back to agents or written to logs.
+var DefaultRedactedHeaders = append([]string{"Authorization"}, x402local.PaymentHeaders...)
+
+// BuildPaymentMeta returns the _meta object proxy_tool_call expects, with the
+// payment under "x402/payment". For v2, resource and accepted are required and
+// sent as-is. For v1, the scheme and network are taken from accepted and
+// resource is ignored.
+func BuildPaymentMeta(version int, resource, accepted, payload map[string]any) (map[string]any, error) {
+	if payload == nil {
+		return nil, fmt.Errorf("x402/payment metadata missing payload")
+	}
+
+	payment := map[string]any{
+		"x402Version": version,
+		"payload":     payload,
+	}
+	switch version {
+	case 1:
+		scheme, _ := accepted["scheme"].(string)
+		network, _ := accepted["network"].(string)
+		if scheme == "" || network == "" {
+			return nil, fmt.Errorf("x402/payment metadata missing scheme or network for v1 payment")
+		}
+		payment["scheme"] = scheme
+		payment["network"] = network
+	case 2:
+		if resource != nil {
+			payment["resource"] = resource
+		}
+		if accepted != nil {
+			payment["accepted"] = accepted
+		}
+	default:
+		return nil, fmt.Errorf("unsupported x402 version %d", version)
+	}
+	if err := validatePaymentShape(version, payment); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{x402local.MetaKeyPayment: payment}, nil
+}
+
+// validatePaymentShape checks the fields a payment of the given version must carry.
+func validatePaymentShape(version int, payment map[string]any) error {
+	if version >= 2 {
+		if _, ok := payment["resource"].(map[string]any); !ok {
+			return fmt.Errorf("x402/payment metadata missing resource for v2 payment")
+		}
+		if _, ok := payment["accepted"].(map[string]any); !ok {
+			return fmt.Errorf("x402/payment metadata missing accepted for v2 payment")
+		}
+	}
+	return nil
+}
+
 type paymentHeader struct {
 	Name    string
 	Value   string
 	Version int
 }
 
-func encodePaymentHeader(payment any) (*paymentHeader, error) {
+// encodePaymentHeader base64-encodes payment into the header its x402 version
+// uses, or into headerName when set.
+func encodePaymentHeader(payment any, headerName string) (*paymentHeader, error) {
 	payloadBytes, err := json.Marshal(payment)
 	if err != nil {
 		return nil, err
@@ -23,18 +87,20 @@ func encodePaymentHeader(payment any) (*paymentHeader, error) {
 	version, err := x402types.DetectVersion(payloadBytes)
 	if err != nil {
 		if version, ok := normalizeX402Version(extractX402Version(payment)); ok {
-			return buildPaymentHeader(version, payloadBytes), nil
+			return buildPaymentHeader(version, payloadBytes, headerName), nil
 		}
 		return nil, err
 	}
 
-	return buildPaymentHeader(version, payloadBytes), nil
+	return buildPaymentHeader(version, payloadBytes, headerName), nil
 }
 
-func buildPaymentHeader(version int, payloadBytes []byte) *paymentHeader {
-	headerName := "X-PAYMENT"
-	if version >= 2 {
-		headerName = "PAYMENT-SIGNATURE"
+func buildPaymentHeader(version int, payloadBytes []byte, headerName string) *paymentHeader {
+	if headerName == "" {
+		headerName = x402local.HeaderXPayment
+		if version >= 2 {
+			headerName = x402local.HeaderPaymentSignature
+		}
 	}
 	return &paymentHeader{
 		Name:    headerName,
@@ -47,7 +113,7 @@ func decodePaymentRequired(resp *http.Response, body []byte) map[string]any {
 	if resp == nil {
 		return nil
 	}
-	if paymentRequired := decodePaymentHeader(resp.Header.Get("PAYMENT-REQUIRED")); paymentRequired != nil {
+	if paymentRequired := decodePaymentHeader(x402local.HeaderValue(resp.Header, x402local.HeaderPaymentRequired)); paymentRequired != nil {
 		return paymentRequired
 	}
 	if resp.StatusCode != http.StatusPaymentRequired || len(body) == 0 {
@@ -60,11 +126,13 @@ func decodePaymentRequired(resp *http.Response, body []byte) map[string]any {
 	}
 	version, err := x402types.DetectVersion(body)
 	if err != nil {
-		version, ok := normalizeX402Version(decoded["x402Version"])
-		if !ok || version != 1 {
+		declared, ok := normalizeX402Version(decoded["x402Version"])
+		if !ok {
 			return nil
 		}
-	} else if version != 1 {
+		version = declared
+	}
+	if version != 1 && version != 2 {
 		return nil
 	}
 	if _, ok := decoded["accepts"]; !ok {
@@ -73,14 +141,43 @@ func decodePaymentRequired(resp *http.Response, body []byte) map[string]any {
 	return decoded
 }
 
-func decodePaymentResponse(resp *http.Response) map[string]any {
+// decodePaymentResponse returns the settlement reported by resp: one object
+// for a single settlement, a list when the upstream reported several (e.g. a
+// multi-settlement gateway), or nil when there is none.
+func decodePaymentResponse(resp *http.Response) any {
+	decoded := decodePaymentResponses(resp)
+	switch len(decoded) {
+	case 0:
+		return nil
+	case 1:
+		return decoded[0]
+	default:
+		return decoded
+	}
+}
+
+// decodePaymentResponses decodes every settlement header value on resp,
+// including values a gateway folded into one comma-separated line. Base64
+// never contains a comma, so splitting on it is safe. PAYMENT-RESPONSE takes
+// precedence over the v1 X-PAYMENT-RESPONSE.
+func decodePaymentResponses(resp *http.Response) []map[string]any {
 	if resp == nil {
 		return nil
 	}
-	if paymentResponse := decodePaymentHeader(resp.Header.Get("PAYMENT-RESPONSE")); paymentResponse != nil {
-		return paymentResponse
+	for _, name := range []string{x402local.HeaderPaymentResponse, x402local.HeaderXPaymentResponse} {
+		var decoded []map[string]any
+		for _, value := range x402local.HeaderValues(resp.Header, name) {
+			for _, part := range strings.Split(value, ",") {
+				if paymentResponse := decodePaymentHeader(strings.TrimSpace(part)); paymentResponse != nil {
+					decoded = append(decoded, paymentResponse)
+				}
+			}
+		}
+		if len(decoded) > 0 {
+			return decoded
+		}
 	}
-	return decodePaymentHeader(resp.Header.Get("X-PAYMENT-RESPONSE"))
+	return nil
 }
 
 func extractX402Version(payment any) any {
@@ -90,3 +187,18 @@ func extractX402Version(payment any) any {
 	}
 	return paymentMap["x402Version"]
 }
+
+// RedactHeaders returns a copy of headers with the values of names masked,
+// matching names in any casing.
+func RedactHeaders(headers http.Header, names []string) http.Header {
+	redacted := headers.Clone()
+	for key, values := range redacted {
+		if !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, key) }) {
+			continue
+		}
+		for i := range values {
+			values[i] = redactedHeaderValue
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,133 @@
+package x402
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// DefaultTokenDecimals is assumed for assets whose decimals are not
+// configured; it matches USDC
+const DefaultTokenDecimals = 6
+
+// TokenDecimals returns decimals as a ToolPricingConfig.Decimals value
+func TokenDecimals(decimals int) *int {
+	return &decimals
+}
+
+// decimalsOrDefault returns the configured decimals, or DefaultTokenDecimals
+// when none are set
+func decimalsOrDefault(decimals *int) int {
+	if decimals == nil {
+		return DefaultTokenDecimals
+	}
+	return *decimals
+}
+
+// maxTokenDecimals bounds the decimals accepted by the amount helpers
+const maxTokenDecimals = 36
+
+// ErrInvalidAmount is returned for amounts that are malformed, negative or
+// would lose precision at the token's decimals
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// AmountFromDecimal converts a human decimal amount such as "0.01" to the
+// token's smallest unit ("10000" for 6 decimals). Fractional digits beyond
+// decimals are rejected unless they are zeros, so no value is silently rounded.
+func AmountFromDecimal(amount string, decimals int) (string, error) {
+	if decimals < 0 || decimals > maxTokenDecimals {
+		return "", fmt.Errorf("%w: decimals %d out of range", ErrInvalidAmount, decimals)
+	}
+	value := strings.TrimSpace(amount)
+	whole, frac, _ := strings.Cut(value, ".")
+	if whole == "" && frac == "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return "", fmt.Errorf("%w: %q must be a non-negative decimal number", ErrInvalidAmount, amount)
+	}
+	if len(frac) > decimals {
+		if strings.Trim(frac[decimals:], "0") != "" {
+			return "", fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, amount, decimals)
+		}
+		frac = frac[:decimals]
+	}
+	digits := whole + frac + strings.Repeat("0", decimals-len(frac))
+	units, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return units.String(), nil
+}
+
+// AmountToDecimal converts a smallest-unit amount back to a human decimal
+// string, trimming trailing zeros ("10000" with 6 decimals is "0.01").
+func AmountToDecimal(amount string, decimals int) (string, error) {
+	if decimals < 0 || decimals > maxTokenDecimals {
+		return "", fmt.Errorf("%w: decimals %d out of range", ErrInvalidAmount, decimals)
+	}
+	value := strings.TrimSpace(amount)
+	if value == "" || !isDigits(value) {
+		return "", fmt.Errorf("%w: %q must be a non-negative integer", ErrInvalidAmount, amount)
+	}
+	units, _ := new(big.Int).SetString(value, 10)
+	digits := units.String()
+	if decimals == 0 {
+		return digits, nil
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, frac := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if frac == "" {
+		return whole, nil
+	}
+	return whole + "." + frac, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizedAmount returns a smallest-unit amount in whole tokens so prices in
+// assets with different decimals can be compared. ok is false for malformed
+// amounts or decimals.
+func normalizedAmount(amount string, decimals int) (*big.Rat, bool) {
+	if decimals < 0 || decimals > maxTokenDecimals || !isDigits(amount) || amount == "" {
+		return nil, false
+	}
+	units, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return nil, false
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Rat).SetFrac(units, scale), true
+}
+
+// sortedByCost returns a copy of options ordered by normalized amount,
+// cheapest first. Ties and options with malformed amounts keep their relative
+// order; malformed amounts sort last.
+func sortedByCost(options []ToolPricingConfig) []ToolPricingConfig {
+	sorted := slices.Clone(options)
+	slices.SortStableFunc(sorted, func(a, b ToolPricingConfig) int {
+		costA, okA := normalizedAmount(a.Amount, decimalsOrDefault(a.Decimals))
+		costB, okB := normalizedAmount(b.Amount, decimalsOrDefault(b.Decimals))
+		switch {
+		case !okA && !okB:
+			return 0
+		case !okA:
+			return 1
+		case !okB:
+			return -1
+		}
+		return costA.Cmp(costB)
+	})
+	return sorted
+}
@@ -0,0 +1,155 @@
+package x402
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAmountFromDecimal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+		wantErr  bool
+	}{
+		{amount: "0.01", decimals: 6, want: "10000"},
+		{amount: "1", decimals: 6, want: "1000000"},
+		{amount: "1.5", decimals: 0, wantErr: true},
+		{amount: "42", decimals: 0, want: "42"},
+		{amount: ".5", decimals: 2, want: "50"},
+		{amount: "3.", decimals: 2, want: "300"},
+		{amount: "0.000001", decimals: 6, want: "1"},
+		{amount: "0.0000001", decimals: 6, wantErr: true},
+		{amount: "0.0100000", decimals: 6, want: "10000"},
+		{amount: "0.000000000000000001", decimals: 18, want: "1"},
+		{amount: "1.12345678901234567891", decimals: 18, wantErr: true},
+		{amount: "123456789.123456789123456789", decimals: 30, want: "123456789123456789123456789000000000000"},
+		{amount: "007.10", decimals: 2, want: "710"},
+		{amount: "0", decimals: 6, want: "0"},
+		{amount: "-0.01", decimals: 6, wantErr: true},
+		{amount: "1e3", decimals: 6, wantErr: true},
+		{amount: "", decimals: 6, wantErr: true},
+		{amount: ".", decimals: 6, wantErr: true},
+		{amount: "1.2.3", decimals: 6, wantErr: true},
+		{amount: "1", decimals: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := AmountFromDecimal(tt.amount, tt.decimals)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("AmountFromDecimal(%q, %d) = %q, want ErrInvalidAmount, got %v", tt.amount, tt.decimals, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("AmountFromDecimal(%q, %d) = %q, %v; want %q", tt.amount, tt.decimals, got, err, tt.want)
+		}
+	}
+}
+
+func TestAmountToDecimal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		amount   string
+		decimals int
+		want     string
+		wantErr  bool
+	}{
+		{amount: "10000", decimals: 6, want: "0.01"},
+		{amount: "1000000", decimals: 6, want: "1"},
+		{amount: "1", decimals: 18, want: "0.000000000000000001"},
+		{amount: "42", decimals: 0, want: "42"},
+		{amount: "0", decimals: 6, want: "0"},
+		{amount: "00150", decimals: 2, want: "1.5"},
+		{amount: "-5", decimals: 6, wantErr: true},
+		{amount: "0.5", decimals: 6, wantErr: true},
+		{amount: "", decimals: 6, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := AmountToDecimal(tt.amount, tt.decimals)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("AmountToDecimal(%q, %d) = %q, want ErrInvalidAmount, got %v", tt.amount, tt.decimals, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("AmountToDecimal(%q, %d) = %q, %v; want %q", tt.amount, tt.decimals, got, err, tt.want)
+		}
+		back, err := AmountFromDecimal(got, tt.decimals)
+		if err != nil || strings.TrimLeft(back, "0") != strings.TrimLeft(tt.amount, "0") {
+			t.Errorf("round trip of %q with %d decimals gave %q, %v", tt.amount, tt.decimals, back, err)
+		}
+	}
+}
+
+func TestSetToolPriceDecimal(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	if err := m.SetToolPriceDecimal("weather", "0.01", 6); err != nil {
+		t.Fatalf("SetToolPriceDecimal: %v", err)
+	}
+	options, _ := m.toolPricing("weather")
+	if len(options) != 1 || options[0].Amount != "10000" {
+		t.Fatalf("expected 10000 smallest units, got %+v", options)
+	}
+	if err := m.SetToolPriceDecimal("weather", "0.0000001", 6); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount for excess precision, got %v", err)
+	}
+}
+
+func TestGetPaymentRequirementsSortedByCost(t *testing.T) {
+	m := newTestMiddleware("http://localhost:8003")
+	m.SetToolPrice("mixed_tool", "20000") // 0.02 USDC
+	m.AddToolPaymentOption("mixed_tool", ToolPricingConfig{
+		Amount: "5000000000000000", Asset: "0xdai", Network: "eip155:8453", PayTo: "0xpay", Decimals: TokenDecimals(18), // 0.005
+	})
+	m.AddToolPaymentOption("mixed_tool", ToolPricingConfig{
+		Amount: "10000", Asset: "0xusdc-a", Network: "eip155:8453", PayTo: "0xpay", // 0.01
+	})
+	m.AddToolPaymentOption("mixed_tool", ToolPricingConfig{
+		Amount: "10000", Asset: "0xusdc-b", Network: "eip155:8453", PayTo: "0xpay", // 0.01, ties keep order
+	})
+	m.AddToolPaymentOption("mixed_tool", ToolPricingConfig{
+		Amount: "not-a-number", Asset: "0xbroken", Network: "eip155:8453", PayTo: "0xpay",
+	})
+
+	assets := func() []string {
+		var out []string
+		for _, accept := range m.GetPaymentRequirements("mixed_tool").Accepts {
+			out = append(out, accept.Asset)
+		}
+		return out
+	}
+
+	configured := []string{"0x036CbD53842c5426634e7929541eC2318f3dCF7e", "0xdai", "0xusdc-a", "0xusdc-b", "0xbroken"}
+	if got := assets(); !slices.Equal(got, configured) {
+		t.Fatalf("expected configured order by default, got %v", got)
+	}
+
+	m.SetSortAcceptsByCost(true)
+	want := []string{"0xdai", "0xusdc-a", "0xusdc-b", "0x036CbD53842c5426634e7929541eC2318f3dCF7e", "0xbroken"}
+	if got := assets(); !slices.Equal(got, want) {
+		t.Fatalf("expected cheapest first %v, got %v", want, got)
+	}
+}
+
+func TestSortedByCostZeroDecimals(t *testing.T) {
+	t.Parallel()
+
+	// 5 units of a 0-decimal token is 5 whole tokens, not 0.000005
+	options := []ToolPricingConfig{
+		{Amount: "5", Asset: "0xpoints", Decimals: TokenDecimals(0)},
+		{Amount: "20000", Asset: "0xusdc"},
+	}
+	sorted := sortedByCost(options)
+	if sorted[0].Asset != "0xusdc" || sorted[1].Asset != "0xpoints" {
+		t.Fatalf("expected 0.02 USDC before 5 whole tokens, got %+v", sorted)
+	}
+}
@@ -0,0 +1,195 @@
+package x402
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultAuditBuffer is how many records a FileAuditSink queues before it
+// starts dropping them
+const DefaultAuditBuffer = 1024
+
+// ErrAuditSinkClosed is returned by Close on a FileAuditSink closed before
+var ErrAuditSinkClosed = errors.New("audit sink closed")
+
+// AuditRecord is the reconciliation record of one settlement attempt
+type AuditRecord struct {
+	Time        time.Time `json:"time"`
+	Tool        string    `json:"tool"`
+	RequestID   string    `json:"requestId,omitempty"`
+	Payer       string    `json:"payer,omitempty"`
+	Scheme      string    `json:"scheme"`
+	Network     string    `json:"network"`
+	Asset       string    `json:"asset"`
+	Amount      string    `json:"amount"`
+	Transaction string    `json:"transaction,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	// Abandoned marks a deferred settlement given up on after its tool ran;
+	// the payment was never collected and needs manual reconciliation
+	Abandoned bool `json:"abandoned,omitempty"`
+}
+
+// AuditSink receives a record after every settlement attempt, successful or
+// not. Record is called on the tool call's goroutine and must not block.
+type AuditSink interface {
+	Record(record AuditRecord)
+}
+
+// SetAuditSink registers the sink that receives an AuditRecord for each
+// settlement attempt made by WrapToolHandler
+func (m *Middleware) SetAuditSink(sink AuditSink) {
+	m.auditSink = sink
+}
+
+// auditSettlement reports one settlement attempt to the audit sink
+func (m *Middleware) auditSettlement(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements, settle *SettleResponse, err error) {
+	if m.auditSink == nil {
+		return
+	}
+	record := newAuditRecord(ctx, toolName, payment, requirements)
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+	case settle != nil:
+		record.Success = settle.Success
+		record.Transaction = settle.Transaction
+		record.Error = settle.ErrorReason
+		if settle.Payer != "" {
+			record.Payer = settle.Payer
+		}
+	}
+	m.auditSink.Record(record)
+}
+
+// auditAbandoned reports a deferred settlement that ran out of retries
+func (m *Middleware) auditAbandoned(ctx context.Context, pending PendingSettlement, err error) {
+	if m.auditSink == nil {
+		return
+	}
+	record := newAuditRecord(ctx, pending.ToolName, pending.Payment, pending.Requirements)
+	record.Abandoned = true
+	record.Error = err.Error()
+	m.auditSink.Record(record)
+}
+
+func newAuditRecord(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements) AuditRecord {
+	return AuditRecord{
+		Time:      time.Now().UTC(),
+		Tool:      toolName,
+		RequestID: RequestIDFromContext(ctx),
+		Payer:     payerFromPayload(payment.Payload),
+		Scheme:    requirements.Scheme,
+		Network:   requirements.Network,
+		Asset:     requirements.Asset,
+		Amount:    requirements.Amount,
+	}
+}
+
+// FileAuditSink appends AuditRecords to a file as JSON lines. Records are
+// queued and written by a background goroutine so settlement never waits on
+// disk; Close flushes the queue and syncs the file.
+type FileAuditSink struct {
+	file    *os.File
+	logger  Logger
+	records chan AuditRecord
+	done    chan struct{}
+
+	// mu guards closed so Record never sends on a closed channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewFileAuditSink opens path for appending, creating it if needed, and
+// starts the background writer. A nil logger uses StdLogger.
+func NewFileAuditSink(path string, logger Logger) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	if logger == nil {
+		logger = StdLogger{}
+	}
+	s := &FileAuditSink{
+		file:    file,
+		logger:  logger,
+		records: make(chan AuditRecord, DefaultAuditBuffer),
+		done:    make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Record queues record for writing. When the queue is full or the sink is
+// closed the record is logged at error level instead, so it is never lost
+// silently.
+func (s *FileAuditSink) Record(record AuditRecord) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.closed {
+		select {
+		case s.records <- record:
+			return
+		default:
+		}
+	}
+	s.logger.Error("x402 audit record dropped",
+		"tool", record.Tool,
+		"requestId", record.RequestID,
+		"payer", record.Payer,
+		"network", record.Network,
+		"amount", record.Amount,
+		"transaction", record.Transaction,
+		"success", record.Success,
+	)
+}
+
+// Close stops accepting records, writes the queued ones and closes the file.
+// It returns early with ctx's error if the queue does not drain in time.
+func (s *FileAuditSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrAuditSinkClosed
+	}
+	s.closed = true
+	close(s.records)
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return fmt.Errorf("flush audit log: %w", ctx.Err())
+	}
+	if err := s.file.Sync(); err != nil {
+		s.file.Close()
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+	return s.file.Close()
+}
+
+// run writes queued records, flushing whenever the queue is momentarily empty
+func (s *FileAuditSink) run() {
+	defer close(s.done)
+	w := bufio.NewWriter(s.file)
+	encoder := json.NewEncoder(w)
+	for record := range s.records {
+		if err := encoder.Encode(record); err != nil {
+			s.logger.Error("x402 audit write error", "tool", record.Tool, "requestId", record.RequestID, "err", err)
+		}
+		if len(s.records) == 0 {
+			if err := w.Flush(); err != nil {
+				s.logger.Error("x402 audit flush error", "err", err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		s.logger.Error("x402 audit flush error", "err", err)
+	}
+}
@@ -0,0 +1,151 @@
+package x402
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type recordingAuditSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *recordingAuditSink) Record(record AuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+}
+
+func TestWrapToolHandlerAuditsSettlements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		program     func(*FakeFacilitator)
+		wantSuccess bool
+		wantTx      string
+		wantError   string
+	}{
+		{name: "settled", program: func(*FakeFacilitator) {}, wantSuccess: true, wantTx: "0xfake0001"},
+		{name: "settle rejected", program: func(f *FakeFacilitator) { f.FailSettlement("insufficient_funds") }, wantError: "insufficient_funds"},
+		{name: "settle error", program: func(f *FakeFacilitator) { f.SetSettleError(errors.New("boom")) }, wantError: "payment settlement failed: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fake := NewFakeFacilitator()
+			tt.program(fake)
+			m := newFakeMiddleware(fake)
+			sink := &recordingAuditSink{}
+			m.SetAuditSink(sink)
+
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+			req := paidRequest()
+			req.Params.Meta[MetaKeyRequestID] = "req-audit"
+			if _, _, err := handler(context.Background(), req, nil); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+
+			if len(sink.records) != 1 {
+				t.Fatalf("expected 1 audit record, got %d", len(sink.records))
+			}
+			got := sink.records[0]
+			want := AuditRecord{
+				Time:        got.Time,
+				Tool:        "paid_tool",
+				RequestID:   "req-audit",
+				Scheme:      SchemeExact,
+				Network:     "eip155:84532",
+				Asset:       "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				Amount:      "10000",
+				Transaction: tt.wantTx,
+				Success:     tt.wantSuccess,
+				Error:       tt.wantError,
+			}
+			if got != want {
+				t.Fatalf("unexpected audit record:\n got %+v\nwant %+v", got, want)
+			}
+			if got.Time.IsZero() {
+				t.Fatalf("expected a timestamp")
+			}
+		})
+	}
+}
+
+func TestWrapToolHandlerSkipsAuditWithoutSettlement(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.RejectPayments("invalid_signature")
+	m := newFakeMiddleware(fake)
+	sink := &recordingAuditSink{}
+	m.SetAuditSink(sink)
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	if _, _, err := handler(context.Background(), paidRequest(), nil); err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if len(sink.records) != 0 {
+		t.Fatalf("expected no audit record for a payment that never settled, got %+v", sink.records)
+	}
+}
+
+func TestFileAuditSinkWritesJSONLines(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := &recordingLogger{}
+	sink, err := NewFileAuditSink(path, logger)
+	if err != nil {
+		t.Fatalf("NewFileAuditSink error: %v", err)
+	}
+	sink.Record(AuditRecord{Tool: "paid_tool", Network: "eip155:84532", Amount: "10000", Transaction: "0xabc", Success: true})
+	sink.Record(AuditRecord{Tool: "paid_tool", Network: "eip155:84532", Amount: "10000", Error: "insufficient_funds"})
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	sink.Record(AuditRecord{Tool: "late_tool"})
+	if len(logger.records) != 1 || logger.records[0].msg != "x402 audit record dropped" {
+		t.Fatalf("expected a record after Close to be logged as dropped, got %+v", logger.records)
+	}
+	if err := sink.Close(context.Background()); !errors.Is(err, ErrAuditSinkClosed) {
+		t.Fatalf("expected ErrAuditSinkClosed on second Close, got %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer file.Close()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("audit line is not JSON: %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 audit lines, got %d", len(records))
+	}
+	if !records[0].Success || records[0].Transaction != "0xabc" {
+		t.Fatalf("unexpected first record %+v", records[0])
+	}
+	if records[1].Success || records[1].Error != "insufficient_funds" {
+		t.Fatalf("unexpected second record %+v", records[1])
+	}
+}
@@ -0,0 +1,119 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AuthorizePaymentToolName is the name AddAuthorizePaymentTool registers
+const AuthorizePaymentToolName = "authorize_payment"
+
+// AuthorizePaymentParams defines the input of the authorize_payment tool. The
+// payment itself travels in _meta["x402/payment"], as for a paid call.
+type AuthorizePaymentParams struct {
+	ToolName string `json:"toolName" jsonschema:"name of the paid tool the payment is for,required"`
+}
+
+// AuthorizePaymentOutput reports whether a payment would be accepted by a tool
+type AuthorizePaymentOutput struct {
+	ToolName string `json:"toolName"`
+	Valid    bool   `json:"valid"`
+	// Free is set when the tool needs no payment
+	Free bool `json:"free,omitempty"`
+	// Reason explains an invalid authorization; InvalidCode is the stable
+	// code of a facilitator rejection
+	Reason       string               `json:"reason,omitempty"`
+	InvalidCode  string               `json:"invalidCode,omitempty"`
+	Requirements *PaymentRequirements `json:"requirements,omitempty"`
+}
+
+// AddAuthorizePaymentTool registers authorize_payment on server so agents can
+// check a payment before making an expensive paid call
+func (m *Middleware) AddAuthorizePaymentTool(server *mcp.Server) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name: AuthorizePaymentToolName,
+		Description: "Check whether the payment in _meta[\"x402/payment\"] would be accepted by a paid tool, " +
+			"without settling it or running the tool.",
+	}, m.AuthorizePayment)
+}
+
+// AuthorizePayment verifies the attached payment against params.ToolName's
+// requirements with the facilitator, applying the same local checks as a paid
+// call, but never settles it or runs the tool
+func (m *Middleware) AuthorizePayment(ctx context.Context, req *mcp.CallToolRequest, params *AuthorizePaymentParams) (*mcp.CallToolResult, any, error) {
+	output := AuthorizePaymentOutput{ToolName: params.ToolName}
+	pricing := m.GetPaymentRequirements(params.ToolName)
+	if pricing == nil {
+		if _, free := m.freeTools[params.ToolName]; m.strict && !free {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnpricedTool, params.ToolName)
+		}
+		output.Valid = true
+		output.Free = true
+		return authorizeResult(output, DefaultPaymentMetaKeys, nil)
+	}
+
+	meta := extractMeta(req)
+	if id, ok := meta[MetaKeyRequestID].(string); ok {
+		ctx = WithRequestID(ctx, id)
+	} else if RequestIDFromContext(ctx) == "" {
+		ctx = WithRequestID(ctx, "")
+	}
+	ctx, cancel := WithCallBudget(ctx, m.callBudget)
+	defer cancel()
+
+	keys := m.PaymentMetaKeysFor(params.ToolName)
+	payment, err := m.VerifyPayment(ctx, params.ToolName, meta)
+	if errors.Is(err, ErrCallBudgetExhausted) {
+		return callBudgetExhaustedResult(err), nil, nil
+	}
+	if err == nil && payment == nil {
+		output.Reason = "no payment attached in _meta[\"" + keys.Payment + "\"]"
+		return authorizeResult(output, keys, pricing)
+	}
+	if err == nil {
+		var requirements *PaymentRequirements
+		requirements, err = matchRequirements(pricing.Accepts, payment)
+		if err == nil {
+			output.Requirements = requirements
+			err = checkPaymentCovers(payment, requirements, m.requirementDecimals(params.ToolName, requirements))
+		}
+	}
+	if err != nil {
+		output.Reason = err.Error()
+		var invalid *PaymentInvalidError
+		if errors.As(err, &invalid) {
+			output.InvalidCode = invalid.Code
+			output.Reason = invalid.Reason
+		}
+		m.logger.Info("x402 payment authorization rejected", "tool", params.ToolName, "requestId", RequestIDFromContext(ctx), "reason", output.Reason)
+		return authorizeResult(output, keys, pricing)
+	}
+
+	output.Valid = true
+	return authorizeResult(output, DefaultPaymentMetaKeys, nil)
+}
+
+// authorizeResult renders output; pricing is attached under the tool's
+// payment-required key when the caller needs to pay differently
+func authorizeResult(output AuthorizePaymentOutput, keys PaymentMetaKeys, pricing *PaymentRequiredData) (*mcp.CallToolResult, any, error) {
+	text, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal authorization: %w", err)
+	}
+	result := &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+		StructuredContent: output,
+	}
+	if pricing != nil {
+		result.Meta = unpaidMeta(keys, pricing)
+	}
+	return result, nil, nil
+}
@@ -0,0 +1,165 @@
+package x402
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func authorizeOutput(t *testing.T, result *mcp.CallToolResult) AuthorizePaymentOutput {
+	t.Helper()
+	if result.IsError {
+		t.Fatalf("expected a non-error result, got %q", resultText(t, result))
+	}
+	output, ok := result.StructuredContent.(AuthorizePaymentOutput)
+	if !ok {
+		t.Fatalf("expected AuthorizePaymentOutput, got %T", result.StructuredContent)
+	}
+	return output
+}
+
+func TestAuthorizePaymentValid(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	result, _, err := m.AuthorizePayment(context.Background(), paidRequest(), &AuthorizePaymentParams{ToolName: "paid_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+
+	output := authorizeOutput(t, result)
+	if !output.Valid || output.Reason != "" {
+		t.Fatalf("expected a valid authorization, got %+v", output)
+	}
+	if output.Requirements == nil || output.Requirements.Amount != "10000" {
+		t.Fatalf("expected the matched requirements, got %+v", output.Requirements)
+	}
+	if len(fake.Verified()) != 1 {
+		t.Fatalf("expected one verify call, got %d", len(fake.Verified()))
+	}
+	if len(fake.Settled()) != 0 {
+		t.Fatalf("authorize_payment must not settle, got %d settlements", len(fake.Settled()))
+	}
+}
+
+func TestAuthorizePaymentInvalid(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.RejectPayments("insufficient_funds")
+	m := newFakeMiddleware(fake)
+	result, _, err := m.AuthorizePayment(context.Background(), paidRequest(), &AuthorizePaymentParams{ToolName: "paid_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+
+	output := authorizeOutput(t, result)
+	if output.Valid || output.Reason != "insufficient_funds" || output.InvalidCode != InvalidReasonCode("insufficient_funds") {
+		t.Fatalf("expected an invalid authorization with the facilitator's reason, got %+v", output)
+	}
+	if _, ok := result.Meta[MetaKeyPaymentRequired]; !ok {
+		t.Fatalf("expected payment requirements in _meta, got %v", result.Meta)
+	}
+	if len(fake.Settled()) != 0 {
+		t.Fatalf("authorize_payment must not settle, got %d settlements", len(fake.Settled()))
+	}
+}
+
+func TestAuthorizePaymentRejectsUnderpayment(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	req := paidRequest()
+	req.Params.Meta[MetaKeyPayment].(map[string]any)["payload"] = map[string]any{
+		"authorization": map[string]any{"value": "9999"},
+	}
+	result, _, err := m.AuthorizePayment(context.Background(), req, &AuthorizePaymentParams{ToolName: "paid_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+	if output := authorizeOutput(t, result); output.Valid || output.Reason == "" {
+		t.Fatalf("expected the local amount check to reject the payment, got %+v", output)
+	}
+}
+
+func TestAuthorizePaymentWithoutPayment(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	result, _, err := m.AuthorizePayment(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, &AuthorizePaymentParams{ToolName: "paid_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+	if output := authorizeOutput(t, result); output.Valid || output.Reason == "" {
+		t.Fatalf("expected an invalid authorization without a payment, got %+v", output)
+	}
+	if len(fake.Verified()) != 0 {
+		t.Fatalf("expected no verify call without a payment")
+	}
+}
+
+func TestAuthorizePaymentFreeTool(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMiddleware(NewFakeFacilitator())
+	result, _, err := m.AuthorizePayment(context.Background(), paidRequest(), &AuthorizePaymentParams{ToolName: "free_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+	if output := authorizeOutput(t, result); !output.Valid || !output.Free {
+		t.Fatalf("expected a free tool to authorize, got %+v", output)
+	}
+}
+
+func TestAddAuthorizePaymentToolOverMCP(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	var ran bool
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "0.0.1"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "paid_tool"}, WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in map[string]any) (*mcp.CallToolResult, any, error) {
+		ran = true
+		return &mcp.CallToolResult{}, nil, nil
+	}))
+	m.AddAuthorizePaymentTool(server)
+
+	clientTransport, serverTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(context.Background(), serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(context.Background(), clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(context.Background(), &mcp.CallToolParams{
+		Meta:      paidRequest().Params.Meta,
+		Name:      AuthorizePaymentToolName,
+		Arguments: map[string]any{"toolName": "paid_tool"},
+	})
+	if err != nil {
+		t.Fatalf("CallTool error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected authorize_payment to succeed, got %+v", result.Content)
+	}
+	structured, ok := result.StructuredContent.(map[string]any)
+	if !ok || structured["valid"] != true {
+		t.Fatalf("expected valid=true, got %v", result.StructuredContent)
+	}
+	if ran {
+		t.Fatalf("authorize_payment must not run the paid tool")
+	}
+	if len(fake.Settled()) != 0 {
+		t.Fatalf("authorize_payment must not settle, got %d settlements", len(fake.Settled()))
+	}
+}
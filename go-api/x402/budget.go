@@ -0,0 +1,80 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCallBudgetExhausted is returned when a tool call has used up its attempt
+// or time allowance.
+var ErrCallBudgetExhausted = errors.New("call budget exhausted")
+
+// CallBudget caps the attempts and wall-clock time spent across every
+// sub-operation (facilitator verify/settle, proxy requests) of one tool call.
+// Zero values mean unlimited.
+type CallBudget struct {
+	MaxAttempts int
+	MaxDuration time.Duration
+}
+
+type callBudgetKey struct{}
+
+type budgetTracker struct {
+	mu          sync.Mutex
+	maxAttempts int
+	used        int
+}
+
+// WithCallBudget attaches budget to ctx. If ctx already carries a budget it is
+// kept, so nested layers share the allowance of the outermost call.
+func WithCallBudget(ctx context.Context, budget CallBudget) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Value(callBudgetKey{}).(*budgetTracker); ok {
+		return ctx, func() {}
+	}
+	if budget.MaxAttempts <= 0 && budget.MaxDuration <= 0 {
+		return ctx, func() {}
+	}
+	ctx = context.WithValue(ctx, callBudgetKey{}, &budgetTracker{maxAttempts: budget.MaxAttempts})
+	if budget.MaxDuration > 0 {
+		return context.WithTimeout(ctx, budget.MaxDuration)
+	}
+	return ctx, func() {}
+}
+
+// SpendAttempt records one sub-operation attempt against the budget on ctx.
+// It returns ErrCallBudgetExhausted when no attempts or time remain.
+func SpendAttempt(ctx context.Context) error {
+	tracker, ok := ctx.Value(callBudgetKey{}).(*budgetTracker)
+	if !ok {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCallBudgetExhausted, err)
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if tracker.maxAttempts > 0 && tracker.used >= tracker.maxAttempts {
+		return fmt.Errorf("%w: %d attempts used", ErrCallBudgetExhausted, tracker.used)
+	}
+	tracker.used++
+	return nil
+}
+
+// BudgetError maps err to ErrCallBudgetExhausted when it was caused by the
+// budget deadline on ctx expiring; other errors are returned unchanged.
+func BudgetError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, ErrCallBudgetExhausted) {
+		return err
+	}
+	if _, ok := ctx.Value(callBudgetKey{}).(*budgetTracker); !ok {
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrCallBudgetExhausted, err)
+	}
+	return err
+}
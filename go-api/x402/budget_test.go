@@ -0,0 +1,53 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpendAttemptWithoutBudget(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 10; i++ {
+		if err := SpendAttempt(context.Background()); err != nil {
+			t.Fatalf("expected unlimited attempts without a budget, got %v", err)
+		}
+	}
+}
+
+func TestSpendAttemptSharedAcrossNestedBudgets(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := WithCallBudget(context.Background(), CallBudget{MaxAttempts: 2})
+	defer cancel()
+	// A nested layer with a larger allowance must not reset the outer budget.
+	nested, nestedCancel := WithCallBudget(ctx, CallBudget{MaxAttempts: 10})
+	defer nestedCancel()
+
+	if err := SpendAttempt(ctx); err != nil {
+		t.Fatalf("first attempt: %v", err)
+	}
+	if err := SpendAttempt(nested); err != nil {
+		t.Fatalf("second attempt: %v", err)
+	}
+	if err := SpendAttempt(nested); !errors.Is(err, ErrCallBudgetExhausted) {
+		t.Fatalf("expected ErrCallBudgetExhausted, got %v", err)
+	}
+}
+
+func TestSpendAttemptAfterDeadline(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := WithCallBudget(context.Background(), CallBudget{MaxDuration: time.Millisecond})
+	defer cancel()
+	<-ctx.Done()
+
+	if err := SpendAttempt(ctx); !errors.Is(err, ErrCallBudgetExhausted) {
+		t.Fatalf("expected ErrCallBudgetExhausted, got %v", err)
+	}
+	if err := BudgetError(ctx, ctx.Err()); !errors.Is(err, ErrCallBudgetExhausted) {
+		t.Fatalf("expected BudgetError to map deadline, got %v", err)
+	}
+}
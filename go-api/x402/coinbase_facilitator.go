@@ -7,6 +7,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	cdpjwt "github.com/coinbase/cdp-sdk/go/auth"
 	x402http "github.com/coinbase/x402/go/http"
@@ -16,6 +17,10 @@ const (
 	CoinbaseFacilitatorBaseURL = "https://api.cdp.coinbase.com"
 	CoinbaseFacilitatorV2Route = "/platform/v2/x402"
 
+	// CoinbaseFacilitatorBaseURLEnv overrides CoinbaseFacilitatorBaseURL, e.g.
+	// for staging or a proxy. JWTs are signed for the overridden host and path.
+	CoinbaseFacilitatorBaseURLEnv = "CDP_FACILITATOR_BASE_URL"
+
 	X402SDKVersion = "0.7.3"
 	CDPSDKVersion  = "1.29.0"
 )
@@ -25,28 +30,36 @@ type CoinbaseAuthProvider struct {
 	apiKeyID     string
 	apiKeySecret string
 	requestHost  string
+	// routePath is the facilitator route signed into each JWT, including any
+	// path prefix from the base URL
+	routePath string
+	tokens    *jwtCache
 }
 
 // NewCoinbaseAuthProvider builds a provider for Coinbase facilitator auth.
 func NewCoinbaseAuthProvider(apiKeyID, apiKeySecret string) *CoinbaseAuthProvider {
+	baseURL := coinbaseFacilitatorBaseURL()
 	return &CoinbaseAuthProvider{
 		apiKeyID:     apiKeyID,
 		apiKeySecret: apiKeySecret,
-		requestHost:  coinbaseRequestHost(),
+		requestHost:  coinbaseRequestHost(baseURL),
+		routePath:    coinbaseRoutePath(baseURL),
+		tokens:       newJWTCache(),
 	}
 }
 
 // GetAuthHeaders implements the x402 HTTP AuthProvider interface.
 func (p *CoinbaseAuthProvider) GetAuthHeaders(ctx context.Context) (x402http.AuthHeaders, error) {
+	correlation := createCorrelationHeader(RequestIDFromContext(ctx))
 	headers := x402http.AuthHeaders{
 		Verify: map[string]string{
-			"Correlation-Context": createCorrelationHeader(),
+			"Correlation-Context": correlation,
 		},
 		Settle: map[string]string{
-			"Correlation-Context": createCorrelationHeader(),
+			"Correlation-Context": correlation,
 		},
 		Supported: map[string]string{
-			"Correlation-Context": createCorrelationHeader(),
+			"Correlation-Context": correlation,
 		},
 	}
 
@@ -54,15 +67,15 @@ func (p *CoinbaseAuthProvider) GetAuthHeaders(ctx context.Context) (x402http.Aut
 		return headers, nil
 	}
 
-	verify, err := createAuthHeader(p.apiKeyID, p.apiKeySecret, "POST", p.requestHost, CoinbaseFacilitatorV2Route+"/verify")
+	verify, err := p.authHeader("POST", p.routePath+"/verify")
 	if err != nil {
 		return x402http.AuthHeaders{}, err
 	}
-	settle, err := createAuthHeader(p.apiKeyID, p.apiKeySecret, "POST", p.requestHost, CoinbaseFacilitatorV2Route+"/settle")
+	settle, err := p.authHeader("POST", p.routePath+"/settle")
 	if err != nil {
 		return x402http.AuthHeaders{}, err
 	}
-	supported, err := createAuthHeader(p.apiKeyID, p.apiKeySecret, "GET", p.requestHost, CoinbaseFacilitatorV2Route+"/supported")
+	supported, err := p.authHeader("GET", p.routePath+"/supported")
 	if err != nil {
 		return x402http.AuthHeaders{}, err
 	}
@@ -75,22 +88,31 @@ func (p *CoinbaseAuthProvider) GetAuthHeaders(ctx context.Context) (x402http.Aut
 }
 
 // FacilitatorConfigFromEnv builds a facilitator config using env vars when present.
-func FacilitatorConfigFromEnv(defaultURL string) *x402http.FacilitatorConfig {
+// When TestModeEnv is set, CDP credentials are ignored and the plain client
+// talks to FACILITATOR_URL or defaultURL. The client uses the shared pooled
+// transport from NewFacilitatorTransport unless opts override it.
+func FacilitatorConfigFromEnv(defaultURL string, opts ...FacilitatorConfigOption) *x402http.FacilitatorConfig {
 	apiKeyID := strings.TrimSpace(os.Getenv("CDP_API_KEY"))
 	apiKeySecret := strings.TrimSpace(os.Getenv("CDP_API_KEY_SECRET"))
 	facilitatorURL := strings.TrimSpace(os.Getenv("FACILITATOR_URL"))
 
+	if mode := TestModeFromEnv(); mode != TestModeOff {
+		warnTestMode(StdLogger{}, mode)
+		if facilitatorURL == "" {
+			facilitatorURL = defaultURL
+		}
+		return newFacilitatorConfig(facilitatorURL, opts)
+	}
+
 	if facilitatorURL == "" {
 		if apiKeyID != "" || apiKeySecret != "" {
-			facilitatorURL = CoinbaseFacilitatorBaseURL + CoinbaseFacilitatorV2Route
+			facilitatorURL = coinbaseFacilitatorURL()
 		} else {
 			facilitatorURL = defaultURL
 		}
 	}
 
-	config := &x402http.FacilitatorConfig{
-		URL: facilitatorURL,
-	}
+	config := newFacilitatorConfig(facilitatorURL, opts)
 
 	if apiKeyID != "" && apiKeySecret != "" {
 		config.AuthProvider = NewCoinbaseAuthProvider(apiKeyID, apiKeySecret)
@@ -99,6 +121,18 @@ func FacilitatorConfigFromEnv(defaultURL string) *x402http.FacilitatorConfig {
 	return config
 }
 
+// authHeader returns a bearer header for the request, reusing a cached JWT
+// while it is still comfortably valid
+func (p *CoinbaseAuthProvider) authHeader(requestMethod, requestPath string) (string, error) {
+	generate := func() (string, error) {
+		return createAuthHeader(p.apiKeyID, p.apiKeySecret, requestMethod, p.requestHost, requestPath)
+	}
+	if p.tokens == nil {
+		return generate()
+	}
+	return p.tokens.get(p.apiKeyID, p.apiKeySecret, requestMethod, p.requestHost, requestPath, generate)
+}
+
 func createAuthHeader(apiKeyID, apiKeySecret, requestMethod, requestHost, requestPath string) (string, error) {
 	jwt, err := cdpjwt.GenerateJWT(cdpjwt.JwtOptions{
 		KeyID:         apiKeyID,
@@ -106,6 +140,7 @@ func createAuthHeader(apiKeyID, apiKeySecret, requestMethod, requestHost, reques
 		RequestMethod: requestMethod,
 		RequestHost:   requestHost,
 		RequestPath:   requestPath,
+		ExpiresIn:     int64(coinbaseJWTLifetime / time.Second),
 	})
 	if err != nil {
 		return "", fmt.Errorf("generate JWT: %w", err)
@@ -113,13 +148,16 @@ func createAuthHeader(apiKeyID, apiKeySecret, requestMethod, requestHost, reques
 	return "Bearer " + jwt, nil
 }
 
-func createCorrelationHeader() string {
+func createCorrelationHeader(requestID string) string {
 	data := map[string]string{
 		"sdk_version":    CDPSDKVersion,
 		"sdk_language":   "go",
 		"source":         "x402",
 		"source_version": X402SDKVersion,
 	}
+	if requestID != "" {
+		data["request_id"] = requestID
+	}
 
 	keys := make([]string, 0, len(data))
 	for key := range data {
@@ -134,26 +172,46 @@ func createCorrelationHeader() string {
 	return strings.Join(parts, ",")
 }
 
-func coinbaseRequestHost() string {
-	parsed, err := url.Parse(CoinbaseFacilitatorBaseURL)
+// coinbaseFacilitatorBaseURL returns CDP_FACILITATOR_BASE_URL when set,
+// otherwise CoinbaseFacilitatorBaseURL
+func coinbaseFacilitatorBaseURL() string {
+	if baseURL := strings.TrimSpace(os.Getenv(CoinbaseFacilitatorBaseURLEnv)); baseURL != "" {
+		return strings.TrimRight(baseURL, "/")
+	}
+	return CoinbaseFacilitatorBaseURL
+}
+
+func coinbaseFacilitatorURL() string {
+	return coinbaseFacilitatorBaseURL() + CoinbaseFacilitatorV2Route
+}
+
+func coinbaseRequestHost(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
 	if err != nil || parsed.Host == "" {
-		return strings.TrimPrefix(CoinbaseFacilitatorBaseURL, "https://")
+		return strings.TrimPrefix(baseURL, "https://")
 	}
 	return parsed.Host
 }
 
-func GetFacilitatorClient() *x402http.HTTPFacilitatorClient {
+// coinbaseRoutePath is the v2 route under any path prefix in baseURL
+func coinbaseRoutePath(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return CoinbaseFacilitatorV2Route
+	}
+	return strings.TrimRight(parsed.Path, "/") + CoinbaseFacilitatorV2Route
+}
+
+// GetFacilitatorClient builds a client for FACILITATOR_URL, defaulting to the
+// Coinbase facilitator, on the shared pooled transport unless opts override it.
+func GetFacilitatorClient(opts ...FacilitatorConfigOption) *x402http.HTTPFacilitatorClient {
 	facilitatorURL := os.Getenv("FACILITATOR_URL")
 	if facilitatorURL == "" {
-		facilitatorURL = CoinbaseFacilitatorBaseURL + CoinbaseFacilitatorV2Route
+		facilitatorURL = coinbaseFacilitatorURL()
 	}
-	if strings.Contains(facilitatorURL, "coinbase") {
-		return x402http.NewHTTPFacilitatorClient(&x402http.FacilitatorConfig{
-			URL:          facilitatorURL,
-			AuthProvider: NewCoinbaseAuthProvider(os.Getenv("CDP_API_KEY"), os.Getenv("CDP_API_KEY_SECRET")),
-		})
+	config := newFacilitatorConfig(facilitatorURL, opts)
+	if strings.Contains(facilitatorURL, "coinbase") || strings.HasPrefix(facilitatorURL, coinbaseFacilitatorBaseURL()) {
+		config.AuthProvider = NewCoinbaseAuthProvider(os.Getenv("CDP_API_KEY"), os.Getenv("CDP_API_KEY_SECRET"))
 	}
-	return x402http.NewHTTPFacilitatorClient(&x402http.FacilitatorConfig{
-		URL: facilitatorURL,
-	})
+	return x402http.NewHTTPFacilitatorClient(config)
 }
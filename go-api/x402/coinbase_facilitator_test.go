@@ -0,0 +1,147 @@
+package x402
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCDPKeySecret(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+}
+
+// jwtURIs returns the uris claim of a "Bearer <jwt>" header
+func jwtURIs(t *testing.T, header string) []string {
+	t.Helper()
+	parts := strings.Split(strings.TrimPrefix(header, "Bearer "), ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT %q", header)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("decode JWT payload: %v", err)
+	}
+	var claims struct {
+		URIs []string `json:"uris"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("parse JWT claims: %v", err)
+	}
+	return claims.URIs
+}
+
+func TestCoinbaseAuthProviderSignsForBaseURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseURL       string
+		wantVerify    string
+		wantSupported string
+		wantURL       string
+	}{
+		{
+			name:          "default",
+			wantVerify:    "POST api.cdp.coinbase.com/platform/v2/x402/verify",
+			wantSupported: "GET api.cdp.coinbase.com/platform/v2/x402/supported",
+			wantURL:       "https://api.cdp.coinbase.com/platform/v2/x402",
+		},
+		{
+			name:          "override with path prefix",
+			baseURL:       "https://staging.example.com:8443/cdp/",
+			wantVerify:    "POST staging.example.com:8443/cdp/platform/v2/x402/verify",
+			wantSupported: "GET staging.example.com:8443/cdp/platform/v2/x402/supported",
+			wantURL:       "https://staging.example.com:8443/cdp/platform/v2/x402",
+		},
+	}
+	secret := testCDPKeySecret(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(CoinbaseFacilitatorBaseURLEnv, tt.baseURL)
+			t.Setenv("FACILITATOR_URL", "")
+			t.Setenv("CDP_API_KEY", "key-id")
+			t.Setenv("CDP_API_KEY_SECRET", secret)
+
+			config := FacilitatorConfigFromEnv("http://localhost:4021")
+			if config.URL != tt.wantURL {
+				t.Fatalf("expected facilitator URL %q, got %q", tt.wantURL, config.URL)
+			}
+			headers, err := config.AuthProvider.GetAuthHeaders(context.Background())
+			if err != nil {
+				t.Fatalf("GetAuthHeaders: %v", err)
+			}
+			if uris := jwtURIs(t, headers.Verify["Authorization"]); len(uris) != 1 || uris[0] != tt.wantVerify {
+				t.Fatalf("expected verify JWT for %q, got %v", tt.wantVerify, uris)
+			}
+			if uris := jwtURIs(t, headers.Supported["Authorization"]); len(uris) != 1 || uris[0] != tt.wantSupported {
+				t.Fatalf("expected supported JWT for %q, got %v", tt.wantSupported, uris)
+			}
+		})
+	}
+}
+
+func TestCoinbaseAuthProviderReusesJWTs(t *testing.T) {
+	t.Setenv(CoinbaseFacilitatorBaseURLEnv, "")
+	provider := NewCoinbaseAuthProvider("key-id", testCDPKeySecret(t))
+	now := time.Unix(1700000000, 0)
+	provider.tokens.now = func() time.Time { return now }
+
+	first, err := provider.GetAuthHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthHeaders: %v", err)
+	}
+	now = now.Add(coinbaseJWTLifetime - coinbaseJWTRefreshMargin - time.Second)
+	second, err := provider.GetAuthHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthHeaders: %v", err)
+	}
+	if first.Verify["Authorization"] != second.Verify["Authorization"] {
+		t.Fatal("expected the verify JWT to be reused within its validity window")
+	}
+	if first.Verify["Authorization"] == first.Settle["Authorization"] {
+		t.Fatal("expected verify and settle to carry JWTs for their own paths")
+	}
+
+	now = now.Add(2 * time.Second)
+	third, err := provider.GetAuthHeaders(context.Background())
+	if err != nil {
+		t.Fatalf("GetAuthHeaders: %v", err)
+	}
+	if third.Verify["Authorization"] == first.Verify["Authorization"] {
+		t.Fatal("expected the verify JWT to be regenerated near expiry")
+	}
+}
+
+func TestJWTCacheKeyedByKeyMaterial(t *testing.T) {
+	t.Parallel()
+
+	cache := newJWTCache()
+	generated := 0
+	generate := func() (string, error) {
+		generated++
+		return fmt.Sprintf("Bearer token-%d", generated), nil
+	}
+
+	a, _ := cache.get("key-id", "secret-a", "POST", "api.cdp.coinbase.com", "/verify", generate)
+	b, _ := cache.get("key-id", "secret-a", "POST", "api.cdp.coinbase.com", "/verify", generate)
+	c, _ := cache.get("key-id", "secret-b", "POST", "api.cdp.coinbase.com", "/verify", generate)
+	if a != b || a == c || generated != 2 {
+		t.Fatalf("expected reuse for the same key and regeneration for a new one, got %q %q %q (%d generated)", a, b, c, generated)
+	}
+}
@@ -0,0 +1,63 @@
+package x402
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// inflightTracker counts paid tool calls between verify and completion
+type inflightTracker struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{} // closed when count drops back to zero
+}
+
+func (t *inflightTracker) begin() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.count == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.count++
+}
+
+func (t *inflightTracker) end() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count--
+	if t.count == 0 {
+		close(t.idle)
+	}
+}
+
+func (t *inflightTracker) wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.count == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("drain in-flight payments: %w", ctx.Err())
+	}
+}
+
+// Drain waits until every paid tool call that has started verification has
+// settled and returned, or until ctx is done. Call it during shutdown so a
+// verified payment is not abandoned before settlement. Settlements deferred
+// by SetSettlementRetry are retried immediately and waited for as well.
+func (m *Middleware) Drain(ctx context.Context) error {
+	if err := m.inflight.wait(ctx); err != nil {
+		return err
+	}
+	if m.settlementRetry == nil {
+		return nil
+	}
+	return m.settlementRetry.drain(ctx, m)
+}
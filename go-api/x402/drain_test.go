@@ -0,0 +1,76 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestDrainWaitsForInFlightSettlement(t *testing.T) {
+	t.Parallel()
+
+	settling := make(chan struct{})
+	release := make(chan struct{})
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+		case "/settle":
+			close(settling)
+			<-release
+			json.NewEncoder(w).Encode(SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	m := newTestMiddleware(facilitator.URL)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	results := make(chan *mcp.CallToolResult, 1)
+	go func() {
+		result, _, _ := handler(context.Background(), paidRequest(), nil)
+		results <- result
+	}()
+	<-settling
+
+	drained := make(chan error, 1)
+	go func() {
+		drained <- m.Drain(context.Background())
+	}()
+
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned before settlement finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	if result := <-results; result == nil || result.IsError {
+		t.Fatalf("expected in-flight call to complete successfully, got %+v", result)
+	}
+}
+
+func TestDrainHonorsDeadline(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.inflight.begin()
+	defer m.inflight.end()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := m.Drain(ctx); err == nil {
+		t.Fatalf("expected Drain to give up at the deadline")
+	}
+}
@@ -0,0 +1,159 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Facilitator is the subset of the x402 facilitator API the middleware uses.
+// *x402http.HTTPFacilitatorClient satisfies it.
+type Facilitator interface {
+	Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error)
+	Settle(ctx context.Context, payloadBytes, requirementsBytes []byte) (*SettleResponse, error)
+	GetSupported(ctx context.Context) (SupportedResponse, error)
+}
+
+// WithFacilitator replaces the HTTP facilitator client built from
+// facilitatorURL, e.g. with a FakeFacilitator in tests
+func WithFacilitator(facilitator Facilitator) MiddlewareOption {
+	return func(m *Middleware) {
+		if facilitator != nil {
+			m.facilitator = facilitator
+		}
+	}
+}
+
+// FakeFacilitator is an in-memory Facilitator for tests. By default it
+// accepts every payment and settles it successfully; RejectPayments,
+// FailSettlement and the error setters program other outcomes.
+type FakeFacilitator struct {
+	mu            sync.Mutex
+	invalidReason string
+	settleReason  string
+	verifyErr     error
+	settleErr     error
+	supported     SupportedResponse
+	verified      []PaymentPayload
+	settled       []PaymentPayload
+}
+
+// NewFakeFacilitator returns a FakeFacilitator that accepts and settles
+// every payment
+func NewFakeFacilitator() *FakeFacilitator {
+	return &FakeFacilitator{}
+}
+
+// RejectPayments makes Verify report the payment invalid with reason.
+// An empty reason accepts payments again.
+func (f *FakeFacilitator) RejectPayments(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.invalidReason = reason
+}
+
+// FailSettlement makes Settle report failure with reason. An empty reason
+// settles successfully again.
+func (f *FakeFacilitator) FailSettlement(reason string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.settleReason = reason
+}
+
+// SetVerifyError makes Verify return err, as a transport failure would
+func (f *FakeFacilitator) SetVerifyError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.verifyErr = err
+}
+
+// SetSettleError makes Settle return err, as a transport failure would
+func (f *FakeFacilitator) SetSettleError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.settleErr = err
+}
+
+// SetSupported sets the response returned by GetSupported
+func (f *FakeFacilitator) SetSupported(supported SupportedResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.supported = supported
+}
+
+// Verified returns the payments passed to Verify, in order
+func (f *FakeFacilitator) Verified() []PaymentPayload {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]PaymentPayload(nil), f.verified...)
+}
+
+// Settled returns the payments passed to Settle, in order
+func (f *FakeFacilitator) Settled() []PaymentPayload {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]PaymentPayload(nil), f.settled...)
+}
+
+// Verify implements Facilitator
+func (f *FakeFacilitator) Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error) {
+	payment, _, err := decodeFacilitatorRequest(payloadBytes, requirementsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.verified = append(f.verified, payment)
+	if f.verifyErr != nil {
+		return nil, f.verifyErr
+	}
+	if f.invalidReason != "" {
+		return &VerifyResponse{IsValid: false, InvalidReason: f.invalidReason}, nil
+	}
+	return &VerifyResponse{IsValid: true, Payer: payerFromPayload(payment.Payload)}, nil
+}
+
+// Settle implements Facilitator
+func (f *FakeFacilitator) Settle(ctx context.Context, payloadBytes, requirementsBytes []byte) (*SettleResponse, error) {
+	payment, requirements, err := decodeFacilitatorRequest(payloadBytes, requirementsBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.settled = append(f.settled, payment)
+	if f.settleErr != nil {
+		return nil, f.settleErr
+	}
+	if f.settleReason != "" {
+		return &SettleResponse{Success: false, ErrorReason: f.settleReason, Network: Network(requirements.Network)}, nil
+	}
+	return &SettleResponse{
+		Success:     true,
+		Transaction: fmt.Sprintf("0xfake%04d", len(f.settled)),
+		Network:     Network(requirements.Network),
+		Payer:       payerFromPayload(payment.Payload),
+	}, nil
+}
+
+// GetSupported implements Facilitator
+func (f *FakeFacilitator) GetSupported(ctx context.Context) (SupportedResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.supported, nil
+}
+
+func decodeFacilitatorRequest(payloadBytes, requirementsBytes []byte) (PaymentPayload, PaymentRequirements, error) {
+	var payment PaymentPayload
+	if err := json.Unmarshal(payloadBytes, &payment); err != nil {
+		return PaymentPayload{}, PaymentRequirements{}, fmt.Errorf("decode payment: %w", err)
+	}
+	var requirements PaymentRequirements
+	if err := json.Unmarshal(requirementsBytes, &requirements); err != nil {
+		return PaymentPayload{}, PaymentRequirements{}, fmt.Errorf("decode requirements: %w", err)
+	}
+	return payment, requirements, nil
+}
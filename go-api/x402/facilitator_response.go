@@ -0,0 +1,42 @@
+package x402
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIndeterminateFacilitatorResponse is returned when a facilitator answers
+// without an error but also without a usable verdict, e.g. an HTTP 200 whose
+// error envelope decodes to a zero-value response. Such a response is never
+// treated as a pass or as a plain rejection.
+var ErrIndeterminateFacilitatorResponse = errors.New("indeterminate facilitator response")
+
+// checkVerifyResponse rejects a verify response that is missing, or neither
+// valid nor carrying a reason, or valid and carrying one
+func checkVerifyResponse(resp *VerifyResponse) error {
+	switch {
+	case resp == nil:
+		return fmt.Errorf("%w: empty verify response", ErrIndeterminateFacilitatorResponse)
+	case !resp.IsValid && resp.InvalidReason == "" && resp.InvalidMessage == "":
+		return fmt.Errorf("%w: verify response is neither valid nor invalid with a reason", ErrIndeterminateFacilitatorResponse)
+	case resp.IsValid && resp.InvalidReason != "":
+		return fmt.Errorf("%w: verify response is valid but has invalid reason %q", ErrIndeterminateFacilitatorResponse, resp.InvalidReason)
+	}
+	return nil
+}
+
+// checkSettleResponse rejects a settle response that is missing, or neither
+// successful nor carrying a reason, or successful and carrying one without a
+// transaction. A successful response with a transaction has moved funds, so
+// it counts as settled even if it also names a reason; the caller logs it
+func checkSettleResponse(resp *SettleResponse) error {
+	switch {
+	case resp == nil:
+		return fmt.Errorf("%w: empty settle response", ErrIndeterminateFacilitatorResponse)
+	case !resp.Success && resp.ErrorReason == "" && resp.ErrorMessage == "":
+		return fmt.Errorf("%w: settle response is neither successful nor failed with a reason", ErrIndeterminateFacilitatorResponse)
+	case resp.Success && resp.ErrorReason != "" && resp.Transaction == "":
+		return fmt.Errorf("%w: settle response is successful but has error reason %q and no transaction", ErrIndeterminateFacilitatorResponse, resp.ErrorReason)
+	}
+	return nil
+}
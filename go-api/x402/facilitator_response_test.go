@@ -0,0 +1,148 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ambiguousFacilitator answers like a deployment that returns HTTP 200 with
+// an error envelope: no error, but responses that carry no usable verdict
+type ambiguousFacilitator struct {
+	*FakeFacilitator
+	verify       *VerifyResponse
+	settle       *SettleResponse
+	passVerify   bool
+	verifyCalled bool
+}
+
+func (f *ambiguousFacilitator) Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error) {
+	if f.passVerify {
+		return f.FakeFacilitator.Verify(ctx, payloadBytes, requirementsBytes)
+	}
+	f.verifyCalled = true
+	return f.verify, nil
+}
+
+func (f *ambiguousFacilitator) Settle(ctx context.Context, payloadBytes, requirementsBytes []byte) (*SettleResponse, error) {
+	return f.settle, nil
+}
+
+func TestIndeterminateFacilitatorResponses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		facilitator *ambiguousFacilitator
+		wantReason  string
+	}{
+		{name: "nil verify", facilitator: &ambiguousFacilitator{}, wantReason: ErrorReasonVerifyFailed},
+		{name: "zero-value verify", facilitator: &ambiguousFacilitator{verify: &VerifyResponse{}}, wantReason: ErrorReasonVerifyFailed},
+		{name: "valid verify with a reason", facilitator: &ambiguousFacilitator{verify: &VerifyResponse{IsValid: true, InvalidReason: "unexpected_error"}}, wantReason: ErrorReasonVerifyFailed},
+		{name: "nil settle", facilitator: &ambiguousFacilitator{passVerify: true}, wantReason: ErrorReasonSettleFailed},
+		{name: "zero-value settle", facilitator: &ambiguousFacilitator{passVerify: true, settle: &SettleResponse{}}, wantReason: ErrorReasonSettleFailed},
+		{name: "successful settle with a reason and no transaction", facilitator: &ambiguousFacilitator{passVerify: true, settle: &SettleResponse{Success: true, ErrorReason: "unexpected_error"}}, wantReason: ErrorReasonSettleFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			tt.facilitator.FakeFacilitator = NewFakeFacilitator()
+			m := NewMiddleware(
+				"http://localhost:8080",
+				"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				Network("eip155:84532"),
+				"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				"http://facilitator.invalid",
+				WithFacilitator(tt.facilitator),
+			)
+			m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+			m.SetToolPrice("paid_tool", "10000")
+
+			ran := false
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				ran = true
+				return &mcp.CallToolResult{}, nil, nil
+			})
+			result, _, err := handler(context.Background(), paidRequest(), nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			if ran {
+				t.Fatal("expected an indeterminate response not to run the tool")
+			}
+			paymentErr := assertPaymentError(t, result, tt.wantReason)
+			if !strings.Contains(paymentErr.Message, ErrIndeterminateFacilitatorResponse.Error()) {
+				t.Fatalf("expected the message to name the indeterminate response, got %q", paymentErr.Message)
+			}
+		})
+	}
+}
+
+func TestVerifyPaymentIndeterminateIsNotInvalid(t *testing.T) {
+	t.Parallel()
+
+	facilitator := &ambiguousFacilitator{FakeFacilitator: NewFakeFacilitator(), verify: &VerifyResponse{}}
+	m := newFakeMiddleware(facilitator.FakeFacilitator)
+	WithFacilitator(facilitator)(m)
+
+	_, err := m.VerifyPayment(context.Background(), "paid_tool", extractMeta(paidRequest()))
+	if !errors.Is(err, ErrIndeterminateFacilitatorResponse) {
+		t.Fatalf("expected ErrIndeterminateFacilitatorResponse, got %v", err)
+	}
+	var invalid *PaymentInvalidError
+	if errors.As(err, &invalid) {
+		t.Fatalf("expected an indeterminate response not to be reported as an invalid payment, got %v", invalid)
+	}
+	if !facilitator.verifyCalled {
+		t.Fatal("expected the ambiguous facilitator to be consulted")
+	}
+
+	// A rejection that only carries a message is still a concrete verdict
+	if err := checkVerifyResponse(&VerifyResponse{InvalidMessage: "insufficient balance"}); err != nil {
+		t.Fatalf("expected a message-only rejection to be accepted, got %v", err)
+	}
+	if err := checkSettleResponse(&SettleResponse{ErrorMessage: "reverted"}); err != nil {
+		t.Fatalf("expected a message-only settle failure to be accepted, got %v", err)
+	}
+}
+
+func TestSuccessfulSettleWithReasonAndTransactionIsSettled(t *testing.T) {
+	t.Parallel()
+
+	facilitator := &ambiguousFacilitator{
+		FakeFacilitator: NewFakeFacilitator(),
+		passVerify:      true,
+		settle:          &SettleResponse{Success: true, ErrorReason: "unexpected_error", Transaction: "0xabc", Network: "eip155:84532"},
+	}
+	logger := &recordingLogger{}
+	m := newFakeMiddleware(facilitator.FakeFacilitator)
+	WithFacilitator(facilitator)(m)
+	WithLogger(logger)(m)
+
+	ran := false
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		ran = true
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !ran || result.IsError {
+		t.Fatalf("expected the settled call to run the tool, got %+v", result)
+	}
+	if settled, ok := result.Meta[MetaKeyPaymentResponse].(*SettleResponse); !ok || settled.Transaction != "0xabc" {
+		t.Fatalf("expected the settlement in the result meta, got %+v", result.Meta)
+	}
+	if !logger.has("x402 settle response is successful but has an error reason") {
+		t.Fatalf("expected the inconsistent response to be logged, got %+v", logger.records)
+	}
+
+	// Verify keeps the strict check
+	if err := checkVerifyResponse(&VerifyResponse{IsValid: true, InvalidReason: "unexpected_error", Payer: "0xabc"}); !errors.Is(err, ErrIndeterminateFacilitatorResponse) {
+		t.Fatalf("expected a valid verify with a reason to stay indeterminate, got %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package x402
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxStatusErrorBody bounds how much of a facilitator error body is kept
+const maxStatusErrorBody = 64 << 10
+
+// FacilitatorStatusError is returned for a facilitator 5xx response that
+// carries no verify or settle verdict, e.g. a proxy's 502 page. Retries and
+// failover match it with errors.As instead of parsing client error strings.
+type FacilitatorStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error implements the error interface
+func (e *FacilitatorStatusError) Error() string {
+	return fmt.Sprintf("facilitator responded %d: %s", e.StatusCode, e.Body)
+}
+
+// facilitatorStatusTransport turns facilitator 5xx responses without a
+// verdict into a FacilitatorStatusError, which the x402 HTTP client wraps
+// with %w, so the status survives whatever message the client builds
+type facilitatorStatusTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *facilitatorStatusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusInternalServerError {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxStatusErrorBody))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if hasVerdict(body) {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	return nil, &FacilitatorStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+}
+
+// hasVerdict reports whether a facilitator error body names a verify or
+// settle reason, which the client turns into a definitive VerifyError or
+// SettleError
+func hasVerdict(body []byte) bool {
+	var verdict struct {
+		InvalidReason string `json:"invalidReason"`
+		ErrorReason   string `json:"errorReason"`
+	}
+	if err := json.Unmarshal(body, &verdict); err != nil {
+		return false
+	}
+	return verdict.InvalidReason != "" || verdict.ErrorReason != ""
+}
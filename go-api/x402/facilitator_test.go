@@ -0,0 +1,76 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newFakeMiddleware(fake *FakeFacilitator) *Middleware {
+	m := NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		"http://facilitator.invalid",
+		WithFacilitator(fake),
+	)
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	m.SetToolPrice("paid_tool", "10000")
+	return m
+}
+
+func TestWrapToolHandlerWithFakeFacilitator(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		program     func(*FakeFacilitator)
+		wantRun     bool
+		wantReason  string
+		wantSettles int
+	}{
+		{name: "verified and settled", program: func(*FakeFacilitator) {}, wantRun: true, wantSettles: 1},
+		{name: "invalid payment", program: func(f *FakeFacilitator) { f.RejectPayments("insufficient_funds") }, wantReason: ErrorReasonVerifyFailed},
+		{name: "verify transport error", program: func(f *FakeFacilitator) { f.SetVerifyError(errors.New("connection reset")) }, wantReason: ErrorReasonVerifyFailed},
+		{name: "settlement failed", program: func(f *FakeFacilitator) { f.FailSettlement("transaction_failed") }, wantReason: ErrorReasonSettleFailed, wantSettles: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fake := NewFakeFacilitator()
+			tt.program(fake)
+			m := newFakeMiddleware(fake)
+
+			ran := false
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				ran = true
+				return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "sunny"}}}, nil, nil
+			})
+			result, _, err := handler(context.Background(), paidRequest(), nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+
+			if ran != tt.wantRun {
+				t.Fatalf("expected handler run=%v, got %v", tt.wantRun, ran)
+			}
+			if len(fake.Verified()) != 1 {
+				t.Fatalf("expected one verify, got %d", len(fake.Verified()))
+			}
+			if len(fake.Settled()) != tt.wantSettles {
+				t.Fatalf("expected %d settles, got %d", tt.wantSettles, len(fake.Settled()))
+			}
+			if tt.wantReason != "" {
+				assertPaymentError(t, result, tt.wantReason)
+				return
+			}
+			settle, ok := result.Meta[MetaKeyPaymentResponse].(*SettleResponse)
+			if !ok || !settle.Success || settle.Transaction != "0xfake0001" {
+				t.Fatalf("expected settlement meta from the fake, got %#v", result.Meta[MetaKeyPaymentResponse])
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package x402
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	x402http "github.com/coinbase/x402/go/http"
+)
+
+// Defaults of NewFacilitatorTransport, tuned for one long-lived client shared
+// by every request to the facilitator
+const (
+	DefaultFacilitatorMaxIdleConns        = 32
+	DefaultFacilitatorMaxIdleConnsPerHost = 16
+	DefaultFacilitatorMaxConnsPerHost     = 64
+	DefaultFacilitatorIdleConnTimeout     = 90 * time.Second
+	DefaultFacilitatorTimeout             = 30 * time.Second
+)
+
+// NewFacilitatorTransport returns a transport that keeps a bounded pool of
+// idle connections to the facilitator instead of the handful net/http keeps
+// per host by default, so bursts of verify and settle calls reuse connections
+func NewFacilitatorTransport() *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          DefaultFacilitatorMaxIdleConns,
+		MaxIdleConnsPerHost:   DefaultFacilitatorMaxIdleConnsPerHost,
+		MaxConnsPerHost:       DefaultFacilitatorMaxConnsPerHost,
+		IdleConnTimeout:       DefaultFacilitatorIdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// sharedFacilitatorTransport is the transport of facilitator clients built
+// without WithFacilitatorTransport, so they all draw on one pool
+var sharedFacilitatorTransport = sync.OnceValue(NewFacilitatorTransport)
+
+// FacilitatorConfigOption customizes the config built by FacilitatorConfigFromEnv
+type FacilitatorConfigOption func(*facilitatorConfigOptions)
+
+type facilitatorConfigOptions struct {
+	transport *http.Transport
+	timeout   time.Duration
+}
+
+// WithFacilitatorTransport makes the facilitator client use transport, e.g.
+// one with different pool limits or a custom TLS config. A nil transport keeps
+// the shared default.
+func WithFacilitatorTransport(transport *http.Transport) FacilitatorConfigOption {
+	return func(o *facilitatorConfigOptions) {
+		o.transport = transport
+	}
+}
+
+// WithFacilitatorTimeout bounds each facilitator request, including reading
+// the response. A non-positive timeout keeps DefaultFacilitatorTimeout.
+func WithFacilitatorTimeout(timeout time.Duration) FacilitatorConfigOption {
+	return func(o *facilitatorConfigOptions) {
+		o.timeout = timeout
+	}
+}
+
+// facilitatorHTTPClient returns the HTTP client described by opts. Its
+// transport reports verdict-less 5xx responses as FacilitatorStatusError.
+func facilitatorHTTPClient(opts []FacilitatorConfigOption) *http.Client {
+	var o facilitatorConfigOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	transport := o.transport
+	if transport == nil {
+		transport = sharedFacilitatorTransport()
+	}
+	timeout := o.timeout
+	if timeout <= 0 {
+		timeout = DefaultFacilitatorTimeout
+	}
+	return &http.Client{Transport: &facilitatorStatusTransport{base: transport}, Timeout: timeout}
+}
+
+// newFacilitatorConfig returns a config for url using the client from opts
+func newFacilitatorConfig(url string, opts []FacilitatorConfigOption) *x402http.FacilitatorConfig {
+	return &x402http.FacilitatorConfig{
+		URL:        url,
+		HTTPClient: facilitatorHTTPClient(opts),
+	}
+}
@@ -0,0 +1,76 @@
+package x402
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFacilitatorClientUsesConfiguredTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"kinds":[]}`))
+	}))
+	defer server.Close()
+	t.Setenv(TestModeEnv, "")
+	t.Setenv("CDP_API_KEY", "")
+	t.Setenv("CDP_API_KEY_SECRET", "")
+	t.Setenv("FACILITATOR_URL", server.URL)
+
+	// Without the transport's TLS config the test server's certificate is
+	// untrusted, so the call only succeeds through the configured transport
+	var dials atomic.Int32
+	transport := NewFacilitatorTransport()
+	transport.TLSClientConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+	transport.TLSClientConfig.RootCAs.AddCert(server.Certificate())
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return dial(ctx, network, addr)
+	}
+
+	if _, err := GetFacilitatorClient().GetSupported(context.Background()); err == nil {
+		t.Fatal("expected the default transport to reject the test certificate")
+	}
+
+	client := GetFacilitatorClient(WithFacilitatorTransport(transport))
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetSupported(context.Background()); err != nil {
+			t.Fatalf("GetSupported through the configured transport: %v", err)
+		}
+	}
+	if got := dials.Load(); got != 1 {
+		t.Fatalf("expected the configured transport to dial once and reuse the connection, got %d dials", got)
+	}
+
+	config := FacilitatorConfigFromEnv("http://localhost:4021", WithFacilitatorTransport(transport))
+	if config.HTTPClient == nil || config.HTTPClient.Transport.(*facilitatorStatusTransport).base != transport {
+		t.Fatalf("expected FacilitatorConfigFromEnv to use the configured transport, got %+v", config.HTTPClient)
+	}
+}
+
+func TestFacilitatorConfigFromEnvSharesDefaultTransport(t *testing.T) {
+	t.Setenv(TestModeEnv, "")
+	t.Setenv("FACILITATOR_URL", "http://localhost:4021")
+
+	first := FacilitatorConfigFromEnv("")
+	second := FacilitatorConfigFromEnv("")
+	if first.HTTPClient == nil || first.HTTPClient.Transport == nil {
+		t.Fatal("expected a pooled transport by default")
+	}
+	if first.HTTPClient.Transport.(*facilitatorStatusTransport).base != second.HTTPClient.Transport.(*facilitatorStatusTransport).base {
+		t.Fatal("expected facilitator clients to share the default transport")
+	}
+	if first.HTTPClient.Timeout != DefaultFacilitatorTimeout {
+		t.Fatalf("expected timeout %s, got %s", DefaultFacilitatorTimeout, first.HTTPClient.Timeout)
+	}
+	transport := first.HTTPClient.Transport.(*facilitatorStatusTransport).base.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != DefaultFacilitatorMaxIdleConnsPerHost || transport.IdleConnTimeout != DefaultFacilitatorIdleConnTimeout {
+		t.Fatalf("expected tuned pool settings, got idle per host %d, idle timeout %s", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+}
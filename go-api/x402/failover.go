@@ -0,0 +1,164 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	x402http "github.com/coinbase/x402/go/http"
+)
+
+// FacilitatorFallbackURLsEnv lists comma-separated facilitator URLs tried, in
+// order, after the primary from FacilitatorConfigFromEnv
+const FacilitatorFallbackURLsEnv = "FACILITATOR_FALLBACK_URLS"
+
+// Facilitator operations reported to FacilitatorServedFunc
+const (
+	FacilitatorOpVerify    = "verify"
+	FacilitatorOpSettle    = "settle"
+	FacilitatorOpSupported = "supported"
+)
+
+// errNoFacilitators is returned by a FailoverFacilitator with an empty list
+var errNoFacilitators = errors.New("no facilitators configured")
+
+// NamedFacilitator pairs a facilitator with the name reported in logs and
+// metrics, typically its URL
+type NamedFacilitator struct {
+	Name        string
+	Facilitator Facilitator
+}
+
+// FacilitatorServedFunc is told which facilitator answered op
+type FacilitatorServedFunc func(ctx context.Context, op, name string)
+
+// FacilitatorMetrics is optionally implemented by a Metrics sink to count
+// which facilitator served each verify and settle call
+type FacilitatorMetrics interface {
+	FacilitatorServed(name, op string)
+}
+
+// FailoverFacilitator tries an ordered list of facilitators, moving on to the
+// next only when one fails with a transport error or 5xx. A definitive
+// verdict, including an invalid payment, is returned without failing over.
+type FailoverFacilitator struct {
+	facilitators []NamedFacilitator
+	logger       Logger
+	served       FacilitatorServedFunc
+}
+
+// NewFailoverFacilitator returns a FailoverFacilitator that tries
+// facilitators in order, logging each failover to logger
+func NewFailoverFacilitator(logger Logger, facilitators ...NamedFacilitator) *FailoverFacilitator {
+	if logger == nil {
+		logger = StdLogger{}
+	}
+	return &FailoverFacilitator{
+		facilitators: append([]NamedFacilitator{}, facilitators...),
+		logger:       logger,
+	}
+}
+
+// OnServed registers fn to be told which facilitator answered each call
+func (f *FailoverFacilitator) OnServed(fn FacilitatorServedFunc) {
+	f.served = fn
+}
+
+// Verify asks each facilitator in turn until one gives a verdict
+func (f *FailoverFacilitator) Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error) {
+	return failover(ctx, f, FacilitatorOpVerify, func(ctx context.Context, facilitator Facilitator) (*VerifyResponse, error) {
+		return facilitator.Verify(ctx, payloadBytes, requirementsBytes)
+	})
+}
+
+// Settle asks each facilitator in turn until one gives a verdict
+func (f *FailoverFacilitator) Settle(ctx context.Context, payloadBytes, requirementsBytes []byte) (*SettleResponse, error) {
+	return failover(ctx, f, FacilitatorOpSettle, func(ctx context.Context, facilitator Facilitator) (*SettleResponse, error) {
+		return facilitator.Settle(ctx, payloadBytes, requirementsBytes)
+	})
+}
+
+// GetSupported returns the supported kinds of the first reachable facilitator
+func (f *FailoverFacilitator) GetSupported(ctx context.Context) (SupportedResponse, error) {
+	return failover(ctx, f, FacilitatorOpSupported, func(ctx context.Context, facilitator Facilitator) (SupportedResponse, error) {
+		return facilitator.GetSupported(ctx)
+	})
+}
+
+// failover runs call against each facilitator until one succeeds or fails
+// with an error that is not a transport failure or 5xx
+func failover[T any](ctx context.Context, f *FailoverFacilitator, op string, call func(context.Context, Facilitator) (T, error)) (T, error) {
+	var zero T
+	lastErr := errNoFacilitators
+	for i, named := range f.facilitators {
+		result, err := call(ctx, named.Facilitator)
+		if err == nil || !isRetryableFacilitatorError(err) || ctx.Err() != nil {
+			if f.served != nil {
+				f.served(ctx, op, named.Name)
+			}
+			return result, err
+		}
+		lastErr = err
+		if i+1 < len(f.facilitators) {
+			f.logger.Warn("x402 facilitator failover",
+				"op", op,
+				"from", named.Name,
+				"to", f.facilitators[i+1].Name,
+				"requestId", RequestIDFromContext(ctx),
+				"err", err,
+			)
+		}
+	}
+	return zero, lastErr
+}
+
+// WithFacilitators replaces the HTTP facilitator client built from
+// facilitatorURL with an ordered failover list. The facilitator serving each
+// call is logged at debug level and reported to a Metrics sink implementing
+// FacilitatorMetrics.
+func WithFacilitators(facilitators ...NamedFacilitator) MiddlewareOption {
+	return func(m *Middleware) {
+		if len(facilitators) > 0 {
+			m.facilitators = append([]NamedFacilitator{}, facilitators...)
+		}
+	}
+}
+
+// facilitatorServed logs and counts the facilitator that answered op
+func (m *Middleware) facilitatorServed(ctx context.Context, op, name string) {
+	m.logger.Debug("x402 facilitator served", "op", op, "facilitator", name, "requestId", RequestIDFromContext(ctx))
+	if metrics, ok := m.metrics.(FacilitatorMetrics); ok {
+		metrics.FacilitatorServed(name, op)
+	}
+}
+
+// FacilitatorFromEnv builds the facilitator client from FacilitatorConfigFromEnv,
+// failing over to any plain facilitators listed in FacilitatorFallbackURLsEnv.
+// opts apply to the primary and the fallbacks alike.
+func FacilitatorFromEnv(defaultURL string, logger Logger, opts ...FacilitatorConfigOption) Facilitator {
+	config := FacilitatorConfigFromEnv(defaultURL, opts...)
+	primary := x402http.NewHTTPFacilitatorClient(config)
+
+	var fallbacks []NamedFacilitator
+	for _, url := range strings.Split(os.Getenv(FacilitatorFallbackURLsEnv), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			fallbacks = append(fallbacks, NamedFacilitator{
+				Name:        url,
+				Facilitator: x402http.NewHTTPFacilitatorClient(newFacilitatorConfig(url, opts)),
+			})
+		}
+	}
+	if len(fallbacks) == 0 {
+		return primary
+	}
+
+	if logger == nil {
+		logger = StdLogger{}
+	}
+	f := NewFailoverFacilitator(logger, append([]NamedFacilitator{{Name: config.URL, Facilitator: primary}}, fallbacks...)...)
+	f.OnServed(func(ctx context.Context, op, name string) {
+		logger.Debug("x402 facilitator served", "op", op, "facilitator", name, "requestId", RequestIDFromContext(ctx))
+	})
+	return f
+}
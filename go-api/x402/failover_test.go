@@ -0,0 +1,157 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// servedMetrics records which facilitator answered each operation
+type servedMetrics struct {
+	*recordingMetrics
+	mu     sync.Mutex
+	served []string
+}
+
+func (s *servedMetrics) FacilitatorServed(name, op string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.served = append(s.served, op+":"+name)
+}
+
+func newFailoverMiddleware(primary, secondary *FakeFacilitator, metrics Metrics) *Middleware {
+	m := NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		"http://facilitator.invalid",
+		WithFacilitators(
+			NamedFacilitator{Name: "primary", Facilitator: primary},
+			NamedFacilitator{Name: "secondary", Facilitator: secondary},
+		),
+	)
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	m.SetMetrics(metrics)
+	m.SetToolPrice("paid_tool", "10000")
+	return m
+}
+
+func TestWithFacilitatorsFailsOver(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		program     func(primary *FakeFacilitator)
+		wantRun     bool
+		wantReason  string
+		wantServed  []string
+		wantSecond  int
+		wantSettles int
+	}{
+		{
+			name: "primary transport error on verify",
+			program: func(primary *FakeFacilitator) {
+				primary.SetVerifyError(&url.Error{Op: "Post", URL: "http://primary/verify", Err: errors.New("connection refused")})
+				primary.SetSettleError(&url.Error{Op: "Post", URL: "http://primary/settle", Err: errors.New("connection refused")})
+			},
+			wantRun:     true,
+			wantServed:  []string{"verify:secondary", "settle:secondary"},
+			wantSecond:  1,
+			wantSettles: 1,
+		},
+		{
+			name: "primary 5xx on settle",
+			program: func(primary *FakeFacilitator) {
+				primary.SetSettleError(&FacilitatorStatusError{StatusCode: 503, Body: "unavailable"})
+			},
+			wantRun:     true,
+			wantServed:  []string{"verify:primary", "settle:secondary"},
+			wantSettles: 1,
+		},
+		{
+			name:       "primary invalid verdict does not fail over",
+			program:    func(primary *FakeFacilitator) { primary.RejectPayments("insufficient_funds") },
+			wantReason: ErrorReasonVerifyFailed,
+			wantServed: []string{"verify:primary"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			primary, secondary := NewFakeFacilitator(), NewFakeFacilitator()
+			tt.program(primary)
+			metrics := &servedMetrics{recordingMetrics: newRecordingMetrics()}
+			m := newFailoverMiddleware(primary, secondary, metrics)
+
+			ran := false
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				ran = true
+				return &mcp.CallToolResult{}, nil, nil
+			})
+			result, _, err := handler(context.Background(), paidRequest(), nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			if ran != tt.wantRun {
+				t.Fatalf("expected handler run=%v, got %v", tt.wantRun, ran)
+			}
+			if tt.wantReason != "" {
+				assertPaymentError(t, result, tt.wantReason)
+			}
+			if got := len(secondary.Verified()); got != tt.wantSecond {
+				t.Fatalf("expected %d verifies on the secondary, got %d", tt.wantSecond, got)
+			}
+			if got := len(secondary.Settled()); got != tt.wantSettles {
+				t.Fatalf("expected %d settles on the secondary, got %d", tt.wantSettles, got)
+			}
+			if fmt.Sprint(metrics.served) != fmt.Sprint(tt.wantServed) {
+				t.Fatalf("expected served %v, got %v", tt.wantServed, metrics.served)
+			}
+		})
+	}
+}
+
+func TestFailoverFacilitatorReturnsLastErrorWhenAllFail(t *testing.T) {
+	t.Parallel()
+
+	first, second := NewFakeFacilitator(), NewFakeFacilitator()
+	first.SetVerifyError(&url.Error{Op: "Post", URL: "http://first/verify", Err: errors.New("connection refused")})
+	lastErr := &url.Error{Op: "Post", URL: "http://second/verify", Err: errors.New("connection reset")}
+	second.SetVerifyError(lastErr)
+
+	logger := &recordingLogger{}
+	m := NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		"http://facilitator.invalid",
+		WithLogger(logger),
+		WithFacilitators(
+			NamedFacilitator{Name: "first", Facilitator: first},
+			NamedFacilitator{Name: "second", Facilitator: second},
+		),
+	)
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	m.SetToolPrice("paid_tool", "10000")
+
+	if _, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta); !errors.Is(err, lastErr) {
+		t.Fatalf("expected the last facilitator's error, got %v", err)
+	}
+	var failovers []logRecord
+	for _, record := range logger.records {
+		if record.msg == "x402 facilitator failover" {
+			failovers = append(failovers, record)
+		}
+	}
+	if len(failovers) != 1 || failovers[0].value("from") != "first" || failovers[0].value("to") != "second" {
+		t.Fatalf("expected one failover from first to second, got %+v", failovers)
+	}
+}
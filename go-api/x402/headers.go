@@ -0,0 +1,61 @@
+package x402
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// x402 HTTP header names as the spec spells them. Header lookups must go
+// through HeaderValues or HeaderValue so any casing a client sends matches.
+const (
+	// HeaderPaymentSignature carries a v2 payment
+	HeaderPaymentSignature = "PAYMENT-SIGNATURE"
+	// HeaderXPayment carries a v1 payment
+	HeaderXPayment = "X-PAYMENT"
+	// HeaderPaymentRequired carries the v2 payment requirements of a 402
+	HeaderPaymentRequired = "PAYMENT-REQUIRED"
+	// HeaderPaymentResponse carries a v2 settlement
+	HeaderPaymentResponse = "PAYMENT-RESPONSE"
+	// HeaderXPaymentResponse carries a v1 settlement
+	HeaderXPaymentResponse = "X-PAYMENT-RESPONSE"
+)
+
+// PaymentHeaders lists every x402 header that carries payment data
+var PaymentHeaders = []string{
+	HeaderPaymentSignature,
+	HeaderXPayment,
+	HeaderPaymentResponse,
+	HeaderXPaymentResponse,
+}
+
+// HeaderValues returns the values of header name whatever its casing.
+// http.Header canonicalizes keys on Set and Add but not in literals, so a
+// hand-built http.Header{"PAYMENT-RESPONSE": ...} is missed by Values; this
+// falls back to a case-insensitive match, in key order for determinism.
+func HeaderValues(header http.Header, name string) []string {
+	if values := header.Values(name); len(values) > 0 {
+		return values
+	}
+	var keys []string
+	for key := range header {
+		if strings.EqualFold(key, name) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var values []string
+	for _, key := range keys {
+		values = append(values, header[key]...)
+	}
+	return values
+}
+
+// HeaderValue returns the first value of header name whatever its casing, or
+// "" when it is absent
+func HeaderValue(header http.Header, name string) string {
+	if values := HeaderValues(header, name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package x402
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestHeaderValuesMatchesAnyCasing(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{"PAYMENT-SIGNATURE", "Payment-Signature", "payment-signature", "pAyMeNt-SiGnAtUrE"} {
+		t.Run(key, func(t *testing.T) {
+			t.Parallel()
+			// A literal keeps its key as written, unlike Set
+			header := http.Header{key: {"signed"}}
+			for _, name := range []string{HeaderPaymentSignature, "payment-signature", http.CanonicalHeaderKey(HeaderPaymentSignature)} {
+				if got := HeaderValue(header, name); got != "signed" {
+					t.Fatalf("HeaderValue(%q) = %q, want signed", name, got)
+				}
+			}
+		})
+	}
+
+	header := http.Header{}
+	header.Add(HeaderPaymentResponse, "first")
+	header["PAYMENT-RESPONSE"] = []string{"literal"}
+	if got := HeaderValues(header, HeaderPaymentResponse); !slices.Equal(got, []string{"first"}) {
+		t.Fatalf("expected the canonical key to win, got %v", got)
+	}
+	if got := HeaderValue(http.Header{}, HeaderXPayment); got != "" {
+		t.Fatalf("expected no value for a missing header, got %q", got)
+	}
+}
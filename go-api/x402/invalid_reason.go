@@ -0,0 +1,63 @@
+package x402
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stable codes for facilitator InvalidReason values, reported in
+// PaymentError.InvalidCode so agents can react without parsing strings
+const (
+	InvalidCodeInsufficientFunds = "insufficient_funds"
+	InvalidCodeExpired           = "expired"
+	InvalidCodeNotYetValid       = "not_yet_valid"
+	InvalidCodeWrongNetwork      = "wrong_network"
+	InvalidCodeInvalidSignature  = "invalid_signature"
+	InvalidCodeRecipientMismatch = "recipient_mismatch"
+	InvalidCodeNonceUsed         = "nonce_used"
+	InvalidCodeUnsupportedScheme = "unsupported_scheme"
+	InvalidCodeUnknown           = "unknown"
+)
+
+// invalidReasonRules maps facilitator reason fragments to codes. Reasons are
+// scheme-prefixed (e.g. invalid_exact_evm_insufficient_funds), so rules match
+// on substrings and are checked in order.
+var invalidReasonRules = []struct {
+	fragment string
+	code     string
+}{
+	{"insufficient", InvalidCodeInsufficientFunds},
+	{"valid_before", InvalidCodeExpired},
+	{"expired", InvalidCodeExpired},
+	{"valid_after", InvalidCodeNotYetValid},
+	{"network_mismatch", InvalidCodeWrongNetwork},
+	{"unsupported_network", InvalidCodeWrongNetwork},
+	{"nonce_already_used", InvalidCodeNonceUsed},
+	{"recipient_mismatch", InvalidCodeRecipientMismatch},
+	{"signature", InvalidCodeInvalidSignature},
+	{"unsupported_scheme", InvalidCodeUnsupportedScheme},
+}
+
+// InvalidReasonCode maps a facilitator InvalidReason to a stable code.
+// Unrecognized reasons map to InvalidCodeUnknown.
+func InvalidReasonCode(reason string) string {
+	reason = strings.ToLower(strings.TrimSpace(reason))
+	for _, rule := range invalidReasonRules {
+		if strings.Contains(reason, rule.fragment) {
+			return rule.code
+		}
+	}
+	return InvalidCodeUnknown
+}
+
+// PaymentInvalidError is returned by VerifyPayment when the facilitator
+// rejects a payment. Reason is the facilitator's string, Code its stable
+// mapping.
+type PaymentInvalidError struct {
+	Reason string
+	Code   string
+}
+
+func (e *PaymentInvalidError) Error() string {
+	return fmt.Sprintf("payment invalid: %s", e.Reason)
+}
@@ -0,0 +1,71 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestInvalidReasonCode(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"insufficient_funds":                                   InvalidCodeInsufficientFunds,
+		"invalid_exact_evm_insufficient_balance":               InvalidCodeInsufficientFunds,
+		"invalid_exact_evm_payload_authorization_valid_before": InvalidCodeExpired,
+		"invalid_exact_evm_payload_authorization_valid_after":  InvalidCodeNotYetValid,
+		"invalid_exact_solana_network_mismatch":                InvalidCodeWrongNetwork,
+		"invalid_signature":                                    InvalidCodeInvalidSignature,
+		"invalid_exact_evm_nonce_already_used":                 InvalidCodeNonceUsed,
+		"invalid_exact_evm_payload_recipient_mismatch":         InvalidCodeRecipientMismatch,
+		"invalid_exact_solana_unsupported_scheme":              InvalidCodeUnsupportedScheme,
+		"quantum_flux_misaligned":                              InvalidCodeUnknown,
+		"":                                                     InvalidCodeUnknown,
+	}
+	for reason, want := range tests {
+		if got := InvalidReasonCode(reason); got != want {
+			t.Errorf("InvalidReasonCode(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
+
+func TestWrapToolHandlerSurfacesInvalidReason(t *testing.T) {
+	t.Parallel()
+
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+	tests := []struct {
+		reason   string
+		wantCode string
+	}{
+		{reason: "insufficient_funds", wantCode: InvalidCodeInsufficientFunds},
+		{reason: "invalid_exact_evm_payload_authorization_valid_before", wantCode: InvalidCodeExpired},
+		{reason: "facilitator_mood_swing", wantCode: InvalidCodeUnknown},
+	}
+	for _, tt := range tests {
+		facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: false, InvalidReason: tt.reason})
+		})
+		m := newTestMiddleware(facilitator.URL)
+
+		_, err := m.VerifyPayment(context.Background(), "paid_tool", extractMeta(paidRequest()))
+		var invalid *PaymentInvalidError
+		if !errors.As(err, &invalid) || invalid.Code != tt.wantCode {
+			t.Fatalf("%s: expected PaymentInvalidError with code %q, got %v", tt.reason, tt.wantCode, err)
+		}
+
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("%s: handler error: %v", tt.reason, err)
+		}
+		paymentErr := assertPaymentError(t, result, ErrorReasonVerifyFailed)
+		if paymentErr.InvalidCode != tt.wantCode || paymentErr.InvalidReason != tt.reason {
+			t.Fatalf("%s: expected code %q and raw reason, got %+v", tt.reason, tt.wantCode, paymentErr)
+		}
+	}
+}
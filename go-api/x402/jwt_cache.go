@@ -0,0 +1,61 @@
+package x402
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	// coinbaseJWTLifetime is the validity requested for each CDP JWT
+	coinbaseJWTLifetime = 120 * time.Second
+	// coinbaseJWTRefreshMargin regenerates a cached JWT this long before it
+	// expires so requests in flight never carry a stale token
+	coinbaseJWTRefreshMargin = 30 * time.Second
+)
+
+type cachedJWT struct {
+	header    string
+	expiresAt time.Time
+}
+
+// jwtCache reuses generated auth headers until shortly before they expire.
+// Entries are keyed by a hash of the key material and the signed request, so
+// a different key or route never reuses another's token.
+type jwtCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedJWT
+	now     func() time.Time
+}
+
+func newJWTCache() *jwtCache {
+	return &jwtCache{entries: make(map[string]cachedJWT), now: time.Now}
+}
+
+// get returns a cached header for the request or generates and stores one
+func (c *jwtCache) get(apiKeyID, apiKeySecret, method, host, path string, generate func() (string, error)) (string, error) {
+	key := jwtCacheKey(apiKeyID, apiKeySecret, method, host, path)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok && now.Before(entry.expiresAt.Add(-coinbaseJWTRefreshMargin)) {
+		return entry.header, nil
+	}
+	header, err := generate()
+	if err != nil {
+		return "", err
+	}
+	c.entries[key] = cachedJWT{header: header, expiresAt: now.Add(coinbaseJWTLifetime)}
+	return header, nil
+}
+
+func jwtCacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,47 @@
+package x402
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger receives leveled, structured log records. keyvals are alternating
+// key/value pairs, e.g. Error("verify failed", "tool", name, "err", err).
+type Logger interface {
+	Debug(msg string, keyvals ...any)
+	Info(msg string, keyvals ...any)
+	Warn(msg string, keyvals ...any)
+	Error(msg string, keyvals ...any)
+}
+
+// StdLogger writes records through the standard library log package.
+// Debug records are dropped unless Verbose is set.
+type StdLogger struct {
+	Verbose bool
+}
+
+func (l StdLogger) Debug(msg string, keyvals ...any) {
+	if l.Verbose {
+		writeStdLog("DEBUG", msg, keyvals)
+	}
+}
+
+func (StdLogger) Info(msg string, keyvals ...any)  { writeStdLog("INFO", msg, keyvals) }
+func (StdLogger) Warn(msg string, keyvals ...any)  { writeStdLog("WARN", msg, keyvals) }
+func (StdLogger) Error(msg string, keyvals ...any) { writeStdLog("ERROR", msg, keyvals) }
+
+func writeStdLog(level, msg string, keyvals []any) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value any = "(MISSING)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], value)
+	}
+	log.Print(b.String())
+}
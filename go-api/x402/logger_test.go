@@ -0,0 +1,79 @@
+package x402
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type logRecord struct {
+	level   string
+	msg     string
+	keyvals []any
+}
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) record(level, msg string, keyvals []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level: level, msg: msg, keyvals: keyvals})
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...any) { l.record("debug", msg, keyvals) }
+func (l *recordingLogger) Info(msg string, keyvals ...any)  { l.record("info", msg, keyvals) }
+func (l *recordingLogger) Warn(msg string, keyvals ...any)  { l.record("warn", msg, keyvals) }
+func (l *recordingLogger) Error(msg string, keyvals ...any) { l.record("error", msg, keyvals) }
+
+func (r logRecord) value(key string) any {
+	for i := 0; i+1 < len(r.keyvals); i += 2 {
+		if r.keyvals[i] == key {
+			return r.keyvals[i+1]
+		}
+	}
+	return nil
+}
+
+func TestVerifyPaymentLogsFacilitatorErrors(t *testing.T) {
+	t.Parallel()
+
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	logger := &recordingLogger{}
+	m := NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		facilitator.URL,
+		WithLogger(logger),
+	)
+	m.SetToolPrice("paid_tool", "10000")
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+
+	if _, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta); err == nil {
+		t.Fatalf("expected verify to fail")
+	}
+
+	if len(logger.records) != 1 {
+		t.Fatalf("expected one log record, got %+v", logger.records)
+	}
+	record := logger.records[0]
+	if record.level != "error" || record.msg != "x402 verify error" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	if got := record.value("tool"); got != "paid_tool" {
+		t.Fatalf("expected tool field, got %v", got)
+	}
+	if got := record.value("network"); got != "eip155:84532" {
+		t.Fatalf("expected network field, got %v", got)
+	}
+	if record.value("err") == nil {
+		t.Fatalf("expected err field")
+	}
+}
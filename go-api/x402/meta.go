@@ -0,0 +1,50 @@
+package x402
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPaymentMeta is returned when the x402/payment _meta entry is not a
+// JSON object
+var ErrInvalidPaymentMeta = errors.New(MetaKeyPayment + " must be a JSON object")
+
+// PaymentMetaObject returns the x402/payment _meta entry as a JSON object.
+// Typed values such as structs are converted through JSON; anything that does
+// not encode to an object fails with ErrInvalidPaymentMeta naming its JSON type.
+func PaymentMetaObject(value any) (map[string]any, error) {
+	if object, ok := value.(map[string]any); ok {
+		return object, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w, got unencodable %T: %v", ErrInvalidPaymentMeta, value, err)
+	}
+	var object map[string]any
+	if kind := jsonKind(raw); kind != "object" || json.Unmarshal(raw, &object) != nil {
+		return nil, fmt.Errorf("%w, got %s", ErrInvalidPaymentMeta, kind)
+	}
+	return object, nil
+}
+
+// jsonKind names the JSON type of an encoded value
+func jsonKind(raw []byte) string {
+	if len(raw) == 0 {
+		return "nothing"
+	}
+	switch raw[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
@@ -0,0 +1,73 @@
+package x402
+
+// PaymentMetaKeys names the _meta keys a wrapped tool reads its payment from
+// and reports payment requirements and settlement in. Empty fields use the
+// standard MetaKeyPayment, MetaKeyPaymentRequired and MetaKeyPaymentResponse.
+type PaymentMetaKeys struct {
+	Payment         string `json:"payment"`
+	PaymentRequired string `json:"paymentRequired"`
+	PaymentResponse string `json:"paymentResponse"`
+}
+
+// DefaultPaymentMetaKeys are the standard x402 MCP _meta keys
+var DefaultPaymentMetaKeys = PaymentMetaKeys{
+	Payment:         MetaKeyPayment,
+	PaymentRequired: MetaKeyPaymentRequired,
+	PaymentResponse: MetaKeyPaymentResponse,
+}
+
+// SetPaymentMetaKeys moves toolName's payment meta to keys, e.g. so a tool
+// that forwards its own x402/payment to an upstream can charge a separate fee
+// without the two payments colliding. Must be called before serving.
+func (m *Middleware) SetPaymentMetaKeys(toolName string, keys PaymentMetaKeys) {
+	if keys == (PaymentMetaKeys{}) || keys == DefaultPaymentMetaKeys {
+		delete(m.metaKeys, toolName)
+		return
+	}
+	m.metaKeys[toolName] = keys
+}
+
+// PaymentMetaKeysFor returns the _meta keys toolName uses, with defaults
+// filled in
+func (m *Middleware) PaymentMetaKeysFor(toolName string) PaymentMetaKeys {
+	keys := m.metaKeys[toolName]
+	if keys.Payment == "" {
+		keys.Payment = MetaKeyPayment
+	}
+	if keys.PaymentRequired == "" {
+		keys.PaymentRequired = MetaKeyPaymentRequired
+	}
+	if keys.PaymentResponse == "" {
+		keys.PaymentResponse = MetaKeyPaymentResponse
+	}
+	return keys
+}
+
+// unpaidMeta attaches pricing under the tool's payment-required key and, when
+// the tool reads its payment from a non-standard key, names that key so an
+// agent knows where to put the payment
+func unpaidMeta(keys PaymentMetaKeys, pricing *PaymentRequiredData) map[string]interface{} {
+	meta := map[string]interface{}{
+		keys.PaymentRequired: pricing,
+	}
+	if keys.Payment != MetaKeyPayment {
+		meta[MetaKeyPaymentKey] = keys.Payment
+	}
+	return meta
+}
+
+// paymentRequiredError is NewPaymentRequiredError for a tool using keys; a
+// non-standard payment key is named in error.data.extensions, since a JSON-RPC
+// error carries no _meta
+func paymentRequiredError(keys PaymentMetaKeys, pricing *PaymentRequiredData) error {
+	if keys.Payment == MetaKeyPayment {
+		return NewPaymentRequiredError(pricing)
+	}
+	data := *pricing
+	data.Extensions = make(map[string]interface{}, len(pricing.Extensions)+1)
+	for k, v := range pricing.Extensions {
+		data.Extensions[k] = v
+	}
+	data.Extensions[MetaKeyPaymentKey] = keys.Payment
+	return NewPaymentRequiredError(&data)
+}
@@ -0,0 +1,94 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestWrapToolHandlerCustomPaymentMetaKeys(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	keys := PaymentMetaKeys{Payment: "x402/fee", PaymentResponse: "x402/fee-response"}
+	m.SetPaymentMetaKeys("paid_tool", keys)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, input any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	// A payment under the standard key is left for the tool itself
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !result.IsError || result.Meta[MetaKeyPaymentRequired] == nil || result.Meta[MetaKeyPaymentKey] != "x402/fee" {
+		t.Fatalf("expected payment required naming x402/fee, got %+v", result.Meta)
+	}
+
+	req := paidRequest()
+	req.Params.Meta["x402/fee"] = req.Params.Meta[MetaKeyPayment]
+	result, _, err = handler(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if result.IsError || result.Meta["x402/fee-response"] == nil {
+		t.Fatalf("expected settlement under x402/fee-response, got %+v", result.Meta)
+	}
+	if _, ok := result.Meta[MetaKeyPaymentResponse]; ok {
+		t.Fatal("expected the standard payment-response key to stay untouched")
+	}
+	if got := m.PaymentMetaKeysFor("other_tool"); got != DefaultPaymentMetaKeys {
+		t.Fatalf("expected defaults for other tools, got %+v", got)
+	}
+}
+
+func TestPaymentRequiredErrorNamesPaymentKey(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMiddleware(NewFakeFacilitator())
+	m.SetPaymentRequiredMode(PaymentRequiredError)
+	m.SetPaymentMetaKeys("paid_tool", PaymentMetaKeys{Payment: "x402/fee"})
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, input any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	_, _, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, nil)
+	var wireErr *jsonrpc.Error
+	if !errors.As(err, &wireErr) {
+		t.Fatalf("expected *jsonrpc.Error, got %v", err)
+	}
+	var data PaymentRequiredData
+	if err := json.Unmarshal(wireErr.Data, &data); err != nil {
+		t.Fatalf("unmarshal error.data: %v", err)
+	}
+	if data.Extensions[MetaKeyPaymentKey] != "x402/fee" {
+		t.Fatalf("expected error.data to name x402/fee, got %+v", data.Extensions)
+	}
+	if pricing := m.GetPaymentRequirements("paid_tool"); pricing.Extensions[MetaKeyPaymentKey] != nil {
+		t.Fatal("expected the tool's pricing to stay untouched")
+	}
+}
+
+func TestAuthorizePaymentCustomPaymentMetaKeys(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMiddleware(NewFakeFacilitator())
+	m.SetPaymentMetaKeys("paid_tool", PaymentMetaKeys{Payment: "x402/fee", PaymentRequired: "x402/fee-required"})
+
+	// A payment under the standard key is not the one paid_tool reads
+	result, _, err := m.AuthorizePayment(context.Background(), paidRequest(), &AuthorizePaymentParams{ToolName: "paid_tool"})
+	if err != nil {
+		t.Fatalf("AuthorizePayment error: %v", err)
+	}
+	if result.Meta["x402/fee-required"] == nil || result.Meta[MetaKeyPaymentKey] != "x402/fee" {
+		t.Fatalf("expected pricing under x402/fee-required naming x402/fee, got %+v", result.Meta)
+	}
+	if _, ok := result.Meta[MetaKeyPaymentRequired]; ok {
+		t.Fatal("expected the standard payment-required key to stay untouched")
+	}
+}
@@ -0,0 +1,115 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPaymentMetaObject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		value    any
+		wantKind string
+	}{
+		{name: "object", value: map[string]any{"x402Version": 2}},
+		{name: "raw object", value: json.RawMessage(`{"x402Version":2}`)},
+		{name: "typed object", value: PaymentPayload{X402Version: 2}},
+		{name: "string", value: "eyJ4NDAyVmVyc2lvbiI6Mn0=", wantKind: "string"},
+		{name: "number", value: 402, wantKind: "number"},
+		{name: "array", value: []any{map[string]any{"x402Version": 2}}, wantKind: "array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			object, err := PaymentMetaObject(tt.value)
+			if tt.wantKind == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if object["x402Version"] != 2 && object["x402Version"] != float64(2) {
+					t.Fatalf("expected x402Version 2, got %v", object)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidPaymentMeta) {
+				t.Fatalf("expected ErrInvalidPaymentMeta, got %v", err)
+			}
+			want := "x402/payment must be a JSON object, got " + tt.wantKind
+			if err.Error() != want {
+				t.Fatalf("expected %q, got %q", want, err.Error())
+			}
+		})
+	}
+}
+
+func TestWrapToolHandlerRejectsMalformedPaymentMeta(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		payment  any
+		wantKind string
+	}{
+		{name: "string", payment: "0xdeadbeef", wantKind: "string"},
+		{name: "number", payment: 10000, wantKind: "number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fake := NewFakeFacilitator()
+			m := newFakeMiddleware(fake)
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				t.Fatalf("handler should not run for malformed payment metadata")
+				return nil, nil, nil
+			})
+			req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{
+				Meta: mcp.Meta{MetaKeyPayment: tt.payment},
+			}}
+			result, _, err := handler(context.Background(), req, nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			assertPaymentError(t, result, ErrorReasonVerifyFailed)
+			if text := resultText(t, result); !strings.Contains(text, "x402/payment must be a JSON object, got "+tt.wantKind) {
+				t.Fatalf("expected actionable meta error, got %q", text)
+			}
+			if len(fake.Verified()) != 0 {
+				t.Fatalf("expected no facilitator call for malformed metadata")
+			}
+		})
+	}
+
+	t.Run("object", func(t *testing.T) {
+		t.Parallel()
+		fake := NewFakeFacilitator()
+		m := newFakeMiddleware(fake)
+		handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+		result, _, err := handler(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("expected a well-formed payment to succeed, got %q", resultText(t, result))
+		}
+	})
+}
+
+func TestExtractMetaWithoutParams(t *testing.T) {
+	t.Parallel()
+
+	if meta := extractMeta(&mcp.CallToolRequest{}); meta == nil || len(meta) != 0 {
+		t.Fatalf("expected empty meta for a request without params, got %v", meta)
+	}
+	if meta := extractMeta(nil); meta == nil || len(meta) != 0 {
+		t.Fatalf("expected empty meta for a nil request, got %v", meta)
+	}
+}
@@ -0,0 +1,25 @@
+package x402
+
+import "time"
+
+// Metrics receives operational events from the middleware and MCP proxy.
+// Implementations must be safe for concurrent use; adapt it to Prometheus or
+// any other backend by implementing these methods.
+type Metrics interface {
+	// ToolCalled counts a call to toolName
+	ToolCalled(toolName string)
+	// PaymentVerified counts a verify outcome on network
+	PaymentVerified(network string, success bool)
+	// PaymentSettled counts a settle outcome on network
+	PaymentSettled(network string, success bool)
+	// ProxyLatency observes one upstream proxy request for toolName
+	ProxyLatency(toolName string, status int, elapsed time.Duration)
+}
+
+// NopMetrics discards all events. It is the default when no metrics are set.
+type NopMetrics struct{}
+
+func (NopMetrics) ToolCalled(string)                       {}
+func (NopMetrics) PaymentVerified(string, bool)            {}
+func (NopMetrics) PaymentSettled(string, bool)             {}
+func (NopMetrics) ProxyLatency(string, int, time.Duration) {}
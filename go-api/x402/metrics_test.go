@@ -0,0 +1,81 @@
+package x402
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type recordingMetrics struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	verified map[string]int
+	settled  map[string]int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{
+		calls:    make(map[string]int),
+		verified: make(map[string]int),
+		settled:  make(map[string]int),
+	}
+}
+
+func outcomeKey(network string, success bool) string {
+	if success {
+		return network + ":ok"
+	}
+	return network + ":fail"
+}
+
+func (r *recordingMetrics) ToolCalled(toolName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[toolName]++
+}
+
+func (r *recordingMetrics) PaymentVerified(network string, success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verified[outcomeKey(network, success)]++
+}
+
+func (r *recordingMetrics) PaymentSettled(network string, success bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settled[outcomeKey(network, success)]++
+}
+
+func (r *recordingMetrics) ProxyLatency(string, int, time.Duration) {}
+
+func TestWrapToolHandlerRecordsMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := newRecordingMetrics()
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.SetMetrics(metrics)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected paid call to succeed, got %q", resultText(t, result))
+	}
+
+	if got := metrics.calls["paid_tool"]; got != 1 {
+		t.Fatalf("expected 1 tool call, got %d", got)
+	}
+	if got := metrics.verified["eip155:84532:ok"]; got != 1 {
+		t.Fatalf("expected 1 successful verify, got %v", metrics.verified)
+	}
+	if got := metrics.settled["eip155:84532:ok"]; got != 1 {
+		t.Fatalf("expected 1 successful settle, got %v", metrics.settled)
+	}
+}
@@ -3,63 +3,362 @@ package x402
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
 
+	x402sdk "github.com/coinbase/x402/go"
 	x402http "github.com/coinbase/x402/go/http"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// ToolPricing maps tool names to their pricing configuration
-type ToolPricing map[string]ToolPricingConfig
+// ToolPricing maps tool names to the payment options they accept
+type ToolPricing map[string][]ToolPricingConfig
 
 // Middleware wraps MCP tool handlers with x402 payment verification
 type Middleware struct {
-	pricing          ToolPricing
-	payToAddr        string
-	network          Network
-	asset            string
-	serverURL        string
-	facilitatorURL   string
-	facilitator      *x402http.HTTPFacilitatorClient
+	// pricingMu guards pricing, which LoadPricingFromFile may replace while
+	// calls are in flight
+	pricingMu      sync.RWMutex
+	pricing        ToolPricing
+	payToAddr      string
+	network        Network
+	asset          string
+	serverURL      string
+	facilitatorURL string
+	facilitator    Facilitator
+	callBudget     CallBudget
+	retryPolicy    RetryPolicy
+	supported      supportedCache
+	replayStore    ReplayStore
+	replayTTL      time.Duration
+	requiredMode   PaymentRequiredMode
+	metrics        Metrics
+	logger         Logger
+	inflight       inflightTracker
+	freeTools      map[string]struct{}
+	strict         bool
+	settlementHook SettlementHook
+	auditSink      AuditSink
+	unpaidBodies   map[string]UnpaidBodyFunc
+	metaKeys       map[string]PaymentMetaKeys
+	// facilitatorTimeout overrides the requirement's MaxTimeoutSeconds when set
+	facilitatorTimeout time.Duration
+	rateLimitsMu       sync.RWMutex
+	rateLimits         map[string]*toolRateLimit
+	sortAcceptsByCost  bool
+	freeTrialsMu       sync.RWMutex
+	freeTrials         map[string]int
+	// allowUnknownNamespaces accepts CAIP-2 namespaces other than eip155
+	// and solana in pricing
+	allowUnknownNamespaces bool
+	testMode               TestMode
+	// facilitators, when set, replaces facilitator with a FailoverFacilitator
+	facilitators []NamedFacilitator
+	// settlementSummary appends a human-readable settlement line to results
+	settlementSummary bool
+	// settlementRetry, when set, defers transiently failed settlements
+	settlementRetry *settlementRetrier
+}
+
+// ErrFacilitatorTimeout is returned when a verify or settle call exceeds the
+// payment requirement's MaxTimeoutSeconds (or the configured override)
+var ErrFacilitatorTimeout = errors.New("facilitator call timed out")
+
+// UnpaidBodyFunc builds the text and structured content returned when a tool
+// is called without payment
+type UnpaidBodyFunc func(requirements *PaymentRequiredData) (text string, structured any)
+
+// SettlementHook is notified after a payment for toolName settles successfully
+type SettlementHook func(ctx context.Context, toolName string, settle *SettleResponse)
+
+// ErrUnpricedTool is returned in strict mode for a tool that is neither priced
+// nor registered as free
+var ErrUnpricedTool = errors.New("tool has no price and is not registered as free")
+
+// MiddlewareOption configures a Middleware at construction time
+type MiddlewareOption func(*Middleware)
+
+// WithUnknownNetworkNamespaces accepts pricing on CAIP-2 namespaces other
+// than eip155 and solana, checking only the generic CAIP-2 shape
+func WithUnknownNetworkNamespaces() MiddlewareOption {
+	return func(m *Middleware) {
+		m.allowUnknownNamespaces = true
+	}
+}
+
+// WithLogger sets the logger used for payment errors. The default is StdLogger.
+func WithLogger(logger Logger) MiddlewareOption {
+	return func(m *Middleware) {
+		if logger != nil {
+			m.logger = logger
+		}
+	}
 }
 
 // NewMiddleware creates a new x402 middleware instance
-func NewMiddleware(serverURL, payToAddr string, network Network, asset, facilitatorURL string) *Middleware {
+func NewMiddleware(serverURL, payToAddr string, network Network, asset, facilitatorURL string, opts ...MiddlewareOption) *Middleware {
 	// Create facilitator client
-	facilitator := x402http.NewHTTPFacilitatorClient(&x402http.FacilitatorConfig{
-		URL: facilitatorURL,
-	})
+	facilitator := x402http.NewHTTPFacilitatorClient(newFacilitatorConfig(facilitatorURL, nil))
 
-	return &Middleware{
+	m := &Middleware{
 		pricing:        make(ToolPricing),
+		freeTools:      make(map[string]struct{}),
+		unpaidBodies:   make(map[string]UnpaidBodyFunc),
+		metaKeys:       make(map[string]PaymentMetaKeys),
+		rateLimits:     make(map[string]*toolRateLimit),
+		freeTrials:     make(map[string]int),
 		payToAddr:      payToAddr,
 		network:        network,
 		asset:          asset,
 		serverURL:      serverURL,
 		facilitatorURL: facilitatorURL,
 		facilitator:    facilitator,
+		replayStore:    NewMemoryReplayStore(),
+		replayTTL:      DefaultReplayTTL,
+		retryPolicy:    DefaultRetryPolicy,
+		supported:      supportedCache{ttl: DefaultSupportedTTL},
+		metrics:        NopMetrics{},
+		logger:         StdLogger{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if len(m.facilitators) > 0 {
+		failover := NewFailoverFacilitator(m.logger, m.facilitators...)
+		failover.OnServed(m.facilitatorServed)
+		m.facilitator = failover
+	}
+	if m.testMode != TestModeOff {
+		warnTestMode(m.logger, m.testMode)
+		if m.testMode == TestModeAutoApprove {
+			m.facilitator = autoApproveFacilitator{m.facilitator}
+		}
+	}
+	return m
+}
+
+// SetToolPrice sets the price for a specific tool. It fails if the
+// middleware's network is not a valid CAIP-2 identifier.
+func (m *Middleware) SetToolPrice(toolName, amount string) error {
+	return m.SetToolPriceWithScheme(toolName, SchemeExact, amount)
+}
+
+// SetToolPriceDecimal sets the price for a tool from a human decimal amount,
+// e.g. "0.01" with 6 decimals for one cent of USDC
+func (m *Middleware) SetToolPriceDecimal(toolName, amount string, decimals int) error {
+	units, err := AmountFromDecimal(amount, decimals)
+	if err != nil {
+		return err
 	}
+	return m.SetToolPrice(toolName, units)
 }
 
-// SetToolPrice sets the price for a specific tool
-func (m *Middleware) SetToolPrice(toolName, amount string) {
-	m.pricing[toolName] = ToolPricingConfig{
+// SetToolPriceWithScheme sets the price and payment scheme for a specific tool.
+// For SchemeUpto, amount is the maximum that may be settled. It fails if the
+// middleware's network is not a valid CAIP-2 identifier.
+func (m *Middleware) SetToolPriceWithScheme(toolName, scheme, amount string) error {
+	if err := ValidateNetwork(string(m.network), m.allowUnknownNamespaces); err != nil {
+		return err
+	}
+	m.pricingMu.Lock()
+	defer m.pricingMu.Unlock()
+	m.pricing[toolName] = []ToolPricingConfig{{
+		Scheme:  scheme,
 		Amount:  amount,
 		Asset:   m.asset,
 		Network: m.network,
 		PayTo:   m.payToAddr,
+	}}
+	return nil
+}
+
+// AddToolPaymentOption advertises an additional way to pay for a tool.
+// The agent picks one option and settlement uses the option it paid with.
+// It fails if the option's network is not a valid CAIP-2 identifier.
+func (m *Middleware) AddToolPaymentOption(toolName string, option ToolPricingConfig) error {
+	if err := ValidateNetwork(string(option.Network), m.allowUnknownNamespaces); err != nil {
+		return err
+	}
+	m.pricingMu.Lock()
+	defer m.pricingMu.Unlock()
+	m.pricing[toolName] = append(m.pricing[toolName], option)
+	return nil
+}
+
+// toolPricing returns the payment options configured for toolName
+func (m *Middleware) toolPricing(toolName string) ([]ToolPricingConfig, bool) {
+	m.pricingMu.RLock()
+	defer m.pricingMu.RUnlock()
+	options, ok := m.pricing[toolName]
+	return options, ok
+}
+
+// pricingSnapshot returns a copy of the whole pricing table
+func (m *Middleware) pricingSnapshot() ToolPricing {
+	m.pricingMu.RLock()
+	defer m.pricingMu.RUnlock()
+	snapshot := make(ToolPricing, len(m.pricing))
+	for toolName, options := range m.pricing {
+		snapshot[toolName] = append([]ToolPricingConfig(nil), options...)
+	}
+	return snapshot
+}
+
+// RegisterFreeTool marks a tool as intentionally free of charge
+func (m *Middleware) RegisterFreeTool(toolName string) {
+	m.freeTools[toolName] = struct{}{}
+}
+
+// SetStrict makes WrapToolHandler reject calls to tools that are neither
+// priced nor registered with RegisterFreeTool
+func (m *Middleware) SetStrict(strict bool) {
+	m.strict = strict
+}
+
+// SetSettlementHook registers a hook run asynchronously after each successful
+// settlement, e.g. to notify a billing system. Hook panics are recovered and
+// never affect the tool result.
+func (m *Middleware) SetSettlementHook(hook SettlementHook) {
+	m.settlementHook = hook
+}
+
+// notifySettlement runs the settlement hook in the background. Drain waits
+// for it like any other in-flight payment work.
+func (m *Middleware) notifySettlement(ctx context.Context, toolName string, settle *SettleResponse) {
+	hook := m.settlementHook
+	if hook == nil {
+		return
 	}
+	ctx = context.WithoutCancel(ctx)
+	m.inflight.begin()
+	go func() {
+		defer m.inflight.end()
+		defer func() {
+			if r := recover(); r != nil {
+				m.logger.Error("x402 settlement hook panicked", "tool", toolName, "panic", r)
+			}
+		}()
+		hook(ctx, toolName, settle)
+	}()
+}
+
+// SetUnpaidBody customizes the result returned when toolName is called
+// without payment. The payment requirements are still attached as meta.
+func (m *Middleware) SetUnpaidBody(toolName string, fn UnpaidBodyFunc) {
+	if fn == nil {
+		delete(m.unpaidBodies, toolName)
+		return
+	}
+	m.unpaidBodies[toolName] = fn
+}
+
+// unpaidBody returns the text and structured content for an unpaid call
+func (m *Middleware) unpaidBody(toolName string, pricing *PaymentRequiredData) (string, any) {
+	if fn, ok := m.unpaidBodies[toolName]; ok {
+		return fn(pricing)
+	}
+	paymentReqJSON, _ := json.Marshal(pricing)
+	return string(paymentReqJSON), &PaymentError{
+		Code:    ErrorCodePaymentRequired,
+		Reason:  ErrorReasonPaymentRequired,
+		Message: pricing.Error,
+	}
+}
+
+// SetFacilitatorTimeout bounds each facilitator verify/settle operation,
+// including retries. Zero uses the matched requirement's MaxTimeoutSeconds.
+func (m *Middleware) SetFacilitatorTimeout(timeout time.Duration) {
+	m.facilitatorTimeout = timeout
+}
+
+// facilitatorTimeoutFor returns the bound for a facilitator call on requirements
+func (m *Middleware) facilitatorTimeoutFor(requirements *PaymentRequirements) time.Duration {
+	if m.facilitatorTimeout > 0 {
+		return m.facilitatorTimeout
+	}
+	return time.Duration(requirements.MaxTimeoutSeconds) * time.Second
+}
+
+// SetCallBudget caps the attempts and time spent on each wrapped tool call
+func (m *Middleware) SetCallBudget(budget CallBudget) {
+	m.callBudget = budget
+}
+
+// SetPaymentRequiredMode selects how unpaid tool calls are reported
+func (m *Middleware) SetPaymentRequiredMode(mode PaymentRequiredMode) {
+	m.requiredMode = mode
+}
+
+// SetMetrics sets the sink for tool call and payment metrics
+func (m *Middleware) SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = NopMetrics{}
+	}
+	m.metrics = metrics
+}
+
+// SetRetryPolicy sets how facilitator Verify/Settle calls are retried
+func (m *Middleware) SetRetryPolicy(policy RetryPolicy) {
+	m.retryPolicy = policy
+}
+
+// SetReplayStore replaces the store used to reject replayed payments.
+// A nil store disables replay protection.
+func (m *Middleware) SetReplayStore(store ReplayStore, ttl time.Duration) {
+	m.replayStore = store
+	m.replayTTL = ttl
+}
+
+// SetSettlementSummary appends a text line such as "x402 payment settled on
+// eip155:8453, transaction 0xabc" to paid results so agents can relay the
+// payment to users. The payment-response meta is set either way
+func (m *Middleware) SetSettlementSummary(enabled bool) {
+	m.settlementSummary = enabled
+}
+
+// SetSortAcceptsByCost orders the accepts in payment requirements cheapest
+// first, comparing amounts in whole tokens using each option's Decimals.
+// Options with equal cost keep their configured order
+func (m *Middleware) SetSortAcceptsByCost(enabled bool) {
+	m.sortAcceptsByCost = enabled
 }
 
 // GetPaymentRequirements returns the payment requirements for a tool
 // Uses official x402 types
 func (m *Middleware) GetPaymentRequirements(toolName string) *PaymentRequiredData {
-	pricing, ok := m.pricing[toolName]
-	if !ok {
+	options, ok := m.toolPricing(toolName)
+	if !ok || len(options) == 0 {
 		return nil // Tool is free
 	}
 
+	if m.sortAcceptsByCost {
+		options = sortedByCost(options)
+	}
+
+	accepts := make([]PaymentRequirements, 0, len(options))
+	for _, pricing := range options {
+		extra := map[string]interface{}{
+			"name":    "USDC",
+			"version": "2",
+		}
+		if pricing.Approval != nil {
+			extra["approval"] = pricing.Approval.extra(pricing.Amount)
+		}
+		accepts = append(accepts, PaymentRequirements{
+			Scheme:            pricing.scheme(),
+			Network:           canonicalNetwork(string(pricing.Network)),
+			Amount:            pricing.Amount,
+			Asset:             pricing.Asset,
+			PayTo:             pricing.PayTo,
+			MaxTimeoutSeconds: 60,
+			Extra:             extra,
+		})
+	}
+
 	return &PaymentRequiredData{
 		X402Version: X402Version,
 		Error:       "Payment required to access this tool",
@@ -68,32 +367,48 @@ func (m *Middleware) GetPaymentRequirements(toolName string) *PaymentRequiredDat
 			Description: fmt.Sprintf("MCP Tool: %s", toolName),
 			MimeType:    "application/json",
 		},
-		Accepts: []PaymentRequirements{
-			{
-				Scheme:            "exact",
-				Network:           string(pricing.Network),
-				Amount:            pricing.Amount,
-				Asset:             pricing.Asset,
-				PayTo:             pricing.PayTo,
-				MaxTimeoutSeconds: 60,
-				Extra: map[string]interface{}{
-					"name":    "USDC",
-					"version": "2",
-				},
-			},
-		},
+		Accepts: accepts,
 	}
 }
 
+// matchRequirements returns the advertised option the payment was made
+// against. Fields the payment leaves empty match any option.
+func matchRequirements(accepts []PaymentRequirements, payment *PaymentPayload) (*PaymentRequirements, error) {
+	accepted := payment.Accepted
+	for i := range accepts {
+		option := &accepts[i]
+		if accepted.Scheme != "" && accepted.Scheme != option.Scheme {
+			continue
+		}
+		if accepted.Network != "" && !sameNetwork(accepted.Network, option.Network) {
+			continue
+		}
+		if accepted.Asset != "" && !strings.EqualFold(accepted.Asset, option.Asset) {
+			continue
+		}
+		return option, nil
+	}
+	return nil, fmt.Errorf("payment (scheme=%s network=%s asset=%s) does not match any accepted option",
+		accepted.Scheme, accepted.Network, accepted.Asset)
+}
+
 // VerifyPayment validates a payment using the facilitator
 func (m *Middleware) VerifyPayment(ctx context.Context, toolName string, meta map[string]interface{}) (*PaymentPayload, error) {
-	paymentData, ok := meta[MetaKeyPayment]
+	paymentData, ok := meta[m.PaymentMetaKeysFor(toolName).Payment]
 	if !ok {
 		return nil, nil // No payment provided
 	}
 
+	if paymentData == nil {
+		return nil, nil // Explicit null, treated as no payment
+	}
+	paymentObject, err := PaymentMetaObject(paymentData)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse the payment payload
-	paymentBytes, err := json.Marshal(paymentData)
+	paymentBytes, err := json.Marshal(paymentObject)
 	if err != nil {
 		return nil, fmt.Errorf("invalid payment format: %w", err)
 	}
@@ -109,21 +424,38 @@ func (m *Middleware) VerifyPayment(ctx context.Context, toolName string, meta ma
 		return &payment, nil // Tool is free, payment not required
 	}
 
+	requirements, err := matchRequirements(expectedReqs.Accepts, &payment)
+	if err != nil {
+		return nil, err
+	}
+
 	// Marshal requirements for facilitator
-	requirementsBytes, err := json.Marshal(expectedReqs.Accepts[0])
+	requirementsBytes, err := json.Marshal(requirements)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal requirements: %w", err)
 	}
 
 	// Verify payment using facilitator
-	verifyResp, err := m.facilitator.Verify(ctx, paymentBytes, requirementsBytes)
+	verifyResp, err := withFacilitatorTimeout(ctx, m.facilitatorTimeoutFor(requirements), m.retryPolicy, func(ctx context.Context) (*VerifyResponse, error) {
+		return m.facilitator.Verify(ctx, paymentBytes, requirementsBytes)
+	})
+	if err == nil {
+		err = checkVerifyResponse(verifyResp)
+	}
+	m.metrics.PaymentVerified(requirements.Network, err == nil && verifyResp.IsValid)
 	if err != nil {
-		log.Printf("x402 verify error (tool=%s network=%s): %v", toolName, m.network, err)
+		if errors.Is(err, ErrCallBudgetExhausted) {
+			return nil, err
+		}
+		m.logger.Error("x402 verify error", "tool", toolName, "network", requirements.Network, "requestId", RequestIDFromContext(ctx), "err", err)
 		return nil, fmt.Errorf("payment verification failed: %w", err)
 	}
 
 	if !verifyResp.IsValid {
-		return nil, fmt.Errorf("payment invalid: %s", verifyResp.InvalidReason)
+		return nil, &PaymentInvalidError{
+			Reason: verifyResp.InvalidReason,
+			Code:   InvalidReasonCode(verifyResp.InvalidReason),
+		}
 	}
 
 	return &payment, nil
@@ -131,27 +463,158 @@ func (m *Middleware) VerifyPayment(ctx context.Context, toolName string, meta ma
 
 // SettlePayment settles a payment using the facilitator
 func (m *Middleware) SettlePayment(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements) (*SettleResponse, error) {
+	settleResp, release, err := m.reserveAndSettle(ctx, toolName, payment, requirements)
+	if settleRejected(settleResp, err) {
+		release()
+	}
+	return settleResp, err
+}
+
+// settleRejected reports whether the facilitator definitively refused the
+// settlement, or never received it, so the payment cannot have moved and its
+// replay reservation may be released. Transport errors, timeouts and
+// indeterminate responses keep the reservation: the payment may already be
+// settled on-chain.
+func settleRejected(settleResp *SettleResponse, err error) bool {
+	if err == nil {
+		return !settleResp.Success && settleResp.ErrorReason != ""
+	}
+	var notSent *settleNotSentError
+	if errors.As(err, &notSent) {
+		return true
+	}
+	var settleErr *x402sdk.SettleError
+	return errors.As(err, &settleErr) && settleErr.ErrorReason != ""
+}
+
+// settleNotSentError marks a settlement that failed before any request went
+// to the facilitator, e.g. because the call budget was already spent
+type settleNotSentError struct {
+	err error
+}
+
+// Error implements the error interface
+func (e *settleNotSentError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *settleNotSentError) Unwrap() error {
+	return e.err
+}
+
+// reserveAndSettle reserves the payment against replays and settles it. The
+// caller runs release only when settleRejected; a payment that settled, is
+// kept for a retry or may have settled stays reserved.
+func (m *Middleware) reserveAndSettle(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements) (*SettleResponse, func(), error) {
+	release := func() {}
+	if requirements.Scheme == SchemeUpto {
+		if err := m.checkUptoAmount(toolName, payment, requirements); err != nil {
+			return nil, release, err
+		}
+	}
+
+	// Reject payments that were already settled
+	if m.replayStore != nil {
+		key, err := replayKey(payment)
+		if err != nil {
+			return nil, release, fmt.Errorf("failed to derive replay key: %w", err)
+		}
+		reserved, err := m.replayStore.Reserve(ctx, key, m.replayTTL)
+		if err != nil {
+			return nil, release, fmt.Errorf("replay check failed: %w", err)
+		}
+		if !reserved {
+			return nil, release, ErrPaymentReplayed
+		}
+		release = func() {
+			if err := m.replayStore.Release(ctx, key); err != nil {
+				m.logger.Warn("x402 replay release error", "tool", toolName, "err", err)
+			}
+		}
+	}
+
+	settleResp, err := m.settle(ctx, toolName, payment, requirements)
+	return settleResp, release, err
+}
+
+// settle sends one settlement, with the configured retries, to the facilitator
+func (m *Middleware) settle(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements) (*SettleResponse, error) {
 	// Marshal payment and requirements
 	payloadBytes, err := json.Marshal(payment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payment: %w", err)
+		return nil, &settleNotSentError{fmt.Errorf("failed to marshal payment: %w", err)}
 	}
 
 	requirementsBytes, err := json.Marshal(requirements)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal requirements: %w", err)
+		return nil, &settleNotSentError{fmt.Errorf("failed to marshal requirements: %w", err)}
 	}
 
 	// Settle payment using facilitator
-	settleResp, err := m.facilitator.Settle(ctx, payloadBytes, requirementsBytes)
+	sent := false
+	settleResp, err := withFacilitatorTimeout(ctx, m.facilitatorTimeoutFor(requirements), m.retryPolicy, func(ctx context.Context) (*SettleResponse, error) {
+		sent = true
+		return m.facilitator.Settle(ctx, payloadBytes, requirementsBytes)
+	})
+	if err != nil && !sent {
+		err = &settleNotSentError{err}
+	}
+	if err == nil {
+		err = checkSettleResponse(settleResp)
+	}
+	if err == nil && settleResp.Success && settleResp.ErrorReason != "" {
+		m.logger.Warn("x402 settle response is successful but has an error reason", "tool", toolName, "network", requirements.Network, "requestId", RequestIDFromContext(ctx), "transaction", settleResp.Transaction, "errorReason", settleResp.ErrorReason)
+	}
+	m.metrics.PaymentSettled(requirements.Network, err == nil && settleResp.Success)
 	if err != nil {
-		log.Printf("x402 settle error (tool=%s network=%s): %v", toolName, requirements.Network, err)
+		if errors.Is(err, ErrCallBudgetExhausted) {
+			return nil, err
+		}
+		m.logger.Error("x402 settle error", "tool", toolName, "network", requirements.Network, "requestId", RequestIDFromContext(ctx), "err", err)
 		return nil, fmt.Errorf("payment settlement failed: %w", err)
 	}
 
 	return settleResp, nil
 }
 
+// checkUptoAmount ensures an "upto" settlement does not exceed the advertised
+// max: neither the requirement settled against nor the amounts the payment
+// authorizes, such as the signed authorization value
+func (m *Middleware) checkUptoAmount(toolName string, payment *PaymentPayload, requirements *PaymentRequirements) error {
+	pricing, ok := m.pricingOption(toolName, requirements)
+	if !ok {
+		return nil
+	}
+	limit, ok := new(big.Int).SetString(pricing.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid configured amount %q for tool %s", pricing.Amount, toolName)
+	}
+	for _, amount := range append([]string{requirements.Amount}, declaredAmounts(payment)...) {
+		settle, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid settlement amount %q", amount)
+		}
+		if settle.Cmp(limit) > 0 {
+			return fmt.Errorf("settlement amount %s exceeds maximum %s for tool %s", amount, pricing.Amount, toolName)
+		}
+	}
+	return nil
+}
+
+// pricingOption finds the configured option requirements were built from
+func (m *Middleware) pricingOption(toolName string, requirements *PaymentRequirements) (ToolPricingConfig, bool) {
+	options, _ := m.toolPricing(toolName)
+	for _, pricing := range options {
+		if pricing.scheme() == requirements.Scheme &&
+			sameNetwork(string(pricing.Network), requirements.Network) &&
+			strings.EqualFold(pricing.Asset, requirements.Asset) {
+			return pricing, true
+		}
+	}
+	return ToolPricingConfig{}, false
+}
+
 // WrapToolHandler wraps an MCP tool handler with x402 payment verification
 func WrapToolHandler[In, Out any](
 	m *Middleware,
@@ -160,30 +623,78 @@ func WrapToolHandler[In, Out any](
 ) func(context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, Out, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, input In) (*mcp.CallToolResult, Out, error) {
 		var zero Out
+		m.metrics.ToolCalled(toolName)
+
+		if retryAfter, ok := m.checkRateLimit(toolName, req); !ok {
+			m.logger.Warn("x402 rate limit exceeded", "tool", toolName, "retryAfter", retryAfter)
+			return rateLimitedResult(toolName, retryAfter), zero, nil
+		}
 
 		// Check if this tool requires payment
 		pricing := m.GetPaymentRequirements(toolName)
 		if pricing == nil {
+			if _, free := m.freeTools[toolName]; m.strict && !free {
+				m.logger.Error("x402 unpriced tool called in strict mode", "tool", toolName)
+				return nil, zero, fmt.Errorf("%w: %s", ErrUnpricedTool, toolName)
+			}
 			// Tool is free, proceed normally
 			return handler(ctx, req, input)
 		}
 
+		// Correlate facilitator calls and logs with the caller's request ID
+		if id, ok := extractMeta(req)[MetaKeyRequestID].(string); ok {
+			ctx = WithRequestID(ctx, id)
+		} else if RequestIDFromContext(ctx) == "" {
+			ctx = WithRequestID(ctx, "")
+		}
+
+		// Keep shutdown from abandoning a payment between verify and settle
+		m.inflight.begin()
+		defer m.inflight.end()
+
+		// Share one budget across verify, settle and the wrapped handler
+		ctx, cancel := WithCallBudget(ctx, m.callBudget)
+		defer cancel()
+
 		// Extract _meta from the request
 		meta := extractMeta(req)
+		keys := m.PaymentMetaKeysFor(toolName)
 
 		// Verify payment using facilitator
 		payment, err := m.VerifyPayment(ctx, toolName, meta)
+		if errors.Is(err, ErrCallBudgetExhausted) {
+			return callBudgetExhaustedResult(err), zero, nil
+		}
+		if err == nil {
+			// The payer is authenticated now, so its own limit applies too
+			if retryAfter, ok := m.checkPayerRateLimit(toolName, payment); !ok {
+				m.logger.Warn("x402 payer rate limit exceeded", "tool", toolName, "retryAfter", retryAfter)
+				return rateLimitedResult(toolName, retryAfter), zero, nil
+			}
+		}
 		if err != nil {
 			// Invalid payment - return 402 with error
+			message := fmt.Sprintf("Payment verification failed: %s", err.Error())
+			paymentErr := &PaymentError{
+				Code:    ErrorCodePaymentRequired,
+				Reason:  ErrorReasonVerifyFailed,
+				Message: message,
+			}
+			var invalid *PaymentInvalidError
+			if errors.As(err, &invalid) {
+				paymentErr.InvalidCode = invalid.Code
+				paymentErr.InvalidReason = invalid.Reason
+			}
 			return &mcp.CallToolResult{
 				IsError: true,
 				Content: []mcp.Content{
 					&mcp.TextContent{
-						Text: fmt.Sprintf("Payment verification failed: %s", err.Error()),
+						Text: message,
 					},
 				},
+				StructuredContent: paymentErr,
 				Meta: map[string]interface{}{
-					MetaKeyPaymentResponse: &SettleResponse{
+					keys.PaymentResponse: &SettleResponse{
 						Success:     false,
 						Network:     m.network,
 						ErrorReason: err.Error(),
@@ -194,32 +705,82 @@ func WrapToolHandler[In, Out any](
 
 		if payment == nil {
 			// No payment provided - return 402 Payment Required
-			paymentReqJSON, _ := json.Marshal(pricing)
+			if m.requiredMode == PaymentRequiredError {
+				return nil, zero, paymentRequiredError(keys, pricing)
+			}
+			text, structured := m.unpaidBody(toolName, pricing)
 			return &mcp.CallToolResult{
 				IsError: true,
 				Content: []mcp.Content{
 					&mcp.TextContent{
-						Text: string(paymentReqJSON),
+						Text: text,
 					},
 				},
-				Meta: map[string]interface{}{
-					MetaKeyPaymentRequired: pricing,
-				},
+				StructuredContent: structured,
+				Meta:              unpaidMeta(keys, pricing),
 			}, zero, nil
 		}
 
-		// Payment verified - settle it
-		settleResp, err := m.SettlePayment(ctx, toolName, payment, &pricing.Accepts[0])
+		// The payer is verified, so it may spend a free call; the payment is
+		// then left unsettled
+		if m.claimFreeTrial(ctx, toolName, payment) {
+			m.logger.Info("x402 free trial call", "tool", toolName)
+			return handler(ctx, req, input)
+		}
+
+		// Payment verified - settle against the option the agent paid with,
+		// as priced now so a quote that went stale during verify is caught
+		if current := m.GetPaymentRequirements(toolName); current != nil {
+			pricing = current
+		}
+		var settleResp *SettleResponse
+		var pending *SettlementPending
+		requirements, err := matchRequirements(pricing.Accepts, payment)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrPaymentMismatch, err)
+		} else {
+			err = checkPaymentCovers(payment, requirements, m.requirementDecimals(toolName, requirements))
+		}
+		if err == nil {
+			var release func()
+			settleResp, release, err = m.reserveAndSettle(ctx, toolName, payment, requirements)
+			m.auditSettlement(ctx, toolName, payment, requirements, settleResp, err)
+			if err != nil || !settleResp.Success {
+				// A deferred settlement keeps its replay reservation until it resolves
+				var deferred bool
+				if pending, deferred = m.deferSettlement(ctx, toolName, payment, requirements, err); deferred {
+					err = nil
+				} else if settleRejected(settleResp, err) {
+					release()
+				}
+			}
+		}
+		if errors.Is(err, ErrCallBudgetExhausted) {
+			return callBudgetExhaustedResult(err), zero, nil
+		}
 		if err != nil {
+			message := fmt.Sprintf("Payment settlement failed: %s", err.Error())
+			reason := ErrorReasonSettleFailed
+			switch {
+			case errors.Is(err, ErrPaymentReplayed):
+				reason = ErrorReasonPaymentReplayed
+			case errors.Is(err, ErrPaymentMismatch):
+				reason = ErrorReasonPaymentMismatch
+			}
 			return &mcp.CallToolResult{
 				IsError: true,
 				Content: []mcp.Content{
 					&mcp.TextContent{
-						Text: fmt.Sprintf("Payment settlement failed: %s", err.Error()),
+						Text: message,
 					},
 				},
+				StructuredContent: &PaymentError{
+					Code:    ErrorCodePaymentRequired,
+					Reason:  reason,
+					Message: message,
+				},
 				Meta: map[string]interface{}{
-					MetaKeyPaymentResponse: &SettleResponse{
+					keys.PaymentResponse: &SettleResponse{
 						Success:     false,
 						Network:     m.network,
 						ErrorReason: err.Error(),
@@ -228,21 +789,31 @@ func WrapToolHandler[In, Out any](
 			}, zero, nil
 		}
 
-		if !settleResp.Success {
+		if pending == nil && !settleResp.Success {
+			message := fmt.Sprintf("Payment settlement failed: %s", settleResp.ErrorReason)
 			return &mcp.CallToolResult{
 				IsError: true,
 				Content: []mcp.Content{
 					&mcp.TextContent{
-						Text: fmt.Sprintf("Payment settlement failed: %s", settleResp.ErrorReason),
+						Text: message,
 					},
 				},
+				StructuredContent: &PaymentError{
+					Code:    ErrorCodePaymentRequired,
+					Reason:  ErrorReasonSettleFailed,
+					Message: message,
+				},
 				Meta: map[string]interface{}{
-					MetaKeyPaymentResponse: settleResp,
+					keys.PaymentResponse: settleResp,
 				},
 			}, zero, nil
 		}
 
-		// Payment settled - execute the tool
+		if pending == nil {
+			m.notifySettlement(ctx, toolName, settleResp)
+		}
+
+		// Payment settled or its settlement deferred - execute the tool
 		result, out, err := handler(ctx, req, input)
 		if err != nil {
 			return result, out, err
@@ -255,19 +826,52 @@ func WrapToolHandler[In, Out any](
 		if result.Meta == nil {
 			result.Meta = make(map[string]interface{})
 		}
-		result.Meta[MetaKeyPaymentResponse] = settleResp
+		if pending != nil {
+			result.Meta[MetaKeySettlementPending] = pending
+			return result, out, nil
+		}
+		result.Meta[keys.PaymentResponse] = settleResp
+		if m.settlementSummary {
+			result.Content = append(result.Content, &mcp.TextContent{
+				Text: settlementSummary(settleResp),
+			})
+		}
 
 		return result, out, nil
 	}
 }
 
-// extractMeta extracts the _meta field from a CallToolRequest
-func extractMeta(req *mcp.CallToolRequest) map[string]interface{} {
-	if req.Params.Meta == nil {
-		return make(map[string]interface{})
+// settlementSummary describes a successful settlement in one line
+func settlementSummary(settle *SettleResponse) string {
+	summary := fmt.Sprintf("x402 payment settled on %s", settle.Network)
+	if settle.Transaction != "" {
+		summary += ", transaction " + settle.Transaction
+	}
+	if settle.Payer != "" {
+		summary += ", payer " + settle.Payer
+	}
+	return summary
+}
+
+// callBudgetExhaustedResult reports that a call ran out of attempts or time
+func callBudgetExhaustedResult(err error) *mcp.CallToolResult {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Tool call aborted: %s", err.Error()),
+			},
+		},
 	}
+}
 
+// extractMeta extracts the _meta field from a CallToolRequest. A request
+// without params yields an empty map.
+func extractMeta(req *mcp.CallToolRequest) map[string]interface{} {
 	result := make(map[string]interface{})
+	if req == nil || req.Params == nil {
+		return result
+	}
 	for k, v := range req.Params.Meta {
 		result[k] = v
 	}
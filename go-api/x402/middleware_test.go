@@ -0,0 +1,661 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func newStubFacilitator(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func okFacilitator(t *testing.T) *httptest.Server {
+	return newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+		case "/settle":
+			json.NewEncoder(w).Encode(SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func newTestMiddleware(facilitatorURL string) *Middleware {
+	m := NewMiddleware(
+		"http://localhost:8080",
+		"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+		Network("eip155:84532"),
+		"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		facilitatorURL,
+	)
+	m.SetToolPrice("paid_tool", "10000")
+	return m
+}
+
+func paidRequest() *mcp.CallToolRequest {
+	return &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{
+		Meta: mcp.Meta{
+			MetaKeyPayment: map[string]any{
+				"x402Version": 2,
+				"accepted": map[string]any{
+					"scheme":  "exact",
+					"network": "eip155:84532",
+					"amount":  "10000",
+					"asset":   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+					"payTo":   "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				},
+				"payload": map[string]any{"signature": "0xdeadbeef"},
+			},
+		},
+	}}
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+	if result == nil || len(result.Content) == 0 {
+		t.Fatalf("expected result content")
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatalf("expected TextContent, got %T", result.Content[0])
+	}
+	return text.Text
+}
+
+func TestWrapToolHandlerCallBudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.SetCallBudget(CallBudget{MaxAttempts: 2})
+
+	handlerCalls := 0
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		handlerCalls++
+		// Simulates the proxy request drawing from the same budget.
+		if err := SpendAttempt(ctx); err != nil {
+			return callBudgetExhaustedResult(err), nil, nil
+		}
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if handlerCalls != 1 {
+		t.Fatalf("expected handler to run once, got %d", handlerCalls)
+	}
+	if !result.IsError {
+		t.Fatalf("expected budget exhaustion to return IsError=true")
+	}
+	if text := resultText(t, result); !strings.Contains(text, ErrCallBudgetExhausted.Error()) {
+		t.Fatalf("expected budget exhausted message, got %q", text)
+	}
+}
+
+func TestWrapToolHandlerCallBudgetAbortsBeforeSettle(t *testing.T) {
+	t.Parallel()
+
+	var settled atomic.Bool
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/settle" {
+			settled.Store(true)
+		}
+		json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+	})
+	m := newTestMiddleware(facilitator.URL)
+	m.SetCallBudget(CallBudget{MaxAttempts: 1})
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		t.Fatalf("handler should not run when the budget is exhausted")
+		return nil, nil, nil
+	})
+
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if settled.Load() {
+		t.Fatalf("expected settle to be skipped")
+	}
+	if text := resultText(t, result); !strings.Contains(text, ErrCallBudgetExhausted.Error()) {
+		t.Fatalf("expected budget exhausted message, got %q", text)
+	}
+}
+
+func TestGetPaymentRequirementsUptoScheme(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	m.SetToolPriceWithScheme("metered_tool", SchemeUpto, "50000")
+
+	reqs := m.GetPaymentRequirements("metered_tool")
+	if reqs == nil || len(reqs.Accepts) != 1 {
+		t.Fatalf("expected one accepted requirement, got %+v", reqs)
+	}
+	if reqs.Accepts[0].Scheme != SchemeUpto {
+		t.Fatalf("expected scheme %q, got %q", SchemeUpto, reqs.Accepts[0].Scheme)
+	}
+	if reqs.Accepts[0].Amount != "50000" {
+		t.Fatalf("expected max amount 50000, got %q", reqs.Accepts[0].Amount)
+	}
+
+	if got := m.GetPaymentRequirements("paid_tool").Accepts[0].Scheme; got != SchemeExact {
+		t.Fatalf("expected SetToolPrice to default to %q, got %q", SchemeExact, got)
+	}
+}
+
+func TestGetPaymentRequirementsApprovalHint(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	option := ToolPricingConfig{
+		Amount:  "10000",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Network: "eip155:84532",
+		PayTo:   "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+	}
+	withDefault, withAllowance := option, option
+	withDefault.Approval = &ApprovalHint{Spender: "0xSpender"}
+	withAllowance.Approval = &ApprovalHint{Spender: "0xSpender", Allowance: "1000000"}
+	for _, tool := range []struct {
+		name   string
+		option ToolPricingConfig
+	}{{"default_allowance", withDefault}, {"explicit_allowance", withAllowance}, {"no_hint", option}} {
+		if err := m.AddToolPaymentOption(tool.name, tool.option); err != nil {
+			t.Fatalf("AddToolPaymentOption(%s): %v", tool.name, err)
+		}
+	}
+
+	tests := []struct {
+		tool          string
+		wantAllowance string
+	}{
+		{tool: "default_allowance", wantAllowance: "10000"},
+		{tool: "explicit_allowance", wantAllowance: "1000000"},
+		{tool: "no_hint"},
+	}
+	for _, tt := range tests {
+		extra := m.GetPaymentRequirements(tt.tool).Accepts[0].Extra
+		if extra["name"] != "USDC" || extra["version"] != "2" {
+			t.Fatalf("%s: expected the EIP-712 domain in extra, got %v", tt.tool, extra)
+		}
+		approval, ok := extra["approval"].(map[string]interface{})
+		if tt.wantAllowance == "" {
+			if _, present := extra["approval"]; present {
+				t.Fatalf("%s: expected no approval hint, got %v", tt.tool, extra["approval"])
+			}
+			continue
+		}
+		if !ok || approval["spender"] != "0xSpender" || approval["allowance"] != tt.wantAllowance {
+			t.Fatalf("%s: expected approval for 0xSpender of %s, got %v", tt.tool, tt.wantAllowance, extra["approval"])
+		}
+	}
+}
+
+func TestSettlePaymentUptoRejectsAmountAboveMax(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.SetToolPriceWithScheme("metered_tool", SchemeUpto, "50000")
+
+	requirements := m.GetPaymentRequirements("metered_tool").Accepts[0]
+	requirements.Amount = "60000"
+	payment := &PaymentPayload{X402Version: X402Version, Accepted: requirements}
+	if _, err := m.SettlePayment(context.Background(), "metered_tool", payment, &requirements); err == nil {
+		t.Fatalf("expected settlement above the advertised max to fail")
+	}
+
+	requirements.Amount = "12000"
+	payment.Accepted = requirements
+	settleResp, err := m.SettlePayment(context.Background(), "metered_tool", payment, &requirements)
+	if err != nil {
+		t.Fatalf("expected settlement below max to succeed: %v", err)
+	}
+	if !settleResp.Success {
+		t.Fatalf("expected successful settlement")
+	}
+}
+
+func TestSettlePaymentUptoRejectsAuthorizationAboveMax(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := NewMiddleware("http://localhost:8080", "0xpay", Network("eip155:84532"), "0xusdc", "http://facilitator.invalid", WithFacilitator(fake))
+	m.SetToolPriceWithScheme("metered_tool", SchemeUpto, "50000")
+
+	// The requirement is the advertised one; only the signed value is over
+	requirements := m.GetPaymentRequirements("metered_tool").Accepts[0]
+	payment := &PaymentPayload{
+		X402Version: X402Version,
+		Accepted:    requirements,
+		Payload:     map[string]interface{}{"authorization": map[string]interface{}{"value": "60000"}},
+	}
+	if _, err := m.SettlePayment(context.Background(), "metered_tool", payment, &requirements); err == nil {
+		t.Fatalf("expected an authorization above the advertised max to fail")
+	}
+	if len(fake.Settled()) != 0 {
+		t.Fatalf("expected nothing sent to the facilitator")
+	}
+
+	payment.Payload["authorization"] = map[string]interface{}{"value": "50000"}
+	if _, err := m.SettlePayment(context.Background(), "metered_tool", payment, &requirements); err != nil {
+		t.Fatalf("expected an authorization at the max to settle: %v", err)
+	}
+}
+
+func TestWrapToolHandlerRejectsReplayedPayment(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	first, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if first.IsError {
+		t.Fatalf("expected first call to succeed, got %q", resultText(t, first))
+	}
+
+	replayed, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !replayed.IsError {
+		t.Fatalf("expected replayed payment to be rejected")
+	}
+	if text := resultText(t, replayed); !strings.Contains(text, ErrPaymentReplayed.Error()) {
+		t.Fatalf("expected replay error, got %q", text)
+	}
+}
+
+func assertPaymentError(t *testing.T, result *mcp.CallToolResult, reason string) *PaymentError {
+	t.Helper()
+	if !result.IsError {
+		t.Fatalf("expected IsError=true")
+	}
+	paymentErr, ok := result.StructuredContent.(*PaymentError)
+	if !ok {
+		t.Fatalf("expected *PaymentError structured content, got %T", result.StructuredContent)
+	}
+	if paymentErr.Code != ErrorCodePaymentRequired {
+		t.Fatalf("expected code %d, got %d", ErrorCodePaymentRequired, paymentErr.Code)
+	}
+	if paymentErr.Reason != reason {
+		t.Fatalf("expected reason %q, got %q", reason, paymentErr.Reason)
+	}
+	if paymentErr.Message == "" || resultText(t, result) == "" {
+		t.Fatalf("expected message and text content to be set")
+	}
+	return paymentErr
+}
+
+func TestWrapToolHandlerStructuredPaymentErrors(t *testing.T) {
+	t.Parallel()
+
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+
+	t.Run("missing payment", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMiddleware(okFacilitator(t).URL)
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		assertPaymentError(t, result, ErrorReasonPaymentRequired)
+	})
+
+	t.Run("verify failed", func(t *testing.T) {
+		t.Parallel()
+		facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: false, InvalidReason: "insufficient_funds"})
+		})
+		m := newTestMiddleware(facilitator.URL)
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		assertPaymentError(t, result, ErrorReasonVerifyFailed)
+	})
+
+	t.Run("settle failed", func(t *testing.T) {
+		t.Parallel()
+		facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/settle" {
+				json.NewEncoder(w).Encode(SettleResponse{Success: false, ErrorReason: "transaction_failed"})
+				return
+			}
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+		})
+		m := newTestMiddleware(facilitator.URL)
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		assertPaymentError(t, result, ErrorReasonSettleFailed)
+	})
+
+	t.Run("replayed payment", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMiddleware(okFacilitator(t).URL)
+		handler := WrapToolHandler(m, "paid_tool", noop)
+		if _, _, err := handler(context.Background(), paidRequest(), nil); err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		result, _, err := handler(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		assertPaymentError(t, result, ErrorReasonPaymentReplayed)
+	})
+}
+
+func TestWrapToolHandlerPaymentRequiredModes(t *testing.T) {
+	t.Parallel()
+
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+	unpaid := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}
+
+	t.Run("legacy result", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMiddleware(okFacilitator(t).URL)
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), unpaid, nil)
+		if err != nil {
+			t.Fatalf("expected no error in legacy mode, got %v", err)
+		}
+		if _, ok := result.Meta[MetaKeyPaymentRequired]; !ok {
+			t.Fatalf("expected %s in result meta", MetaKeyPaymentRequired)
+		}
+	})
+
+	t.Run("json-rpc error", func(t *testing.T) {
+		t.Parallel()
+		m := newTestMiddleware(okFacilitator(t).URL)
+		m.SetPaymentRequiredMode(PaymentRequiredError)
+		result, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), unpaid, nil)
+		if result != nil {
+			t.Fatalf("expected no result in error mode, got %+v", result)
+		}
+		var wireErr *jsonrpc.Error
+		if !errors.As(err, &wireErr) {
+			t.Fatalf("expected *jsonrpc.Error, got %T", err)
+		}
+		if wireErr.Code != ErrorCodePaymentRequired {
+			t.Fatalf("expected code %d, got %d", ErrorCodePaymentRequired, wireErr.Code)
+		}
+		var data PaymentRequiredData
+		if err := json.Unmarshal(wireErr.Data, &data); err != nil {
+			t.Fatalf("unmarshal error.data: %v", err)
+		}
+		if len(data.Accepts) != 1 || data.Accepts[0].Amount != "10000" {
+			t.Fatalf("expected error.data.accepts to carry the price, got %+v", data.Accepts)
+		}
+	})
+}
+
+func TestWrapToolHandlerSettlesAgainstMatchedOption(t *testing.T) {
+	t.Parallel()
+
+	var settledNetwork atomic.Value
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			PaymentRequirements PaymentRequirements `json:"paymentRequirements"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/verify":
+			json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+		case "/settle":
+			settledNetwork.Store(body.PaymentRequirements.Network)
+			json.NewEncoder(w).Encode(SettleResponse{Success: true, Transaction: "0xabc", Network: Network(body.PaymentRequirements.Network)})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	m := newTestMiddleware(facilitator.URL)
+	m.AddToolPaymentOption("paid_tool", ToolPricingConfig{
+		Amount:  "20000",
+		Asset:   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Network: Network("eip155:8453"),
+		PayTo:   "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+	})
+	if accepts := m.GetPaymentRequirements("paid_tool").Accepts; len(accepts) != 2 {
+		t.Fatalf("expected two accepted options, got %d", len(accepts))
+	}
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	req := paidRequest()
+	payment := req.Params.Meta[MetaKeyPayment].(map[string]any)
+	payment["accepted"] = map[string]any{
+		"scheme":  "exact",
+		"network": "eip155:8453",
+		"amount":  "20000",
+		"asset":   "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		"payTo":   "0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+	}
+
+	result, _, err := handler(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected payment on second option to succeed, got %q", resultText(t, result))
+	}
+	if got, _ := settledNetwork.Load().(string); got != "eip155:8453" {
+		t.Fatalf("expected settlement on eip155:8453, got %q", got)
+	}
+}
+
+func TestWrapToolHandlerRejectsUnadvertisedOption(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		t.Fatalf("handler should not run for an unadvertised option")
+		return nil, nil, nil
+	})
+
+	req := paidRequest()
+	payment := req.Params.Meta[MetaKeyPayment].(map[string]any)
+	payment["accepted"].(map[string]any)["network"] = "eip155:1"
+
+	result, _, err := handler(context.Background(), req, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	assertPaymentError(t, result, ErrorReasonVerifyFailed)
+	if text := resultText(t, result); !strings.Contains(text, "does not match any accepted option") {
+		t.Fatalf("expected option mismatch error, got %q", text)
+	}
+}
+
+func TestWrapToolHandlerFreeTools(t *testing.T) {
+	t.Parallel()
+
+	called := func(m *Middleware, toolName string) (bool, error) {
+		var ran bool
+		handler := WrapToolHandler(m, toolName, func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+			ran = true
+			return &mcp.CallToolResult{}, nil, nil
+		})
+		_, _, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, nil)
+		return ran, err
+	}
+
+	t.Run("registered free tool in strict mode", func(t *testing.T) {
+		m := newTestMiddleware(okFacilitator(t).URL)
+		m.SetStrict(true)
+		m.RegisterFreeTool("free_tool")
+		ran, err := called(m, "free_tool")
+		if err != nil || !ran {
+			t.Fatalf("expected free tool to run, ran=%t err=%v", ran, err)
+		}
+	})
+
+	t.Run("unpriced tool in strict mode", func(t *testing.T) {
+		m := newTestMiddleware(okFacilitator(t).URL)
+		m.SetStrict(true)
+		ran, err := called(m, "forgotten_tool")
+		if !errors.Is(err, ErrUnpricedTool) {
+			t.Fatalf("expected ErrUnpricedTool, got %v", err)
+		}
+		if ran {
+			t.Fatalf("expected handler not to run")
+		}
+	})
+
+	t.Run("unpriced tool in default mode", func(t *testing.T) {
+		m := newTestMiddleware(okFacilitator(t).URL)
+		ran, err := called(m, "forgotten_tool")
+		if err != nil || !ran {
+			t.Fatalf("expected unpriced tool to run outside strict mode, ran=%t err=%v", ran, err)
+		}
+	})
+}
+
+func TestWrapToolHandlerSettlementHook(t *testing.T) {
+	t.Parallel()
+
+	type settlement struct {
+		toolName string
+		settle   *SettleResponse
+	}
+	settlements := make(chan settlement, 2)
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.SetSettlementHook(func(ctx context.Context, toolName string, settle *SettleResponse) {
+		settlements <- settlement{toolName: toolName, settle: settle}
+		panic("billing system unavailable")
+	})
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected hook failure not to affect the result, got %q", resultText(t, result))
+	}
+
+	if err := m.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	select {
+	case got := <-settlements:
+		if got.toolName != "paid_tool" {
+			t.Fatalf("expected hook for paid_tool, got %q", got.toolName)
+		}
+		if got.settle == nil || !got.settle.Success || got.settle.Transaction != "0xabc" {
+			t.Fatalf("unexpected settle response %+v", got.settle)
+		}
+	default:
+		t.Fatalf("expected settlement hook to run")
+	}
+}
+
+func TestWrapToolHandlerSettlementSummary(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{true, false} {
+		m := newFakeMiddleware(NewFakeFacilitator())
+		m.SetSettlementSummary(enabled)
+		handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: `{"temperature":71.2}`}}}, nil, nil
+		})
+
+		result, _, err := handler(context.Background(), paidRequest(), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		if resultText(t, result) != `{"temperature":71.2}` {
+			t.Fatalf("expected the tool's own content first, got %q", resultText(t, result))
+		}
+		settle, ok := result.Meta[MetaKeyPaymentResponse].(*SettleResponse)
+		if !ok || settle.Transaction != "0xfake0001" {
+			t.Fatalf("expected payment-response meta either way, got %v", result.Meta)
+		}
+
+		if !enabled {
+			if len(result.Content) != 1 {
+				t.Fatalf("expected no summary when disabled, got %d content items", len(result.Content))
+			}
+			continue
+		}
+		if len(result.Content) != 2 {
+			t.Fatalf("expected a summary content item, got %d items", len(result.Content))
+		}
+		summary, _ := result.Content[1].(*mcp.TextContent)
+		if want := "x402 payment settled on eip155:84532, transaction 0xfake0001"; summary == nil || summary.Text != want {
+			t.Fatalf("expected summary %q, got %+v", want, result.Content[1])
+		}
+	}
+}
+
+func TestWrapToolHandlerCustomUnpaidBody(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	m.SetToolPrice("documented_tool", "5000")
+	m.SetUnpaidBody("documented_tool", func(requirements *PaymentRequiredData) (string, any) {
+		return "Pay " + requirements.Accepts[0].Amount + " to continue; see https://docs.example.com/pay",
+			map[string]any{"docs": "https://docs.example.com/pay"}
+	})
+	unpaid := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}
+	noop := func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	}
+
+	custom, _, err := WrapToolHandler(m, "documented_tool", noop)(context.Background(), unpaid, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if text := resultText(t, custom); text != "Pay 5000 to continue; see https://docs.example.com/pay" {
+		t.Fatalf("expected custom unpaid text, got %q", text)
+	}
+	if structured, ok := custom.StructuredContent.(map[string]any); !ok || structured["docs"] == nil {
+		t.Fatalf("expected custom structured content, got %#v", custom.StructuredContent)
+	}
+	if custom.Meta[MetaKeyPaymentRequired] == nil {
+		t.Fatalf("expected payment requirements to stay in meta")
+	}
+
+	standard, _, err := WrapToolHandler(m, "paid_tool", noop)(context.Background(), unpaid, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	assertPaymentError(t, standard, ErrorReasonPaymentRequired)
+	var requirements PaymentRequiredData
+	if err := json.Unmarshal([]byte(resultText(t, standard)), &requirements); err != nil {
+		t.Fatalf("expected default text to be the requirements JSON: %v", err)
+	}
+}
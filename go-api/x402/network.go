@@ -0,0 +1,94 @@
+package x402
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LegacyNetworks maps x402 v1 network names to their CAIP-2 identifiers.
+// Add entries to support further networks.
+var LegacyNetworks = map[string]Network{
+	"base":           "eip155:8453",
+	"base-sepolia":   "eip155:84532",
+	"ethereum":       "eip155:1",
+	"sepolia":        "eip155:11155111",
+	"polygon":        "eip155:137",
+	"polygon-amoy":   "eip155:80002",
+	"avalanche":      "eip155:43114",
+	"avalanche-fuji": "eip155:43113",
+	"sei":            "eip155:1329",
+	"sei-testnet":    "eip155:1328",
+	"iotex":          "eip155:4689",
+	"solana":         "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+	"solana-devnet":  "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1",
+}
+
+// caip2Pattern matches a CAIP-2 chain ID (namespace:reference)
+var caip2Pattern = regexp.MustCompile(`^[-a-z0-9]{3,8}:[-_a-zA-Z0-9]{1,32}$`)
+
+// ErrInvalidNetwork is returned for a network that is neither a known legacy
+// name nor a well-formed CAIP-2 chain ID
+var ErrInvalidNetwork = errors.New("invalid network")
+
+// networkReferencePatterns validates the CAIP-2 reference of each known
+// namespace: EVM chain IDs are decimal, Solana references are the first 32
+// base58 characters of the genesis hash
+var networkReferencePatterns = map[string]*regexp.Regexp{
+	"eip155": regexp.MustCompile(`^[1-9][0-9]{0,31}$`),
+	"solana": regexp.MustCompile(`^[1-9A-HJ-NP-Za-km-z]{32}$`),
+}
+
+// ValidateNetwork checks that network is a legacy name from LegacyNetworks or
+// a CAIP-2 chain ID whose reference is valid for its namespace. Namespaces
+// other than eip155 and solana are rejected unless allowUnknownNamespace is
+// set, in which case only the generic CAIP-2 shape is checked.
+func ValidateNetwork(network string, allowUnknownNamespace bool) error {
+	if _, ok := LegacyNetworks[strings.ToLower(network)]; ok {
+		return nil
+	}
+	if !caip2Pattern.MatchString(network) {
+		return fmt.Errorf("%w %q: expected a CAIP-2 identifier such as eip155:8453", ErrInvalidNetwork, network)
+	}
+	namespace, reference, _ := strings.Cut(network, ":")
+	pattern, known := networkReferencePatterns[namespace]
+	if !known {
+		if allowUnknownNamespace {
+			return nil
+		}
+		return fmt.Errorf("%w %q: unknown CAIP-2 namespace %q", ErrInvalidNetwork, network, namespace)
+	}
+	if !pattern.MatchString(reference) {
+		return fmt.Errorf("%w %q: malformed %s reference %q", ErrInvalidNetwork, network, namespace, reference)
+	}
+	return nil
+}
+
+// NormalizeNetwork returns the CAIP-2 form of a network, translating legacy
+// names via LegacyNetworks
+func NormalizeNetwork(network string) (Network, error) {
+	name := strings.TrimSpace(network)
+	if caip2, ok := LegacyNetworks[strings.ToLower(name)]; ok {
+		return caip2, nil
+	}
+	if caip2Pattern.MatchString(name) {
+		return Network(name), nil
+	}
+	return "", fmt.Errorf("unknown network %q: expected a CAIP-2 identifier or a known legacy name", network)
+}
+
+// canonicalNetwork returns the CAIP-2 form of network, or network unchanged
+// when it is not recognized
+func canonicalNetwork(network string) string {
+	normalized, err := NormalizeNetwork(network)
+	if err != nil {
+		return network
+	}
+	return string(normalized)
+}
+
+// sameNetwork reports whether a and b name the same network
+func sameNetwork(a, b string) bool {
+	return canonicalNetwork(a) == canonicalNetwork(b)
+}
@@ -0,0 +1,115 @@
+package x402
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeNetwork(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]Network{
+		"base":           "eip155:8453",
+		"base-sepolia":   "eip155:84532",
+		"Base-Sepolia":   "eip155:84532",
+		"avalanche-fuji": "eip155:43113",
+		"polygon":        "eip155:137",
+		"solana":         "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+		"solana-devnet":  "solana:EtWTRABZaYq6iMfeYKouRu166VU2xqa1",
+		"eip155:84532":   "eip155:84532",
+		"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp": "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+	}
+	for input, want := range tests {
+		got, err := NormalizeNetwork(input)
+		if err != nil {
+			t.Fatalf("NormalizeNetwork(%q) error: %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("NormalizeNetwork(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestNormalizeNetworkRejectsUnknown(t *testing.T) {
+	t.Parallel()
+
+	for _, input := range []string{"", "moonbase", "eip155", "eip155:"} {
+		if got, err := NormalizeNetwork(input); err == nil {
+			t.Fatalf("NormalizeNetwork(%q) = %q, expected error", input, got)
+		}
+	}
+}
+
+func TestMatchRequirementsAcceptsLegacyNetworkName(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware(okFacilitator(t).URL)
+	accepts := m.GetPaymentRequirements("paid_tool").Accepts
+	payment := &PaymentPayload{Accepted: PaymentRequirements{Scheme: SchemeExact, Network: "base-sepolia"}}
+	if _, err := matchRequirements(accepts, payment); err != nil {
+		t.Fatalf("expected legacy network name to match eip155:84532: %v", err)
+	}
+}
+
+func TestValidateNetwork(t *testing.T) {
+	t.Parallel()
+
+	valid := []string{
+		"eip155:8453",
+		"eip155:84532",
+		"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp",
+		"base-sepolia",
+	}
+	for _, network := range valid {
+		if err := ValidateNetwork(network, false); err != nil {
+			t.Fatalf("ValidateNetwork(%q) error: %v", network, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"eip155:8453x",
+		"eip155:08453",
+		"eip155:",
+		"eip155",
+		"solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvd0",
+		"solana:short",
+		"EIP155:8453",
+		"cosmos:cosmoshub-4",
+	}
+	for _, network := range invalid {
+		if err := ValidateNetwork(network, false); !errors.Is(err, ErrInvalidNetwork) {
+			t.Fatalf("ValidateNetwork(%q) = %v, expected ErrInvalidNetwork", network, err)
+		}
+	}
+
+	if err := ValidateNetwork("cosmos:cosmoshub-4", true); err != nil {
+		t.Fatalf("expected unknown namespace to be allowed with opt-in, got %v", err)
+	}
+	if err := ValidateNetwork("eip155:8453x", true); err == nil {
+		t.Fatal("expected opt-in not to relax known namespaces")
+	}
+}
+
+func TestSetToolPriceRejectsInvalidNetwork(t *testing.T) {
+	t.Parallel()
+
+	m := NewMiddleware("http://localhost:8080", "0xpay", Network("eip155:8453x"), "0xasset", "http://facilitator.invalid")
+	if err := m.SetToolPrice("paid_tool", "10000"); !errors.Is(err, ErrInvalidNetwork) {
+		t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+	}
+	if m.GetPaymentRequirements("paid_tool") != nil {
+		t.Fatal("expected the invalid price not to be recorded")
+	}
+
+	m = newTestMiddleware("http://facilitator.invalid")
+	err := m.AddToolPaymentOption("paid_tool", ToolPricingConfig{Amount: "1", Asset: "0xasset", Network: "cosmos:cosmoshub-4", PayTo: "0xpay"})
+	if !errors.Is(err, ErrInvalidNetwork) {
+		t.Fatalf("expected ErrInvalidNetwork, got %v", err)
+	}
+
+	m = NewMiddleware("http://localhost:8080", "0xpay", Network("eip155:8453"), "0xasset", "http://facilitator.invalid", WithUnknownNetworkNamespaces())
+	if err := m.AddToolPaymentOption("paid_tool", ToolPricingConfig{Amount: "1", Asset: "0xasset", Network: "cosmos:cosmoshub-4", PayTo: "0xpay"}); err != nil {
+		t.Fatalf("expected opt-in to allow unknown namespaces, got %v", err)
+	}
+}
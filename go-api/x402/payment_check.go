@@ -0,0 +1,62 @@
+package x402
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPaymentMismatch is returned when a verified payment does not cover the
+// requirement it would be settled against, e.g. it was made against a stale
+// quote
+var ErrPaymentMismatch = errors.New("payment does not match requirements")
+
+// checkPaymentCovers compares the network, asset and amounts declared by the
+// payment with requirements before settling. Amounts are compared in whole
+// tokens at decimals; fields the payment leaves empty are not checked.
+func checkPaymentCovers(payment *PaymentPayload, requirements *PaymentRequirements, decimals int) error {
+	accepted := payment.Accepted
+	if accepted.Network != "" && !sameNetwork(accepted.Network, requirements.Network) {
+		return fmt.Errorf("%w: paid on network %s, requirement is %s", ErrPaymentMismatch, accepted.Network, requirements.Network)
+	}
+	if accepted.Asset != "" && !strings.EqualFold(accepted.Asset, requirements.Asset) {
+		return fmt.Errorf("%w: paid in asset %s, requirement is %s", ErrPaymentMismatch, accepted.Asset, requirements.Asset)
+	}
+
+	required, ok := normalizedAmount(requirements.Amount, decimals)
+	if !ok {
+		return fmt.Errorf("%w: invalid required amount %q", ErrPaymentMismatch, requirements.Amount)
+	}
+	for _, declared := range declaredAmounts(payment) {
+		paid, ok := normalizedAmount(declared, decimals)
+		if !ok {
+			return fmt.Errorf("%w: invalid payment amount %q", ErrPaymentMismatch, declared)
+		}
+		if paid.Cmp(required) < 0 {
+			return fmt.Errorf("%w: paid amount %s is below the required %s", ErrPaymentMismatch, declared, requirements.Amount)
+		}
+	}
+	return nil
+}
+
+// declaredAmounts returns the amounts a payment declares: the accepted amount
+// and, for EVM payloads, the signed authorization value
+func declaredAmounts(payment *PaymentPayload) []string {
+	var amounts []string
+	if payment.Accepted.Amount != "" {
+		amounts = append(amounts, payment.Accepted.Amount)
+	}
+	if authorization, ok := payment.Payload["authorization"].(map[string]interface{}); ok {
+		if value, ok := authorization["value"].(string); ok {
+			amounts = append(amounts, value)
+		}
+	}
+	return amounts
+}
+
+// requirementDecimals returns the configured decimals of the tool's pricing
+// option behind requirements, or DefaultTokenDecimals when none are set
+func (m *Middleware) requirementDecimals(toolName string, requirements *PaymentRequirements) int {
+	pricing, _ := m.pricingOption(toolName, requirements)
+	return decimalsOrDefault(pricing.Decimals)
+}
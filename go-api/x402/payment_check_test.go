@@ -0,0 +1,140 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// repricingFacilitator runs reprice once Verify succeeds, simulating a
+// pricing change while the payment was being verified
+type repricingFacilitator struct {
+	*FakeFacilitator
+	reprice func()
+}
+
+func (f *repricingFacilitator) Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error) {
+	resp, err := f.FakeFacilitator.Verify(ctx, payloadBytes, requirementsBytes)
+	if err == nil && f.reprice != nil {
+		f.reprice()
+	}
+	return resp, err
+}
+
+func TestWrapToolHandlerRejectsMismatchedPaymentBeforeSettling(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		reprice func(m *Middleware)
+		payload map[string]any
+		want    string
+	}{
+		{
+			name:    "underpayment after price change",
+			reprice: func(m *Middleware) { m.SetToolPrice("paid_tool", "20000") },
+			want:    "paid amount 10000 is below the required 20000",
+		},
+		{
+			name:    "underpaid authorization",
+			payload: map[string]any{"authorization": map[string]any{"value": "9999"}},
+			want:    "paid amount 9999 is below the required 10000",
+		},
+		{
+			name: "wrong asset",
+			reprice: func(m *Middleware) {
+				m.pricingMu.Lock()
+				defer m.pricingMu.Unlock()
+				m.pricing["paid_tool"][0].Asset = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+			},
+			want: "asset=0x036CbD53842c5426634e7929541eC2318f3dCF7e) does not match any accepted option",
+		},
+		{
+			name: "wrong network",
+			reprice: func(m *Middleware) {
+				m.pricingMu.Lock()
+				defer m.pricingMu.Unlock()
+				m.pricing["paid_tool"][0].Network = "eip155:8453"
+			},
+			want: "network=eip155:84532",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fake := NewFakeFacilitator()
+			facilitator := &repricingFacilitator{FakeFacilitator: fake}
+			m := newFakeMiddleware(fake)
+			WithFacilitator(facilitator)(m)
+			if tt.reprice != nil {
+				facilitator.reprice = func() { tt.reprice(m) }
+			}
+
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				t.Fatalf("handler should not run for a mismatched payment")
+				return nil, nil, nil
+			})
+			req := paidRequest()
+			if tt.payload != nil {
+				req.Params.Meta[MetaKeyPayment].(map[string]any)["payload"] = tt.payload
+			}
+			result, _, err := handler(context.Background(), req, nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+
+			assertPaymentError(t, result, ErrorReasonPaymentMismatch)
+			if text := resultText(t, result); !strings.Contains(text, tt.want) {
+				t.Fatalf("expected %q in error, got %q", tt.want, text)
+			}
+			if len(fake.Verified()) != 1 {
+				t.Fatalf("expected the payment to be verified once, got %d", len(fake.Verified()))
+			}
+			if settled := fake.Settled(); len(settled) != 0 {
+				t.Fatalf("expected no settlement for a mismatched payment, got %+v", settled)
+			}
+		})
+	}
+}
+
+func TestCheckPaymentCovers(t *testing.T) {
+	t.Parallel()
+
+	requirements := &PaymentRequirements{
+		Scheme:  SchemeExact,
+		Network: "eip155:84532",
+		Asset:   "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Amount:  "10000",
+	}
+	tests := []struct {
+		name     string
+		accepted PaymentRequirements
+		decimals int
+		wantErr  bool
+	}{
+		{name: "exact", accepted: PaymentRequirements{Network: "eip155:84532", Asset: "0x036cbd53842c5426634e7929541ec2318f3dcf7e", Amount: "10000"}},
+		{name: "overpayment", accepted: PaymentRequirements{Amount: "20000"}},
+		{name: "legacy network name", accepted: PaymentRequirements{Network: "base-sepolia"}},
+		{name: "undeclared fields", accepted: PaymentRequirements{}},
+		{name: "underpayment", accepted: PaymentRequirements{Amount: "9999"}, wantErr: true},
+		{name: "underpayment at 18 decimals", accepted: PaymentRequirements{Amount: "9999"}, decimals: 18, wantErr: true},
+		{name: "malformed amount", accepted: PaymentRequirements{Amount: "0.01"}, wantErr: true},
+		{name: "wrong asset", accepted: PaymentRequirements{Asset: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"}, wantErr: true},
+		{name: "wrong network", accepted: PaymentRequirements{Network: "eip155:8453"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkPaymentCovers(&PaymentPayload{Accepted: tt.accepted}, requirements, tt.decimals)
+			if tt.wantErr && !errors.Is(err, ErrPaymentMismatch) {
+				t.Fatalf("expected ErrPaymentMismatch, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package x402
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/goccy/go-yaml"
+)
+
+// PricingFileOption is one payment option for a tool in a pricing file
+type PricingFileOption struct {
+	Amount  string `json:"amount"`
+	Asset   string `json:"asset"`
+	Network string `json:"network"`
+	PayTo   string `json:"payTo"`
+	Scheme  string `json:"scheme,omitempty"`
+	// Decimals is the asset's token decimals; omitted means
+	// DefaultTokenDecimals, while 0 is a token without fractional units
+	Decimals *int `json:"decimals,omitempty"`
+	// Approval is an optional ERC-20 allowance hint for wallets
+	Approval *ApprovalHint `json:"approval,omitempty"`
+}
+
+// pricingFileOptions accepts either a single option object or a list of them
+type pricingFileOptions []PricingFileOption
+
+func (o *pricingFileOptions) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(data, (*[]PricingFileOption)(o))
+	}
+	var single PricingFileOption
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*o = pricingFileOptions{single}
+	return nil
+}
+
+// amountPattern matches a non-negative amount in the asset's smallest unit
+var amountPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// LoadPricingFromFile replaces the tool pricing with the table in path. The
+// file maps tool names to an option object (or a list of them) with amount,
+// asset, network, payTo and optional scheme, decimals and approval hint.
+// Files ending in .yaml or .yml are parsed as YAML, anything else as JSON. On
+// error the current pricing is left untouched.
+func (m *Middleware) LoadPricingFromFile(path string) error {
+	pricing, err := parsePricingFile(path, m.allowUnknownNamespaces)
+	if err != nil {
+		return err
+	}
+	m.pricingMu.Lock()
+	m.pricing = pricing
+	m.pricingMu.Unlock()
+	return nil
+}
+
+// ReloadPricingOnSIGHUP reloads pricing from path each time the process
+// receives SIGHUP, until ctx is done. A file that fails to load is logged and
+// the previous pricing stays in effect.
+func (m *Middleware) ReloadPricingOnSIGHUP(ctx context.Context, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := m.LoadPricingFromFile(path); err != nil {
+					m.logger.Error("x402 pricing reload failed", "path", path, "err", err)
+					continue
+				}
+				m.logger.Info("x402 pricing reloaded", "path", path)
+			}
+		}
+	}()
+}
+
+func parsePricingFile(path string, allowUnknownNamespaces bool) (ToolPricing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read pricing file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("parse pricing file %s: %w", path, err)
+		}
+	}
+
+	var raw map[string]pricingFileOptions
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse pricing file %s: %w", path, err)
+	}
+
+	toolNames := make([]string, 0, len(raw))
+	for toolName := range raw {
+		toolNames = append(toolNames, toolName)
+	}
+	sort.Strings(toolNames)
+
+	pricing := make(ToolPricing, len(raw))
+	for _, toolName := range toolNames {
+		if len(raw[toolName]) == 0 {
+			return nil, fmt.Errorf("pricing file %s: tool %s has no payment options", path, toolName)
+		}
+		for _, option := range raw[toolName] {
+			config, err := option.toConfig(allowUnknownNamespaces)
+			if err != nil {
+				return nil, fmt.Errorf("pricing file %s: tool %s: %w", path, toolName, err)
+			}
+			pricing[toolName] = append(pricing[toolName], config)
+		}
+	}
+	return pricing, nil
+}
+
+// toConfig validates the option and converts it to a ToolPricingConfig
+func (o PricingFileOption) toConfig(allowUnknownNamespaces bool) (ToolPricingConfig, error) {
+	for _, field := range []struct{ name, value string }{
+		{"amount", o.Amount},
+		{"asset", o.Asset},
+		{"network", o.Network},
+		{"payTo", o.PayTo},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			return ToolPricingConfig{}, fmt.Errorf("missing required field %q", field.name)
+		}
+	}
+	if !amountPattern.MatchString(o.Amount) {
+		return ToolPricingConfig{}, fmt.Errorf("amount %q must be a decimal integer in the asset's smallest unit", o.Amount)
+	}
+	if !caip2Pattern.MatchString(o.Network) {
+		if normalized, err := NormalizeNetwork(o.Network); err == nil {
+			return ToolPricingConfig{}, fmt.Errorf("network %q must be CAIP-2; use %q", o.Network, normalized)
+		}
+		return ToolPricingConfig{}, fmt.Errorf("network %q is not a CAIP-2 identifier", o.Network)
+	}
+	if err := ValidateNetwork(o.Network, allowUnknownNamespaces); err != nil {
+		return ToolPricingConfig{}, err
+	}
+	switch o.Scheme {
+	case "", SchemeExact, SchemeUpto:
+	default:
+		return ToolPricingConfig{}, fmt.Errorf("unsupported scheme %q", o.Scheme)
+	}
+	if o.Decimals != nil && (*o.Decimals < 0 || *o.Decimals > maxTokenDecimals) {
+		return ToolPricingConfig{}, fmt.Errorf("decimals %d out of range", *o.Decimals)
+	}
+	if o.Approval != nil {
+		if o.Approval.Spender == "" {
+			return ToolPricingConfig{}, fmt.Errorf("missing required field %q", "approval.spender")
+		}
+		if o.Approval.Allowance != "" && !amountPattern.MatchString(o.Approval.Allowance) {
+			return ToolPricingConfig{}, fmt.Errorf("approval allowance %q must be a decimal integer in the asset's smallest unit", o.Approval.Allowance)
+		}
+	}
+	return ToolPricingConfig{
+		Scheme:   o.Scheme,
+		Amount:   o.Amount,
+		Asset:    o.Asset,
+		Network:  Network(o.Network),
+		PayTo:    o.PayTo,
+		Decimals: o.Decimals,
+		Approval: o.Approval,
+	}, nil
+}
@@ -0,0 +1,153 @@
+package x402
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func writePricingFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("write pricing file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPricingFromFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+	}{
+		{
+			name: "json",
+			file: "pricing.json",
+			contents: `{
+				"weather": {"amount": "10000", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay"},
+				"forecast": [
+					{"amount": "20000", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay", "scheme": "upto", "approval": {"spender": "0xspender"}},
+					{"amount": "5", "asset": "EPjF", "network": "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", "payTo": "sol", "decimals": 0}
+				]
+			}`,
+		},
+		{
+			name: "yaml",
+			file: "pricing.yaml",
+			contents: `
+weather:
+  amount: "10000"
+  asset: 0xusdc
+  network: eip155:84532
+  payTo: 0xpay
+forecast:
+  - {amount: "20000", asset: 0xusdc, network: "eip155:84532", payTo: 0xpay, scheme: upto, approval: {spender: 0xspender}}
+  - {amount: "5", asset: EPjF, network: "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp", payTo: sol, decimals: 0}
+`,
+		},
+	}
+	for _, tt := range tests {
+		m := newTestMiddleware("http://facilitator.invalid")
+		if err := m.LoadPricingFromFile(writePricingFile(t, tt.file, tt.contents)); err != nil {
+			t.Fatalf("%s: LoadPricingFromFile: %v", tt.name, err)
+		}
+		if _, ok := m.toolPricing("paid_tool"); ok {
+			t.Fatalf("%s: expected file to replace existing pricing", tt.name)
+		}
+		weather, _ := m.toolPricing("weather")
+		want := ToolPricingConfig{Amount: "10000", Asset: "0xusdc", Network: "eip155:84532", PayTo: "0xpay"}
+		if len(weather) != 1 || weather[0] != want {
+			t.Fatalf("%s: unexpected weather pricing %+v", tt.name, weather)
+		}
+		forecast, _ := m.toolPricing("forecast")
+		if len(forecast) != 2 || forecast[0].Scheme != SchemeUpto || forecast[1].Network != "solana:5eykt4UsFv8P8NJdTREpY1vzqKqZKvdp" ||
+			forecast[0].Approval == nil || forecast[0].Approval.Spender != "0xspender" || forecast[1].Approval != nil ||
+			forecast[0].Decimals != nil || forecast[1].Decimals == nil || *forecast[1].Decimals != 0 {
+			t.Fatalf("%s: unexpected forecast pricing %+v", tt.name, forecast)
+		}
+	}
+}
+
+func TestLoadPricingFromFileRejectsInvalidEntries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "decimal amount",
+			contents: `{"weather": {"amount": "0.01", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay"}}`,
+			wantErr:  "must be a decimal integer",
+		},
+		{
+			name:     "negative amount",
+			contents: `{"weather": {"amount": "-1", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay"}}`,
+			wantErr:  "must be a decimal integer",
+		},
+		{
+			name:     "missing payTo",
+			contents: `{"weather": {"amount": "10000", "asset": "0xusdc", "network": "eip155:84532"}}`,
+			wantErr:  `missing required field "payTo"`,
+		},
+		{
+			name:     "legacy network",
+			contents: `{"weather": {"amount": "10000", "asset": "0xusdc", "network": "base-sepolia", "payTo": "0xpay"}}`,
+			wantErr:  `use "eip155:84532"`,
+		},
+		{
+			name:     "unknown scheme",
+			contents: `{"weather": {"amount": "10000", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay", "scheme": "stream"}}`,
+			wantErr:  "unsupported scheme",
+		},
+		{
+			name:     "approval without spender",
+			contents: `{"weather": {"amount": "10000", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay", "approval": {"allowance": "10000"}}}`,
+			wantErr:  `missing required field "approval.spender"`,
+		},
+	}
+	for _, tt := range tests {
+		m := newTestMiddleware("http://facilitator.invalid")
+		err := m.LoadPricingFromFile(writePricingFile(t, "pricing.json", tt.contents))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Fatalf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+		if !strings.Contains(err.Error(), "tool weather") {
+			t.Fatalf("%s: expected error to name the tool, got %v", tt.name, err)
+		}
+		if _, ok := m.toolPricing("paid_tool"); !ok {
+			t.Fatalf("%s: expected existing pricing to survive a failed load", tt.name)
+		}
+	}
+}
+
+func TestReloadPricingOnSIGHUP(t *testing.T) {
+	path := writePricingFile(t, "pricing.json",
+		`{"weather": {"amount": "10000", "asset": "0xusdc", "network": "eip155:84532", "payTo": "0xpay"}}`)
+	m := newTestMiddleware("http://facilitator.invalid")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.ReloadPricingOnSIGHUP(ctx, path)
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("send SIGHUP: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := m.toolPricing("weather"); ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("pricing was not reloaded after SIGHUP")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
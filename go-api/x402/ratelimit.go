@@ -0,0 +1,172 @@
+package x402
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/time/rate"
+)
+
+// Rate limit error reported in PaymentError
+const (
+	ErrorCodeRateLimited   = 429
+	ErrorReasonRateLimited = "rate-limited"
+)
+
+// maxRateLimitBuckets bounds the callers tracked per tool. Idle buckets are
+// pruned first, then the least recently used are evicted.
+const maxRateLimitBuckets = 10000
+
+// toolRateLimit is a token bucket per caller for one tool, kept in an LRU
+type toolRateLimit struct {
+	limit   rate.Limit
+	burst   int
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	// order holds *callerBucket values, most recently used first
+	order *list.List
+}
+
+type callerBucket struct {
+	caller  string
+	limiter *rate.Limiter
+}
+
+// SetRateLimit allows each caller rps sustained calls per second to toolName,
+// with bursts of up to burst calls. The limit applies per MCP session before
+// the payment is verified, so over-rate calls never reach the facilitator,
+// and again per payer once verification has authenticated the payer, so one
+// payer cannot spread calls across sessions. Calls without a session share
+// one bucket. A non-positive rps removes the limit.
+func (m *Middleware) SetRateLimit(toolName string, rps float64, burst int) {
+	m.rateLimitsMu.Lock()
+	defer m.rateLimitsMu.Unlock()
+	if rps <= 0 {
+		delete(m.rateLimits, toolName)
+		return
+	}
+	m.rateLimits[toolName] = &toolRateLimit{
+		limit:   rate.Limit(rps),
+		burst:   max(burst, 1),
+		buckets: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// checkRateLimit reports whether the call may proceed and, if not, how long
+// the caller should wait before retrying. It is keyed on the MCP session,
+// which the server issues, never on payment fields the caller controls.
+func (m *Middleware) checkRateLimit(toolName string, req *mcp.CallToolRequest) (time.Duration, bool) {
+	limit := m.rateLimit(toolName)
+	if limit == nil {
+		return 0, true
+	}
+	return limit.allow(sessionIdentity(req), time.Now())
+}
+
+// checkPayerRateLimit applies the tool's limit to the payer of a verified
+// payment. Payments without a payer address are not limited here.
+func (m *Middleware) checkPayerRateLimit(toolName string, payment *PaymentPayload) (time.Duration, bool) {
+	limit := m.rateLimit(toolName)
+	if limit == nil || payment == nil {
+		return 0, true
+	}
+	payer := payerFromPayload(payment.Payload)
+	if payer == "" {
+		return 0, true
+	}
+	return limit.allow("payer:"+strings.ToLower(payer), time.Now())
+}
+
+func (m *Middleware) rateLimit(toolName string) *toolRateLimit {
+	m.rateLimitsMu.RLock()
+	defer m.rateLimitsMu.RUnlock()
+	return m.rateLimits[toolName]
+}
+
+func (l *toolRateLimit) allow(caller string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var bucket *rate.Limiter
+	if elem, ok := l.buckets[caller]; ok {
+		l.order.MoveToFront(elem)
+		bucket = elem.Value.(*callerBucket).limiter
+	} else {
+		if len(l.buckets) >= maxRateLimitBuckets {
+			l.pruneIdle(now)
+		}
+		for len(l.buckets) >= maxRateLimitBuckets {
+			l.evict(l.order.Back())
+		}
+		bucket = rate.NewLimiter(l.limit, l.burst)
+		l.buckets[caller] = l.order.PushFront(&callerBucket{caller: caller, limiter: bucket})
+	}
+	reservation := bucket.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// pruneIdle drops buckets that have refilled completely, which are
+// indistinguishable from new ones
+func (l *toolRateLimit) pruneIdle(now time.Time) {
+	for _, elem := range l.buckets {
+		if elem.Value.(*callerBucket).limiter.TokensAt(now) >= float64(l.burst) {
+			l.evict(elem)
+		}
+	}
+}
+
+func (l *toolRateLimit) evict(elem *list.Element) {
+	delete(l.buckets, elem.Value.(*callerBucket).caller)
+	l.order.Remove(elem)
+}
+
+// sessionIdentity keys rate limits by MCP session; calls without one share
+// the "anonymous" bucket
+func sessionIdentity(req *mcp.CallToolRequest) string {
+	if req != nil && req.Session != nil {
+		if id := req.Session.ID(); id != "" {
+			return "session:" + id
+		}
+	}
+	return "anonymous"
+}
+
+// payerFromPayload returns the authorization.from address of a payment
+// payload, or "" for schemes without one
+func payerFromPayload(payload map[string]interface{}) string {
+	authorization, ok := payload["authorization"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	from, _ := authorization["from"].(string)
+	return from
+}
+
+func rateLimitedResult(toolName string, retryAfter time.Duration) *mcp.CallToolResult {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	message := fmt.Sprintf("Rate limit exceeded for %s; retry after %ds", toolName, seconds)
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: message,
+			},
+		},
+		StructuredContent: &PaymentError{
+			Code:              ErrorCodeRateLimited,
+			Reason:            ErrorReasonRateLimited,
+			Message:           message,
+			RetryAfterSeconds: seconds,
+		},
+	}
+}
@@ -0,0 +1,165 @@
+package x402
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func requestFromPayer(payer string) *mcp.CallToolRequest {
+	return &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{
+		Meta: mcp.Meta{
+			MetaKeyPayment: map[string]any{
+				"x402Version": 2,
+				"payload": map[string]any{
+					"authorization": map[string]any{"from": payer, "nonce": "0x01"},
+				},
+			},
+		},
+	}}
+}
+
+func TestToolRateLimitBurstThenSustained(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	m.SetRateLimit("weather", 2, 3)
+	limit := m.rateLimits["weather"]
+
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		if _, ok := limit.allow("payer:a", now); !ok {
+			t.Fatalf("call %d: expected burst to be allowed", i+1)
+		}
+	}
+	delay, ok := limit.allow("payer:a", now)
+	if ok || delay <= 0 || delay > 500*time.Millisecond {
+		t.Fatalf("expected over-burst call rejected with <=500ms delay, got ok=%v delay=%v", ok, delay)
+	}
+	if _, ok := limit.allow("payer:b", now); !ok {
+		t.Fatal("expected a different caller to have its own bucket")
+	}
+
+	// Sustained calls at 4/s against a 2/s limit: about half are rejected
+	allowed := 0
+	for i := 1; i <= 20; i++ {
+		if _, ok := limit.allow("payer:a", now.Add(time.Duration(i)*250*time.Millisecond)); ok {
+			allowed++
+		}
+	}
+	if allowed != 10 {
+		t.Fatalf("expected 10 of 20 sustained calls allowed, got %d", allowed)
+	}
+}
+
+func TestWrapToolHandlerRateLimitsBeforeVerify(t *testing.T) {
+	t.Parallel()
+
+	var verifies atomic.Int32
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		verifies.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+	m := newTestMiddleware(facilitator.URL)
+	m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+	m.SetRateLimit("paid_tool", 0.5, 1)
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	req := requestFromPayer("0xAbC")
+	if _, _, err := handler(context.Background(), req, struct{}{}); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	result, _, err := handler(context.Background(), requestFromPayer("0xabc"), struct{}{})
+	if err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+	paymentErr, ok := result.StructuredContent.(*PaymentError)
+	if !ok || paymentErr.Code != ErrorCodeRateLimited || paymentErr.Reason != ErrorReasonRateLimited {
+		t.Fatalf("expected rate-limited PaymentError, got %#v", result.StructuredContent)
+	}
+	if paymentErr.RetryAfterSeconds != 2 {
+		t.Fatalf("expected retry hint of 2s, got %d", paymentErr.RetryAfterSeconds)
+	}
+	if got := verifies.Load(); got != 1 {
+		t.Fatalf("expected only the first call to reach the facilitator, got %d verifies", got)
+	}
+}
+
+func TestRateLimitIgnoresUnverifiedPayer(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.RejectPayments("invalid_signature")
+	m := newFakeMiddleware(fake)
+	m.SetRateLimit("paid_tool", 0.5, 1)
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, input struct{}) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	// Rotating the claimed payer does not open a fresh bucket
+	if _, _, err := handler(context.Background(), requestFromPayer("0xaaa"), struct{}{}); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	rotated, _, err := handler(context.Background(), requestFromPayer("0xbbb"), struct{}{})
+	if err != nil {
+		t.Fatalf("rotated call: %v", err)
+	}
+	if paymentErr, ok := rotated.StructuredContent.(*PaymentError); !ok || paymentErr.Code != ErrorCodeRateLimited {
+		t.Fatalf("expected a rotated payer to share the session's bucket, got %#v", rotated.StructuredContent)
+	}
+
+	// Payments that fail verification never touch the claimed payer's bucket
+	limit := m.rateLimits["paid_tool"]
+	for caller := range limit.buckets {
+		if caller != "anonymous" {
+			t.Fatalf("expected only the session bucket, got %q", caller)
+		}
+	}
+}
+
+func TestPayerRateLimitAppliesAfterVerify(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	m.SetRateLimit("paid_tool", 0.5, 1)
+	payment := &PaymentPayload{Payload: map[string]interface{}{"authorization": map[string]interface{}{"from": "0xAbC"}}}
+
+	if _, ok := m.checkPayerRateLimit("paid_tool", payment); !ok {
+		t.Fatal("expected the payer's first call to be allowed")
+	}
+	payment.Payload["authorization"] = map[string]interface{}{"from": "0xabc"}
+	if _, ok := m.checkPayerRateLimit("paid_tool", payment); ok {
+		t.Fatal("expected the same payer to be limited across sessions")
+	}
+}
+
+func TestRateLimitBucketsAreCapped(t *testing.T) {
+	t.Parallel()
+
+	m := newTestMiddleware("http://facilitator.invalid")
+	m.SetRateLimit("paid_tool", 0.5, 1)
+	limit := m.rateLimits["paid_tool"]
+
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < maxRateLimitBuckets+10; i++ {
+		limit.allow(fmt.Sprintf("session:%d", i), now)
+	}
+	// None of the buckets is idle, so the oldest are evicted
+	if len(limit.buckets) != maxRateLimitBuckets || limit.order.Len() != maxRateLimitBuckets {
+		t.Fatalf("expected %d buckets, got %d", maxRateLimitBuckets, len(limit.buckets))
+	}
+	if _, ok := limit.buckets["session:0"]; ok {
+		t.Fatal("expected the least recently used bucket to be evicted")
+	}
+	if _, ok := limit.buckets[fmt.Sprintf("session:%d", maxRateLimitBuckets+9)]; !ok {
+		t.Fatal("expected the newest bucket to be kept")
+	}
+}
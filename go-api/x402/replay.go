@@ -0,0 +1,94 @@
+package x402
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultReplayTTL is how long a settled payment is remembered by default
+const DefaultReplayTTL = time.Hour
+
+// ErrPaymentReplayed is returned when a payment has already been settled
+var ErrPaymentReplayed = errors.New("payment already settled")
+
+// ReplayStore remembers settled payments so the same payload cannot be
+// settled twice. Implementations must be safe for concurrent use.
+type ReplayStore interface {
+	// Reserve records key for ttl. It returns false if key is already recorded.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Release forgets key, e.g. after a settlement attempt failed.
+	Release(ctx context.Context, key string) error
+}
+
+// MemoryReplayStore is an in-process ReplayStore
+type MemoryReplayStore struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryReplayStore creates an empty in-memory replay store
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return &MemoryReplayStore{
+		entries: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Reserve implements ReplayStore
+func (s *MemoryReplayStore) Reserve(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, expires := range s.entries {
+		if !now.Before(expires) {
+			delete(s.entries, k)
+		}
+	}
+	if _, exists := s.entries[key]; exists {
+		return false, nil
+	}
+	s.entries[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Release implements ReplayStore
+func (s *MemoryReplayStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// replayKey identifies a payment by its authorization nonce when present,
+// falling back to a hash of the full payload.
+func replayKey(payment *PaymentPayload) (string, error) {
+	if nonce := paymentNonce(payment); nonce != "" {
+		sum := sha256.Sum256([]byte(payment.Accepted.Network + ":" + nonce))
+		return "nonce:" + hex.EncodeToString(sum[:]), nil
+	}
+	payloadBytes, err := json.Marshal(payment)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payloadBytes)
+	return "payload:" + hex.EncodeToString(sum[:]), nil
+}
+
+func paymentNonce(payment *PaymentPayload) string {
+	if payment == nil || payment.Payload == nil {
+		return ""
+	}
+	authorization, ok := payment.Payload["authorization"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	nonce, _ := authorization["nonce"].(string)
+	return nonce
+}
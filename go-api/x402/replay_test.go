@@ -0,0 +1,104 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestMemoryReplayStoreExpiry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryReplayStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if ok, err := store.Reserve(ctx, "key", time.Minute); err != nil || !ok {
+		t.Fatalf("expected first reservation to succeed, got ok=%t err=%v", ok, err)
+	}
+	if ok, _ := store.Reserve(ctx, "key", time.Minute); ok {
+		t.Fatalf("expected immediate replay to be rejected")
+	}
+
+	now = now.Add(time.Minute)
+	if ok, err := store.Reserve(ctx, "key", time.Minute); err != nil || !ok {
+		t.Fatalf("expected reservation after expiry to succeed, got ok=%t err=%v", ok, err)
+	}
+}
+
+func TestReplayKeyPrefersNonce(t *testing.T) {
+	t.Parallel()
+
+	payment := func(signature string) *PaymentPayload {
+		return &PaymentPayload{
+			X402Version: 2,
+			Accepted:    PaymentRequirements{Network: "eip155:84532"},
+			Payload: map[string]interface{}{
+				"signature":     signature,
+				"authorization": map[string]interface{}{"nonce": "0x01"},
+			},
+		}
+	}
+
+	first, err := replayKey(payment("0xaa"))
+	if err != nil {
+		t.Fatalf("replayKey error: %v", err)
+	}
+	second, err := replayKey(payment("0xbb"))
+	if err != nil {
+		t.Fatalf("replayKey error: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected payments sharing a nonce to share a replay key")
+	}
+}
+
+func TestSettleFailureKeepsReplayReservationUnlessRejected(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		fail         func(*FakeFacilitator)
+		budget       CallBudget
+		wantReserved bool
+	}{
+		{name: "transport error", fail: func(f *FakeFacilitator) { f.SetSettleError(errors.New("connection reset")) }, wantReserved: true},
+		{name: "timeout", fail: func(f *FakeFacilitator) { f.SetSettleError(context.DeadlineExceeded) }, wantReserved: true},
+		{name: "definitive rejection", fail: func(f *FakeFacilitator) { f.FailSettlement("insufficient_funds") }, wantReserved: false},
+		// Verify spends the only attempt, so settle never reaches the facilitator
+		{name: "budget spent before settle", fail: func(*FakeFacilitator) {}, budget: CallBudget{MaxAttempts: 1}, wantReserved: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := NewFakeFacilitator()
+			m := newFakeMiddleware(fake)
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				return &mcp.CallToolResult{}, nil, nil
+			})
+
+			tt.fail(fake)
+			m.SetCallBudget(tt.budget)
+			failed, _, err := handler(context.Background(), paidRequest(), nil)
+			if err != nil || !failed.IsError {
+				t.Fatalf("expected the settlement to fail, got %+v, %v", failed, err)
+			}
+
+			m.SetCallBudget(CallBudget{})
+			fake.SetSettleError(nil)
+			fake.FailSettlement("")
+			retried, _, err := handler(context.Background(), paidRequest(), nil)
+			if err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			if replayed := retried.IsError; replayed != tt.wantReserved {
+				t.Fatalf("expected the retried payment rejected as a replay %t, got %q", tt.wantReserved, resultText(t, retried))
+			}
+		})
+	}
+}
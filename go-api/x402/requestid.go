@@ -0,0 +1,44 @@
+package x402
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// RequestIDHeader carries the correlation ID on HTTP requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// MetaKeyRequestID carries the correlation ID in MCP request and result meta
+const MetaKeyRequestID = "x402/request-id"
+
+// maxRequestIDLength bounds caller-supplied IDs so they stay log-friendly
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random 16-byte hex correlation ID
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID attaches a correlation ID to ctx. A blank or oversized id is
+// replaced with a generated one.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > maxRequestIDLength {
+		id = NewRequestID()
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the correlation ID on ctx, or "" if none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
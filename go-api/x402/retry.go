@@ -0,0 +1,122 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	x402sdk "github.com/coinbase/x402/go"
+)
+
+// RetryPolicy controls how facilitator Verify/Settle calls are retried on
+// transient failures
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; values <= 1 disable retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled on each subsequent retry
+	MaxDelay    time.Duration // Upper bound on a single delay; zero means unbounded
+}
+
+// DefaultRetryPolicy is used by NewMiddleware
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// backoff returns the delay before retry number attempt (zero-based), using
+// exponential growth with jitter in [delay/2, delay]
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// withRetry runs call until it succeeds, fails with a non-retryable error, or
+// the policy or call budget on ctx is exhausted
+func withRetry[T any](ctx context.Context, policy RetryPolicy, call func(context.Context) (T, error)) (T, error) {
+	return retryWithin(ctx, ctx, policy, call)
+}
+
+// retryWithin is withRetry with attempts made on callCtx, which may carry a
+// tighter deadline than the budget on budgetCtx. Only budgetCtx's deadline is
+// reported as ErrCallBudgetExhausted.
+func retryWithin[T any](budgetCtx, callCtx context.Context, policy RetryPolicy, call func(context.Context) (T, error)) (T, error) {
+	var zero T
+	for attempt := 0; ; attempt++ {
+		if err := SpendAttempt(budgetCtx); err != nil {
+			return zero, err
+		}
+		result, err := call(callCtx)
+		if err == nil {
+			return result, nil
+		}
+		if attempt+1 >= policy.MaxAttempts || !isRetryableFacilitatorError(err) {
+			return zero, BudgetError(budgetCtx, err)
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-callCtx.Done():
+			timer.Stop()
+			return zero, BudgetError(budgetCtx, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// withFacilitatorTimeout runs withRetry bounded by timeout, reporting
+// ErrFacilitatorTimeout when that bound, rather than ctx, expires. The
+// timeout is checked before the call budget, so it is never reported as
+// ErrCallBudgetExhausted.
+func withFacilitatorTimeout[T any](ctx context.Context, timeout time.Duration, policy RetryPolicy, call func(context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return withRetry(ctx, policy, call)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := retryWithin(ctx, callCtx, policy, call)
+	if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		var zero T
+		return zero, fmt.Errorf("%w after %s: %v", ErrFacilitatorTimeout, timeout, err)
+	}
+	return result, err
+}
+
+// isRetryableFacilitatorError reports whether err is a transport failure or a
+// facilitator 5xx. Definitive verify/settle verdicts are never retried.
+func isRetryableFacilitatorError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *FacilitatorStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	var verifyErr *x402sdk.VerifyError
+	if errors.As(err, &verifyErr) {
+		// The client reports undecodable responses (e.g. a proxy's 502 page) this way
+		return verifyErr.InvalidReason == x402sdk.ErrInvalidResponse
+	}
+	var settleErr *x402sdk.SettleError
+	if errors.As(err, &settleErr) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
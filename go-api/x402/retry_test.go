@@ -0,0 +1,224 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetryPolicy = RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// flakyFacilitator fails the first failures calls to each endpoint with a 503
+func flakyFacilitator(t *testing.T, failures int32, verifyCalls, settleCalls *atomic.Int32) string {
+	return newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		calls := verifyCalls
+		if r.URL.Path == "/settle" {
+			calls = settleCalls
+		}
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("upstream unavailable"))
+			return
+		}
+		if r.URL.Path == "/settle" {
+			json.NewEncoder(w).Encode(SettleResponse{Success: true, Transaction: "0xabc"})
+			return
+		}
+		json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+	}).URL
+}
+
+func TestVerifyAndSettleRetryTransientFailures(t *testing.T) {
+	t.Parallel()
+
+	var verifyCalls, settleCalls atomic.Int32
+	m := newTestMiddleware(flakyFacilitator(t, 2, &verifyCalls, &settleCalls))
+	m.SetRetryPolicy(fastRetryPolicy)
+
+	payment, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta)
+	if err != nil {
+		t.Fatalf("VerifyPayment error: %v", err)
+	}
+	if verifyCalls.Load() != 3 {
+		t.Fatalf("expected 3 verify calls, got %d", verifyCalls.Load())
+	}
+
+	requirements := m.GetPaymentRequirements("paid_tool").Accepts[0]
+	settleResp, err := m.SettlePayment(context.Background(), "paid_tool", payment, &requirements)
+	if err != nil {
+		t.Fatalf("SettlePayment error: %v", err)
+	}
+	if !settleResp.Success {
+		t.Fatalf("expected settlement to succeed")
+	}
+	if settleCalls.Load() != 3 {
+		t.Fatalf("expected 3 settle calls, got %d", settleCalls.Load())
+	}
+}
+
+func TestVerifyGivesUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+
+	var verifyCalls, settleCalls atomic.Int32
+	m := newTestMiddleware(flakyFacilitator(t, 10, &verifyCalls, &settleCalls))
+	m.SetRetryPolicy(fastRetryPolicy)
+
+	if _, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta); err == nil {
+		t.Fatalf("expected verification to fail")
+	}
+	if verifyCalls.Load() != int32(fastRetryPolicy.MaxAttempts) {
+		t.Fatalf("expected %d verify calls, got %d", fastRetryPolicy.MaxAttempts, verifyCalls.Load())
+	}
+}
+
+func TestVerifyDoesNotRetryInvalidVerdict(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	facilitator := newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(VerifyResponse{IsValid: false, InvalidReason: "invalid_signature"})
+	})
+	m := newTestMiddleware(facilitator.URL)
+	m.SetRetryPolicy(fastRetryPolicy)
+
+	if _, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta); err == nil {
+		t.Fatalf("expected verification to fail")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single verify call, got %d", calls.Load())
+	}
+}
+
+func TestWithRetryStopsWhenBudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	var verifyCalls, settleCalls atomic.Int32
+	m := newTestMiddleware(flakyFacilitator(t, 10, &verifyCalls, &settleCalls))
+	m.SetRetryPolicy(fastRetryPolicy)
+
+	ctx, cancel := WithCallBudget(context.Background(), CallBudget{MaxAttempts: 2})
+	defer cancel()
+	_, err := m.VerifyPayment(ctx, "paid_tool", paidRequest().Params.Meta)
+	if !errors.Is(err, ErrCallBudgetExhausted) {
+		t.Fatalf("expected ErrCallBudgetExhausted, got %v", err)
+	}
+	if verifyCalls.Load() != 2 {
+		t.Fatalf("expected retries to stop at the budget, got %d calls", verifyCalls.Load())
+	}
+}
+
+func TestFacilitatorTimeout(t *testing.T) {
+	t.Parallel()
+
+	slowFacilitator := func(t *testing.T, slowPath string) string {
+		return newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == slowPath {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(300 * time.Millisecond):
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/verify":
+				json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+			case "/settle":
+				json.NewEncoder(w).Encode(SettleResponse{Success: true, Transaction: "0xabc", Network: "eip155:84532"})
+			}
+		}).URL
+	}
+
+	t.Run("verify", func(t *testing.T) {
+		m := newTestMiddleware(slowFacilitator(t, "/verify"))
+		m.SetFacilitatorTimeout(50 * time.Millisecond)
+
+		_, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta)
+		if !errors.Is(err, ErrFacilitatorTimeout) {
+			t.Fatalf("expected ErrFacilitatorTimeout, got %v", err)
+		}
+	})
+
+	t.Run("settle", func(t *testing.T) {
+		m := newTestMiddleware(slowFacilitator(t, "/settle"))
+		m.SetFacilitatorTimeout(50 * time.Millisecond)
+
+		requirements := m.GetPaymentRequirements("paid_tool").Accepts[0]
+		payment := &PaymentPayload{X402Version: X402Version, Accepted: requirements}
+		_, err := m.SettlePayment(context.Background(), "paid_tool", payment, &requirements)
+		if !errors.Is(err, ErrFacilitatorTimeout) {
+			t.Fatalf("expected ErrFacilitatorTimeout, got %v", err)
+		}
+	})
+
+	t.Run("within a call budget", func(t *testing.T) {
+		m := newTestMiddleware(slowFacilitator(t, "/verify"))
+		m.SetFacilitatorTimeout(50 * time.Millisecond)
+
+		ctx, cancel := WithCallBudget(context.Background(), CallBudget{MaxAttempts: 10, MaxDuration: time.Minute})
+		defer cancel()
+		_, err := m.VerifyPayment(ctx, "paid_tool", paidRequest().Params.Meta)
+		if !errors.Is(err, ErrFacilitatorTimeout) || errors.Is(err, ErrCallBudgetExhausted) || strings.Contains(err.Error(), ErrCallBudgetExhausted.Error()) {
+			t.Fatalf("expected only ErrFacilitatorTimeout, got %v", err)
+		}
+	})
+
+	t.Run("requirement max timeout", func(t *testing.T) {
+		m := newTestMiddleware(okFacilitator(t).URL)
+		requirements := m.GetPaymentRequirements("paid_tool").Accepts[0]
+		if got := m.facilitatorTimeoutFor(&requirements); got != 60*time.Second {
+			t.Fatalf("expected default timeout from MaxTimeoutSeconds, got %s", got)
+		}
+	})
+}
+
+func TestFacilitatorStatusErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		body      string
+		wantTyped bool
+		wantCalls int32
+	}{
+		{name: "verdict-less 502 is retried", body: "<html>bad gateway</html>", wantTyped: true, wantCalls: int32(fastRetryPolicy.MaxAttempts)},
+		{name: "502 with a verdict is definitive", body: `{"success":false,"errorReason":"transaction_failed"}`, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var settleCalls atomic.Int32
+			m := newTestMiddleware(newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/verify" {
+					json.NewEncoder(w).Encode(VerifyResponse{IsValid: true})
+					return
+				}
+				settleCalls.Add(1)
+				w.WriteHeader(http.StatusBadGateway)
+				w.Write([]byte(tt.body))
+			}).URL)
+			m.SetRetryPolicy(fastRetryPolicy)
+			payment, err := m.VerifyPayment(context.Background(), "paid_tool", paidRequest().Params.Meta)
+			if err != nil {
+				t.Fatalf("VerifyPayment error: %v", err)
+			}
+			requirements := m.GetPaymentRequirements("paid_tool").Accepts[0]
+
+			_, err = m.SettlePayment(context.Background(), "paid_tool", payment, &requirements)
+			var statusErr *FacilitatorStatusError
+			if typed := errors.As(err, &statusErr); typed != tt.wantTyped || (typed && statusErr.StatusCode != http.StatusBadGateway) {
+				t.Fatalf("expected a typed 502 status error %t, got %v", tt.wantTyped, err)
+			}
+			if got := settleCalls.Load(); got != tt.wantCalls {
+				t.Fatalf("expected %d settle calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package x402
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSettlementQueueSize bounds a MemorySettlementQueue created with a
+	// non-positive size
+	DefaultSettlementQueueSize = 256
+
+	// SettlementStatusPending is the status reported for a deferred settlement
+	SettlementStatusPending = "pending"
+
+	// minSettlementPollInterval keeps a tiny BaseDelay from spinning the worker
+	minSettlementPollInterval = 10 * time.Millisecond
+)
+
+// DefaultSettlementRetryPolicy is a sensible policy for SetSettlementRetry:
+// about a minute of background attempts before a settlement is abandoned
+var DefaultSettlementRetryPolicy = RetryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// ErrSettlementQueueFull is returned by Push when the queue is at capacity
+var ErrSettlementQueueFull = errors.New("settlement queue full")
+
+// PendingSettlement is a verified payment whose settlement failed with a
+// transient facilitator error and is waiting to be retried. It encodes to
+// JSON so queues can persist it.
+type PendingSettlement struct {
+	ID           string               `json:"id"`
+	ToolName     string               `json:"toolName"`
+	RequestID    string               `json:"requestId,omitempty"`
+	Payment      *PaymentPayload      `json:"payment"`
+	Requirements *PaymentRequirements `json:"requirements"`
+	// Attempts counts background attempts so far; the failed attempt made
+	// during the tool call is not included
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// SettlementPending is attached to a paid result under
+// MetaKeySettlementPending when its settlement was deferred
+type SettlementPending struct {
+	Status  string  `json:"status"`
+	ID      string  `json:"id"`
+	Network Network `json:"network"`
+	Reason  string  `json:"reason"`
+}
+
+// SettlementQueue holds pending settlements between attempts. Implementations
+// must be safe for concurrent use; a persistent implementation lets pending
+// settlements survive a restart.
+type SettlementQueue interface {
+	// Push adds pending, failing with ErrSettlementQueueFull when at capacity
+	Push(ctx context.Context, pending PendingSettlement) error
+	// PopDue removes and returns the entries whose NextAttempt is not after now
+	PopDue(ctx context.Context, now time.Time) ([]PendingSettlement, error)
+	// Len reports how many entries are waiting
+	Len(ctx context.Context) (int, error)
+}
+
+// MemorySettlementQueue is a bounded in-process SettlementQueue
+type MemorySettlementQueue struct {
+	mu      sync.Mutex
+	size    int
+	entries []PendingSettlement
+}
+
+// NewMemorySettlementQueue creates a queue holding at most size entries
+func NewMemorySettlementQueue(size int) *MemorySettlementQueue {
+	if size <= 0 {
+		size = DefaultSettlementQueueSize
+	}
+	return &MemorySettlementQueue{size: size}
+}
+
+// Push implements SettlementQueue
+func (q *MemorySettlementQueue) Push(ctx context.Context, pending PendingSettlement) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) >= q.size {
+		return ErrSettlementQueueFull
+	}
+	q.entries = append(q.entries, pending)
+	return nil
+}
+
+// PopDue implements SettlementQueue
+func (q *MemorySettlementQueue) PopDue(ctx context.Context, now time.Time) ([]PendingSettlement, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []PendingSettlement
+	kept := q.entries[:0]
+	for _, entry := range q.entries {
+		if entry.NextAttempt.After(now) {
+			kept = append(kept, entry)
+		} else {
+			due = append(due, entry)
+		}
+	}
+	clear(q.entries[len(kept):])
+	q.entries = kept
+	return due, nil
+}
+
+// Len implements SettlementQueue
+func (q *MemorySettlementQueue) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries), nil
+}
+
+// settlementRetrier runs one background worker while the queue is non-empty
+type settlementRetrier struct {
+	queue  SettlementQueue
+	policy RetryPolicy
+
+	mu       sync.Mutex
+	running  bool
+	draining bool
+	wake     chan struct{}
+	done     chan struct{} // closed when the running worker exits
+}
+
+// SetSettlementRetry defers settlements that fail with a transient facilitator
+// error to queue instead of failing the call: the tool runs, its result
+// carries a SettlementPending under MetaKeySettlementPending, and a background
+// worker retries the settlement with policy's backoff until it succeeds or
+// policy.MaxAttempts background attempts fail. An abandoned settlement keeps
+// its replay reservation and is reported to the audit sink with Abandoned set
+// for manual reconciliation. Drain retries whatever is still queued without
+// waiting for backoff. A nil queue disables deferral
+func (m *Middleware) SetSettlementRetry(queue SettlementQueue, policy RetryPolicy) {
+	if queue == nil {
+		m.settlementRetry = nil
+		return
+	}
+	m.settlementRetry = &settlementRetrier{queue: queue, policy: policy}
+	// Resume entries a persistent queue kept from an earlier run
+	if n, err := queue.Len(context.Background()); err == nil && n > 0 {
+		m.settlementRetry.mu.Lock()
+		m.startSettlementWorker()
+		m.settlementRetry.mu.Unlock()
+	}
+}
+
+// deferSettlement queues a settlement that failed with err for background
+// retry. It reports false when deferral is disabled, err is not transient or
+// the queue rejects the entry; the caller then fails the call as usual.
+func (m *Middleware) deferSettlement(ctx context.Context, toolName string, payment *PaymentPayload, requirements *PaymentRequirements, err error) (*SettlementPending, bool) {
+	r := m.settlementRetry
+	if r == nil || err == nil || !isTransientSettleError(err) {
+		return nil, false
+	}
+	raw := make([]byte, 8)
+	if _, randErr := rand.Read(raw); randErr != nil {
+		m.logger.Warn("x402 settlement deferral unavailable", "tool", toolName, "err", randErr)
+		return nil, false
+	}
+	pending := PendingSettlement{
+		ID:           hex.EncodeToString(raw),
+		ToolName:     toolName,
+		RequestID:    RequestIDFromContext(ctx),
+		Payment:      payment,
+		Requirements: requirements,
+		NextAttempt:  time.Now().Add(r.policy.backoff(0)),
+		LastError:    err.Error(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pushErr := r.queue.Push(context.WithoutCancel(ctx), pending); pushErr != nil {
+		m.logger.Warn("x402 settlement deferral failed", "tool", toolName, "requestId", pending.RequestID, "err", pushErr)
+		return nil, false
+	}
+	m.startSettlementWorker()
+	m.logger.Warn("x402 settlement deferred", "tool", toolName, "settlement", pending.ID, "requestId", pending.RequestID, "err", err)
+	return &SettlementPending{
+		Status:  SettlementStatusPending,
+		ID:      pending.ID,
+		Network: Network(requirements.Network),
+		Reason:  err.Error(),
+	}, true
+}
+
+// isTransientSettleError reports whether a failed settlement may succeed later
+func isTransientSettleError(err error) bool {
+	return errors.Is(err, ErrFacilitatorTimeout) || isRetryableFacilitatorError(err)
+}
+
+// startSettlementWorker starts the worker unless it runs already. The caller
+// holds settlementRetry.mu.
+func (m *Middleware) startSettlementWorker() {
+	r := m.settlementRetry
+	if r.running {
+		return
+	}
+	r.running = true
+	r.wake = make(chan struct{}, 1)
+	r.done = make(chan struct{})
+	go m.runSettlementWorker(r)
+}
+
+// runSettlementWorker retries due settlements until the queue is empty
+func (m *Middleware) runSettlementWorker(r *settlementRetrier) {
+	ctx := context.Background()
+	poll := max(r.policy.BaseDelay, minSettlementPollInterval)
+	for {
+		r.mu.Lock()
+		now := time.Now()
+		if r.draining {
+			// Retry everything still queued without waiting for backoff
+			now = now.Add(r.policy.MaxDelay + poll + time.Hour)
+		}
+		r.mu.Unlock()
+
+		due, err := r.queue.PopDue(ctx, now)
+		if err != nil {
+			m.logger.Error("x402 settlement queue error", "err", err)
+		}
+		for _, pending := range due {
+			m.retrySettlement(r, pending)
+		}
+
+		r.mu.Lock()
+		if n, err := r.queue.Len(ctx); err == nil && n == 0 {
+			r.running = false
+			r.draining = false
+			close(r.done)
+			r.mu.Unlock()
+			return
+		}
+		wake := r.wake
+		r.mu.Unlock()
+
+		timer := time.NewTimer(poll)
+		select {
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+		}
+	}
+}
+
+// retrySettlement makes one background attempt at pending, requeueing it with
+// backoff on another transient failure
+func (m *Middleware) retrySettlement(r *settlementRetrier, pending PendingSettlement) {
+	ctx := WithRequestID(context.Background(), pending.RequestID)
+	pending.Attempts++
+	settleResp, err := m.settle(ctx, pending.ToolName, pending.Payment, pending.Requirements)
+	m.auditSettlement(ctx, pending.ToolName, pending.Payment, pending.Requirements, settleResp, err)
+	if err == nil && settleResp.Success {
+		m.logger.Info("x402 deferred settlement succeeded", "tool", pending.ToolName, "settlement", pending.ID, "requestId", pending.RequestID, "attempts", pending.Attempts, "transaction", settleResp.Transaction)
+		m.notifySettlement(ctx, pending.ToolName, settleResp)
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("settlement rejected: %s", settleResp.ErrorReason)
+	}
+	pending.LastError = err.Error()
+	if isTransientSettleError(err) && pending.Attempts < r.policy.MaxAttempts {
+		pending.NextAttempt = time.Now().Add(r.policy.backoff(pending.Attempts))
+		pushErr := r.queue.Push(ctx, pending)
+		if pushErr == nil {
+			return
+		}
+		err = fmt.Errorf("%w (requeue failed: %v)", err, pushErr)
+	}
+	// The tool already ran, so the payment keeps its replay reservation for
+	// the full TTL rather than becoming spendable again
+	m.logger.Error("x402 deferred settlement abandoned", "tool", pending.ToolName, "settlement", pending.ID, "requestId", pending.RequestID, "attempts", pending.Attempts, "err", err)
+	m.auditAbandoned(ctx, pending, err)
+}
+
+// drain retries every queued settlement without waiting for backoff and
+// returns once the queue is empty or ctx is done
+func (r *settlementRetrier) drain(ctx context.Context, m *Middleware) error {
+	r.mu.Lock()
+	if n, err := r.queue.Len(ctx); err == nil && n > 0 {
+		m.startSettlementWorker()
+	}
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	r.draining = true
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	done := r.done
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		n, _ := r.queue.Len(context.Background())
+		return fmt.Errorf("drain pending settlements (%d left): %w", n, ctx.Err())
+	}
+}
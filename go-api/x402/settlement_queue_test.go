@@ -0,0 +1,208 @@
+package x402
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// errFacilitatorUnavailable looks like the x402 client's error for a 503
+var errFacilitatorUnavailable = &FacilitatorStatusError{StatusCode: 503, Body: "unavailable"}
+
+func newSettlementRetryMiddleware(fake *FakeFacilitator, maxAttempts int) (*Middleware, *recordingLogger) {
+	logger := &recordingLogger{}
+	m := newFakeMiddleware(fake)
+	WithLogger(logger)(m)
+	m.SetReplayStore(NewMemoryReplayStore(), DefaultReplayTTL)
+	m.SetSettlementRetry(NewMemorySettlementQueue(4), RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    20 * time.Millisecond,
+	})
+	return m, logger
+}
+
+func callPaidTool(t *testing.T, m *Middleware) *mcp.CallToolResult {
+	t.Helper()
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "ok"}}}, nil, nil
+	})
+	result, _, err := handler(context.Background(), paidRequest(), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	return result
+}
+
+func (l *recordingLogger) has(msg string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, record := range l.records {
+		if record.msg == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWrapToolHandlerDefersFailedSettlement(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.SetSettleError(errFacilitatorUnavailable)
+	m, logger := newSettlementRetryMiddleware(fake, 5)
+	settled := make(chan *SettleResponse, 1)
+	m.SetSettlementHook(func(ctx context.Context, toolName string, settle *SettleResponse) {
+		settled <- settle
+	})
+
+	result := callPaidTool(t, m)
+	if result.IsError {
+		t.Fatalf("expected the tool to run while settlement is pending, got %q", resultText(t, result))
+	}
+	pending, ok := result.Meta[MetaKeySettlementPending].(*SettlementPending)
+	if !ok || pending.Status != SettlementStatusPending || pending.ID == "" {
+		t.Fatalf("expected settlement-pending meta, got %+v", result.Meta)
+	}
+	if _, ok := result.Meta[MetaKeyPaymentResponse]; ok {
+		t.Fatal("expected no payment-response meta before the payment settles")
+	}
+
+	// The pending payment keeps its replay reservation
+	if _, err := m.SettlePayment(context.Background(), "paid_tool", verifiedPayment(t, fake), pendingRequirements(m)); !errors.Is(err, ErrPaymentReplayed) {
+		t.Fatalf("expected a pending payment to be reserved against replays, got %v", err)
+	}
+
+	fake.SetSettleError(nil)
+	select {
+	case settle := <-settled:
+		if !settle.Success || settle.Transaction == "" {
+			t.Fatalf("expected a successful background settlement, got %+v", settle)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the background settlement")
+	}
+	if err := m.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	if !logger.has("x402 deferred settlement succeeded") {
+		t.Fatalf("expected the retry to be logged, got %+v", logger.records)
+	}
+}
+
+func TestWrapToolHandlerAbandonsSettlementAfterRetries(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.SetSettleError(errFacilitatorUnavailable)
+	m, logger := newSettlementRetryMiddleware(fake, 2)
+	sink := &recordingAuditSink{}
+	m.SetAuditSink(sink)
+
+	result := callPaidTool(t, m)
+	if _, ok := result.Meta[MetaKeySettlementPending]; !ok {
+		t.Fatalf("expected settlement-pending meta, got %+v", result.Meta)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Drain(ctx); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	// One attempt during the call, then MaxAttempts in the background
+	if got := len(fake.Settled()); got != 3 {
+		t.Fatalf("expected 3 settle attempts, got %d", got)
+	}
+	if !logger.has("x402 deferred settlement abandoned") {
+		t.Fatalf("expected the abandoned settlement to be logged, got %+v", logger.records)
+	}
+
+	sink.mu.Lock()
+	last := sink.records[len(sink.records)-1]
+	sink.mu.Unlock()
+	if !last.Abandoned || last.Success || last.Error == "" {
+		t.Fatalf("expected an abandoned audit record, got %+v", last)
+	}
+
+	// The tool already ran, so the payment cannot be spent again
+	fake.SetSettleError(nil)
+	if _, err := m.SettlePayment(context.Background(), "paid_tool", verifiedPayment(t, fake), pendingRequirements(m)); !errors.Is(err, ErrPaymentReplayed) {
+		t.Fatalf("expected the abandoned payment to stay reserved, got %v", err)
+	}
+}
+
+func TestWrapToolHandlerFailsDefinitiveSettlementWithRetryQueue(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.FailSettlement("insufficient_funds")
+	m, _ := newSettlementRetryMiddleware(fake, 5)
+
+	result := callPaidTool(t, m)
+	assertPaymentError(t, result, ErrorReasonSettleFailed)
+	if _, ok := result.Meta[MetaKeySettlementPending]; ok {
+		t.Fatal("expected a rejected settlement not to be deferred")
+	}
+}
+
+func TestDrainRetriesPendingSettlementsImmediately(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.SetSettleError(errFacilitatorUnavailable)
+	m := newFakeMiddleware(fake)
+	m.SetSettlementRetry(NewMemorySettlementQueue(4), RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	callPaidTool(t, m)
+	fake.SetSettleError(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Drain(ctx); err != nil {
+		t.Fatalf("Drain error: %v", err)
+	}
+	if got := len(fake.Settled()); got != 2 {
+		t.Fatalf("expected drain to retry the settlement once, got %d attempts", got)
+	}
+}
+
+func TestMemorySettlementQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Now()
+	q := NewMemorySettlementQueue(2)
+	if err := q.Push(ctx, PendingSettlement{ID: "later", NextAttempt: now.Add(time.Minute)}); err != nil {
+		t.Fatalf("Push error: %v", err)
+	}
+	if err := q.Push(ctx, PendingSettlement{ID: "due", NextAttempt: now}); err != nil {
+		t.Fatalf("Push error: %v", err)
+	}
+	if err := q.Push(ctx, PendingSettlement{ID: "overflow"}); !errors.Is(err, ErrSettlementQueueFull) {
+		t.Fatalf("expected ErrSettlementQueueFull, got %v", err)
+	}
+
+	due, err := q.PopDue(ctx, now)
+	if err != nil || len(due) != 1 || due[0].ID != "due" {
+		t.Fatalf("expected only the due entry, got %+v, %v", due, err)
+	}
+	if n, _ := q.Len(ctx); n != 1 {
+		t.Fatalf("expected one entry left, got %d", n)
+	}
+}
+
+func verifiedPayment(t *testing.T, fake *FakeFacilitator) *PaymentPayload {
+	t.Helper()
+	verified := fake.Verified()
+	if len(verified) == 0 {
+		t.Fatal("expected a verified payment")
+	}
+	return &verified[0]
+}
+
+func pendingRequirements(m *Middleware) *PaymentRequirements {
+	return &m.GetPaymentRequirements("paid_tool").Accepts[0]
+}
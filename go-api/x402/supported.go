@@ -0,0 +1,98 @@
+package x402
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSupportedTTL is how long a facilitator /supported response is cached
+const DefaultSupportedTTL = 5 * time.Minute
+
+// supportedCache memoizes the facilitator /supported response
+type supportedCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	fetchedAt time.Time
+	response  *SupportedResponse
+}
+
+// SetSupportedTTL sets how long the facilitator /supported response is cached
+func (m *Middleware) SetSupportedTTL(ttl time.Duration) {
+	m.supported.mu.Lock()
+	defer m.supported.mu.Unlock()
+	m.supported.ttl = ttl
+	m.supported.response = nil
+}
+
+// Supported returns the facilitator's supported payment kinds, fetching
+// /supported at most once per TTL
+func (m *Middleware) Supported(ctx context.Context) (*SupportedResponse, error) {
+	m.supported.mu.Lock()
+	defer m.supported.mu.Unlock()
+
+	if m.supported.response != nil && time.Since(m.supported.fetchedAt) < m.supported.ttl {
+		return m.supported.response, nil
+	}
+	supported, err := m.facilitator.GetSupported(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch facilitator supported kinds: %w", err)
+	}
+	m.supported.response = &supported
+	m.supported.fetchedAt = time.Now()
+	return m.supported.response, nil
+}
+
+// ValidatePricing checks every configured tool price against the networks
+// and schemes the facilitator supports. If a supported kind lists assets in
+// extra.assets, the configured asset must be one of them.
+func (m *Middleware) ValidatePricing(ctx context.Context) error {
+	supported, err := m.Supported(ctx)
+	if err != nil {
+		return err
+	}
+
+	pricing := m.pricingSnapshot()
+	toolNames := make([]string, 0, len(pricing))
+	for toolName := range pricing {
+		toolNames = append(toolNames, toolName)
+	}
+	sort.Strings(toolNames)
+
+	var problems []string
+	for _, toolName := range toolNames {
+		for _, option := range pricing[toolName] {
+			if !supportsPricing(supported.Kinds, option) {
+				problems = append(problems, fmt.Sprintf(
+					"tool %s: scheme=%s network=%s asset=%s",
+					toolName, option.scheme(), option.Network, option.Asset,
+				))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("pricing not supported by facilitator: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func supportsPricing(kinds []SupportedKind, pricing ToolPricingConfig) bool {
+	for _, kind := range kinds {
+		if kind.Scheme != pricing.scheme() || !sameNetwork(string(kind.Network), string(pricing.Network)) {
+			continue
+		}
+		assets, ok := kind.Extra["assets"].([]interface{})
+		if !ok {
+			return true
+		}
+		for _, asset := range assets {
+			if value, ok := asset.(string); ok && strings.EqualFold(value, pricing.Asset) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package x402
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func supportedFacilitator(t *testing.T, calls *atomic.Int32, kinds ...SupportedKind) string {
+	return newStubFacilitator(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/supported" {
+			http.NotFound(w, r)
+			return
+		}
+		calls.Add(1)
+		json.NewEncoder(w).Encode(SupportedResponse{Kinds: kinds})
+	}).URL
+}
+
+func TestValidatePricingAcceptsSupportedPair(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	m := newTestMiddleware(supportedFacilitator(t, &calls, SupportedKind{
+		X402Version: 2,
+		Scheme:      SchemeExact,
+		Network:     "eip155:84532",
+	}))
+
+	if err := m.ValidatePricing(context.Background()); err != nil {
+		t.Fatalf("expected pricing to validate, got %v", err)
+	}
+	if err := m.ValidatePricing(context.Background()); err != nil {
+		t.Fatalf("expected cached pricing to validate, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected /supported to be fetched once, got %d", calls.Load())
+	}
+}
+
+func TestValidatePricingRejectsUnsupportedPair(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	m := newTestMiddleware(supportedFacilitator(t, &calls,
+		SupportedKind{X402Version: 2, Scheme: SchemeExact, Network: "eip155:8453"},
+		SupportedKind{
+			X402Version: 2,
+			Scheme:      SchemeExact,
+			Network:     "eip155:84532",
+			Extra:       map[string]interface{}{"assets": []interface{}{"0x0000000000000000000000000000000000000001"}},
+		},
+	))
+	m.SetToolPriceWithScheme("metered_tool", SchemeUpto, "50000")
+
+	err := m.ValidatePricing(context.Background())
+	if err == nil {
+		t.Fatalf("expected unsupported pricing to be rejected")
+	}
+	for _, toolName := range []string{"paid_tool", "metered_tool"} {
+		if !strings.Contains(err.Error(), toolName) {
+			t.Fatalf("expected error to mention %s, got %v", toolName, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package x402
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// TestModeEnv enables test mode for local integration runs. "1", "true" or
+// "plain" force the plain facilitator client; "approve" also approves every
+// payment verification without asking the facilitator. Never set it in
+// production.
+const TestModeEnv = "X402_TEST_MODE"
+
+// TestMode selects how far payment checks are relaxed for integration tests
+type TestMode int
+
+const (
+	// TestModeOff is normal operation
+	TestModeOff TestMode = iota
+	// TestModePlain uses the plain facilitator client even when CDP
+	// credentials are set, so no Coinbase JWTs are signed
+	TestModePlain
+	// TestModeAutoApprove is TestModePlain and also reports every payment
+	// valid without calling the facilitator's /verify
+	TestModeAutoApprove
+)
+
+// TestModeFromEnv reads TestModeEnv
+func TestModeFromEnv() TestMode {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(TestModeEnv))) {
+	case "1", "true", "plain":
+		return TestModePlain
+	case "approve", "auto-approve":
+		return TestModeAutoApprove
+	default:
+		return TestModeOff
+	}
+}
+
+// String returns the TestModeEnv value for mode
+func (mode TestMode) String() string {
+	switch mode {
+	case TestModePlain:
+		return "plain"
+	case TestModeAutoApprove:
+		return "approve"
+	default:
+		return "off"
+	}
+}
+
+// WithTestMode relaxes payment checks for integration tests against a local
+// mock facilitator. The middleware logs a warning at construction whenever
+// test mode is on.
+func WithTestMode(mode TestMode) MiddlewareOption {
+	return func(m *Middleware) {
+		m.testMode = mode
+	}
+}
+
+// warnTestMode logs loudly that payment checks are relaxed
+func warnTestMode(logger Logger, mode TestMode) {
+	logger.Warn(
+		"x402 TEST MODE ENABLED: payments are not fully checked; never enable this in production",
+		"mode", mode.String(),
+		"env", TestModeEnv,
+	)
+}
+
+// autoApproveFacilitator approves every verification and passes settlement
+// and /supported through to the wrapped facilitator
+type autoApproveFacilitator struct {
+	Facilitator
+}
+
+// Verify implements Facilitator
+func (f autoApproveFacilitator) Verify(ctx context.Context, payloadBytes, requirementsBytes []byte) (*VerifyResponse, error) {
+	payment, _, err := decodeFacilitatorRequest(payloadBytes, requirementsBytes)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyResponse{IsValid: true, Payer: payerFromPayload(payment.Payload)}, nil
+}
@@ -0,0 +1,92 @@
+package x402
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestFacilitatorConfigFromEnvTestModeSkipsJWTAuth(t *testing.T) {
+	secret := testCDPKeySecret(t)
+	t.Setenv("FACILITATOR_URL", "")
+	t.Setenv("CDP_API_KEY", "key-id")
+	t.Setenv("CDP_API_KEY_SECRET", secret)
+
+	t.Setenv(TestModeEnv, "")
+	config := FacilitatorConfigFromEnv("http://localhost:8003/v2/x402")
+	if config.AuthProvider == nil {
+		t.Fatal("expected Coinbase JWT auth outside test mode")
+	}
+	if config.URL == "http://localhost:8003/v2/x402" {
+		t.Fatalf("expected the Coinbase facilitator URL outside test mode, got %s", config.URL)
+	}
+
+	for _, value := range []string{"1", "approve"} {
+		t.Setenv(TestModeEnv, value)
+		config = FacilitatorConfigFromEnv("http://localhost:8003/v2/x402")
+		if config.AuthProvider != nil {
+			t.Fatalf("%s=%s: expected no JWT auth provider", TestModeEnv, value)
+		}
+		if config.URL != "http://localhost:8003/v2/x402" {
+			t.Fatalf("%s=%s: expected the default URL, got %s", TestModeEnv, value, config.URL)
+		}
+	}
+}
+
+func TestWithTestModeAutoApprovesVerification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		mode       TestMode
+		wantRun    bool
+		wantVerify int
+	}{
+		{name: "normal mode asks the facilitator", mode: TestModeOff, wantVerify: 1},
+		{name: "plain mode asks the facilitator", mode: TestModePlain, wantVerify: 1},
+		{name: "auto-approve skips verify", mode: TestModeAutoApprove, wantRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fake := NewFakeFacilitator()
+			fake.RejectPayments("invalid_signature")
+			warnings := &recordingLogger{}
+			m := NewMiddleware(
+				"http://localhost:8080",
+				"0x8D170Db9aB247E7013d024566093E13dc7b0f181",
+				Network("eip155:84532"),
+				"0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+				"http://facilitator.invalid",
+				WithFacilitator(fake),
+				WithLogger(warnings),
+				WithTestMode(tt.mode),
+			)
+			m.SetRetryPolicy(RetryPolicy{MaxAttempts: 1})
+			m.SetToolPrice("paid_tool", "10000")
+
+			ran := false
+			handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+				ran = true
+				return &mcp.CallToolResult{}, nil, nil
+			})
+			if _, _, err := handler(context.Background(), paidRequest(), nil); err != nil {
+				t.Fatalf("handler error: %v", err)
+			}
+			if ran != tt.wantRun {
+				t.Fatalf("expected handler run=%v, got %v", tt.wantRun, ran)
+			}
+			if got := len(fake.Verified()); got != tt.wantVerify {
+				t.Fatalf("expected %d facilitator verifies, got %d", tt.wantVerify, got)
+			}
+			warned := false
+			for _, record := range warnings.records {
+				warned = warned || record.level == "warn"
+			}
+			if warned != (tt.mode != TestModeOff) {
+				t.Fatalf("expected test mode warning=%v", tt.mode != TestModeOff)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package x402
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultFreeTrialWindow is how long a used free call counts against a
+// payer's allotment before it is forgotten
+const DefaultFreeTrialWindow = 30 * 24 * time.Hour
+
+// SetFreeTrial lets each payer make freeCalls calls to toolName without being
+// charged. The caller still attaches a payment, which the facilitator
+// verifies but which is not settled during the trial, so the allotment is
+// keyed on a payer address the caller has proven it controls. Callers without
+// a verified payment get no free calls. Used calls are recorded in the replay
+// store, so trials are disabled when that store is nil. A non-positive
+// freeCalls removes the trial.
+func (m *Middleware) SetFreeTrial(toolName string, freeCalls int) {
+	m.freeTrialsMu.Lock()
+	defer m.freeTrialsMu.Unlock()
+	if freeCalls <= 0 {
+		delete(m.freeTrials, toolName)
+		return
+	}
+	m.freeTrials[toolName] = freeCalls
+}
+
+// claimFreeTrial consumes one of the verified payer's free calls to toolName
+// and reports whether one was available. Store errors fail closed, so the
+// call falls through to settlement.
+func (m *Middleware) claimFreeTrial(ctx context.Context, toolName string, payment *PaymentPayload) bool {
+	m.freeTrialsMu.RLock()
+	freeCalls := m.freeTrials[toolName]
+	m.freeTrialsMu.RUnlock()
+	if freeCalls == 0 || m.replayStore == nil {
+		return false
+	}
+	payer := payerFromPayload(payment.Payload)
+	if payer == "" {
+		return false
+	}
+	caller := "payer:" + strings.ToLower(payer)
+
+	sum := sha256.Sum256([]byte(toolName + "\x00" + caller))
+	prefix := "trial:" + hex.EncodeToString(sum[:])
+	// Each free call reserves its own slot, so the count works with any
+	// ReplayStore without a counter primitive
+	for slot := 0; slot < freeCalls; slot++ {
+		reserved, err := m.replayStore.Reserve(ctx, fmt.Sprintf("%s:%d", prefix, slot), DefaultFreeTrialWindow)
+		if err != nil {
+			m.logger.Error("x402 free trial store error", "tool", toolName, "err", err)
+			return false
+		}
+		if reserved {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package x402
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// payerRequest is paidRequest signed by from, with a unique nonce so the
+// replay store accepts each call
+func payerRequest(from string, nonce int) *mcp.CallToolRequest {
+	req := paidRequest()
+	payment := req.Params.Meta[MetaKeyPayment].(map[string]any)
+	payment["payload"] = map[string]any{
+		"signature":     "0xdeadbeef",
+		"authorization": map[string]any{"from": from, "nonce": fmt.Sprintf("0x%064x", nonce)},
+	}
+	return req
+}
+
+func TestWrapToolHandlerFreeTrial(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	m := newFakeMiddleware(fake)
+	m.SetFreeTrial("paid_tool", 2)
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "sunny"}}}, nil, nil
+	})
+	call := func(from string, nonce int) *mcp.CallToolResult {
+		t.Helper()
+		result, _, err := handler(context.Background(), payerRequest(from, nonce), nil)
+		if err != nil {
+			t.Fatalf("handler error: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("expected call %d from %s to succeed, got %s", nonce, from, resultText(t, result))
+		}
+		return result
+	}
+
+	// Free window: two calls are verified but never settled
+	for nonce := 1; nonce <= 2; nonce++ {
+		if result := call("0xAlice", nonce); result.Meta[MetaKeyPaymentResponse] != nil {
+			t.Fatalf("expected free call %d not to settle", nonce)
+		}
+	}
+	if len(fake.Verified()) != 2 || len(fake.Settled()) != 0 {
+		t.Fatalf("expected verified, unsettled trial calls, got %d verifies and %d settles", len(fake.Verified()), len(fake.Settled()))
+	}
+
+	// Transition: the third call is settled
+	if result := call("0xAlice", 3); result.Meta[MetaKeyPaymentResponse] == nil {
+		t.Fatal("expected the call after the trial to settle")
+	}
+	if len(fake.Settled()) != 1 {
+		t.Fatalf("expected one settle, got %d", len(fake.Settled()))
+	}
+
+	// A distinct payer (case-insensitively) has their own allotment
+	if result := call("0xbob", 4); result.Meta[MetaKeyPaymentResponse] != nil {
+		t.Fatal("expected a new payer to get a free call")
+	}
+	if result := call("0XALICE", 5); result.Meta[MetaKeyPaymentResponse] == nil {
+		t.Fatal("expected the first payer to keep paying")
+	}
+}
+
+func TestWrapToolHandlerFreeTrialRequiresIdentity(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMiddleware(NewFakeFacilitator())
+	m.SetFreeTrial("paid_tool", 1)
+
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{}, nil, nil
+	})
+	result, _, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !result.IsError || result.Meta[MetaKeyPaymentRequired] == nil {
+		t.Fatalf("expected an anonymous caller to be asked for payment, got %+v", result)
+	}
+}
+
+func TestWrapToolHandlerFreeTrialRequiresVerifiedPayer(t *testing.T) {
+	t.Parallel()
+
+	fake := NewFakeFacilitator()
+	fake.RejectPayments("invalid_signature")
+	m := newFakeMiddleware(fake)
+	m.SetFreeTrial("paid_tool", 1)
+
+	var calls int
+	handler := WrapToolHandler(m, "paid_tool", func(ctx context.Context, req *mcp.CallToolRequest, in any) (*mcp.CallToolResult, any, error) {
+		calls++
+		return &mcp.CallToolResult{}, nil, nil
+	})
+
+	// A forged authorization.from gets no free call and uses up no slot
+	result, _, err := handler(context.Background(), payerRequest("0xVictim", 1), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if !result.IsError || calls != 0 {
+		t.Fatalf("expected an unverified payment to be refused, got %+v", result)
+	}
+
+	fake.RejectPayments("")
+	result, _, err = handler(context.Background(), payerRequest("0xVictim", 2), nil)
+	if err != nil {
+		t.Fatalf("handler error: %v", err)
+	}
+	if result.IsError || calls != 1 || len(fake.Settled()) != 0 {
+		t.Fatalf("expected the verified payer to keep its free call, got %+v", result)
+	}
+}
@@ -5,19 +5,67 @@ package x402
 // Based on: https://github.com/coinbase/x402/blob/main/specs/transports-v2/mcp.md
 
 import (
+	"encoding/json"
+	"fmt"
+
 	x402sdk "github.com/coinbase/x402/go"
 	"github.com/coinbase/x402/go/types"
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
 )
 
 // MCP-specific constants (not in official x402 which is HTTP-focused)
 const (
-	X402Version            = 2
-	MetaKeyPayment         = "x402/payment"
-	MetaKeyPaymentResponse = "x402/payment-response"
-	MetaKeyPaymentRequired = "x402/payment-required"
+	X402Version              = 2
+	MetaKeyPayment           = "x402/payment"
+	MetaKeyPaymentResponse   = "x402/payment-response"
+	MetaKeyPaymentRequired   = "x402/payment-required"
+	MetaKeySettlementPending = "x402/settlement-pending"
+	MetaKeyPaymentKey        = "x402/payment-key"
 	ErrorCodePaymentRequired = 402
 )
 
+// PaymentRequiredMode selects how WrapToolHandler reports a missing payment
+type PaymentRequiredMode int
+
+const (
+	// PaymentRequiredResult returns a CallToolResult with IsError set (legacy)
+	PaymentRequiredResult PaymentRequiredMode = iota
+	// PaymentRequiredError returns a JSON-RPC error with code 402 and the
+	// PaymentRequiredData in error.data, per the x402 MCP transport spec
+	PaymentRequiredError
+)
+
+// Payment error reasons reported in PaymentError.Reason
+const (
+	ErrorReasonPaymentRequired = "payment-required"
+	ErrorReasonVerifyFailed    = "verify-failed"
+	ErrorReasonSettleFailed    = "settle-failed"
+	ErrorReasonPaymentReplayed = "payment-replayed"
+	ErrorReasonPaymentMismatch = "payment-mismatch"
+)
+
+// PaymentError is the structured content of a failed paid tool call so
+// clients can branch on Reason instead of parsing the text content
+type PaymentError struct {
+	Code    int    `json:"code"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// RetryAfterSeconds hints when a rate-limited call may be retried
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty"`
+	// InvalidCode is the stable code for a facilitator rejection, and
+	// InvalidReason the facilitator's own reason string
+	InvalidCode   string `json:"invalidCode,omitempty"`
+	InvalidReason string `json:"invalidReason,omitempty"`
+}
+
+// Payment schemes supported by the MCP middleware
+const (
+	// SchemeExact charges exactly the advertised amount
+	SchemeExact = "exact"
+	// SchemeUpto authorizes up to the advertised amount and settles actual usage
+	SchemeUpto = "upto"
+)
+
 // Re-export official types for convenience
 type (
 	// PaymentRequirements is the official x402 payment requirements type
@@ -40,18 +88,37 @@ type (
 
 	// Network is the official x402 network type (CAIP-2 format)
 	Network = x402sdk.Network
+
+	// SupportedResponse is the official facilitator /supported response type
+	SupportedResponse = types.SupportedResponse
+
+	// SupportedKind is a single scheme/network pair supported by a facilitator
+	SupportedKind = types.SupportedKind
 )
 
 // PaymentRequiredData extends PaymentRequired with MCP-specific error field
 // This is the error.data payload for 402 responses in MCP
 type PaymentRequiredData struct {
-	X402Version int                   `json:"x402Version"`
-	Error       string                `json:"error"`
-	Resource    *ResourceInfo         `json:"resource"`
-	Accepts     []PaymentRequirements `json:"accepts"`
+	X402Version int                    `json:"x402Version"`
+	Error       string                 `json:"error"`
+	Resource    *ResourceInfo          `json:"resource"`
+	Accepts     []PaymentRequirements  `json:"accepts"`
 	Extensions  map[string]interface{} `json:"extensions,omitempty"`
 }
 
+// NewPaymentRequiredError builds the JSON-RPC 402 error for an unpaid tool call
+func NewPaymentRequiredError(data *PaymentRequiredData) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment required data: %w", err)
+	}
+	return &jsonrpc.Error{
+		Code:    ErrorCodePaymentRequired,
+		Message: data.Error,
+		Data:    payload,
+	}
+}
+
 // ToPaymentRequired converts PaymentRequiredData to official PaymentRequired
 func (p *PaymentRequiredData) ToPaymentRequired() *PaymentRequired {
 	return &PaymentRequired{
@@ -80,8 +147,44 @@ type PaymentResponse = SettleResponse
 
 // ToolPricingConfig defines pricing for a tool
 type ToolPricingConfig struct {
-	Amount  string  // Amount in smallest unit (e.g., wei, satoshi)
+	Scheme  string  // Payment scheme ("exact" or "upto"); empty means "exact"
+	Amount  string  // Amount in smallest unit (e.g., wei, satoshi); the maximum for "upto"
 	Asset   string  // Asset contract address or identifier
 	Network Network // Network identifier (e.g., "eip155:84532")
 	PayTo   string  // Recipient address
+	// Decimals is the asset's token decimals, used to compare prices across
+	// assets; nil means DefaultTokenDecimals. Set it with TokenDecimals
+	Decimals *int
+	// Approval, when set, is advertised in the requirements' extra so ERC-20
+	// wallets can approve an allowance before paying
+	Approval *ApprovalHint
+}
+
+// ApprovalHint describes the ERC-20 allowance a wallet should approve before
+// an exact-scheme transfer
+type ApprovalHint struct {
+	Spender string `json:"spender"`
+	// Allowance is in the asset's smallest unit; empty means the option's
+	// amount
+	Allowance string `json:"allowance,omitempty"`
+}
+
+// extra renders the hint for PaymentRequirements.Extra
+func (h *ApprovalHint) extra(amount string) map[string]interface{} {
+	allowance := h.Allowance
+	if allowance == "" {
+		allowance = amount
+	}
+	return map[string]interface{}{
+		"spender":   h.Spender,
+		"allowance": allowance,
+	}
+}
+
+// scheme returns the configured scheme, defaulting to SchemeExact
+func (c ToolPricingConfig) scheme() string {
+	if c.Scheme == "" {
+		return SchemeExact
+	}
+	return c.Scheme
 }